@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
@@ -19,8 +20,8 @@ import (
 	"github.com/briangreenhill/coachgpt/internal/auth"
 	"github.com/briangreenhill/coachgpt/internal/config"
 	"github.com/briangreenhill/coachgpt/internal/db"
-	"github.com/briangreenhill/coachgpt/internal/email"
 	"github.com/briangreenhill/coachgpt/internal/http/routes"
+	"github.com/briangreenhill/coachgpt/internal/llm"
 )
 
 func main() {
@@ -50,6 +51,26 @@ func main() {
 		"div":  func(a, b int32) int32 { return a / b },
 		"mod":  func(a, b int32) int32 { return a % b },
 		"divf": func(a, b float64) float64 { return a / b },
+		"pace": func(mps float64) string {
+			if mps <= 0 {
+				return "-"
+			}
+			secs := int(1000/mps + 0.5)
+			return fmt.Sprintf("%d:%02d /km", secs/60, secs%60)
+		},
+		"hm": func(secs int32) string {
+			if secs < 3600 {
+				return fmt.Sprintf("%dm", secs/60)
+			}
+			return fmt.Sprintf("%dh%02d", secs/3600, secs%3600/60)
+		},
+		"pct": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+		"clock": func(secs int32) string {
+			if secs < 3600 {
+				return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+			}
+			return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs%3600/60, secs%60)
+		},
 	}
 	tmpl := template.Must(template.New("").Funcs(funcMap).ParseGlob("web/templates/*.tmpl"))
 
@@ -59,24 +80,33 @@ func main() {
 		BaseURL: cfg.BaseURL,
 	}
 
+	// Athlete portal links, which a coach's link can't stand in for
+	aml := auth.AthletePortal([]byte(cfg.JWTSecret), cfg.BaseURL)
+
 	// Invite link helper
 	inv := auth.InviteLink{
 		Secret:  []byte(cfg.JWTSecret),
 		BaseURL: cfg.BaseURL,
 	}
 
-	// Mail sender (MailHog on localhost:1025)
-	sender := email.NewSMTPSender("localhost:1025", "no-reply@coachgpt.local")
+	// Language model, metered per coach
+	provider, err := llm.New(cfg.LLM)
+	if err != nil {
+		log.Fatalf("llm error: %v", err)
+	}
+	model := &llm.Metered{Provider: provider, Store: queries, MonthlyLimit: cfg.LLM.MonthlyTokenLimit}
 
 	// Router / server
 	s := routes.New(routes.ServerOptions{
-		Sess:   sess,
-		Tmpl:   tmpl,
-		Q:      queries,
-		Magic:  ml,
-		Invite: inv,
-		Cfg:    cfg,
-		Email:  sender,
+		Sess:         sess,
+		Tmpl:         tmpl,
+		Q:            queries,
+		DB:           pool,
+		Magic:        ml,
+		AthleteMagic: aml,
+		Invite:       inv,
+		Cfg:          cfg,
+		LLM:          model,
 	})
 	h := hlog.NewHandler(logger)(s.Router)
 
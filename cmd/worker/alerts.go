@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/briangreenhill/coachgpt/internal/config"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// alertWindowDays covers the chronic side of the acute:chronic ratio.
+const alertWindowDays = 28
+
+type alertEvaluator struct {
+	pool      *pgxpool.Pool
+	q         *db.Queries
+	cfg       config.AlertConfig
+	baseURL   string
+	redisAddr string
+}
+
+func (e alertEvaluator) rules() []training.Rule {
+	return []training.Rule{
+		training.ACWRRule{Max: e.cfg.ACWRMax},
+		training.VolumeJumpRule{MaxPct: e.cfg.WeeklyJumpPct},
+		training.HardStreakRule{MaxDays: e.cfg.MaxHardDays},
+		training.GapRule{MaxDays: e.cfg.MaxGapDays},
+	}
+}
+
+// evaluate builds the athlete's recent daily load series, stores any alerts
+// that fired and optionally, with them, an email telling the coach about
+// the new ones.
+func (e alertEvaluator) evaluate(ctx context.Context, athlete db.Athlete) error {
+	loc := training.Location(athlete.Tz)
+	now := time.Now().In(loc)
+	from := training.Day(now, loc).AddDate(0, 0, -(alertWindowDays - 1))
+
+	days, err := training.DailyLoads(ctx, e.q, athlete, from, now)
+	if err != nil {
+		return err
+	}
+	fired := training.Evaluate(days, e.rules())
+	if len(fired) == 0 {
+		return nil
+	}
+
+	var created []db.AthleteAlert
+	var sent db.EmailOutbox
+	err = inTx(ctx, e.pool, e.q, func(q *db.Queries) error {
+		for _, a := range fired {
+			row, err := q.CreateAthleteAlert(ctx, db.CreateAthleteAlertParams{
+				AthleteID: athlete.ID,
+				Kind:      a.Kind,
+				Day:       pgtype.Date{Time: training.Day(now, loc), Valid: true},
+				Value:     a.Value,
+				Threshold: a.Threshold,
+				Message:   a.Message,
+			})
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue // an alert of this kind is already open
+			}
+			if err != nil {
+				return fmt.Errorf("create athlete alert: %w", err)
+			}
+			created = append(created, row)
+		}
+		if len(created) == 0 || !e.cfg.EmailCoach {
+			return nil
+		}
+		var err error
+		sent, err = e.notifyCoach(ctx, q, athlete, created)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+	log.Printf("[alerts] athlete=%s raised %d alerts", athlete.ID, len(created))
+	if sent.ID != uuid.Nil {
+		enqueueEmail(ctx, e.redisAddr, sent.ID)
+	}
+	return nil
+}
+
+// notifyCoach stores the email about the new alerts with q.
+func (e alertEvaluator) notifyCoach(ctx context.Context, q *db.Queries, athlete db.Athlete, alerts []db.AthleteAlert) (db.EmailOutbox, error) {
+	coach, err := q.GetCoach(ctx, athlete.CoachID)
+	if err != nil {
+		return db.EmailOutbox{}, fmt.Errorf("get coach: %w", err)
+	}
+
+	messages := make([]string, len(alerts))
+	for i, a := range alerts {
+		messages[i] = a.Message
+	}
+	m, err := email.Render(coach.Email, outbox.KindAlert, map[string]any{
+		"Athlete": athlete.Name,
+		"Alerts":  messages,
+		"URL":     e.baseURL + "/dashboard",
+	})
+	if err != nil {
+		return db.EmailOutbox{}, err
+	}
+	return outbox.Add(ctx, q, outbox.KindAlert, m, outbox.Owner{
+		CoachID:   pgtype.UUID{Bytes: athlete.CoachID, Valid: true},
+		AthleteID: pgtype.UUID{Bytes: athlete.ID, Valid: true},
+	})
+}
+
+// evaluateAll sweeps every connected athlete, so gap alerts fire even when
+// nothing new has synced.
+func (e alertEvaluator) evaluateAll(ctx context.Context) error {
+	athletes, err := e.q.ListConnectedAthletes(ctx)
+	if err != nil {
+		return fmt.Errorf("list connected athletes: %w", err)
+	}
+	for _, a := range athletes {
+		if err := e.evaluate(ctx, a); err != nil {
+			log.Printf("[alerts] evaluate failed athlete=%s: %v", a.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/training"
+	"github.com/briangreenhill/coachgpt/internal/wellness"
+)
+
+const (
+	// checkinLinkTTL keeps the one-click link good for the rest of the day.
+	checkinLinkTTL = 24 * time.Hour
+	// unsubscribeTTL is long enough for a link in an old email to work.
+	unsubscribeTTL = 365 * 24 * time.Hour
+)
+
+type checkinMailer struct {
+	q           *db.Queries
+	link        auth.MagicLink
+	unsubscribe auth.MagicLink
+	hour        int
+	redisAddr   string
+}
+
+func (cm checkinMailer) handle(ctx context.Context, t *asynq.Task) error {
+	var p jobs.CheckinEmailPayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			log.Printf("[asynq] bad payload: %v", err)
+			return err
+		}
+	}
+	if p.AthleteID == "" {
+		return cm.enqueueDue(ctx, time.Now())
+	}
+	aid, err := uuid.Parse(p.AthleteID)
+	if err != nil {
+		log.Printf("[checkin] bad athlete id %q (dropping job)", p.AthleteID)
+		return nil
+	}
+	athlete, err := cm.q.GetAthlete(ctx, aid)
+	if err != nil {
+		return fmt.Errorf("get athlete: %w", err)
+	}
+	day, err := time.ParseInLocation(time.DateOnly, p.Day, training.Location(athlete.Tz))
+	if err != nil {
+		log.Printf("[checkin] bad day %q: %v (dropping job)", p.Day, err)
+		return nil
+	}
+	return cm.send(ctx, athlete, day)
+}
+
+// enqueueDue fans out one task per athlete for whom it is now the check-in
+// hour. The sweep runs hourly to follow every time zone; task IDs keep an
+// athlete to one email a day.
+func (cm checkinMailer) enqueueDue(ctx context.Context, now time.Time) error {
+	athletes, err := cm.q.ListConnectedAthletes(ctx)
+	if err != nil {
+		return fmt.Errorf("list athletes: %w", err)
+	}
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: cm.redisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	for _, a := range athletes {
+		local := now.In(training.Location(a.Tz))
+		if !a.Email.Valid || local.Hour() != cm.hour {
+			continue
+		}
+		day := local.Format(time.DateOnly)
+		payload, _ := json.Marshal(jobs.CheckinEmailPayload{AthleteID: a.ID.String(), Day: day})
+		_, err := client.EnqueueContext(ctx, asynq.NewTask(jobs.TaskCheckinEmail, payload),
+			asynq.TaskID("checkin-email:"+a.ID.String()+":"+day),
+			asynq.MaxRetry(3),
+			asynq.Retention(36*time.Hour),
+		)
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			log.Printf("[checkin] enqueue athlete=%s: %v", a.ID, err)
+		}
+	}
+	return nil
+}
+
+// send emails the athlete a link to the day's check-in, unless they've
+// already filled it in or have unsubscribed.
+func (cm checkinMailer) send(ctx context.Context, athlete db.Athlete, day time.Time) error {
+	if !athlete.Email.Valid {
+		return nil
+	}
+	off, err := cm.q.IsEmailSuppressed(ctx, db.IsEmailSuppressedParams{Address: athlete.Email.String, Kind: outbox.KindCheckin})
+	if err != nil {
+		return fmt.Errorf("check suppression: %w", err)
+	}
+	if off {
+		return nil
+	}
+	_, err = cm.q.GetWellnessCheckin(ctx, db.GetWellnessCheckinParams{AthleteID: athlete.ID, Day: wellness.Date(day)})
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("get check-in: %w", err)
+	}
+	unrated, err := cm.q.ListWorkoutsAwaitingFeedback(ctx, db.ListWorkoutsAwaitingFeedbackParams{
+		AthleteID: athlete.ID,
+		Since:     pgtype.Timestamptz{Time: day.AddDate(0, 0, -1), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("list unrated workouts: %w", err)
+	}
+
+	names := make([]string, len(unrated))
+	for i, w := range unrated {
+		name := "Untitled Workout"
+		if w.Name.Valid {
+			name = w.Name.String
+		}
+		names[i] = name + " (" + w.Sport + ")"
+	}
+	unsubscribe := cm.unsubscribe.URL(athlete.Email.String, unsubscribeTTL)
+	m, err := email.Render(athlete.Email.String, outbox.KindCheckin, map[string]any{
+		"Name":        athlete.Name,
+		"Unrated":     names,
+		"URL":         cm.link.URLTo(athlete.Email.String, checkinLinkTTL, "/athlete/checkin?day="+day.Format(time.DateOnly)),
+		"Unsubscribe": unsubscribe,
+	})
+	if err != nil {
+		return err
+	}
+	m.Unsubscribe = unsubscribe
+
+	// Only the athlete's: a daily email per athlete would bury the coach's
+	// own on their Emails page.
+	row, err := outbox.Add(ctx, cm.q, outbox.KindCheckin, m, outbox.Owner{AthleteID: pgtype.UUID{Bytes: athlete.ID, Valid: true}})
+	if err != nil {
+		return err
+	}
+	enqueueEmail(ctx, cm.redisAddr, row.ID)
+	log.Printf("[checkin] queued athlete=%s day=%s", athlete.ID, day.Format(time.DateOnly))
+	return nil
+}
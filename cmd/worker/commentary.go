@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/briangreenhill/coachgpt/internal/commentary"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+// commentaryWindow is how recent a synced workout must be to get a note,
+// so connecting an athlete doesn't draft one for every backfilled session.
+const commentaryWindow = 7 * 24 * time.Hour
+
+// commentator drafts workout notes on the commentary queue.
+type commentator struct {
+	q      *db.Queries
+	llm    llm.Provider
+	client *asynq.Client // nil when commentary is turned off
+}
+
+// enqueue asks for a note on a workout sync just stored. Task IDs are kept
+// for a day after the task finishes, so the overlapping sync windows
+// don't queue the same workout again.
+func (c commentator) enqueue(ctx context.Context, workoutID uuid.UUID, startedAt time.Time) {
+	if c.client == nil || time.Since(startedAt) > commentaryWindow {
+		return
+	}
+	payload, _ := json.Marshal(jobs.WorkoutCommentaryPayload{WorkoutID: workoutID.String()})
+	_, err := c.client.EnqueueContext(ctx, asynq.NewTask(jobs.TaskWorkoutCommentary, payload),
+		asynq.Queue(jobs.QueueCommentary),
+		asynq.TaskID("commentary:"+workoutID.String()),
+		asynq.MaxRetry(3),
+		asynq.Timeout(2*time.Minute),
+		asynq.Retention(24*time.Hour),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		log.Printf("[commentary] enqueue workout=%s: %v", workoutID, err)
+	}
+}
+
+func (c commentator) handle(ctx context.Context, t *asynq.Task) error {
+	var p jobs.WorkoutCommentaryPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		log.Printf("[asynq] bad payload: %v", err)
+		return err
+	}
+	wid, err := uuid.Parse(p.WorkoutID)
+	if err != nil {
+		log.Printf("[commentary] bad workout id %q (dropping job)", p.WorkoutID)
+		return nil
+	}
+	return c.run(ctx, wid)
+}
+
+// run drafts the note unless the workout already has one, so retries and
+// repeat syncs never replace a note the coach has seen.
+func (c commentator) run(ctx context.Context, wid uuid.UUID) error {
+	if _, err := c.q.GetWorkoutCommentary(ctx, wid); err == nil {
+		return nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("get commentary: %w", err)
+	}
+	w, err := c.q.GetWorkoutForCommentary(ctx, wid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // deleted since
+	}
+	if err != nil {
+		return fmt.Errorf("get workout: %w", err)
+	}
+	// A workout that looks wrong isn't worth describing until it's accepted
+	if w.ReviewStatus != "ok" && w.ReviewStatus != "accepted" {
+		log.Printf("[commentary] workout=%s is %s, skipping", wid, w.ReviewStatus)
+		return nil
+	}
+	athlete, err := c.q.GetAthlete(ctx, w.AthleteID)
+	if err != nil {
+		return fmt.Errorf("get athlete: %w", err)
+	}
+
+	facts, err := commentary.Collect(ctx, c.q, w, athlete, athleteThresholds(athlete))
+	if err != nil {
+		return fmt.Errorf("collect: %w", err)
+	}
+	note, model, err := commentary.Write(llm.ForCoach(ctx, w.CoachID, commentary.Feature), c.llm, facts)
+	if errors.Is(err, llm.ErrQuotaExceeded) {
+		log.Printf("[commentary] coach=%s over allowance, skipping workout=%s", w.CoachID, wid)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	data, err := json.Marshal(facts)
+	if err != nil {
+		return err
+	}
+	if _, err := c.q.CreateWorkoutCommentary(ctx, db.CreateWorkoutCommentaryParams{
+		WorkoutID:     wid,
+		Draft:         note,
+		Facts:         data,
+		Model:         model,
+		PromptVersion: commentary.Prompt.ID(),
+	}); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	log.Printf("[commentary] drafted workout=%s", wid)
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+type stravaLap struct {
+	LapIndex     int      `json:"lap_index"`
+	ElapsedTime  int      `json:"elapsed_time"`
+	MovingTime   int      `json:"moving_time"`
+	Distance     float64  `json:"distance"`
+	AverageSpeed float64  `json:"average_speed"`
+	AverageHR    *float64 `json:"average_heartrate,omitempty"`
+	MaxHR        *float64 `json:"max_heartrate,omitempty"`
+	AverageWatts *float64 `json:"average_watts,omitempty"`
+}
+
+// fetchStravaLaps loads the device laps for one activity.
+func fetchStravaLaps(ctx context.Context, client *http.Client, access string, activityID int64) ([]stravaLap, error) {
+	url := fmt.Sprintf("https://www.strava.com/api/v3/activities/%d/laps", activityID)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch strava laps: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("strava laps status %d: %s", resp.StatusCode, string(body))
+	}
+	var laps []stravaLap
+	if err := json.Unmarshal(body, &laps); err != nil {
+		return nil, fmt.Errorf("unmarshal strava laps: %w", err)
+	}
+	return laps, nil
+}
+
+// storeWorkoutLaps replaces the workout's laps.
+func storeWorkoutLaps(ctx context.Context, q *db.Queries, workoutID uuid.UUID, laps []stravaLap) error {
+	if err := q.DeleteWorkoutLaps(ctx, workoutID); err != nil {
+		return fmt.Errorf("delete workout laps: %w", err)
+	}
+	for i, l := range laps {
+		index := l.LapIndex
+		if index <= 0 {
+			index = i + 1
+		}
+		if err := q.CreateWorkoutLap(ctx, db.CreateWorkoutLapParams{
+			WorkoutID:  workoutID,
+			LapIndex:   int32(index),
+			ElapsedSec: int32(l.ElapsedTime),
+			MovingSec:  int32(l.MovingTime),
+			DistanceM:  l.Distance,
+			AvgSpeed:   pgtype.Float8{Float64: l.AverageSpeed, Valid: l.AverageSpeed > 0},
+			AvgHr:      optFloat(l.AverageHR),
+			MaxHr:      optFloat(l.MaxHR),
+			AvgWatts:   optFloat(l.AverageWatts),
+		}); err != nil {
+			return fmt.Errorf("create workout lap: %w", err)
+		}
+	}
+	return nil
+}
+
+func optFloat(v *float64) pgtype.Float8 {
+	if v == nil || *v <= 0 {
+		return pgtype.Float8{}
+	}
+	return pgtype.Float8{Float64: *v, Valid: true}
+}
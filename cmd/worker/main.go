@@ -15,9 +15,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/compliance"
 	"github.com/briangreenhill/coachgpt/internal/config"
 	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
 	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/training"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -34,6 +41,42 @@ func main() {
 	defer pool.Close()
 	q := db.New(pool)
 
+	// Mail sender, MailHog on localhost:1025 unless configured
+	sender, err := email.New(cfg.Email)
+	if err != nil {
+		log.Fatal("email:", err)
+	}
+	if c, ok := sender.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	alerts := alertEvaluator{pool: pool, q: q, cfg: cfg.Alerts, baseURL: cfg.BaseURL, redisAddr: cfg.RedisAddr}
+
+	// Language model, metered per coach
+	provider, err := llm.New(cfg.LLM)
+	if err != nil {
+		log.Fatal("llm:", err)
+	}
+	model := &llm.Metered{Provider: provider, Store: q, MonthlyLimit: cfg.LLM.MonthlyTokenLimit}
+	reports := reportWriter{pool: pool, q: q, llm: model, redisAddr: cfg.RedisAddr, baseURL: cfg.BaseURL}
+
+	mail := mailer{q: q, email: sender, redisAddr: cfg.RedisAddr}
+
+	checkins := checkinMailer{
+		q:           q,
+		link:        auth.AthletePortal([]byte(cfg.JWTSecret), cfg.BaseURL),
+		unsubscribe: auth.Unsubscribe([]byte(cfg.JWTSecret), cfg.BaseURL, outbox.KindCheckin),
+		hour:        cfg.Wellness.CheckinHour,
+		redisAddr:   cfg.RedisAddr,
+	}
+
+	notes := commentator{q: q, llm: model}
+	drafts := planDrafter{pool: pool, q: q, llm: model}
+	if cfg.LLM.WorkoutCommentary {
+		notes.client = asynq.NewClient(asynq.RedisClientOpt{Addr: cfg.RedisAddr})
+		defer notes.client.Close()
+	}
+
 	srv := asynq.NewServer(asynq.RedisClientOpt{Addr: cfg.RedisAddr}, asynq.Config{
 		Concurrency:    8,
 		StrictPriority: false,
@@ -52,7 +95,7 @@ func main() {
 		}
 		log.Printf("[sync] start athlete=%s", p.AthleteID)
 		start := time.Now()
-		err := syncStravaForAthlete(ctx, q, cfg.Strava.ClientID, cfg.Strava.ClientSecret, p)
+		err := syncStravaForAthlete(ctx, q, cfg.Strava.ClientID, cfg.Strava.ClientSecret, p, notes)
 		duration := time.Since(start)
 
 		if err != nil {
@@ -65,9 +108,84 @@ func main() {
 			return nil // don't retry permanent failures
 		}
 		log.Printf("[sync] done athlete=%s duration=%v", p.AthleteID, duration)
+
+		aid, err := uuid.Parse(p.AthleteID)
+		if err != nil {
+			log.Printf("[alerts] bad athlete id %q (skipping)", p.AthleteID)
+			return nil
+		}
+		athlete, err := q.GetAthlete(ctx, aid)
+		if err != nil {
+			log.Printf("[alerts] get athlete=%s: %v", p.AthleteID, err)
+			return nil
+		}
+		if err := alerts.evaluate(ctx, athlete); err != nil {
+			log.Printf("[alerts] evaluate failed athlete=%s: %v", p.AthleteID, err)
+		}
 		return nil
 	})
 
+	mux.HandleFunc(jobs.TaskEvaluateAlerts, func(ctx context.Context, t *asynq.Task) error {
+		var p jobs.EvaluateAlertsPayload
+		if len(t.Payload()) > 0 {
+			if err := json.Unmarshal(t.Payload(), &p); err != nil {
+				log.Printf("[asynq] bad payload: %v", err)
+				return err
+			}
+		}
+		if p.AthleteID == "" {
+			return alerts.evaluateAll(ctx)
+		}
+		aid, err := uuid.Parse(p.AthleteID)
+		if err != nil {
+			log.Printf("[alerts] bad athlete id %q (dropping job)", p.AthleteID)
+			return nil
+		}
+		athlete, err := q.GetAthlete(ctx, aid)
+		if err != nil {
+			return fmt.Errorf("get athlete: %w", err)
+		}
+		return alerts.evaluate(ctx, athlete)
+	})
+
+	mux.HandleFunc(jobs.TaskWeeklyReport, reports.handle)
+	mux.HandleFunc(jobs.TaskWorkoutCommentary, notes.handle)
+	mux.HandleFunc(jobs.TaskGeneratePlanDraft, drafts.handle)
+	mux.HandleFunc(jobs.TaskCheckinEmail, checkins.handle)
+	mux.HandleFunc(jobs.TaskSendEmail, mail.handle)
+
+	// Commentary and plan drafts get their own server so model calls
+	// can't take the workers sync needs
+	notesSrv := asynq.NewServer(asynq.RedisClientOpt{Addr: cfg.RedisAddr}, asynq.Config{
+		Concurrency: max(cfg.LLM.CommentaryConcurrency, 1),
+		Queues:      map[string]int{jobs.QueueCommentary: 1},
+	})
+
+	// Daily sweep so gap alerts fire for athletes with nothing new to sync
+	scheduler := asynq.NewScheduler(asynq.RedisClientOpt{Addr: cfg.RedisAddr}, nil)
+	if _, err := scheduler.Register("0 6 * * *", asynq.NewTask(jobs.TaskEvaluateAlerts, nil)); err != nil {
+		log.Fatalf("register alert sweep: %v", err)
+	}
+	// Monday-morning digests, covering the week just ended. Hourly, so each
+	// coach's arrives on Monday morning in their time zone.
+	if _, err := scheduler.Register("0 * * * *", asynq.NewTask(jobs.TaskWeeklyReport, nil)); err != nil {
+		log.Fatalf("register weekly reports: %v", err)
+	}
+	// Picks up emails whose send task was lost
+	if _, err := scheduler.Register("*/5 * * * *", asynq.NewTask(jobs.TaskSendEmail, nil)); err != nil {
+		log.Fatalf("register email sweep: %v", err)
+	}
+	// Hourly, so each athlete's check-in email goes out at their local hour
+	if cfg.Wellness.CheckinEmails {
+		if _, err := scheduler.Register("0 * * * *", asynq.NewTask(jobs.TaskCheckinEmail, nil)); err != nil {
+			log.Fatalf("register check-in emails: %v", err)
+		}
+	}
+	if err := scheduler.Start(); err != nil {
+		log.Fatalf("start scheduler: %v", err)
+	}
+	defer scheduler.Shutdown()
+
 	log.Println("Worker running...")
 
 	// Setup graceful shutdown
@@ -82,6 +200,10 @@ func main() {
 		}
 		close(serverErr)
 	}()
+	if err := notesSrv.Start(mux); err != nil {
+		log.Fatalf("start commentary server: %v", err)
+	}
+	defer notesSrv.Shutdown()
 
 	// Wait for shutdown signal or server error
 	select {
@@ -141,6 +263,9 @@ type stravaActivity struct {
 	DistanceM   float64  `json:"distance"`
 	TotalElevM  float64  `json:"total_elevation_gain"`
 	AvgHR       *float64 `json:"average_heartrate,omitempty"`
+	MaxHR       *float64 `json:"max_heartrate,omitempty"`
+	MaxSpeed    float64  `json:"max_speed"`
+	Manual      bool     `json:"manual"`
 }
 
 type tokenResp struct {
@@ -149,7 +274,7 @@ type tokenResp struct {
 	ExpiresAt    int64  `json:"expires_at"`
 }
 
-func syncStravaForAthlete(ctx context.Context, q *db.Queries, clientID, clientSecret string, p jobs.SyncStravaPayload) error {
+func syncStravaForAthlete(ctx context.Context, q *db.Queries, clientID, clientSecret string, p jobs.SyncStravaPayload, notes commentator) error {
 	aid := uuid.MustParse(p.AthleteID)
 
 	athlete, err := q.GetAthlete(ctx, aid)
@@ -180,6 +305,9 @@ func syncStravaForAthlete(ctx context.Context, q *db.Queries, clientID, clientSe
 		}
 	}
 
+	hr := training.HeartRateFor(int(athlete.MaxHr.Int32), int(athlete.RestingHr.Int32))
+	loc := training.Location(athlete.Tz)
+
 	since := time.Now().AddDate(0, 0, -14) // default 14 days
 	if athlete.LastStravaSync.Valid {
 		since = athlete.LastStravaSync.Time.Add(-12 * time.Hour) // back up 12 hours to be safe
@@ -247,8 +375,9 @@ func syncStravaForAthlete(ctx context.Context, q *db.Queries, clientID, clientSe
 				avgHR = int(*a.AvgHR)
 			}
 
-			// Upsert
-			err := q.UpsertWorkout(ctx, db.UpsertWorkoutParams{
+			// Upsert. A load from streams stays until they give a new one.
+			hrLoad := training.HRTSS(a.ElapsedSecs, avgHR, hr)
+			workoutID, err := q.UpsertWorkout(ctx, db.UpsertWorkoutParams{
 				AthleteID:   aid,
 				SourceID:    a.ID,
 				Name:        pgtype.Text{String: a.Name, Valid: a.Name != ""},
@@ -259,11 +388,41 @@ func syncStravaForAthlete(ctx context.Context, q *db.Queries, clientID, clientSe
 				ElevGainM:   pgtype.Float8{Float64: a.TotalElevM, Valid: a.TotalElevM > 0},
 				AvgHr:       pgtype.Int4{Int32: int32(avgHR), Valid: avgHR > 0},
 				RawJson:     bodySliceToJSONB(a),
+				Load:        pgtype.Float8{Float64: hrLoad, Valid: true},
 			})
 			if err != nil {
 				return fmt.Errorf("upsert workout: %w", err)
 			}
 			total++
+
+			// Stream metrics are best-effort; the next sync window retries them
+			var streams metrics.Streams
+			if !a.Manual {
+				streams, err = fetchStravaStreams(ctx, httpClient, access, a.ID)
+				if err == nil {
+					err = storeWorkoutMetrics(ctx, q, workoutID, a.Type, hrLoad, streams, athleteThresholds(athlete))
+				}
+				if err != nil {
+					log.Printf("[sync] athlete=%s activity=%d metrics: %v", aid, a.ID, err)
+				}
+				laps, err := fetchStravaLaps(ctx, httpClient, access, a.ID)
+				if err == nil {
+					err = storeWorkoutLaps(ctx, q, workoutID, laps)
+				}
+				if err != nil {
+					log.Printf("[sync] athlete=%s activity=%d laps: %v", aid, a.ID, err)
+				}
+			}
+
+			if err := reviewWorkout(ctx, q, athlete, workoutID, a, startedAt, streams); err != nil {
+				log.Printf("[sync] athlete=%s activity=%d review: %v", aid, a.ID, err)
+			}
+
+			if err := compliance.MatchDay(ctx, q, aid, startedAt.In(loc), loc); err != nil {
+				log.Printf("[sync] athlete=%s activity=%d plan match: %v", aid, a.ID, err)
+			}
+
+			notes.enqueue(ctx, workoutID, startedAt)
 		}
 		page++
 	}
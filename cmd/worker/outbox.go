@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+)
+
+type mailer struct {
+	q         *db.Queries
+	email     email.Sender
+	redisAddr string
+}
+
+func (m mailer) handle(ctx context.Context, t *asynq.Task) error {
+	var p jobs.SendEmailPayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			log.Printf("[asynq] bad payload: %v", err)
+			return err
+		}
+	}
+	if p.EmailID == "" {
+		return m.sweep(ctx)
+	}
+	id, err := uuid.Parse(p.EmailID)
+	if err != nil {
+		log.Printf("[email] bad id %q: %v (dropping job)", p.EmailID, err)
+		return nil
+	}
+	retry, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if err := outbox.Deliver(ctx, m.q, m.email, id, retry >= maxRetry); err != nil {
+		log.Printf("[email] send %s attempt %d/%d: %v", id, retry+1, maxRetry+1, err)
+		return err
+	}
+	return nil
+}
+
+// sweep queues pending emails whose send task never made it into the
+// queue, say because Redis was down when the request stored them.
+func (m mailer) sweep(ctx context.Context) error {
+	ids, err := m.q.ListStalePendingEmails(ctx, pgtype.Timestamptz{Time: time.Now().Add(-outbox.StaleAfter), Valid: true})
+	if err != nil {
+		return fmt.Errorf("list pending emails: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: m.redisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	for _, id := range ids {
+		if err := outbox.Enqueue(ctx, client, id); err != nil {
+			log.Printf("[email] enqueue %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// inTx runs fn with queries bound to one transaction, committing only when
+// it succeeds.
+func inTx(ctx context.Context, pool *pgxpool.Pool, q *db.Queries, fn func(q *db.Queries) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// enqueueEmail queues a stored email once its transaction has committed. A
+// failure only delays it: the sweep picks up pending emails.
+func enqueueEmail(ctx context.Context, redisAddr string, id uuid.UUID) {
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	if err := outbox.Enqueue(ctx, client, id); err != nil {
+		log.Printf("[email] enqueue %s failed, leaving it to the sweep: %v", id, err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/plandraft"
+)
+
+// planDrafter generates the plan drafts coaches ask for.
+type planDrafter struct {
+	pool *pgxpool.Pool
+	q    *db.Queries
+	llm  llm.Provider
+}
+
+// handle generates the draft. Errors are recorded on the draft so the
+// coach sees why it's taking long; on the last attempt, or when the coach
+// is over their allowance, the draft is marked failed for them to retry.
+func (p planDrafter) handle(ctx context.Context, t *asynq.Task) error {
+	var payload jobs.GeneratePlanDraftPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		log.Printf("[asynq] bad payload: %v", err)
+		return err
+	}
+	id, err := uuid.Parse(payload.DraftID)
+	if err != nil {
+		log.Printf("[plandraft] bad draft id %q (dropping job)", payload.DraftID)
+		return nil
+	}
+	err = p.run(ctx, id)
+	if err == nil {
+		return nil
+	}
+	log.Printf("[plandraft] draft=%s: %v", id, err)
+
+	status, msg := plandraft.StatusGenerating, err.Error()
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	switch {
+	case errors.Is(err, llm.ErrQuotaExceeded):
+		status, msg = plandraft.StatusFailed, "This month's AI allowance is used up."
+	case retried >= maxRetry:
+		status = plandraft.StatusFailed
+	}
+	if err := p.q.SetPlanDraftError(context.WithoutCancel(ctx), db.SetPlanDraftErrorParams{
+		ID:     id,
+		Status: status,
+		Error:  pgtype.Text{String: msg, Valid: true},
+	}); err != nil {
+		log.Printf("[plandraft] record error draft=%s: %v", id, err)
+	}
+	if status == plandraft.StatusFailed {
+		return nil
+	}
+	return err
+}
+
+// run generates the draft if it is still waiting for its plan.
+func (p planDrafter) run(ctx context.Context, id uuid.UUID) error {
+	d, err := p.q.GetPlanDraftByID(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // discarded since
+	}
+	if err != nil {
+		return fmt.Errorf("get draft: %w", err)
+	}
+	if d.Status != plandraft.StatusGenerating {
+		return nil
+	}
+	athlete, err := p.q.GetAthlete(ctx, d.AthleteID)
+	if err != nil {
+		return fmt.Errorf("get athlete: %w", err)
+	}
+
+	brief, err := plandraft.Collect(ctx, p.q, d, athlete, time.Now())
+	if err != nil {
+		return fmt.Errorf("collect: %w", err)
+	}
+	res, err := plandraft.Generate(llm.ForCoach(ctx, d.CoachID, plandraft.Feature), p.llm, brief)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+	if _, err := plandraft.Save(ctx, p.q.WithTx(tx), d, plandraft.Name(brief), res); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("[plandraft] draft=%s ready: %d sessions, %d dropped", id, len(res.Sessions), len(res.Warnings))
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/report"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// reportHour is the local hour on Monday when a coach's report is due.
+const reportHour = 6
+
+type reportWriter struct {
+	pool      *pgxpool.Pool
+	q         *db.Queries
+	llm       llm.Provider
+	redisAddr string
+	baseURL   string
+}
+
+func (rw reportWriter) handle(ctx context.Context, t *asynq.Task) error {
+	var p jobs.WeeklyReportPayload
+	if len(t.Payload()) > 0 {
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			log.Printf("[asynq] bad payload: %v", err)
+			return err
+		}
+	}
+	if p.CoachID == "" {
+		return rw.enqueueDue(ctx, time.Now())
+	}
+	cid, err := uuid.Parse(p.CoachID)
+	if err != nil {
+		log.Printf("[report] bad coach id %q (dropping job)", p.CoachID)
+		return nil
+	}
+	coach, err := rw.q.GetCoach(ctx, cid)
+	if err != nil {
+		return fmt.Errorf("get coach: %w", err)
+	}
+	week := lastWeek(time.Now(), training.Location(coach.Tz))
+	if p.WeekStart != "" {
+		if week, err = time.Parse(time.DateOnly, p.WeekStart); err != nil {
+			log.Printf("[report] bad week %q: %v (dropping job)", p.WeekStart, err)
+			return nil
+		}
+	}
+	return rw.run(ctx, coach, week)
+}
+
+// lastWeek is the calendar date of the Monday before the one starting now's
+// week in loc.
+func lastWeek(now time.Time, loc *time.Location) time.Time {
+	m := training.WeekStart(now, loc).AddDate(0, 0, -7)
+	return time.Date(m.Year(), m.Month(), m.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// reportDue reports whether it is reportHour on a Monday in loc, and if so
+// the week the report covers.
+func reportDue(now time.Time, loc *time.Location) (time.Time, bool) {
+	local := now.In(loc)
+	if local.Weekday() != time.Monday || local.Hour() != reportHour {
+		return time.Time{}, false
+	}
+	return lastWeek(now, loc), true
+}
+
+// enqueueDue fans out one task per coach for whom it is Monday morning.
+// The sweep runs hourly to follow every time zone, and each coach is
+// retried on their own. Task IDs dedupe a sweep that runs twice.
+func (rw reportWriter) enqueueDue(ctx context.Context, now time.Time) error {
+	coaches, err := rw.q.ListCoachesWithAthletes(ctx)
+	if err != nil {
+		return fmt.Errorf("list coaches: %w", err)
+	}
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: rw.redisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	for _, c := range coaches {
+		monday, ok := reportDue(now, training.Location(c.Tz))
+		if !ok {
+			continue
+		}
+		week := monday.Format(time.DateOnly)
+		payload, _ := json.Marshal(jobs.WeeklyReportPayload{CoachID: c.ID.String(), WeekStart: week})
+		_, err := client.EnqueueContext(ctx, asynq.NewTask(jobs.TaskWeeklyReport, payload),
+			asynq.TaskID("weekly-report:"+c.ID.String()+":"+week),
+			asynq.MaxRetry(3),
+			asynq.Timeout(5*time.Minute),
+		)
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			log.Printf("[report] enqueue coach=%s: %v", c.ID, err)
+		}
+	}
+	return nil
+}
+
+// run writes, stores and emails one coach's report. A report already
+// stored for the week is reused, so a retry after a failed send doesn't
+// pay for the model again. The email is stored with the report marked
+// emailed, so a retry never queues it twice.
+func (rw reportWriter) run(ctx context.Context, coach db.Coach, week time.Time) error {
+	start := time.Now()
+	date := pgtype.Date{Time: week, Valid: true}
+	row, err := rw.q.GetWeeklyReportForWeek(ctx, db.GetWeeklyReportForWeekParams{CoachID: coach.ID, WeekStart: date})
+	switch {
+	case err == nil && row.EmailedAt.Valid:
+		return nil
+	case errors.Is(err, pgx.ErrNoRows):
+		if row, err = rw.write(ctx, coach, week); err != nil {
+			return err
+		}
+	case err != nil:
+		return fmt.Errorf("get weekly report: %w", err)
+	}
+
+	m, err := email.Render(coach.Email, outbox.KindWeeklyReport, map[string]any{
+		"Week":       week.Format("Jan 2"),
+		"Summary":    row.Summary,
+		"Highlights": row.Highlights,
+		"Concerns":   row.Concerns,
+		"URL":        rw.baseURL + "/reports/" + row.ID.String(),
+	})
+	if err != nil {
+		return err
+	}
+	var sent db.EmailOutbox
+	err = inTx(ctx, rw.pool, rw.q, func(q *db.Queries) error {
+		var err error
+		if sent, err = outbox.Add(ctx, q, outbox.KindWeeklyReport, m, outbox.Owner{CoachID: pgtype.UUID{Bytes: coach.ID, Valid: true}}); err != nil {
+			return err
+		}
+		if err := q.MarkWeeklyReportEmailed(ctx, row.ID); err != nil {
+			return fmt.Errorf("mark weekly report emailed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	enqueueEmail(ctx, rw.redisAddr, sent.ID)
+	log.Printf("[report] done coach=%s week=%s model=%q duration=%v", coach.ID, week.Format(time.DateOnly), row.Model, time.Since(start))
+	return nil
+}
+
+func (rw reportWriter) write(ctx context.Context, coach db.Coach, week time.Time) (db.WeeklyReport, error) {
+	facts, err := report.Collect(ctx, rw.q, coach, week)
+	if err != nil {
+		return db.WeeklyReport{}, fmt.Errorf("collect week: %w", err)
+	}
+	n, model, err := report.Write(llm.ForCoach(ctx, coach.ID, report.Feature), rw.llm, facts)
+	if err != nil {
+		return db.WeeklyReport{}, fmt.Errorf("write report: %w", err)
+	}
+	data, err := json.Marshal(facts)
+	if err != nil {
+		return db.WeeklyReport{}, err
+	}
+	row, err := rw.q.CreateWeeklyReport(ctx, db.CreateWeeklyReportParams{
+		CoachID:       coach.ID,
+		WeekStart:     pgtype.Date{Time: week, Valid: true},
+		Facts:         data,
+		Summary:       n.Summary,
+		Highlights:    n.Highlights,
+		Concerns:      n.Concerns,
+		Model:         model,
+		PromptVersion: report.Prompt.ID(),
+	})
+	if err != nil {
+		return db.WeeklyReport{}, fmt.Errorf("create weekly report: %w", err)
+	}
+	return row, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportDue(t *testing.T) {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skip(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, c := range []struct {
+		now  string // UTC
+		loc  *time.Location
+		due  bool
+		week string
+	}{
+		// 06:00 UTC Monday is still Sunday evening in California.
+		{"2025-06-09T06:00:00Z", pacific, false, ""},
+		{"2025-06-09T13:00:00Z", pacific, true, "2025-06-02"},
+		{"2025-06-09T14:00:00Z", pacific, false, ""},
+		// And already Monday 08:00 in Berlin.
+		{"2025-06-09T04:00:00Z", berlin, true, "2025-06-02"},
+		{"2025-06-09T06:00:00Z", berlin, false, ""},
+		{"2025-06-09T06:00:00Z", time.UTC, true, "2025-06-02"},
+	} {
+		now, _ := time.Parse(time.RFC3339, c.now)
+		week, due := reportDue(now, c.loc)
+		if due != c.due || (due && week.Format(time.DateOnly) != c.week) {
+			t.Errorf("reportDue(%s, %s) = %s, %v; want %s, %v", c.now, c.loc, week.Format(time.DateOnly), due, c.week, c.due)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/anomaly"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+)
+
+// reviewWorkout runs the anomaly checks for a freshly synced activity and
+// records the flags. Flagged workouts drop out of load and fitness queries
+// until the coach accepts them.
+func reviewWorkout(ctx context.Context, q *db.Queries, athlete db.Athlete, workoutID uuid.UUID, a stravaActivity, startedAt time.Time, streams metrics.Streams) error {
+	rows, err := q.ListWorkoutHistory(ctx, db.ListWorkoutHistoryParams{
+		AthleteID: athlete.ID,
+		Sport:     a.Type,
+		StartedAt: pgtype.Timestamptz{Time: startedAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("list workout history: %w", err)
+	}
+	history := make([]anomaly.Workout, 0, len(rows))
+	for _, r := range rows {
+		history = append(history, anomaly.Workout{
+			Sport:       a.Type,
+			DurationSec: float64(r.DurationSec),
+			DistanceM:   r.DistanceM.Float64,
+			AvgHR:       float64(r.AvgHr.Int32),
+		})
+	}
+
+	w := anomaly.Workout{
+		Sport:       a.Type,
+		DurationSec: float64(a.ElapsedSecs),
+		DistanceM:   a.DistanceM,
+		MaxSpeed:    a.MaxSpeed,
+	}
+	if a.AvgHR != nil {
+		w.AvgHR = *a.AvgHR
+	}
+	if a.MaxHR != nil {
+		w.MaxHR = *a.MaxHR
+	}
+
+	flags := anomaly.Check(w, streams, float64(athlete.MaxHr.Int32), history)
+	msgs := make([]string, 0, len(flags))
+	for _, f := range flags {
+		msgs = append(msgs, f.String())
+	}
+	if len(msgs) > 0 {
+		log.Printf("[sync] athlete=%s activity=%d flagged: %v", athlete.ID, a.ID, msgs)
+	}
+
+	if err := q.SetWorkoutReviewFlags(ctx, db.SetWorkoutReviewFlagsParams{
+		Flags: msgs,
+		ID:    workoutID,
+	}); err != nil {
+		return fmt.Errorf("set workout review flags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+const streamKeys = "time,heartrate,velocity_smooth,watts,altitude,cadence,moving"
+
+type stravaStream struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// fetchStravaStreams loads the sampled streams for one activity.
+func fetchStravaStreams(ctx context.Context, client *http.Client, access string, activityID int64) (metrics.Streams, error) {
+	url := fmt.Sprintf("https://www.strava.com/api/v3/activities/%d/streams?keys=%s&key_by_type=true", activityID, streamKeys)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+	resp, err := client.Do(req)
+	if err != nil {
+		return metrics.Streams{}, fmt.Errorf("fetch strava streams: %w", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return metrics.Streams{}, nil // manual activities have no streams
+	}
+	if resp.StatusCode >= 300 {
+		return metrics.Streams{}, fmt.Errorf("strava streams status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var raw map[string]stravaStream
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return metrics.Streams{}, fmt.Errorf("unmarshal strava streams: %w", err)
+	}
+
+	var s metrics.Streams
+	for key, dst := range map[string]*[]float64{
+		"time":            &s.Time,
+		"heartrate":       &s.HeartRate,
+		"velocity_smooth": &s.Velocity,
+		"watts":           &s.Watts,
+		"altitude":        &s.Altitude,
+		"cadence":         &s.Cadence,
+	} {
+		if st, ok := raw[key]; ok {
+			if err := json.Unmarshal(st.Data, dst); err != nil {
+				return metrics.Streams{}, fmt.Errorf("unmarshal %s stream: %w", key, err)
+			}
+		}
+	}
+	if st, ok := raw["moving"]; ok {
+		if err := json.Unmarshal(st.Data, &s.Moving); err != nil {
+			return metrics.Streams{}, fmt.Errorf("unmarshal moving stream: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// athleteThresholds converts the athlete's stored thresholds for metrics.
+func athleteThresholds(a db.Athlete) metrics.Thresholds {
+	th := metrics.Thresholds{
+		HRZones: training.HeartRateFor(int(a.MaxHr.Int32), int(a.RestingHr.Int32)).ZoneBounds(),
+	}
+	if a.FtpWatts.Valid {
+		th.FTP = float64(a.FtpWatts.Int32)
+	}
+	if a.ThresholdPaceSec.Valid && a.ThresholdPaceSec.Int32 > 0 {
+		th.ThresholdSpeed = 1000 / float64(a.ThresholdPaceSec.Int32)
+	}
+	return th
+}
+
+// storeWorkoutMetrics derives stream metrics and saves them for the workout.
+// When intensity is known the workout's load is replaced by power or pace
+// TSS, which is more precise than the heart-rate estimate set at upsert.
+// When it isn't, hrLoad, that estimate, replaces any earlier stream load.
+func storeWorkoutMetrics(ctx context.Context, q *db.Queries, workoutID uuid.UUID, sport string, hrLoad float64, s metrics.Streams, th metrics.Thresholds) error {
+	if len(s.Time) == 0 {
+		return nil
+	}
+	sum := metrics.Summarize(s, sport, th)
+	params := db.UpsertWorkoutMetricsParams{
+		WorkoutID: workoutID,
+		MovingSec: int32(sum.MovingSec),
+		GapSpeed:  pgtype.Float8{Float64: sum.GAPSpeed, Valid: sum.GAPSpeed > 0},
+	}
+	if d := sum.Decoupling; sum.HasDecoupling {
+		params.DecouplingBasis = pgtype.Text{String: d.Basis, Valid: true}
+		params.DecouplingPct = pgtype.Float8{Float64: d.Pct, Valid: true}
+		params.EfficiencyFactor = pgtype.Float8{Float64: d.EF, Valid: true}
+	}
+	if p := sum.Power; sum.HasPower {
+		params.AvgPower = pgtype.Float8{Float64: p.Average, Valid: true}
+		params.NormalizedPower = pgtype.Float8{Float64: p.Normalized, Valid: true}
+		params.VariabilityIndex = pgtype.Float8{Float64: p.VI, Valid: true}
+	}
+	params.IntensityFactor = pgtype.Float8{Float64: sum.IF, Valid: sum.IF > 0}
+	for _, z := range sum.ZoneSec {
+		params.ZoneSec = append(params.ZoneSec, int32(z))
+	}
+	if err := q.UpsertWorkoutMetrics(ctx, params); err != nil {
+		return fmt.Errorf("upsert workout metrics: %w", err)
+	}
+
+	if sum.TSS > 0 {
+		if err := q.UpdateWorkoutLoad(ctx, db.UpdateWorkoutLoadParams{
+			ID:   workoutID,
+			Load: pgtype.Float8{Float64: sum.TSS, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("update workout load: %w", err)
+		}
+		return nil
+	}
+	if err := q.ResetWorkoutLoad(ctx, db.ResetWorkoutLoadParams{
+		Load: pgtype.Float8{Float64: hrLoad, Valid: true},
+		ID:   workoutID,
+	}); err != nil {
+		return fmt.Errorf("reset workout load: %w", err)
+	}
+	return nil
+}
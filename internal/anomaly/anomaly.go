@@ -0,0 +1,200 @@
+// Package anomaly flags synced workouts whose data is implausible, so bad
+// GPS, misread heart-rate straps and mislabelled sports stay out of load
+// and fitness numbers until the coach has looked at them.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+)
+
+// Flag codes.
+const (
+	FlagPace          = "implausible_pace"
+	FlagHeartRate     = "implausible_hr"
+	FlagCadenceLock   = "hr_cadence_lock"
+	FlagSportMismatch = "sport_mismatch"
+	FlagSpike         = "history_spike"
+)
+
+// Flag is one reason a workout needs review.
+type Flag struct {
+	Code    string
+	Message string
+}
+
+// String renders the flag for storage.
+func (f Flag) String() string {
+	return f.Code + ": " + f.Message
+}
+
+// Workout holds the summary values checked.
+type Workout struct {
+	Sport       string
+	DurationSec float64
+	DistanceM   float64
+	AvgHR       float64
+	MaxHR       float64
+	MaxSpeed    float64 // m/s
+}
+
+func (w Workout) speed() float64 {
+	if w.DurationSec <= 0 {
+		return 0
+	}
+	return w.DistanceM / w.DurationSec
+}
+
+// Limits used by Check. Elapsed time is used for average speed, so these
+// sit above elite race pace rather than at it.
+const (
+	maxRunSpeed      = 6.5  // m/s, ~2:34/km sustained
+	maxRunPeakSpeed  = 12.0 // m/s, faster than a sprinter's top speed
+	maxRideSpeed     = 20.0 // m/s, 72 km/h sustained
+	maxSwimSpeed     = 2.5  // m/s
+	minAvgHR         = 35
+	maxAvgHR         = 220
+	maxPeakHR        = 230
+	athleteHRMargin  = 15 // bpm above the athlete's recorded max
+	bikeLikeRunSpeed = 8.0
+	walkLikeRide     = 2.2 // m/s
+	cadenceLockShare = 0.8 // share of samples where HR tracks cadence
+	cadenceLockBPM   = 3
+	minHistory       = 10
+	spikeSigma       = 4
+)
+
+var runSports = map[string]bool{"Run": true, "TrailRun": true, "VirtualRun": true, "Walk": true, "Hike": true}
+var rideSports = map[string]bool{"Ride": true, "VirtualRide": true, "GravelRide": true, "MountainBikeRide": true, "EBikeRide": true}
+
+// Check returns every flag raised for w. athleteMaxHR may be zero when
+// unknown; history should hold earlier accepted workouts of the same sport.
+func Check(w Workout, s metrics.Streams, athleteMaxHR float64, history []Workout) []Flag {
+	var flags []Flag
+	flags = append(flags, checkPace(w)...)
+	flags = append(flags, checkHeartRate(w, athleteMaxHR)...)
+	if f, ok := checkCadenceLock(s); ok {
+		flags = append(flags, f)
+	}
+	if f, ok := checkSport(w); ok {
+		flags = append(flags, f)
+	}
+	flags = append(flags, checkHistory(w, history)...)
+	return flags
+}
+
+func checkPace(w Workout) []Flag {
+	v := w.speed()
+	var limit float64
+	switch {
+	case runSports[w.Sport]:
+		limit = maxRunSpeed
+		if w.MaxSpeed > maxRunPeakSpeed {
+			return []Flag{{FlagPace, fmt.Sprintf("peak speed %.1f km/h is faster than humanly possible on foot", w.MaxSpeed*3.6)}}
+		}
+	case rideSports[w.Sport]:
+		limit = maxRideSpeed
+	case w.Sport == "Swim":
+		limit = maxSwimSpeed
+	default:
+		return nil
+	}
+	if v > limit {
+		return []Flag{{FlagPace, fmt.Sprintf("average speed %.1f km/h is implausible for %s", v*3.6, w.Sport)}}
+	}
+	return nil
+}
+
+func checkHeartRate(w Workout, athleteMaxHR float64) []Flag {
+	var flags []Flag
+	if w.AvgHR > 0 && (w.AvgHR < minAvgHR || w.AvgHR > maxAvgHR) {
+		flags = append(flags, Flag{FlagHeartRate, fmt.Sprintf("average HR %.0f bpm is outside the physiological range", w.AvgHR)})
+	}
+	switch {
+	case w.MaxHR > maxPeakHR:
+		flags = append(flags, Flag{FlagHeartRate, fmt.Sprintf("max HR %.0f bpm is implausible", w.MaxHR)})
+	case athleteMaxHR > 0 && w.MaxHR > athleteMaxHR+athleteHRMargin:
+		flags = append(flags, Flag{FlagHeartRate, fmt.Sprintf("max HR %.0f bpm is well above the athlete's max of %.0f", w.MaxHR, athleteMaxHR)})
+	}
+	return flags
+}
+
+// checkCadenceLock catches optical or chest sensors that lock onto step
+// rate: HR tracks cadence (per leg or total steps) for most of the workout.
+func checkCadenceLock(s metrics.Streams) (Flag, bool) {
+	n := len(s.Time)
+	if n == 0 || len(s.HeartRate) != n || len(s.Cadence) != n {
+		return Flag{}, false
+	}
+	for _, mult := range []float64{1, 2} {
+		matched, total := 0, 0
+		for i := range s.Time {
+			if s.Cadence[i] <= 0 || s.HeartRate[i] <= 0 {
+				continue
+			}
+			total++
+			if math.Abs(s.HeartRate[i]-s.Cadence[i]*mult) <= cadenceLockBPM {
+				matched++
+			}
+		}
+		if total > 0 && float64(matched)/float64(total) >= cadenceLockShare {
+			return Flag{FlagCadenceLock, "heart rate tracks cadence; the sensor is probably reading steps"}, true
+		}
+	}
+	return Flag{}, false
+}
+
+func checkSport(w Workout) (Flag, bool) {
+	v := w.speed()
+	switch {
+	case runSports[w.Sport] && v > bikeLikeRunSpeed:
+		return Flag{FlagSportMismatch, fmt.Sprintf("%.1f km/h average looks like a ride, not a %s", v*3.6, w.Sport)}, true
+	case rideSports[w.Sport] && w.Sport != "VirtualRide" && w.DistanceM > 1000 && v > 0 && v < walkLikeRide:
+		return Flag{FlagSportMismatch, fmt.Sprintf("%.1f km/h average looks like a run or walk, not a ride", v*3.6)}, true
+	}
+	return Flag{}, false
+}
+
+// checkHistory compares duration and speed with the athlete's own record.
+func checkHistory(w Workout, history []Workout) []Flag {
+	if len(history) < minHistory {
+		return nil
+	}
+	var flags []Flag
+	durations := make([]float64, 0, len(history))
+	speeds := make([]float64, 0, len(history))
+	for _, h := range history {
+		durations = append(durations, h.DurationSec)
+		if v := h.speed(); v > 0 {
+			speeds = append(speeds, v)
+		}
+	}
+	if z, ok := zScore(w.DurationSec, durations); ok && z > spikeSigma {
+		flags = append(flags, Flag{FlagSpike, fmt.Sprintf("duration is %.1f standard deviations above this athlete's usual %s", z, w.Sport)})
+	}
+	if v := w.speed(); v > 0 && len(speeds) >= minHistory {
+		if z, ok := zScore(v, speeds); ok && z > spikeSigma {
+			flags = append(flags, Flag{FlagSpike, fmt.Sprintf("speed is %.1f standard deviations above this athlete's usual %s", z, w.Sport)})
+		}
+	}
+	return flags
+}
+
+func zScore(x float64, xs []float64) (float64, bool) {
+	var mean float64
+	for _, v := range xs {
+		mean += v
+	}
+	mean /= float64(len(xs))
+	var variance float64
+	for _, v := range xs {
+		variance += (v - mean) * (v - mean)
+	}
+	sd := math.Sqrt(variance / float64(len(xs)))
+	if sd == 0 {
+		return 0, false
+	}
+	return (x - mean) / sd, true
+}
@@ -0,0 +1,76 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+)
+
+func codes(flags []Flag) map[string]bool {
+	out := make(map[string]bool)
+	for _, f := range flags {
+		out[f.Code] = true
+	}
+	return out
+}
+
+func TestCheck_CleanRun(t *testing.T) {
+	w := Workout{Sport: "Run", DurationSec: 3000, DistanceM: 10000, AvgHR: 150, MaxHR: 172, MaxSpeed: 4.5}
+	if flags := Check(w, metrics.Streams{}, 185, nil); len(flags) != 0 {
+		t.Fatalf("expected no flags, got %v", flags)
+	}
+}
+
+func TestCheck_GPSGlitch(t *testing.T) {
+	// 10 km in 20 minutes is a 2:00/km average.
+	w := Workout{Sport: "Run", DurationSec: 1200, DistanceM: 10000}
+	if !codes(Check(w, metrics.Streams{}, 0, nil))[FlagPace] {
+		t.Fatalf("expected pace flag")
+	}
+}
+
+func TestCheck_RideUploadedAsRun(t *testing.T) {
+	w := Workout{Sport: "Run", DurationSec: 3600, DistanceM: 32000}
+	got := codes(Check(w, metrics.Streams{}, 0, nil))
+	if !got[FlagSportMismatch] {
+		t.Fatalf("expected sport mismatch, got %v", got)
+	}
+}
+
+func TestCheck_HeartRate(t *testing.T) {
+	w := Workout{Sport: "Ride", DurationSec: 3600, DistanceM: 30000, AvgHR: 150, MaxHR: 205}
+	if !codes(Check(w, metrics.Streams{}, 180, nil))[FlagHeartRate] {
+		t.Fatalf("expected HR flag above athlete max")
+	}
+	if codes(Check(w, metrics.Streams{}, 0, nil))[FlagHeartRate] {
+		t.Fatalf("205 bpm alone is plausible without a recorded max")
+	}
+}
+
+func TestCheck_CadenceLock(t *testing.T) {
+	s := metrics.Streams{}
+	for i := 0; i < 600; i++ {
+		s.Time = append(s.Time, float64(i))
+		s.Cadence = append(s.Cadence, 88)
+		s.HeartRate = append(s.HeartRate, 176+float64(i%3-1))
+	}
+	w := Workout{Sport: "Run", DurationSec: 600, DistanceM: 1800, AvgHR: 176}
+	if !codes(Check(w, s, 0, nil))[FlagCadenceLock] {
+		t.Fatalf("expected cadence lock flag")
+	}
+}
+
+func TestCheck_HistorySpike(t *testing.T) {
+	var history []Workout
+	for i := 0; i < 20; i++ {
+		history = append(history, Workout{Sport: "Run", DurationSec: 2700 + float64(i%5)*60, DistanceM: 8000 + float64(i%5)*200})
+	}
+	w := Workout{Sport: "Run", DurationSec: 4 * 3600, DistanceM: 40000}
+	if !codes(Check(w, metrics.Streams{}, 0, history))[FlagSpike] {
+		t.Fatalf("expected history spike")
+	}
+	normal := Workout{Sport: "Run", DurationSec: 2900, DistanceM: 8400}
+	if codes(Check(normal, metrics.Streams{}, 0, history))[FlagSpike] {
+		t.Fatalf("typical run should not spike")
+	}
+}
@@ -0,0 +1,233 @@
+// Package assistant answers a coach's questions about one athlete. The
+// model is given tools that read the athlete's training through
+// coach-scoped queries, and Reply runs the tool loop until it has an
+// answer, streaming the answer's text as it comes.
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/prompts"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Feature is the usage label for chat.
+const Feature = "chat"
+
+// MaxRounds caps model calls per reply. The last round offers no tools, so
+// the model has to answer with what it has.
+const MaxRounds = 6
+
+// maxToolResult keeps one tool result from crowding out the conversation.
+const maxToolResult = 24000
+
+// MaxQuestion is the longest question a coach can ask, in bytes.
+const MaxQuestion = 4000
+
+// Call is one tool run, for the audit log.
+type Call struct {
+	llm.ToolCall
+	Result   string
+	Err      error
+	Duration time.Duration
+}
+
+// Turn is what a reply added to the conversation: every message in order,
+// the final answer last, the tools run along the way and the model that
+// answered.
+type Turn struct {
+	Messages []llm.Message
+	Calls    []Call
+	Model    string
+}
+
+// Events are callbacks as a reply progresses. Either may be nil; an error
+// aborts the reply.
+type Events struct {
+	OnDelta func(text string) error
+	OnTool  func(call llm.ToolCall) error
+}
+
+// Context is what the system prompt tells the model about the athlete.
+type Context struct {
+	Athlete    string
+	Today      string
+	Zone       string
+	Thresholds []string
+}
+
+// Prompt is the template conversations start from. It only has the system
+// message; the coach's questions follow it.
+var Prompt = prompts.Register[Context](prompts.Def{
+	Name:    Feature,
+	Version: 1,
+	System: `You are a training analyst helping an endurance coach understand one of their athletes.
+The athlete is {{ .Athlete }}. Today is {{ .Today }} in their time zone ({{ .Zone }}).
+{{ with .Thresholds }}Their thresholds: {{ join . ", " }}.
+{{ end }}Answer from the athlete's data, using the tools to look it up; never guess numbers.
+If the data doesn't answer the question, say so. Resolve relative dates like "since March" against today.
+Be concise: lead with the answer, then the few numbers that support it. Plain text or short markdown lists.`,
+	Temperature: 0.2,
+	MaxTokens:   1500,
+})
+
+// SystemPrompt grounds the model in who it is talking about and when.
+func SystemPrompt(athlete db.Athlete, now time.Time) (string, error) {
+	loc := training.Location(athlete.Tz)
+	c := Context{
+		Athlete: athlete.Name,
+		Today:   now.In(loc).Format("Monday 2006-01-02"),
+		Zone:    loc.String(),
+	}
+	if athlete.MaxHr.Valid {
+		c.Thresholds = append(c.Thresholds, fmt.Sprintf("max HR %d", athlete.MaxHr.Int32))
+	}
+	if athlete.RestingHr.Valid {
+		c.Thresholds = append(c.Thresholds, fmt.Sprintf("resting HR %d", athlete.RestingHr.Int32))
+	}
+	if athlete.FtpWatts.Valid {
+		c.Thresholds = append(c.Thresholds, fmt.Sprintf("FTP %d W", athlete.FtpWatts.Int32))
+	}
+	if athlete.ThresholdPaceSec.Valid {
+		c.Thresholds = append(c.Thresholds, "threshold pace "+clock(float64(athlete.ThresholdPaceSec.Int32))+"/km")
+	}
+	req, err := Prompt.Render(c)
+	if err != nil {
+		return "", err
+	}
+	return req.Messages[0].Content, nil
+}
+
+// Reply answers the last message of history, which should be the coach's
+// question. On error the partial Turn is returned so the tool calls made
+// can still be logged.
+func Reply(ctx context.Context, p llm.Provider, tools Tools, history []llm.Message, ev Events) (Turn, error) {
+	var turn Turn
+	messages := append([]llm.Message(nil), history...)
+	onDelta := ev.OnDelta
+	if onDelta == nil {
+		onDelta = func(string) error { return nil }
+	}
+
+	for round := 1; ; round++ {
+		req := llm.Request{Messages: messages, Temperature: Prompt.Def().Temperature, MaxTokens: Prompt.Def().MaxTokens}
+		if round < MaxRounds {
+			req.Tools = tools.Definitions()
+		}
+		resp, err := p.Stream(ctx, req, onDelta)
+		if err != nil {
+			return turn, err
+		}
+		msg := resp.Message
+		msg.Role = llm.RoleAssistant
+		turn.Model = resp.Model
+		turn.Messages = append(turn.Messages, msg)
+		messages = append(messages, msg)
+		if len(msg.ToolCalls) == 0 {
+			return turn, nil
+		}
+
+		for _, c := range msg.ToolCalls {
+			if ev.OnTool != nil {
+				if err := ev.OnTool(c); err != nil {
+					return turn, err
+				}
+			}
+			start := time.Now()
+			result, err := tools.Call(ctx, c.Name, c.Arguments)
+			call := Call{ToolCall: c, Result: result, Err: err, Duration: time.Since(start)}
+			if err != nil {
+				if ctx.Err() != nil {
+					return turn, ctx.Err()
+				}
+				result = "error: " + err.Error()
+			}
+			if len(result) > maxToolResult {
+				result = result[:maxToolResult] + "…(truncated; ask for a shorter range)"
+			}
+			turn.Calls = append(turn.Calls, call)
+			tm := llm.Message{Role: llm.RoleTool, Content: result, ToolCallID: c.ID}
+			turn.Messages = append(turn.Messages, tm)
+			messages = append(messages, tm)
+		}
+	}
+}
+
+// storedCall is a tool call as kept in chat_message.tool_calls.
+type storedCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// EncodeToolCalls serialises an assistant message's tool calls for
+// storage; nil when there are none.
+func EncodeToolCalls(calls []llm.ToolCall) ([]byte, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	out := make([]storedCall, len(calls))
+	for i, c := range calls {
+		out[i] = storedCall(c)
+	}
+	return json.Marshal(out)
+}
+
+// CreateParams turns a message into a row for the conversation.
+func CreateParams(conversation uuid.UUID, m llm.Message) (db.CreateChatMessageParams, error) {
+	calls, err := EncodeToolCalls(m.ToolCalls)
+	if err != nil {
+		return db.CreateChatMessageParams{}, err
+	}
+	return db.CreateChatMessageParams{
+		ConversationID: conversation,
+		Role:           m.Role,
+		Content:        m.Content,
+		ToolCalls:      calls,
+		ToolCallID:     pgtype.Text{String: m.ToolCallID, Valid: m.ToolCallID != ""},
+	}, nil
+}
+
+// History rebuilds the model conversation from stored rows, behind the
+// system prompt.
+func History(system string, rows []db.ChatMessage) ([]llm.Message, error) {
+	out := []llm.Message{{Role: llm.RoleSystem, Content: system}}
+	for _, r := range rows {
+		m := llm.Message{Role: r.Role, Content: r.Content, ToolCallID: r.ToolCallID.String}
+		if len(r.ToolCalls) > 0 {
+			var calls []storedCall
+			if err := json.Unmarshal(r.ToolCalls, &calls); err != nil {
+				return nil, fmt.Errorf("message %d tool calls: %w", r.ID, err)
+			}
+			for _, c := range calls {
+				m.ToolCalls = append(m.ToolCalls, llm.ToolCall(c))
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Pending reports whether the conversation is waiting on a reply: its last
+// message is the coach's.
+func Pending(rows []db.ChatMessage) bool {
+	return len(rows) > 0 && rows[len(rows)-1].Role == llm.RoleUser
+}
+
+// Title names a conversation after its first question.
+func Title(question string) string {
+	t := strings.Join(strings.Fields(question), " ")
+	if r := []rune(t); len(r) > 80 {
+		t = strings.TrimSpace(string(r[:79])) + "…"
+	}
+	return t
+}
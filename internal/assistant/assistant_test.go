@@ -0,0 +1,294 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+var (
+	coachID   = uuid.New()
+	athleteID = uuid.New()
+	runID     = uuid.New()
+)
+
+// store stands in for the database, enforcing the same coach scoping the
+// queries do.
+type store struct {
+	workouts []db.ListCoachAthleteWorkoutsBetweenRow
+	got      []db.ListCoachAthleteWorkoutsBetweenParams
+}
+
+func (s *store) owned(coach, athlete uuid.UUID) bool {
+	return coach == coachID && athlete == athleteID
+}
+
+func (s *store) ListCoachAthleteWorkoutsBetween(_ context.Context, arg db.ListCoachAthleteWorkoutsBetweenParams) ([]db.ListCoachAthleteWorkoutsBetweenRow, error) {
+	s.got = append(s.got, arg)
+	if !s.owned(arg.CoachID, arg.AthleteID) {
+		return nil, nil
+	}
+	var out []db.ListCoachAthleteWorkoutsBetweenRow
+	for _, w := range s.workouts {
+		t := w.StartedAt.Time
+		if !t.Before(arg.FromTime.Time) && t.Before(arg.ToTime.Time) && (arg.Sport == "" || arg.Sport == w.Sport) {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (s *store) GetCoachAthleteWorkout(_ context.Context, arg db.GetCoachAthleteWorkoutParams) (db.GetCoachAthleteWorkoutRow, error) {
+	if !s.owned(arg.CoachID, arg.AthleteID) || arg.ID != runID {
+		return db.GetCoachAthleteWorkoutRow{}, pgx.ErrNoRows
+	}
+	return db.GetCoachAthleteWorkoutRow{
+		ID:            runID,
+		Sport:         "Run",
+		StartedAt:     pgtype.Timestamptz{Time: time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC), Valid: true},
+		DurationSec:   5400,
+		DistanceM:     pgtype.Float8{Float64: 18000, Valid: true},
+		DecouplingPct: pgtype.Float8{Float64: 4.26, Valid: true},
+		ZoneSec:       []int32{600, 4200, 600},
+	}, nil
+}
+
+func (s *store) ListCoachAthleteWorkoutLoadsBetween(_ context.Context, arg db.ListCoachAthleteWorkoutLoadsBetweenParams) ([]db.ListCoachAthleteWorkoutLoadsBetweenRow, error) {
+	if !s.owned(arg.CoachID, arg.AthleteID) {
+		return nil, nil
+	}
+	var out []db.ListCoachAthleteWorkoutLoadsBetweenRow
+	for d := arg.FromTime.Time; d.Before(arg.ToTime.Time); d = d.AddDate(0, 0, 1) {
+		out = append(out, db.ListCoachAthleteWorkoutLoadsBetweenRow{
+			StartedAt:   pgtype.Timestamptz{Time: d.Add(7 * time.Hour), Valid: true},
+			DurationSec: 3600,
+			DistanceM:   pgtype.Float8{Float64: 10000, Valid: true},
+			Load:        pgtype.Float8{Float64: 50, Valid: true},
+		})
+	}
+	return out, nil
+}
+
+func tools() (Tools, *store) {
+	s := &store{workouts: []db.ListCoachAthleteWorkoutsBetweenRow{
+		{ID: runID, Sport: "Run", StartedAt: pgtype.Timestamptz{Time: time.Date(2024, 3, 3, 8, 0, 0, 0, time.UTC), Valid: true},
+			DurationSec: 5400, DistanceM: pgtype.Float8{Float64: 18000, Valid: true}, ReviewStatus: "ok"},
+		{ID: uuid.New(), Sport: "Ride", StartedAt: pgtype.Timestamptz{Time: time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC), Valid: true},
+			DurationSec: 3600, DistanceM: pgtype.Float8{Float64: 30000, Valid: true}, ReviewStatus: "flagged"},
+	}}
+	return Tools{Q: s, CoachID: coachID, Athlete: db.Athlete{ID: athleteID, Name: "Anna", Tz: "UTC"}}, s
+}
+
+func TestListWorkouts(t *testing.T) {
+	tl, s := tools()
+	out, err := tl.Call(context.Background(), ToolListWorkouts, `{"from":"2024-03-01","to":"2024-03-05"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Count    int
+		Workouts []workoutItem
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count != 2 || got.Workouts[0].Pace != "5:00" || got.Workouts[1].Pace != "" || !got.Workouts[1].Excluded {
+		t.Fatalf("got %s", out)
+	}
+	// "to" is inclusive: the whole last day is read.
+	if arg := s.got[0]; !arg.ToTime.Time.Equal(time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)) || arg.CoachID != coachID {
+		t.Fatalf("query = %+v", arg)
+	}
+
+	out, _ = tl.Call(context.Background(), ToolListWorkouts, `{"from":"2024-03-01","to":"2024-03-05","sport":"Run"}`)
+	if !strings.Contains(out, `"count":1`) {
+		t.Fatalf("sport filter: %s", out)
+	}
+}
+
+func TestToolErrors(t *testing.T) {
+	tl, _ := tools()
+	for args, want := range map[string]string{
+		`{"from":"March","to":"2024-03-05"}`:      "YYYY-MM-DD",
+		`{"from":"2024-03-05","to":"2024-03-01"}`: "before",
+		`{"from":"2020-01-01","to":"2024-03-01"}`: "longer than",
+		`not json`: "not valid JSON",
+	} {
+		if _, err := tl.Call(context.Background(), ToolListWorkouts, args); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", args, err, want)
+		}
+	}
+	if _, err := tl.Call(context.Background(), ToolGetWorkout, `{"workout_id":"`+uuid.NewString()+`"}`); err == nil {
+		t.Error("unknown workout should be an error")
+	}
+	if _, err := tl.Call(context.Background(), "drop_tables", `{}`); err == nil {
+		t.Error("unknown tool should be an error")
+	}
+}
+
+func TestScopedToCoach(t *testing.T) {
+	tl, _ := tools()
+	tl.CoachID = uuid.New()
+	out, err := tl.Call(context.Background(), ToolListWorkouts, `{"from":"2024-03-01","to":"2024-03-05"}`)
+	if err != nil || !strings.Contains(out, `"count":0`) {
+		t.Fatalf("another coach read %s (%v)", out, err)
+	}
+	if _, err := tl.Call(context.Background(), ToolGetWorkout, `{"workout_id":"`+runID.String()+`"}`); err == nil {
+		t.Fatal("another coach fetched the workout")
+	}
+}
+
+func TestGetWorkout(t *testing.T) {
+	tl, _ := tools()
+	out, err := tl.Call(context.Background(), ToolGetWorkout, `{"workout_id":"`+runID.String()+`"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"pace_per_km":"5:00"`, `"decoupling_pct":4.3`, `"hr_zone_minutes":[10,70,10]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in %s", want, out)
+		}
+	}
+}
+
+func TestLoadMetrics(t *testing.T) {
+	tl, _ := tools()
+	// Mon 4 to Sun 17 March: two full weeks of 50 a day.
+	out, err := tl.Call(context.Background(), ToolGetLoadMetrics, `{"from":"2024-03-04","to":"2024-03-17"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Weeks []weekItem
+		ACWR  float64 `json:"acwr_at_end"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Weeks) != 2 || got.Weeks[0].Week != "2024-03-04" || got.Weeks[0].Load != 350 || got.Weeks[0].Sessions != 7 || got.Weeks[1].DistanceKm != 70 {
+		t.Fatalf("weeks = %+v", got.Weeks)
+	}
+	if got.Weeks[1].CTL <= got.Weeks[0].CTL || got.ACWR != 1 {
+		t.Fatalf("ctl/acwr = %s", out)
+	}
+}
+
+func TestReply(t *testing.T) {
+	tl, _ := tools()
+	fake := &llm.Fake{Replies: []llm.Message{
+		{ToolCalls: []llm.ToolCall{
+			{ID: "c1", Name: ToolListWorkouts, Arguments: `{"from":"2024-03-01","to":"2024-03-07","sport":"Run"}`},
+			{ID: "c2", Name: ToolGetWorkout, Arguments: `{"workout_id":"nope"}`},
+		}},
+		{Content: "Anna ran 18 km at 5:00/km on Sunday."},
+	}}
+	system, err := SystemPrompt(tl.Athlete, time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []llm.Message{
+		{Role: llm.RoleSystem, Content: system},
+		{Role: llm.RoleUser, Content: "How was Anna's long run?"},
+	}
+
+	var streamed strings.Builder
+	var ran []string
+	turn, err := Reply(context.Background(), fake, tl, history, Events{
+		OnDelta: func(s string) error { streamed.WriteString(s); return nil },
+		OnTool:  func(c llm.ToolCall) error { ran = append(ran, c.Name); return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != "Anna ran 18 km at 5:00/km on Sunday." || strings.Join(ran, ",") != "list_workouts,get_workout" {
+		t.Fatalf("streamed %q, ran %v", streamed.String(), ran)
+	}
+	roles := make([]string, len(turn.Messages))
+	for i, m := range turn.Messages {
+		roles[i] = m.Role
+	}
+	if strings.Join(roles, ",") != "assistant,tool,tool,assistant" {
+		t.Fatalf("roles = %v", roles)
+	}
+	if !strings.Contains(turn.Messages[1].Content, `"count":1`) || turn.Messages[2].Content != `error: workout_id "nope" is not an id from list_workouts` {
+		t.Fatalf("tool results = %q / %q", turn.Messages[1].Content, turn.Messages[2].Content)
+	}
+	if len(turn.Calls) != 2 || turn.Calls[0].Err != nil || turn.Calls[1].Err == nil {
+		t.Fatalf("calls = %+v", turn.Calls)
+	}
+
+	// The second request carried the tool results back to the model.
+	second := fake.Calls()[1]
+	if len(second.Messages) != 5 || second.Messages[3].ToolCallID != "c1" || len(second.Tools) != 3 {
+		t.Fatalf("second request = %+v", second)
+	}
+	if !strings.Contains(fake.Calls()[0].Messages[0].Content, "Friday 2024-03-08") {
+		t.Fatalf("system prompt lacks today: %q", fake.Calls()[0].Messages[0].Content)
+	}
+}
+
+func TestReplyRoundLimit(t *testing.T) {
+	tl, _ := tools()
+	loop := llm.Message{ToolCalls: []llm.ToolCall{{ID: "c", Name: ToolGetWorkout, Arguments: `{"workout_id":"` + runID.String() + `"}`}}}
+	fake := &llm.Fake{}
+	for range MaxRounds - 1 {
+		fake.Replies = append(fake.Replies, loop)
+	}
+	turn, err := Reply(context.Background(), fake, tl, []llm.Message{{Role: llm.RoleUser, Content: "?"}}, Events{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if len(calls) != MaxRounds || len(calls[MaxRounds-1].Tools) != 0 || turn.Messages[len(turn.Messages)-1].Content == "" {
+		t.Fatalf("made %d calls, last offered %d tools", len(calls), len(calls[len(calls)-1].Tools))
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	conv := uuid.New()
+	msgs := []llm.Message{
+		{Role: llm.RoleUser, Content: "q"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "c1", Name: ToolListWorkouts, Arguments: `{"from":"2024-03-01"}`}}},
+		{Role: llm.RoleTool, Content: "{}", ToolCallID: "c1"},
+		{Role: llm.RoleAssistant, Content: "a"},
+	}
+	rows := make([]db.ChatMessage, len(msgs))
+	for i, m := range msgs {
+		p, err := CreateParams(conv, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows[i] = db.ChatMessage{ID: int64(i), ConversationID: p.ConversationID, Role: p.Role, Content: p.Content, ToolCalls: p.ToolCalls, ToolCallID: p.ToolCallID}
+	}
+	if rows[0].ToolCalls != nil || rows[0].ToolCallID.Valid {
+		t.Fatalf("plain message stored extras: %+v", rows[0])
+	}
+	got, err := History("sys", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Content != "sys" || got[2].ToolCalls[0] != msgs[1].ToolCalls[0] || got[3].ToolCallID != "c1" {
+		t.Fatalf("history = %+v", got)
+	}
+	if Pending(rows) || !Pending(rows[:1]) {
+		t.Fatal("pending is wrong")
+	}
+}
+
+func TestTitle(t *testing.T) {
+	if got := Title("  how has\nAnna's   pace trended? "); got != "how has Anna's pace trended?" {
+		t.Errorf("got %q", got)
+	}
+	if got := Title(strings.Repeat("é", 100)); len([]rune(got)) != 80 || !strings.HasSuffix(got, "…") {
+		t.Errorf("long title = %q", got)
+	}
+}
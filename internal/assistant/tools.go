@@ -0,0 +1,369 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Store is the slice of db.Queries the tools read. Every query takes the
+// coach as well as the athlete, so a tool can't reach another coach's
+// data whatever the model asks for.
+type Store interface {
+	ListCoachAthleteWorkoutsBetween(ctx context.Context, arg db.ListCoachAthleteWorkoutsBetweenParams) ([]db.ListCoachAthleteWorkoutsBetweenRow, error)
+	GetCoachAthleteWorkout(ctx context.Context, arg db.GetCoachAthleteWorkoutParams) (db.GetCoachAthleteWorkoutRow, error)
+	ListCoachAthleteWorkoutLoadsBetween(ctx context.Context, arg db.ListCoachAthleteWorkoutLoadsBetweenParams) ([]db.ListCoachAthleteWorkoutLoadsBetweenRow, error)
+}
+
+// Tool names.
+const (
+	ToolListWorkouts   = "list_workouts"
+	ToolGetWorkout     = "get_workout"
+	ToolGetLoadMetrics = "get_load_metrics"
+)
+
+// maxRangeDays bounds the date range a single tool call may read.
+const maxRangeDays = 400
+
+// ctlWarmup is the history loaded before a load-metrics range so CTL has
+// settled by its first day.
+const ctlWarmup = 120
+
+// Tools runs the assistant's tools for one athlete on behalf of their
+// coach.
+type Tools struct {
+	Q       Store
+	CoachID uuid.UUID
+	Athlete db.Athlete
+}
+
+// Definitions describes the tools to the model.
+func (t Tools) Definitions() []llm.Tool {
+	dateRange := `"from": {"type": "string", "description": "First day, YYYY-MM-DD"},
+		"to": {"type": "string", "description": "Last day, YYYY-MM-DD, inclusive"}`
+	return []llm.Tool{
+		{
+			Name:        ToolListWorkouts,
+			Description: "List the athlete's workouts between two dates: date, sport, name, duration, distance, pace, heart rate and load. At most 200 are returned.",
+			Parameters: []byte(`{"type": "object", "properties": {` + dateRange + `,
+				"sport": {"type": "string", "description": "Only this sport, e.g. Run or Ride; omit for all"}},
+				"required": ["from", "to"]}`),
+		},
+		{
+			Name:        ToolGetWorkout,
+			Description: "Full summary of one workout by id: pace, power, heart-rate drift, efficiency, intensity and time in heart-rate zones.",
+			Parameters: []byte(`{"type": "object", "properties": {
+				"workout_id": {"type": "string", "description": "id from list_workouts"}},
+				"required": ["workout_id"]}`),
+		},
+		{
+			Name:        ToolGetLoadMetrics,
+			Description: "Weekly training load, time and distance between two dates, with fitness (CTL) at the end of each week and the acute:chronic workload ratio at the end of the range.",
+			Parameters:  []byte(`{"type": "object", "properties": {` + dateRange + `}, "required": ["from", "to"]}`),
+		},
+	}
+}
+
+// Call runs the named tool with the model's JSON arguments and returns the
+// result as JSON. Errors are for the model to read and correct.
+func (t Tools) Call(ctx context.Context, name, arguments string) (string, error) {
+	var out any
+	var err error
+	switch name {
+	case ToolListWorkouts:
+		out, err = t.listWorkouts(ctx, arguments)
+	case ToolGetWorkout:
+		out, err = t.getWorkout(ctx, arguments)
+	case ToolGetLoadMetrics:
+		out, err = t.loadMetrics(ctx, arguments)
+	default:
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(out)
+	return string(b), err
+}
+
+type rangeArgs struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Sport string `json:"sport"`
+}
+
+// window parses the arguments into local midnights [from, to+1 day).
+func (t Tools) window(arguments string) (rangeArgs, time.Time, time.Time, error) {
+	var a rangeArgs
+	if err := json.Unmarshal([]byte(arguments), &a); err != nil {
+		return a, time.Time{}, time.Time{}, fmt.Errorf("arguments are not valid JSON: %v", err)
+	}
+	loc := training.Location(t.Athlete.Tz)
+	from, err := time.ParseInLocation(time.DateOnly, a.From, loc)
+	if err != nil {
+		return a, time.Time{}, time.Time{}, fmt.Errorf("from must be YYYY-MM-DD, got %q", a.From)
+	}
+	to, err := time.ParseInLocation(time.DateOnly, a.To, loc)
+	if err != nil {
+		return a, time.Time{}, time.Time{}, fmt.Errorf("to must be YYYY-MM-DD, got %q", a.To)
+	}
+	if to.Before(from) {
+		return a, time.Time{}, time.Time{}, errors.New("to is before from")
+	}
+	if to.Sub(from) > maxRangeDays*24*time.Hour {
+		return a, time.Time{}, time.Time{}, fmt.Errorf("range is longer than %d days; ask for less", maxRangeDays)
+	}
+	return a, from, to.AddDate(0, 0, 1), nil
+}
+
+type workoutItem struct {
+	ID          string   `json:"id"`
+	Date        string   `json:"date"`
+	Sport       string   `json:"sport"`
+	Name        string   `json:"name,omitempty"`
+	DurationMin float64  `json:"duration_min"`
+	DistanceKm  *float64 `json:"distance_km,omitempty"`
+	Pace        string   `json:"pace_per_km,omitempty"`
+	GAP         string   `json:"grade_adjusted_pace_per_km,omitempty"`
+	ElevGainM   *float64 `json:"elev_gain_m,omitempty"`
+	AvgHR       *int32   `json:"avg_hr,omitempty"`
+	Load        *float64 `json:"load,omitempty"`
+	IF          *float64 `json:"intensity_factor,omitempty"`
+	Excluded    bool     `json:"excluded_from_load,omitempty"`
+}
+
+func (t Tools) listWorkouts(ctx context.Context, arguments string) (any, error) {
+	a, from, to, err := t.window(arguments)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := t.Q.ListCoachAthleteWorkoutsBetween(ctx, db.ListCoachAthleteWorkoutsBetweenParams{
+		CoachID:   t.CoachID,
+		AthleteID: t.Athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: to, Valid: true},
+		Sport:     a.Sport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list workouts: %w", err)
+	}
+	loc := training.Location(t.Athlete.Tz)
+	items := make([]workoutItem, 0, len(rows))
+	for _, r := range rows {
+		it := workoutItem{
+			ID:          r.ID.String(),
+			Date:        r.StartedAt.Time.In(loc).Format("2006-01-02 Mon 15:04"),
+			Sport:       r.Sport,
+			Name:        r.Name.String,
+			DurationMin: round(float64(r.DurationSec)/60, 1),
+			DistanceKm:  km(r.DistanceM),
+			Pace:        pace(r.Sport, r.DistanceM, r.DurationSec),
+			ElevGainM:   rounded(r.ElevGainM, 0),
+			Load:        rounded(r.Load, 0),
+			IF:          rounded(r.IntensityFactor, 2),
+			Excluded:    r.ReviewStatus != "ok" && r.ReviewStatus != "accepted",
+		}
+		if r.GapSpeed.Valid && r.GapSpeed.Float64 > 0 {
+			it.GAP = clock(1000 / r.GapSpeed.Float64)
+		}
+		if r.AvgHr.Valid {
+			it.AvgHR = &r.AvgHr.Int32
+		}
+		items = append(items, it)
+	}
+	return map[string]any{"count": len(items), "workouts": items}, nil
+}
+
+func (t Tools) getWorkout(ctx context.Context, arguments string) (any, error) {
+	var a struct {
+		WorkoutID string `json:"workout_id"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &a); err != nil {
+		return nil, fmt.Errorf("arguments are not valid JSON: %v", err)
+	}
+	id, err := uuid.Parse(a.WorkoutID)
+	if err != nil {
+		return nil, fmt.Errorf("workout_id %q is not an id from list_workouts", a.WorkoutID)
+	}
+	r, err := t.Q.GetCoachAthleteWorkout(ctx, db.GetCoachAthleteWorkoutParams{ID: id, AthleteID: t.Athlete.ID, CoachID: t.CoachID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.New("no such workout for this athlete")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get workout: %w", err)
+	}
+
+	out := map[string]any{
+		"id":           r.ID.String(),
+		"date":         r.StartedAt.Time.In(training.Location(t.Athlete.Tz)).Format("2006-01-02 Mon 15:04"),
+		"sport":        r.Sport,
+		"name":         r.Name.String,
+		"duration_min": round(float64(r.DurationSec)/60, 1),
+		"review":       r.ReviewStatus,
+	}
+	if len(r.ReviewFlags) > 0 {
+		out["review_flags"] = r.ReviewFlags
+	}
+	set := func(key string, v *float64) {
+		if v != nil {
+			out[key] = *v
+		}
+	}
+	set("distance_km", km(r.DistanceM))
+	set("elev_gain_m", rounded(r.ElevGainM, 0))
+	set("load", rounded(r.Load, 0))
+	set("decoupling_pct", rounded(r.DecouplingPct, 1))
+	set("efficiency_factor", rounded(r.EfficiencyFactor, 3))
+	set("avg_power_w", rounded(r.AvgPower, 0))
+	set("normalized_power_w", rounded(r.NormalizedPower, 0))
+	set("variability_index", rounded(r.VariabilityIndex, 2))
+	set("intensity_factor", rounded(r.IntensityFactor, 2))
+	if p := pace(r.Sport, r.DistanceM, r.DurationSec); p != "" {
+		out["pace_per_km"] = p
+	}
+	if r.MovingSec.Valid {
+		out["moving_min"] = round(float64(r.MovingSec.Int32)/60, 1)
+	}
+	if r.GapSpeed.Valid && r.GapSpeed.Float64 > 0 {
+		out["grade_adjusted_pace_per_km"] = clock(1000 / r.GapSpeed.Float64)
+	}
+	if r.AvgHr.Valid {
+		out["avg_hr"] = r.AvgHr.Int32
+	}
+	if len(r.ZoneSec) > 0 {
+		zones := make([]float64, len(r.ZoneSec))
+		for i, s := range r.ZoneSec {
+			zones[i] = round(float64(s)/60, 1)
+		}
+		out["hr_zone_minutes"] = zones
+	}
+	return out, nil
+}
+
+type weekItem struct {
+	Week        string  `json:"week_of"`
+	Sessions    int     `json:"sessions"`
+	Load        float64 `json:"load"`
+	DurationMin float64 `json:"duration_min"`
+	DistanceKm  float64 `json:"distance_km"`
+	CTL         float64 `json:"ctl"`
+}
+
+func (t Tools) loadMetrics(ctx context.Context, arguments string) (any, error) {
+	_, from, to, err := t.window(arguments)
+	if err != nil {
+		return nil, err
+	}
+	loc := training.Location(t.Athlete.Tz)
+	start := from.AddDate(0, 0, -ctlWarmup)
+	rows, err := t.Q.ListCoachAthleteWorkoutLoadsBetween(ctx, db.ListCoachAthleteWorkoutLoadsBetweenParams{
+		CoachID:   t.CoachID,
+		AthleteID: t.Athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: start, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list workout loads: %w", err)
+	}
+
+	hr := training.HeartRateFor(int(t.Athlete.MaxHr.Int32), int(t.Athlete.RestingHr.Int32))
+	sessions := make([]training.Session, len(rows))
+	for i, r := range rows {
+		load := r.Load.Float64
+		if !r.Load.Valid {
+			load = training.HRTSS(int(r.DurationSec), int(r.AvgHr.Int32), hr)
+		}
+		sessions[i] = training.Session{Start: r.StartedAt.Time, Load: load}
+	}
+	days := training.DailySeries(sessions, loc, start, to.AddDate(0, 0, -1))
+	ctl := training.CTL(days, 0)
+
+	var weeks []weekItem
+	index := map[string]int{}
+	for i, d := range days {
+		if d.Day.Before(from) {
+			continue
+		}
+		key := training.WeekStart(d.Day, loc).Format(time.DateOnly)
+		j, ok := index[key]
+		if !ok {
+			j = len(weeks)
+			index[key] = j
+			weeks = append(weeks, weekItem{Week: key})
+		}
+		weeks[j].Load += d.Load
+		weeks[j].CTL = round(ctl[i], 1)
+	}
+	for _, r := range rows {
+		if r.StartedAt.Time.Before(from) {
+			continue
+		}
+		j, ok := index[training.WeekStart(r.StartedAt.Time, loc).Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		weeks[j].Sessions++
+		weeks[j].DurationMin += float64(r.DurationSec) / 60
+		weeks[j].DistanceKm += r.DistanceM.Float64 / 1000
+	}
+	for i := range weeks {
+		weeks[i].Load = round(weeks[i].Load, 0)
+		weeks[i].DurationMin = round(weeks[i].DurationMin, 0)
+		weeks[i].DistanceKm = round(weeks[i].DistanceKm, 1)
+	}
+
+	out := map[string]any{"weeks": weeks}
+	if acwr, ok := training.ACWR(days); ok {
+		out["acwr_at_end"] = round(acwr, 2)
+	}
+	return out, nil
+}
+
+func round(f float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(f*p) / p
+}
+
+func rounded(v pgtype.Float8, places int) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := round(v.Float64, places)
+	return &f
+}
+
+func km(m pgtype.Float8) *float64 {
+	if !m.Valid || m.Float64 <= 0 {
+		return nil
+	}
+	f := round(m.Float64/1000, 2)
+	return &f
+}
+
+// pace is minutes per km, for the sports where that's how speed is read.
+func pace(sport string, m pgtype.Float8, secs int32) string {
+	if !strings.Contains(sport, "Run") && sport != "Walk" && sport != "Hike" {
+		return ""
+	}
+	if !m.Valid || m.Float64 < 100 || secs <= 0 {
+		return ""
+	}
+	return clock(float64(secs) / (m.Float64 / 1000))
+}
+
+func clock(secs float64) string {
+	s := int(math.Round(secs))
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
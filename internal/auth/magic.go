@@ -14,6 +14,23 @@ import (
 type MagicLink struct {
 	Secret  []byte
 	BaseURL string
+	Path    string // callback the link opens; /auth/callback when empty
+
+	// Scope keeps links for different kinds of user apart: a token signed
+	// in one scope doesn't verify in another. Coach links have none.
+	Scope string
+}
+
+// AthletePortal is the magic link that signs athletes into their portal.
+func AthletePortal(secret []byte, baseURL string) MagicLink {
+	return MagicLink{Secret: secret, BaseURL: baseURL, Path: "/athlete/auth/callback", Scope: "athlete"}
+}
+
+// Unsubscribe is the link in an email that stops that kind of email to
+// the address. Each kind is its own scope, so one link can't unsubscribe
+// from the others.
+func Unsubscribe(secret []byte, baseURL, kind string) MagicLink {
+	return MagicLink{Secret: secret, BaseURL: baseURL, Path: "/unsubscribe/" + kind, Scope: "unsubscribe:" + kind}
 }
 
 var (
@@ -26,13 +43,20 @@ var (
 // Sign: use URL-safe base64 WITH padding (clearer in URLs)
 func (m MagicLink) Sign(email string, exp time.Time) string {
 	msg := email + "|" + strconv.FormatInt(exp.Unix(), 10)
-	mac := hmac.New(sha256.New, m.Secret)
-	mac.Write([]byte(msg))
-	sig := base64.URLEncoding.EncodeToString(mac.Sum(nil))    // padded
-	payload := base64.URLEncoding.EncodeToString([]byte(msg)) // padded
+	sig := base64.URLEncoding.EncodeToString(m.mac([]byte(msg))) // padded
+	payload := base64.URLEncoding.EncodeToString([]byte(msg))    // padded
 	return payload + "." + sig
 }
 
+func (m MagicLink) mac(msg []byte) []byte {
+	mac := hmac.New(sha256.New, m.Secret)
+	if m.Scope != "" {
+		mac.Write([]byte(m.Scope + "|"))
+	}
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
 // decodeURLB64 tries raw (no padding) then padded
 func decodeURLB64(s string) ([]byte, error) {
 	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
@@ -53,10 +77,9 @@ func (m MagicLink) Verify(token string) (string, error) {
 		return "", ErrBadToken
 	}
 
-	mac := hmac.New(sha256.New, m.Secret)
-	mac.Write(raw)
-	expectedRaw := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	expectedPad := base64.URLEncoding.EncodeToString(mac.Sum(nil))
+	sum := m.mac(raw)
+	expectedRaw := base64.RawURLEncoding.EncodeToString(sum)
+	expectedPad := base64.URLEncoding.EncodeToString(sum)
 
 	if sig != expectedRaw && sig != expectedPad {
 		return "", ErrBadSig
@@ -78,12 +101,23 @@ func (m MagicLink) Verify(token string) (string, error) {
 }
 
 func (m MagicLink) URL(email string, ttl time.Duration) string {
+	return m.URLTo(email, ttl, "")
+}
+
+// URLTo is URL with a local path to land on after signing in.
+func (m MagicLink) URLTo(email string, ttl time.Duration, next string) string {
 	exp := time.Now().Add(ttl)
 	tok := m.Sign(email, exp)
 	u, _ := url.Parse(m.BaseURL)
-	u.Path = "/auth/callback"
+	u.Path = m.Path
+	if u.Path == "" {
+		u.Path = "/auth/callback"
+	}
 	q := u.Query()
 	q.Set("token", tok)
+	if next != "" {
+		q.Set("next", next)
+	}
 	u.RawQuery = q.Encode()
 	return u.String()
 }
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMagicLinkScope(t *testing.T) {
+	coach := MagicLink{Secret: []byte("s"), BaseURL: "http://localhost:8080"}
+	athlete := AthletePortal([]byte("s"), "http://localhost:8080")
+	exp := time.Now().Add(time.Hour)
+
+	if got, err := coach.Verify(coach.Sign("a@example.com", exp)); err != nil || got != "a@example.com" {
+		t.Fatalf("coach token: %q, %v", got, err)
+	}
+	if got, err := athlete.Verify(athlete.Sign("a@example.com", exp)); err != nil || got != "a@example.com" {
+		t.Fatalf("athlete token: %q, %v", got, err)
+	}
+	if _, err := athlete.Verify(coach.Sign("a@example.com", exp)); err != ErrBadSig {
+		t.Fatalf("coach token as athlete: err = %v", err)
+	}
+	if _, err := coach.Verify(athlete.Sign("a@example.com", exp)); err != ErrBadSig {
+		t.Fatalf("athlete token as coach: err = %v", err)
+	}
+	if _, err := athlete.Verify(athlete.Sign("a@example.com", time.Now().Add(-time.Minute))); err != ErrExpired {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
+
+func TestMagicLinkURL(t *testing.T) {
+	for _, c := range []struct {
+		link MagicLink
+		path string
+	}{
+		{MagicLink{Secret: []byte("s"), BaseURL: "http://localhost:8080"}, "/auth/callback"},
+		{AthletePortal([]byte("s"), "http://localhost:8080"), "/athlete/auth/callback"},
+	} {
+		u, err := url.Parse(c.link.URL("a@example.com", time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Path != c.path || !strings.HasPrefix(u.String(), "http://localhost:8080/") {
+			t.Fatalf("URL = %s, want path %s", u, c.path)
+		}
+		if got, err := c.link.Verify(u.Query().Get("token")); err != nil || got != "a@example.com" {
+			t.Fatalf("token from URL: %q, %v", got, err)
+		}
+	}
+}
+
+func TestMagicLinkURLTo(t *testing.T) {
+	link := AthletePortal([]byte("s"), "http://localhost:8080")
+	u, err := url.Parse(link.URLTo("a@example.com", time.Hour, "/athlete/checkin?day=2025-06-02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("next"); got != "/athlete/checkin?day=2025-06-02" {
+		t.Fatalf("next = %q", got)
+	}
+	if u, _ := url.Parse(link.URL("a@example.com", time.Hour)); u.Query().Has("next") {
+		t.Fatalf("URL has next: %s", u)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	secret := []byte("s")
+	checkin := Unsubscribe(secret, "http://localhost:8080", "checkin")
+	u, err := url.Parse(checkin.URL("a@example.com", time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/unsubscribe/checkin" {
+		t.Fatalf("path = %q", u.Path)
+	}
+	tok := u.Query().Get("token")
+	if got, err := checkin.Verify(tok); err != nil || got != "a@example.com" {
+		t.Fatalf("Verify = %q, %v", got, err)
+	}
+	if _, err := Unsubscribe(secret, "http://localhost:8080", "comment").Verify(tok); err != ErrBadSig {
+		t.Fatalf("another kind's link verified: %v", err)
+	}
+	if _, err := AthletePortal(secret, "http://localhost:8080").Verify(tok); err != ErrBadSig {
+		t.Fatalf("unsubscribe link signs in: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+// Package commentary drafts a short analysis of a single workout: how it
+// was paced, how heart rate held up and how the planned session was
+// executed. The numbers are worked out here and handed to the language
+// model as Facts, so the model describes them rather than deriving them.
+// A draft waits for the coach to approve, edit or discard it.
+package commentary
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/prompts"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// Feature is the usage label for workout commentary.
+const Feature = "workout_commentary"
+
+// Review states of a note.
+const (
+	StatusDraft     = "draft"
+	StatusApproved  = "approved"
+	StatusDiscarded = "discarded"
+)
+
+// MaxBody is the longest note a coach can save, in bytes.
+const MaxBody = 4000
+
+// Facts is the workout as the model sees it.
+type Facts struct {
+	Athlete          string    `json:"athlete"`
+	Sport            string    `json:"sport"`
+	Name             string    `json:"name,omitempty"`
+	Date             string    `json:"date"`
+	DurationMin      float64   `json:"duration_min"`
+	MovingMin        float64   `json:"moving_min,omitempty"`
+	DistanceKm       float64   `json:"distance_km,omitempty"`
+	Pace             string    `json:"pace_per_km,omitempty"`
+	GAP              string    `json:"grade_adjusted_pace_per_km,omitempty"`
+	AvgHR            int       `json:"avg_hr,omitempty"`
+	ElevGainM        float64   `json:"elevation_gain_m,omitempty"`
+	Load             float64   `json:"load,omitempty"`
+	DecouplingPct    *float64  `json:"decoupling_pct,omitempty"`
+	DecouplingBasis  string    `json:"decoupling_basis,omitempty"`
+	IntensityFactor  float64   `json:"intensity_factor,omitempty"`
+	NormalizedPower  float64   `json:"normalized_power_w,omitempty"`
+	VariabilityIndex float64   `json:"variability_index,omitempty"`
+	ZoneMinutes      []float64 `json:"hr_zone_minutes,omitempty"`
+	Laps             []Lap     `json:"laps,omitempty"`
+	Split            *Split    `json:"split,omitempty"`
+	Plan             *Plan     `json:"planned_session,omitempty"`
+}
+
+// Lap is one device lap. Speed is m/s; Pace is filled in for foot sports.
+type Lap struct {
+	Index     int     `json:"lap"`
+	DistanceM float64 `json:"distance_m"`
+	MovingSec int32   `json:"moving_sec"`
+	Speed     float64 `json:"-"`
+	Pace      string  `json:"pace_per_km,omitempty"`
+	AvgHR     float64 `json:"avg_hr,omitempty"`
+	AvgWatts  float64 `json:"avg_watts,omitempty"`
+}
+
+// Split compares the two halves of the distance. ChangePct is how much
+// slower the second half was, so a negative split has a negative change.
+type Split struct {
+	FirstHalf  string  `json:"first_half_pace"`
+	SecondHalf string  `json:"second_half_pace"`
+	ChangePct  float64 `json:"change_pct"`
+	Kind       string  `json:"kind"` // negative, even or positive
+}
+
+// Plan is the session the workout was matched to.
+type Plan struct {
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Steps         []string   `json:"steps,omitempty"`
+	TargetMin     float64    `json:"target_duration_min,omitempty"`
+	TargetKm      float64    `json:"target_distance_km,omitempty"`
+	TargetZone    int        `json:"target_hr_zone,omitempty"`
+	Status        string     `json:"status"`
+	CompliancePct *float64   `json:"compliance_pct,omitempty"`
+	Execution     *Execution `json:"intervals,omitempty"`
+	ExecutionNote string     `json:"intervals_note,omitempty"` // why intervals weren't judged
+}
+
+// Execution is how the measurable work steps of a structured session went,
+// judged lap by lap.
+type Execution struct {
+	OnTarget int   `json:"on_target"`
+	Total    int   `json:"total"`
+	Reps     []Rep `json:"reps"`
+}
+
+// Rep is one work step against the lap that recorded it.
+type Rep struct {
+	Lap      int    `json:"lap"`
+	Target   string `json:"target"`
+	Actual   string `json:"actual"`
+	OnTarget bool   `json:"on_target"`
+}
+
+// evenBand is how far apart the halves can be, in percent, and still count
+// as even.
+const evenBand = 1.0
+
+// NewSplit compares the first and second half of the laps' distance, with
+// the lap that straddles halfway shared pro rata. Nil without at least two
+// laps that carry distance.
+func NewSplit(laps []Lap) *Split {
+	var total float64
+	n := 0
+	for _, l := range laps {
+		if l.DistanceM > 0 && l.MovingSec > 0 {
+			total += l.DistanceM
+			n++
+		}
+	}
+	if n < 2 {
+		return nil
+	}
+	half := total / 2
+	var done, first, second float64
+	for _, l := range laps {
+		if l.DistanceM <= 0 || l.MovingSec <= 0 {
+			continue
+		}
+		secs := float64(l.MovingSec)
+		switch {
+		case done >= half:
+			second += secs
+		case done+l.DistanceM <= half:
+			first += secs
+		default:
+			f := (half - done) / l.DistanceM
+			first += secs * f
+			second += secs * (1 - f)
+		}
+		done += l.DistanceM
+	}
+	if first <= 0 || second <= 0 {
+		return nil
+	}
+	km := half / 1000
+	s := &Split{
+		FirstHalf:  clock(first / km),
+		SecondHalf: clock(second / km),
+		ChangePct:  round1((second - first) / first * 100),
+		Kind:       "even",
+	}
+	switch {
+	case s.ChangePct <= -evenBand:
+		s.Kind = "negative"
+	case s.ChangePct >= evenBand:
+		s.Kind = "positive"
+	}
+	return s
+}
+
+// Tolerances when judging a lap against its step's target, as fractions of
+// the target range's ends.
+const (
+	paceTolerance  = 0.02
+	powerTolerance = 0.03
+)
+
+// Execute judges each work step of plan that has a measurable target
+// against its lap. It relies on the device having lapped each step, so it
+// returns nil unless there is exactly one lap per step, or when no work
+// step can be measured.
+func Execute(plan structured.Workout, laps []Lap, th metrics.Thresholds) *Execution {
+	steps := plan.Flatten()
+	if len(steps) == 0 || len(steps) != len(laps) {
+		return nil
+	}
+	var ex Execution
+	for i, s := range steps {
+		work := s.Intent == structured.IntentActive || s.Intent == ""
+		if !work || s.Target == nil {
+			continue
+		}
+		actual, ok, measured := judge(*s.Target, laps[i], th)
+		if !measured {
+			continue
+		}
+		ex.Total++
+		if ok {
+			ex.OnTarget++
+		}
+		ex.Reps = append(ex.Reps, Rep{Lap: laps[i].Index, Target: s.Target.Text(), Actual: actual, OnTarget: ok})
+	}
+	if ex.Total == 0 {
+		return nil
+	}
+	return &ex
+}
+
+// judge describes what the lap did in the target's terms and whether that
+// was on target. measured is false when the lap lacks the data to tell.
+func judge(t structured.Target, l Lap, th metrics.Thresholds) (actual string, ok, measured bool) {
+	switch t.Kind {
+	case structured.TargetPace:
+		if l.Speed <= 0 {
+			return "", false, false
+		}
+		p := 1000 / l.Speed
+		return clock(p) + "/km", p >= t.Low*(1-paceTolerance) && p <= t.High*(1+paceTolerance), true
+	case structured.TargetPower:
+		if l.AvgWatts <= 0 {
+			return "", false, false
+		}
+		return fmt.Sprintf("%.0f W", l.AvgWatts), within(l.AvgWatts, t.Low, t.High, powerTolerance), true
+	case structured.TargetHRZone:
+		if l.AvgHR <= 0 || len(th.HRZones) == 0 {
+			return "", false, false
+		}
+		z := 1
+		for z <= len(th.HRZones) && l.AvgHR > th.HRZones[z-1] {
+			z++
+		}
+		return fmt.Sprintf("Z%d (%.0f bpm)", z, l.AvgHR), float64(z) >= t.Low && float64(z) <= t.High, true
+	case structured.TargetThresholdPct:
+		var pct float64
+		switch {
+		case th.FTP > 0 && l.AvgWatts > 0:
+			pct = l.AvgWatts / th.FTP * 100
+		case th.ThresholdSpeed > 0 && l.Speed > 0:
+			pct = l.Speed / th.ThresholdSpeed * 100
+		default:
+			return "", false, false
+		}
+		return fmt.Sprintf("%.0f%% of threshold", pct), within(pct, t.Low, t.High, powerTolerance), true
+	}
+	return "", false, false
+}
+
+func within(v, lo, hi, tol float64) bool {
+	return v >= lo*(1-tol) && v <= hi*(1+tol)
+}
+
+// Prompt is the template notes are written from.
+var Prompt = prompts.Register[Facts](prompts.Def{
+	Name:    Feature,
+	Version: 1,
+	System: `You are an assistant to an endurance coach, drafting a short note on one workout that the coach will review before the athlete sees it.
+You are given the workout as JSON. Work only from those numbers; don't invent any.
+Write two to four plain sentences addressed to the athlete, no greeting or sign-off. Lead with what matters most, for example:
+- pacing: a negative or positive split, or uneven laps
+- heart-rate drift (decoupling over 5% on a steady session is worth a mention)
+- the planned session: whether it was done as planned, and how many intervals were on target
+Be specific and brief, like "Negative split, with heart rate drifting 6% over the second half; 4 of 5 threshold intervals on target."
+Reply with the note only.`,
+	User:        "{{ json . }}",
+	Temperature: 0.3,
+	MaxTokens:   300,
+})
+
+// Write asks the model for the note and returns it with the model's name.
+// Unlike the weekly report there is no fallback: a note is optional, so
+// llm.ErrQuotaExceeded is returned for the caller to skip the workout.
+func Write(ctx context.Context, p llm.Provider, f Facts) (string, string, error) {
+	req, err := Prompt.Render(f)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return "", "", err
+	}
+	note := strings.TrimSpace(resp.Message.Content)
+	if note == "" {
+		return "", "", fmt.Errorf("model returned an empty note")
+	}
+	return note, resp.Model, nil
+}
+
+func clock(secs float64) string {
+	t := int(math.Round(secs))
+	if t >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", t/3600, t/60%60, t%60)
+	}
+	return fmt.Sprintf("%d:%02d", t/60, t%60)
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
@@ -0,0 +1,144 @@
+package commentary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// kmLaps are 1 km laps at the given paces in seconds.
+func kmLaps(paces ...int32) []Lap {
+	out := make([]Lap, len(paces))
+	for i, p := range paces {
+		out[i] = Lap{Index: i + 1, DistanceM: 1000, MovingSec: p, Speed: 1000 / float64(p)}
+	}
+	return out
+}
+
+func TestNewSplit(t *testing.T) {
+	cases := []struct {
+		name  string
+		laps  []Lap
+		first string
+		kind  string
+	}{
+		{"negative", kmLaps(300, 300, 290, 280), "5:00", "negative"},
+		{"even", kmLaps(300, 302, 299, 301), "5:01", "even"},
+		{"positive", kmLaps(280, 290, 310, 330), "4:45", "positive"},
+		// The middle lap straddles halfway and is shared between halves.
+		{"odd laps", kmLaps(300, 330, 360), "5:10", "positive"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewSplit(c.laps)
+			if s == nil || s.FirstHalf != c.first || s.Kind != c.kind {
+				t.Fatalf("got %+v, want first half %s, %s", s, c.first, c.kind)
+			}
+		})
+	}
+	if s := NewSplit(kmLaps(300)); s != nil {
+		t.Errorf("one lap split = %+v", s)
+	}
+	if s := NewSplit([]Lap{{MovingSec: 60}, {MovingSec: 60}}); s != nil {
+		t.Errorf("laps without distance split = %+v", s)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	plan, err := structured.Parse("wu 10min, 5x(1km @4:00-4:10, rest 2min), cd 10min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laps := kmLaps(600)
+	for _, p := range []int32{245, 248, 252, 265, 240} {
+		laps = append(laps, kmLaps(p, 120)...)
+	}
+	laps = append(laps, kmLaps(600)...)
+	for i := range laps {
+		laps[i].Index = i + 1
+	}
+
+	ex := Execute(plan, laps, metrics.Thresholds{})
+	if ex == nil || ex.OnTarget != 4 || ex.Total != 5 {
+		t.Fatalf("got %+v, want 4 of 5", ex)
+	}
+	if r := ex.Reps[3]; r.OnTarget || r.Lap != 8 || r.Actual != "4:25/km" || r.Target != "4:00-4:10/km" {
+		t.Errorf("fourth rep = %+v", r)
+	}
+
+	if ex := Execute(plan, laps[:5], metrics.Thresholds{}); ex != nil {
+		t.Errorf("misaligned laps judged: %+v", ex)
+	}
+}
+
+func TestExecuteZonesAndThreshold(t *testing.T) {
+	plan, err := structured.Parse("3x(10min @Z3, 2min @Z1), 20min @95-105%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laps := make([]Lap, 7)
+	for i, hr := range []float64{150, 120, 150, 120, 165, 120, 0} {
+		laps[i] = Lap{Index: i + 1, AvgHR: hr, AvgWatts: 250}
+	}
+	th := metrics.Thresholds{HRZones: []float64{130, 145, 155, 170}, FTP: 250}
+
+	ex := Execute(plan, laps, th)
+	if ex == nil || ex.Total != 7 || ex.OnTarget != 6 {
+		t.Fatalf("got %+v", ex)
+	}
+	if r := ex.Reps[4]; r.OnTarget || r.Actual != "Z4 (165 bpm)" {
+		t.Errorf("third work rep = %+v", r)
+	}
+	if r := ex.Reps[6]; !r.OnTarget || r.Actual != "100% of threshold" {
+		t.Errorf("threshold rep = %+v", r)
+	}
+
+	// Without zones or heart rate nothing can be judged but the power step.
+	ex = Execute(plan, laps, metrics.Thresholds{FTP: 250})
+	if ex == nil || ex.Total != 1 {
+		t.Fatalf("got %+v", ex)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	f := Facts{Athlete: "Anna", Sport: "Run", Date: "Sunday 2024-03-03 08:00", Laps: kmLaps(300, 290), Split: NewSplit(kmLaps(300, 290))}
+	fake := &llm.Fake{Replies: []llm.Message{{Content: "  Negative split.\n"}, {Content: " "}}}
+
+	note, model, err := Write(context.Background(), fake, f)
+	if err != nil || note != "Negative split." || model != "fake" {
+		t.Fatalf("got %q %q %v", note, model, err)
+	}
+	req := fake.Calls()[0]
+	var sent Facts
+	if err := json.Unmarshal([]byte(req.Messages[1].Content), &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Split.Kind != "negative" || sent.Laps[1].Pace != "" || sent.Laps[1].MovingSec != 290 {
+		t.Fatalf("sent %+v", sent)
+	}
+	if !strings.Contains(req.Messages[1].Content, `"kind": "negative"`) {
+		t.Errorf("prompt facts = %s", req.Messages[1].Content)
+	}
+
+	if _, _, err := Write(context.Background(), fake, f); err == nil {
+		t.Error("empty note accepted")
+	}
+}
+
+type overQuota struct{ *llm.Fake }
+
+func (overQuota) Complete(context.Context, llm.Request) (llm.Response, error) {
+	return llm.Response{}, llm.ErrQuotaExceeded
+}
+
+func TestWriteOverQuota(t *testing.T) {
+	if _, _, err := Write(context.Background(), overQuota{&llm.Fake{}}, Facts{}); !errors.Is(err, llm.ErrQuotaExceeded) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package commentary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Collect gathers the facts for workout w: its metrics, its laps and the
+// planned session it was matched to, if any. th are the athlete's
+// thresholds, for judging intervals.
+func Collect(ctx context.Context, q *db.Queries, w db.GetWorkoutForCommentaryRow, athlete db.Athlete, th metrics.Thresholds) (Facts, error) {
+	loc := training.Location(athlete.Tz)
+	f := Facts{
+		Athlete:     athlete.Name,
+		Sport:       w.Sport,
+		Name:        w.Name.String,
+		Date:        w.StartedAt.Time.In(loc).Format("Monday 2006-01-02 15:04"),
+		DurationMin: round1(float64(w.DurationSec) / 60),
+		ElevGainM:   math.Round(w.ElevGainM.Float64),
+		Load:        math.Round(w.Load.Float64),
+	}
+	foot := FootSport(w.Sport)
+	if w.MovingSec.Valid {
+		f.MovingMin = round1(float64(w.MovingSec.Int32) / 60)
+	}
+	if w.DistanceM.Valid && w.DistanceM.Float64 > 0 {
+		f.DistanceKm = round1(w.DistanceM.Float64 / 1000)
+		if secs := w.MovingSec.Int32; foot && secs > 0 {
+			f.Pace = clock(float64(secs) / (w.DistanceM.Float64 / 1000))
+		}
+	}
+	if foot && w.GapSpeed.Valid && w.GapSpeed.Float64 > 0 {
+		f.GAP = clock(1000 / w.GapSpeed.Float64)
+	}
+	if w.AvgHr.Valid {
+		f.AvgHR = int(w.AvgHr.Int32)
+	}
+	if w.DecouplingPct.Valid {
+		d := round1(w.DecouplingPct.Float64)
+		f.DecouplingPct, f.DecouplingBasis = &d, w.DecouplingBasis.String
+	}
+	f.IntensityFactor = math.Round(w.IntensityFactor.Float64*100) / 100
+	f.NormalizedPower = math.Round(w.NormalizedPower.Float64)
+	f.VariabilityIndex = math.Round(w.VariabilityIndex.Float64*100) / 100
+	for _, z := range w.ZoneSec {
+		f.ZoneMinutes = append(f.ZoneMinutes, round1(float64(z)/60))
+	}
+
+	rows, err := q.ListWorkoutLaps(ctx, w.ID)
+	if err != nil {
+		return f, fmt.Errorf("list laps: %w", err)
+	}
+	f.Laps = Laps(rows, foot)
+	f.Split = NewSplit(f.Laps)
+	if !foot {
+		f.Split = nil
+	}
+
+	plan, err := q.GetPlannedWorkoutForWorkout(ctx, pgtype.UUID{Bytes: w.ID, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return f, nil
+	}
+	if err != nil {
+		return f, fmt.Errorf("get planned workout: %w", err)
+	}
+	f.Plan = planFacts(plan, f.Laps, th)
+	return f, nil
+}
+
+// Laps converts stored laps, with pace for foot sports.
+func Laps(rows []db.WorkoutLap, foot bool) []Lap {
+	out := make([]Lap, 0, len(rows))
+	for _, r := range rows {
+		l := Lap{
+			Index:     int(r.LapIndex),
+			DistanceM: math.Round(r.DistanceM),
+			MovingSec: r.MovingSec,
+			Speed:     r.AvgSpeed.Float64,
+			AvgHR:     math.Round(r.AvgHr.Float64),
+			AvgWatts:  math.Round(r.AvgWatts.Float64),
+		}
+		if foot && l.Speed > 0 {
+			l.Pace = clock(1000 / l.Speed)
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func planFacts(p db.PlannedWorkout, laps []Lap, th metrics.Thresholds) *Plan {
+	out := &Plan{
+		Title:       p.Title,
+		Description: p.Description.String,
+		TargetMin:   round1(float64(p.TargetDurationSec.Int32) / 60),
+		TargetKm:    round1(p.TargetDistanceM.Float64 / 1000),
+		TargetZone:  int(p.TargetZone.Int32),
+		Status:      p.Status,
+	}
+	if p.Compliance.Valid {
+		c := math.Round(p.Compliance.Float64 * 100)
+		out.CompliancePct = &c
+	}
+	if len(p.Structure) == 0 {
+		return out
+	}
+	s, err := structured.Decode(p.Structure)
+	if err != nil {
+		return out
+	}
+	out.Steps = s.Lines()
+	out.Execution = Execute(s, laps, th)
+	if out.Execution == nil && len(s.Flatten()) != len(laps) {
+		out.ExecutionNote = "laps don't line up one to one with the planned steps, so intervals can't be judged individually"
+	}
+	return out
+}
+
+// FootSport reports whether speed is read as pace for the sport.
+func FootSport(sport string) bool {
+	return strings.Contains(sport, "Run") || sport == "Walk" || sport == "Hike"
+}
@@ -0,0 +1,135 @@
+// Package comments is the conversation between a coach and an athlete
+// about one workout or planned session. Comments are written in the app by
+// the coach, on a token-protected page by the athlete, or by either side
+// replying to the notification email: each side's notifications carry a
+// Reply-To address holding that side's thread token, and the inbound mail
+// webhook turns the reply back into a comment.
+package comments
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Authors.
+const (
+	AuthorCoach   = "coach"
+	AuthorAthlete = "athlete"
+)
+
+// Where a comment was written.
+const (
+	ViaWeb   = "web"
+	ViaEmail = "email"
+)
+
+// MaxBody is the longest comment, in bytes.
+const MaxBody = 4000
+
+// tokenBytes keeps the token short enough for the local part of an
+// address, which is capped at 64 characters.
+const tokenBytes = 12
+
+// replyPrefix starts the local part of a reply address, as in
+// reply+<token>@<domain>.
+const replyPrefix = "reply+"
+
+var (
+	ErrEmpty   = errors.New("comment is empty")
+	ErrTooLong = errors.New("comment is too long")
+)
+
+// Clean normalises a comment's line endings and whitespace and checks its
+// length.
+func Clean(body string) (string, error) {
+	body = strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+	switch {
+	case body == "":
+		return "", ErrEmpty
+	case len(body) > MaxBody:
+		return "", ErrTooLong
+	}
+	return body, nil
+}
+
+// ReplyAddress is the Reply-To for a notification sent to the side that
+// owns token. It is empty when no domain is set up to receive replies.
+func ReplyAddress(token, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	return replyPrefix + token + "@" + domain
+}
+
+// TokenFromAddress finds a reply address on domain among the recipients in
+// list, which is a To header or a bare address, and returns its token.
+func TokenFromAddress(list, domain string) (string, bool) {
+	if domain == "" {
+		return "", false
+	}
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		return "", false
+	}
+	for _, a := range addrs {
+		local, host, ok := strings.Cut(a.Address, "@")
+		if !ok || !strings.EqualFold(host, domain) {
+			continue
+		}
+		if tok, ok := strings.CutPrefix(strings.ToLower(local), replyPrefix); ok && tok != "" {
+			// Tokens are case-sensitive but mail systems may lowercase the
+			// prefix; take the token from the original.
+			return local[len(replyPrefix):], true
+		}
+	}
+	return "", false
+}
+
+// SameAddress reports whether from, a From header, is the address want.
+func SameAddress(from, want string) bool {
+	a, err := mail.ParseAddress(from)
+	return err == nil && want != "" && strings.EqualFold(a.Address, strings.TrimSpace(want))
+}
+
+// Lines that start the quoted message or signature below a reply.
+var replyMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^On .+ wrote:$`),             // Gmail, Apple Mail
+	regexp.MustCompile(`^-+ ?Original Message ?-+$`), // Outlook
+	regexp.MustCompile(`^_{10,}$`),                   // Outlook on the web
+	regexp.MustCompile(`^From: .+`),                  // forwarded headers
+	regexp.MustCompile(`^--$`),                       // signature delimiter
+	regexp.MustCompile(`^Sent from my \w+`),          // mobile signatures
+}
+
+// StripQuoted cuts an emailed reply down to what was written: everything
+// before the quoted message, minus quoted lines and the signature.
+func StripQuoted(text string) string {
+	var keep []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if marker(trimmed) {
+			break
+		}
+		// Gmail wraps a long attribution, leaving "wrote:" on its own line.
+		if trimmed == "wrote:" && len(keep) > 0 && strings.HasPrefix(strings.TrimSpace(keep[len(keep)-1]), "On ") {
+			keep = keep[:len(keep)-1]
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		keep = append(keep, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(strings.Join(keep, "\n"))
+}
+
+func marker(line string) bool {
+	for _, re := range replyMarkers {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
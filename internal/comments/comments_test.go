@@ -0,0 +1,86 @@
+package comments
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	if got, err := Clean("  Felt good.\r\nLegs heavy.\r\n "); err != nil || got != "Felt good.\nLegs heavy." {
+		t.Fatalf("Clean = %q, %v", got, err)
+	}
+	if _, err := Clean(" \n "); err != ErrEmpty {
+		t.Fatalf("blank: err = %v", err)
+	}
+	if _, err := Clean(strings.Repeat("x", MaxBody+1)); err != ErrTooLong {
+		t.Fatalf("long: err = %v", err)
+	}
+}
+
+func TestReplyAddress(t *testing.T) {
+	if got := ReplyAddress("AbC123", ""); got != "" {
+		t.Fatalf("no domain: %q", got)
+	}
+	addr := ReplyAddress("AbC123", "reply.example.com")
+	if addr != "reply+AbC123@reply.example.com" {
+		t.Fatalf("ReplyAddress = %q", addr)
+	}
+
+	cases := []struct {
+		list string
+		want string
+		ok   bool
+	}{
+		{addr, "AbC123", true},
+		{`"Coach" <reply+AbC123@Reply.Example.com>`, "AbC123", true},
+		{"coach@example.com, Reply+AbC123@reply.example.com", "AbC123", true},
+		{"reply+AbC123@other.example.com", "", false},
+		{"reply+@reply.example.com", "", false},
+		{"coach@reply.example.com", "", false},
+		{"not an address", "", false},
+	}
+	for _, c := range cases {
+		got, ok := TokenFromAddress(c.list, "reply.example.com")
+		if got != c.want || ok != c.ok {
+			t.Errorf("TokenFromAddress(%q) = %q, %v; want %q, %v", c.list, got, ok, c.want, c.ok)
+		}
+	}
+	if _, ok := TokenFromAddress(addr, ""); ok {
+		t.Fatal("matched without a domain")
+	}
+}
+
+func TestSameAddress(t *testing.T) {
+	if !SameAddress(`"Ann Runner" <Ann@Example.com>`, "ann@example.com") {
+		t.Fatal("same address with a name and different case")
+	}
+	if SameAddress("ann@example.com", "bob@example.com") {
+		t.Fatal("different addresses")
+	}
+	if SameAddress("ann@example.com", "") {
+		t.Fatal("matched an empty address")
+	}
+}
+
+func TestStripQuoted(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"plain", "Legs felt heavy.\n\nWill rest tomorrow.", "Legs felt heavy.\n\nWill rest tomorrow."},
+		{"gmail", "Sounds good!\r\n\r\nOn Mon, 3 Mar 2025 at 07:00, Coach <reply+x@example.com> wrote:\r\n> How did it go?", "Sounds good!"},
+		{"gmail wrapped", "Sounds good!\n\nOn Mon, 3 Mar 2025 at 07:00, Coach Somebody <reply+x@example.com>\nwrote:\n> How did it go?", "Sounds good!"},
+		{"outlook", "Done.\n\n-----Original Message-----\nFrom: Coach\nHow did it go?", "Done."},
+		{"outlook web", "Done.\n________________________________\nFrom: Coach", "Done."},
+		{"signature", "Done.\n--\nAnn", "Done."},
+		{"mobile", "Done.\n\nSent from my iPhone", "Done."},
+		{"inline quotes", "> How did it go?\nWell.\n> Any pain?\nNone.", "Well.\nNone."},
+		{"on in text", "On the hills I was slow.\nOtherwise fine.", "On the hills I was slow.\nOtherwise fine."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := StripQuoted(c.in); got != c.want {
+				t.Fatalf("StripQuoted = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
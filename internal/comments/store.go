@@ -0,0 +1,62 @@
+package comments
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+// WorkoutThread returns the thread on a workout, starting it if needed.
+func WorkoutThread(ctx context.Context, q *db.Queries, athleteID, workoutID uuid.UUID) (db.CommentThread, error) {
+	coach, athlete, err := newTokens()
+	if err != nil {
+		return db.CommentThread{}, err
+	}
+	return q.EnsureWorkoutCommentThread(ctx, db.EnsureWorkoutCommentThreadParams{
+		AthleteID:    athleteID,
+		WorkoutID:    pgtype.UUID{Bytes: workoutID, Valid: true},
+		CoachToken:   coach,
+		AthleteToken: athlete,
+	})
+}
+
+// PlannedThread returns the thread on a planned session, starting it if
+// needed.
+func PlannedThread(ctx context.Context, q *db.Queries, athleteID, plannedID uuid.UUID) (db.CommentThread, error) {
+	coach, athlete, err := newTokens()
+	if err != nil {
+		return db.CommentThread{}, err
+	}
+	return q.EnsurePlannedWorkoutCommentThread(ctx, db.EnsurePlannedWorkoutCommentThreadParams{
+		AthleteID:        athleteID,
+		PlannedWorkoutID: pgtype.UUID{Bytes: plannedID, Valid: true},
+		CoachToken:       coach,
+		AthleteToken:     athlete,
+	})
+}
+
+func newTokens() (coach, athlete string, err error) {
+	if coach, err = auth.RandomToken(tokenBytes); err != nil {
+		return "", "", fmt.Errorf("thread token: %w", err)
+	}
+	if athlete, err = auth.RandomToken(tokenBytes); err != nil {
+		return "", "", fmt.Errorf("thread token: %w", err)
+	}
+	return coach, athlete, nil
+}
+
+// Author is the side a thread token belongs to, or "" for neither.
+func Author(t db.CommentThread, token string) string {
+	switch token {
+	case t.CoachToken:
+		return AuthorCoach
+	case t.AthleteToken:
+		return AuthorAthlete
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+// Package compliance pairs completed workouts with the sessions a coach
+// planned and scores how closely the athlete hit the targets.
+package compliance
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session statuses. Planned and Missed are never stored together: a planned
+// session becomes missed once its day has passed without a match.
+const (
+	StatusPlanned   = "planned"
+	StatusCompleted = "completed"
+	StatusPartial   = "partial"
+	StatusMissed    = "missed"
+	StatusUnplanned = "unplanned"
+)
+
+// CompletedScore is the score at or above which a session counts as
+// completed rather than partial.
+const CompletedScore = 0.8
+
+// zoneShareGoal is the share of heart-rate time in the target zone that
+// earns a full zone score; warm-ups and recoveries sit outside it.
+const zoneShareGoal = 0.7
+
+// Target is what the coach planned. Zero values are unset.
+type Target struct {
+	Sport       string
+	DurationSec float64
+	DistanceM   float64
+	Load        float64
+	Zone        int // 1-5
+}
+
+// Actual is what the athlete recorded.
+type Actual struct {
+	Sport       string
+	DurationSec float64
+	DistanceM   float64
+	Load        float64
+	ZoneSec     []int // seconds per heart-rate zone, zone 1 first
+}
+
+// Pair links a planned session to the workout matched to it, both as
+// indexes into the slices given to Match.
+type Pair struct {
+	Planned int
+	Actual  int
+	Score   float64
+}
+
+// SameSport reports whether two Strava sport types are interchangeable for
+// matching, e.g. a planned Run done as a TrailRun.
+func SameSport(a, b string) bool {
+	return family(a) == family(b)
+}
+
+func family(sport string) string {
+	s := strings.ToLower(sport)
+	switch {
+	case strings.HasSuffix(s, "run"):
+		return "run"
+	case strings.HasSuffix(s, "ride"):
+		return "ride"
+	case strings.Contains(s, "swim"):
+		return "swim"
+	}
+	return s
+}
+
+// ratio scores actual against target: 1 when equal, falling linearly to 0
+// at 100% off in either direction.
+func ratio(target, actual float64) float64 {
+	return math.Max(0, 1-math.Abs(actual-target)/target)
+}
+
+// Score rates a workout against its target from 0 to 1, averaging duration,
+// distance, load and time in zone over the targets that were set. A session
+// with no targets scores 1 simply for being done.
+func Score(t Target, a Actual) float64 {
+	var sum float64
+	var n int
+	if t.DurationSec > 0 {
+		sum += ratio(t.DurationSec, a.DurationSec)
+		n++
+	}
+	if t.DistanceM > 0 {
+		sum += ratio(t.DistanceM, a.DistanceM)
+		n++
+	}
+	if t.Load > 0 && a.Load > 0 {
+		sum += ratio(t.Load, a.Load)
+		n++
+	}
+	if t.Zone > 0 && len(a.ZoneSec) > 0 {
+		var total, in float64
+		for i, s := range a.ZoneSec {
+			total += float64(s)
+			if i == t.Zone-1 {
+				in = float64(s)
+			}
+		}
+		if total > 0 {
+			sum += math.Min(1, in/total/zoneShareGoal)
+			n++
+		}
+	}
+	if n == 0 {
+		return 1
+	}
+	return sum / float64(n)
+}
+
+// StatusFor turns a match score into completed or partial.
+func StatusFor(score float64) string {
+	if score >= CompletedScore {
+		return StatusCompleted
+	}
+	return StatusPartial
+}
+
+// Effective resolves the displayed status of a stored one: sessions still
+// planned after their day are missed. day and today are local midnights.
+func Effective(status string, day, today time.Time) string {
+	if status == StatusPlanned && day.Before(today) {
+		return StatusMissed
+	}
+	return status
+}
+
+// Match pairs one day's planned sessions with that day's workouts. Only
+// sessions of the same sport are paired, closest to target first, and each
+// side is used at most once. Unpaired workouts are unplanned.
+func Match(planned []Target, actual []Actual) []Pair {
+	var cands []Pair
+	for i, t := range planned {
+		for j, a := range actual {
+			if SameSport(t.Sport, a.Sport) {
+				cands = append(cands, Pair{Planned: i, Actual: j, Score: Score(t, a)})
+			}
+		}
+	}
+	sort.SliceStable(cands, func(x, y int) bool { return cands[x].Score > cands[y].Score })
+
+	usedP := make([]bool, len(planned))
+	usedA := make([]bool, len(actual))
+	var out []Pair
+	for _, c := range cands {
+		if usedP[c.Planned] || usedA[c.Actual] {
+			continue
+		}
+		usedP[c.Planned], usedA[c.Actual] = true, true
+		out = append(out, c)
+	}
+	sort.Slice(out, func(x, y int) bool { return out[x].Planned < out[y].Planned })
+	return out
+}
+
+// Session is a stored planned session for weekly roll-ups.
+type Session struct {
+	Day    time.Time // local midnight
+	Status string
+	Score  float64
+}
+
+// Week summarises compliance for the sessions due so far.
+type Week struct {
+	Due       int
+	Completed int
+	Partial   int
+	Missed    int
+	Percent   float64
+}
+
+// Summarize rolls up sessions up to and including today. Sessions later
+// today that are still planned aren't due yet and are left out; missed
+// sessions count as zero.
+func Summarize(sessions []Session, today time.Time) Week {
+	var w Week
+	var sum float64
+	for _, s := range sessions {
+		if s.Day.After(today) || (s.Day.Equal(today) && s.Status == StatusPlanned) {
+			continue
+		}
+		w.Due++
+		switch Effective(s.Status, s.Day, today) {
+		case StatusCompleted:
+			w.Completed++
+			sum += s.Score
+		case StatusPartial:
+			w.Partial++
+			sum += s.Score
+		case StatusMissed:
+			w.Missed++
+		}
+	}
+	if w.Due > 0 {
+		w.Percent = sum / float64(w.Due) * 100
+	}
+	return w
+}
@@ -0,0 +1,65 @@
+package compliance
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	tgt := Target{Sport: "Run", DurationSec: 3600, DistanceM: 10000}
+	if s := Score(tgt, Actual{Sport: "Run", DurationSec: 3600, DistanceM: 10000}); s != 1 {
+		t.Fatalf("exact match = %v, want 1", s)
+	}
+	// 45 of 60 minutes and 8 of 10 km: (0.75 + 0.8) / 2
+	if s := Score(tgt, Actual{Sport: "Run", DurationSec: 2700, DistanceM: 8000}); math.Abs(s-0.775) > 1e-9 {
+		t.Fatalf("short run = %v, want 0.775", s)
+	}
+	// Load is ignored when the workout has none.
+	if s := Score(Target{Load: 80}, Actual{}); s != 1 {
+		t.Fatalf("unknown load = %v, want 1", s)
+	}
+	// Half the time in zone 2 with a 70% goal.
+	z := Score(Target{Zone: 2}, Actual{ZoneSec: []int{600, 1800, 600, 0, 0}})
+	if math.Abs(z-0.6/0.7) > 1e-9 {
+		t.Fatalf("zone score = %v", z)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	planned := []Target{
+		{Sport: "Run", DurationSec: 1800},
+		{Sport: "Run", DurationSec: 5400},
+		{Sport: "Ride", DurationSec: 3600},
+	}
+	actual := []Actual{
+		{Sport: "TrailRun", DurationSec: 5000},
+		{Sport: "Run", DurationSec: 1700},
+		{Sport: "Swim", DurationSec: 1800},
+	}
+	pairs := Match(planned, actual)
+	if len(pairs) != 2 {
+		t.Fatalf("pairs = %+v, want 2", pairs)
+	}
+	if pairs[0].Planned != 0 || pairs[0].Actual != 1 || pairs[1].Planned != 1 || pairs[1].Actual != 0 {
+		t.Fatalf("runs paired by closest target, got %+v", pairs)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	today := day(12)
+	w := Summarize([]Session{
+		{Day: day(10), Status: StatusCompleted, Score: 1},
+		{Day: day(11), Status: StatusPartial, Score: 0.5},
+		{Day: day(11), Status: StatusPlanned},   // missed
+		{Day: day(12), Status: StatusPlanned},   // later today
+		{Day: day(13), Status: StatusCompleted}, // not due
+	}, today)
+	if w.Due != 3 || w.Completed != 1 || w.Partial != 1 || w.Missed != 1 {
+		t.Fatalf("unexpected counts: %+v", w)
+	}
+	if math.Abs(w.Percent-50) > 1e-9 {
+		t.Fatalf("percent = %v, want 50", w.Percent)
+	}
+}
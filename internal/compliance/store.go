@@ -0,0 +1,81 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+// MatchDay re-pairs the athlete's planned sessions and workouts on one local
+// day and stores the result on each planned session. It is idempotent, so
+// it runs after every workout upsert and every plan edit touching the day.
+func MatchDay(ctx context.Context, q *db.Queries, athleteID uuid.UUID, day time.Time, loc *time.Location) error {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	date := pgtype.Date{Time: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+
+	planned, err := q.ListPlannedWorkoutsBetween(ctx, db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: athleteID,
+		FromDay:   date,
+		ToDay:     date,
+	})
+	if err != nil {
+		return fmt.Errorf("list planned workouts: %w", err)
+	}
+	if len(planned) == 0 {
+		return nil
+	}
+	done, err := q.ListWorkoutsForMatching(ctx, db.ListWorkoutsForMatchingParams{
+		AthleteID: athleteID,
+		FromTime:  pgtype.Timestamptz{Time: day, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: day.AddDate(0, 0, 1), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("list workouts: %w", err)
+	}
+
+	targets := make([]Target, len(planned))
+	for i, p := range planned {
+		targets[i] = Target{
+			Sport:       p.Sport,
+			DurationSec: float64(p.TargetDurationSec.Int32),
+			DistanceM:   p.TargetDistanceM.Float64,
+			Load:        p.TargetLoad.Float64,
+			Zone:        int(p.TargetZone.Int32),
+		}
+	}
+	actual := make([]Actual, len(done))
+	for i, w := range done {
+		zones := make([]int, len(w.ZoneSec))
+		for z, s := range w.ZoneSec {
+			zones[z] = int(s)
+		}
+		actual[i] = Actual{
+			Sport:       w.Sport,
+			DurationSec: float64(w.DurationSec),
+			DistanceM:   w.DistanceM.Float64,
+			Load:        w.Load.Float64,
+			ZoneSec:     zones,
+		}
+	}
+
+	params := make([]db.SetPlannedWorkoutMatchParams, len(planned))
+	for i, p := range planned {
+		params[i] = db.SetPlannedWorkoutMatchParams{ID: p.ID, Status: StatusPlanned}
+	}
+	for _, m := range Match(targets, actual) {
+		params[m.Planned].WorkoutID = pgtype.UUID{Bytes: done[m.Actual].ID, Valid: true}
+		params[m.Planned].Status = StatusFor(m.Score)
+		params[m.Planned].Compliance = pgtype.Float8{Float64: m.Score, Valid: true}
+	}
+	for _, p := range params {
+		if err := q.SetPlannedWorkoutMatch(ctx, p); err != nil {
+			return fmt.Errorf("set planned workout match: %w", err)
+		}
+	}
+	return nil
+}
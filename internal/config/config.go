@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -15,6 +16,10 @@ type Config struct {
 	JWTSecret   string `env:"JWT_SECRET,required"`
 	BaseURL     string `env:"BASE_URL,required"`
 	Strava      StravaConfig
+	Alerts      AlertConfig
+	LLM         LLMConfig
+	Email       EmailConfig
+	Wellness    WellnessConfig
 
 	RedisAddr string `env:"REDIS_ADDR,required"`
 }
@@ -24,6 +29,65 @@ type StravaConfig struct {
 	ClientSecret string `env:"STRAVA_CLIENT_SECRET,required"`
 }
 
+// AlertConfig holds the injury-risk thresholds the worker checks after each
+// sync and on its daily sweep.
+type AlertConfig struct {
+	ACWRMax       float64 `env:"ALERT_ACWR_MAX" envDefault:"1.5"`
+	WeeklyJumpPct float64 `env:"ALERT_WEEKLY_JUMP_PCT" envDefault:"30"`
+	MaxHardDays   int     `env:"ALERT_MAX_HARD_DAYS" envDefault:"2"`
+	MaxGapDays    int     `env:"ALERT_MAX_GAP_DAYS" envDefault:"7"`
+	EmailCoach    bool    `env:"ALERT_EMAIL_COACH" envDefault:"false"`
+}
+
+// LLMConfig selects the language model. The fake provider answers
+// deterministically without a network, so it is the default outside
+// production. Any OpenAI-compatible endpoint works with the openai provider.
+type LLMConfig struct {
+	Provider          string        `env:"LLM_PROVIDER" envDefault:"fake"` // openai or fake
+	BaseURL           string        `env:"LLM_BASE_URL" envDefault:"https://api.openai.com/v1"`
+	APIKey            string        `env:"LLM_API_KEY"`
+	Model             string        `env:"LLM_MODEL" envDefault:"gpt-4o-mini"`
+	Timeout           time.Duration `env:"LLM_TIMEOUT" envDefault:"60s"`
+	MaxRetries        int           `env:"LLM_MAX_RETRIES" envDefault:"2"`
+	MonthlyTokenLimit int64         `env:"LLM_MONTHLY_TOKEN_LIMIT" envDefault:"0"` // per coach; 0 is unlimited
+
+	// Drafting a note on each newly synced workout is opt-in, and runs on
+	// its own small worker pool.
+	WorkoutCommentary     bool `env:"LLM_WORKOUT_COMMENTARY" envDefault:"false"`
+	CommentaryConcurrency int  `env:"LLM_COMMENTARY_CONCURRENCY" envDefault:"2"`
+}
+
+// EmailConfig covers mail going out and coming back in.
+//
+// The worker sends with Provider: SMTP, by default to MailHog on
+// localhost:1025 without TLS or auth, or an HTTP email API at APIURL.
+//
+// Replies to comment notifications go to reply+<token>@ReplyDomain, whose
+// inbound mail service posts them to /inbound/email?secret=InboundSecret.
+// Leaving either unset turns reply-by-email off.
+type EmailConfig struct {
+	Provider     string `env:"EMAIL_PROVIDER" envDefault:"smtp"` // smtp, http or stdout
+	From         string `env:"EMAIL_FROM" envDefault:"no-reply@coachgpt.local"`
+	SMTPHost     string `env:"SMTP_HOST" envDefault:"localhost"`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"1025"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	SMTPTLS      string `env:"SMTP_TLS" envDefault:"none"` // none, starttls or implicit
+	APIURL       string `env:"EMAIL_API_URL"`
+	APIKey       string `env:"EMAIL_API_KEY"`
+
+	ReplyDomain   string `env:"EMAIL_REPLY_DOMAIN"`
+	InboundSecret string `env:"EMAIL_INBOUND_SECRET"`
+}
+
+// WellnessConfig controls the daily check-in email, sent at CheckinHour in
+// each athlete's time zone with a link that signs them straight into the
+// day's check-in.
+type WellnessConfig struct {
+	CheckinEmails bool `env:"WELLNESS_CHECKIN_EMAILS" envDefault:"false"`
+	CheckinHour   int  `env:"WELLNESS_CHECKIN_HOUR" envDefault:"7"`
+}
+
 func Load() Config {
 	var cfg Config
 
@@ -21,6 +21,77 @@ type Athlete struct {
 	StravaTokenExpiry  pgtype.Timestamptz
 	CreatedAt          pgtype.Timestamptz
 	LastStravaSync     pgtype.Timestamptz
+	MaxHr              pgtype.Int4
+	RestingHr          pgtype.Int4
+	FtpWatts           pgtype.Int4
+	ThresholdPaceSec   pgtype.Int4
+}
+
+type AthleteAlert struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	Kind        string
+	Day         pgtype.Date
+	Value       float64
+	Threshold   float64
+	Message     string
+	DismissedAt pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+}
+
+type AthleteEvent struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	Name        string
+	Day         pgtype.Date
+	Priority    string
+	Sport       string
+	DistanceM   pgtype.Float8
+	GoalTimeSec pgtype.Int4
+	Notes       pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+}
+
+type CalendarFeed struct {
+	Token     string
+	CoachID   uuid.UUID
+	AthleteID pgtype.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
+type ChatConversation struct {
+	ID         uuid.UUID
+	CoachID    uuid.UUID
+	AthleteID  uuid.UUID
+	Title      string
+	ReplyingAt pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+}
+
+type ChatMessage struct {
+	ID             int64
+	ConversationID uuid.UUID
+	Role           string
+	Content        string
+	ToolCalls      []byte
+	ToolCallID     pgtype.Text
+	CreatedAt      pgtype.Timestamptz
+	Model          pgtype.Text
+	PromptVersion  pgtype.Text
+}
+
+type ChatToolCall struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	CoachID        uuid.UUID
+	AthleteID      uuid.UUID
+	Tool           string
+	Arguments      string
+	Result         string
+	Error          pgtype.Text
+	DurationMs     int32
+	CreatedAt      pgtype.Timestamptz
 }
 
 type Coach struct {
@@ -31,25 +102,258 @@ type Coach struct {
 	CreatedAt pgtype.Timestamptz
 }
 
+type CommentThread struct {
+	ID               uuid.UUID
+	AthleteID        uuid.UUID
+	WorkoutID        pgtype.UUID
+	PlannedWorkoutID pgtype.UUID
+	CoachToken       string
+	AthleteToken     string
+	CreatedAt        pgtype.Timestamptz
+}
+
+type EmailOutbox struct {
+	ID              uuid.UUID
+	Kind            string
+	CoachID         pgtype.UUID
+	AthleteID       pgtype.UUID
+	ToAddr          string
+	ReplyTo         pgtype.Text
+	Subject         string
+	Html            string
+	Status          string
+	Attempts        int32
+	LastError       pgtype.Text
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	SentAt          pgtype.Timestamptz
+	TextBody        string
+	ListUnsubscribe pgtype.Text
+}
+
+type EmailSuppression struct {
+	Address   string
+	Kind      string
+	CreatedAt pgtype.Timestamptz
+}
+
+type LlmUsage struct {
+	ID               uuid.UUID
+	CoachID          uuid.UUID
+	Feature          string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+	Estimated        bool
+	CreatedAt        pgtype.Timestamptz
+}
+
 type SessionStore struct {
 	Token  string
 	Data   []byte
 	Expiry pgtype.Timestamptz
 }
 
-type Workout struct {
+type PlanDraft struct {
+	ID            uuid.UUID
+	CoachID       uuid.UUID
+	AthleteID     uuid.UUID
+	EventID       pgtype.UUID
+	TemplateID    pgtype.UUID
+	StartDay      pgtype.Date
+	Weeks         int32
+	Days          []int32
+	Notes         pgtype.Text
+	Status        string
+	Error         pgtype.Text
+	Warnings      []string
+	Model         string
+	CreatedAt     pgtype.Timestamptz
+	PublishedAt   pgtype.Timestamptz
+	PromptVersion string
+}
+
+type PlanOperation struct {
+	ID        uuid.UUID
+	CoachID   uuid.UUID
+	Kind      string
+	Summary   string
+	Undo      []byte
+	CreatedAt pgtype.Timestamptz
+	UndoneAt  pgtype.Timestamptz
+}
+
+type PlanTemplate struct {
 	ID          uuid.UUID
-	AthleteID   uuid.UUID
-	Source      string
-	SourceID    int64
-	Name        pgtype.Text
-	Sport       string
-	StartedAt   pgtype.Timestamptz
-	DurationSec int32
-	DistanceM   pgtype.Float8
-	ElevGainM   pgtype.Float8
-	AvgHr       pgtype.Int4
-	RawJson     []byte
+	CoachID     uuid.UUID
+	Name        string
+	Description pgtype.Text
+	Weeks       int32
 	CreatedAt   pgtype.Timestamptz
 	UpdatedAt   pgtype.Timestamptz
 }
+
+type PlanTemplateApplication struct {
+	ID         uuid.UUID
+	TemplateID uuid.UUID
+	AthleteID  uuid.UUID
+	StartDay   pgtype.Date
+	Scale      float64
+	CreatedAt  pgtype.Timestamptz
+}
+
+type PlanTemplateSession struct {
+	ID                uuid.UUID
+	TemplateID        uuid.UUID
+	Week              int32
+	Day               int32
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+}
+
+type PlannedWorkout struct {
+	ID                uuid.UUID
+	AthleteID         uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	TargetZone        pgtype.Int4
+	WorkoutID         pgtype.UUID
+	Status            string
+	Compliance        pgtype.Float8
+	Structure         []byte
+	TemplateSessionID pgtype.UUID
+	ApplicationID     pgtype.UUID
+}
+
+type ThreadComment struct {
+	ID        int64
+	ThreadID  uuid.UUID
+	Author    string
+	Body      string
+	Via       string
+	CreatedAt pgtype.Timestamptz
+}
+
+type TrainingPhase struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	Kind        string
+	StartDay    pgtype.Date
+	EndDay      pgtype.Date
+	RampPerWeek pgtype.Float8
+	Notes       pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+}
+
+type WeeklyReport struct {
+	ID            uuid.UUID
+	CoachID       uuid.UUID
+	WeekStart     pgtype.Date
+	Facts         []byte
+	Summary       string
+	Highlights    []string
+	Concerns      []string
+	Model         string
+	CreatedAt     pgtype.Timestamptz
+	EmailedAt     pgtype.Timestamptz
+	PromptVersion string
+}
+
+type WellnessCheckin struct {
+	AthleteID    uuid.UUID
+	Day          pgtype.Date
+	SleepHours   pgtype.Float8
+	SleepQuality pgtype.Int4
+	Soreness     pgtype.Int4
+	Stress       pgtype.Int4
+	Mood         pgtype.Int4
+	RestingHr    pgtype.Int4
+	Notes        pgtype.Text
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+}
+
+type Workout struct {
+	ID           uuid.UUID
+	AthleteID    uuid.UUID
+	Source       string
+	SourceID     int64
+	Name         pgtype.Text
+	Sport        string
+	StartedAt    pgtype.Timestamptz
+	DurationSec  int32
+	DistanceM    pgtype.Float8
+	ElevGainM    pgtype.Float8
+	AvgHr        pgtype.Int4
+	RawJson      []byte
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+	Load         pgtype.Float8
+	ReviewStatus string
+	ReviewFlags  []string
+	LoadSource   string
+}
+
+type WorkoutCommentary struct {
+	WorkoutID     uuid.UUID
+	Draft         string
+	Body          string
+	Status        string
+	Facts         []byte
+	Model         string
+	CreatedAt     pgtype.Timestamptz
+	ReviewedAt    pgtype.Timestamptz
+	PromptVersion string
+}
+
+type WorkoutFeedback struct {
+	WorkoutID uuid.UUID
+	AthleteID uuid.UUID
+	Rpe       int32
+	Notes     pgtype.Text
+	Pain      []string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type WorkoutLap struct {
+	WorkoutID  uuid.UUID
+	LapIndex   int32
+	ElapsedSec int32
+	MovingSec  int32
+	DistanceM  float64
+	AvgSpeed   pgtype.Float8
+	AvgHr      pgtype.Float8
+	MaxHr      pgtype.Float8
+	AvgWatts   pgtype.Float8
+}
+
+type WorkoutMetric struct {
+	WorkoutID        uuid.UUID
+	MovingSec        int32
+	DecouplingBasis  pgtype.Text
+	DecouplingPct    pgtype.Float8
+	EfficiencyFactor pgtype.Float8
+	ComputedAt       pgtype.Timestamptz
+	GapSpeed         pgtype.Float8
+	AvgPower         pgtype.Float8
+	NormalizedPower  pgtype.Float8
+	VariabilityIndex pgtype.Float8
+	IntensityFactor  pgtype.Float8
+	ZoneSec          []int32
+}
@@ -6,6 +6,9 @@ RETURNING *;
 -- name: GetCoachByEmail :one
 SELECT * FROM coach WHERE email = $1 LIMIT 1;
 
+-- name: GetCoach :one
+SELECT * FROM coach WHERE id = $1 LIMIT 1;
+
 -- name: UpsertCoachByEmail :one
 INSERT INTO coach (email, name, tz)
 VALUES ($1, $2, $3)
@@ -29,6 +32,9 @@ WHERE id = $1;
 -- name: ListAthletesByCoach :many
 SELECT * FROM athlete WHERE coach_id = $1 ORDER BY created_at DESC;
 
+-- name: ListConnectedAthletes :many
+SELECT * FROM athlete WHERE strava_access_token IS NOT NULL ORDER BY created_at;
+
 -- name: GetAthlete :one
 SELECT * FROM athlete WHERE id = $1 LIMIT 1;
 
@@ -39,25 +45,816 @@ SET strava_access_token = $2,
     strava_token_expiry = $4
 WHERE id = $1;
 
+-- name: UpdateAthleteThresholds :exec
+UPDATE athlete
+SET max_hr = $2,
+    resting_hr = $3,
+    ftp_watts = $4,
+    threshold_pace_sec = $5
+WHERE id = $1;
+
 -- name: UpdateAthleteLastStravaSync :exec
 UPDATE athlete
 SET last_strava_sync = $2
 WHERE id = $1;
 
--- name: UpsertWorkout :exec
+-- name: UpsertWorkout :one
+-- load is the heart-rate estimate; it doesn't replace a load from streams.
 INSERT INTO workout (
     athlete_id, source, source_id, name, sport, started_at,
-    duration_sec, distance_m, elev_gain_m, avg_hr, raw_json
-) VALUES ($1, 'strava', $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    duration_sec, distance_m, elev_gain_m, avg_hr, raw_json, load
+) VALUES ($1, 'strava', $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     ON CONFLICT (athlete_id, source, source_id) DO UPDATE
 SET name = $3, sport=$4, started_at=$5,
     duration_sec=$6, distance_m=$7, elev_gain_m=$8, avg_hr=$9,
-    raw_json=$10, updated_at=now();
+    raw_json=$10, updated_at=now(),
+    load = CASE WHEN workout.load_source = 'stream' THEN workout.load ELSE $11 END
+RETURNING id;
 
 -- name: ListWorkoutsByAthlete :many
-SELECT id, athlete_id, source, source_id, name, sport, started_at, 
-       duration_sec, distance_m, elev_gain_m, avg_hr, created_at, updated_at
-FROM workout 
-WHERE athlete_id = $1 
-ORDER BY started_at DESC 
+SELECT w.id, w.athlete_id, w.source, w.source_id, w.name, w.sport, w.started_at,
+       w.duration_sec, w.distance_m, w.elev_gain_m, w.avg_hr, w.created_at, w.updated_at,
+       w.load, m.gap_speed, m.normalized_power, m.variability_index, m.intensity_factor,
+       w.review_status, w.review_flags, c.status AS commentary_status
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+LEFT JOIN workout_commentary c ON c.workout_id = w.id
+WHERE w.athlete_id = $1
+ORDER BY w.started_at DESC
 LIMIT $2;
+
+-- name: ListWorkoutLoadsSince :many
+SELECT w.started_at, w.duration_sec, w.avg_hr, w.load, m.intensity_factor
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = $1 AND w.started_at >= $2
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at;
+
+-- name: CreateAthleteAlert :one
+-- Skips the insert while an undismissed alert of the same kind is open, so a
+-- condition that persists for days doesn't re-alert the coach every sync.
+INSERT INTO athlete_alert (athlete_id, kind, day, value, threshold, message)
+SELECT @athlete_id::uuid, @kind::text, @day::date, @value::float8, @threshold::float8, @message::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM athlete_alert
+    WHERE athlete_id = @athlete_id::uuid AND kind = @kind::text AND dismissed_at IS NULL
+)
+ON CONFLICT (athlete_id, kind, day) DO NOTHING
+RETURNING *;
+
+-- name: ListOpenAlertsByCoach :many
+SELECT al.id, al.athlete_id, a.name AS athlete_name, al.kind, al.day,
+       al.value, al.threshold, al.message, al.created_at
+FROM athlete_alert al
+JOIN athlete a ON a.id = al.athlete_id
+WHERE a.coach_id = $1 AND al.dismissed_at IS NULL
+ORDER BY al.day DESC, al.created_at DESC
+LIMIT 50;
+
+-- name: DismissAthleteAlert :execrows
+UPDATE athlete_alert
+SET dismissed_at = now()
+WHERE athlete_alert.id = $1
+  AND athlete_id IN (SELECT id FROM athlete WHERE coach_id = $2);
+
+-- name: ListRunEffortsSince :many
+SELECT started_at, duration_sec, distance_m, avg_hr
+FROM workout
+WHERE athlete_id = $1 AND started_at >= $2
+  AND sport IN ('Run', 'TrailRun', 'VirtualRun')
+  AND distance_m IS NOT NULL
+  AND review_status IN ('ok', 'accepted')
+ORDER BY started_at;
+
+-- name: UpsertWorkoutMetrics :exec
+INSERT INTO workout_metrics (
+    workout_id, moving_sec, decoupling_basis, decoupling_pct, efficiency_factor,
+    gap_speed, avg_power, normalized_power, variability_index, intensity_factor,
+    zone_sec
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    ON CONFLICT (workout_id) DO UPDATE
+SET moving_sec = $2, decoupling_basis = $3, decoupling_pct = $4,
+    efficiency_factor = $5, gap_speed = $6, avg_power = $7,
+    normalized_power = $8, variability_index = $9, intensity_factor = $10,
+    zone_sec = $11, computed_at = now();
+
+-- name: UpdateWorkoutLoad :exec
+-- Sets the load from the workout's streams.
+UPDATE workout
+SET load = $2, load_source = 'stream'
+WHERE id = $1;
+
+-- name: ResetWorkoutLoad :exec
+-- Puts back the heart-rate estimate on a workout whose streams no longer
+-- give a load, say after its thresholds were cleared.
+UPDATE workout
+SET load = @load, load_source = 'hr'
+WHERE id = @id AND load_source = 'stream';
+
+-- name: ListDecouplingSince :many
+SELECT w.id, w.name, w.sport, w.started_at, m.moving_sec,
+       m.decoupling_basis, m.decoupling_pct, m.efficiency_factor
+FROM workout w
+JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = $1 AND w.started_at >= $2
+  AND m.decoupling_pct IS NOT NULL
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at;
+
+-- name: ListWorkoutHistory :many
+SELECT duration_sec, distance_m, avg_hr
+FROM workout
+WHERE athlete_id = $1 AND sport = $2 AND started_at < $3
+  AND review_status IN ('ok', 'accepted')
+ORDER BY started_at DESC
+LIMIT 60;
+
+-- name: SetWorkoutReviewFlags :exec
+-- Coach decisions (accepted/rejected) are never overwritten by a re-sync.
+UPDATE workout
+SET review_flags = @flags::text[],
+    review_status = CASE WHEN cardinality(@flags::text[]) > 0 THEN 'flagged' ELSE 'ok' END
+WHERE id = @id AND review_status IN ('ok', 'flagged');
+
+-- name: ReviewWorkout :one
+UPDATE workout
+SET review_status = $3, updated_at = now()
+WHERE id = $1 AND athlete_id = $2
+RETURNING started_at;
+
+-- name: ListFlaggedWorkoutsByCoach :many
+SELECT w.id, w.athlete_id, a.name AS athlete_name, w.name, w.sport,
+       w.started_at, w.review_flags
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+WHERE a.coach_id = $1 AND w.review_status = 'flagged'
+ORDER BY w.started_at DESC
+LIMIT 50;
+
+-- name: CreatePlannedWorkout :one
+INSERT INTO planned_workout (
+    athlete_id, day, sport, title, description,
+    target_duration_sec, target_distance_m, target_load, target_zone, structure,
+    template_session_id, application_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetPlannedWorkout :one
+SELECT * FROM planned_workout
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: ListPlannedWorkoutsBetween :many
+SELECT * FROM planned_workout
+WHERE athlete_id = @athlete_id AND day BETWEEN @from_day::date AND @to_day::date
+ORDER BY day, created_at;
+
+-- name: UpdatePlannedWorkout :execrows
+-- Clears the match; the caller re-runs matching for the affected days. A
+-- hand edit also detaches the session from its template session so pushing
+-- template changes won't overwrite it.
+UPDATE planned_workout
+SET day = $3, sport = $4, title = $5, description = $6,
+    target_duration_sec = $7, target_distance_m = $8, target_load = $9,
+    target_zone = $10, structure = $11,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    template_session_id = NULL,
+    updated_at = now()
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: DeletePlannedWorkout :execrows
+DELETE FROM planned_workout
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: ListWorkoutsBetween :many
+SELECT id, name, sport, started_at, duration_sec, distance_m, load, review_status
+FROM workout
+WHERE athlete_id = @athlete_id AND started_at >= @from_time::timestamptz AND started_at < @to_time::timestamptz
+ORDER BY started_at;
+
+-- name: SetPlannedWorkoutMatch :exec
+UPDATE planned_workout
+SET workout_id = $2, status = $3, compliance = $4
+WHERE id = $1;
+
+-- name: ListWorkoutsForMatching :many
+SELECT w.id, w.sport, w.duration_sec, w.distance_m, w.load, m.zone_sec
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = @athlete_id
+  AND w.started_at >= @from_time::timestamptz AND w.started_at < @to_time::timestamptz
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at;
+
+-- name: ListPlanComplianceByCoach :many
+SELECT p.athlete_id, p.day, p.status, p.compliance
+FROM planned_workout p
+JOIN athlete a ON a.id = p.athlete_id
+WHERE a.coach_id = @coach_id AND p.day BETWEEN @from_day::date AND @to_day::date
+ORDER BY p.athlete_id, p.day;
+
+-- name: UpdatePlannedWorkoutFromTemplate :exec
+UPDATE planned_workout
+SET day = $2, sport = $3, title = $4, description = $5,
+    target_duration_sec = $6, target_distance_m = $7, target_load = $8,
+    target_zone = $9, structure = $10,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: CreatePlanTemplate :one
+INSERT INTO plan_template (coach_id, name, description, weeks)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetPlanTemplate :one
+SELECT * FROM plan_template
+WHERE id = $1 AND coach_id = $2;
+
+-- name: GetPlanTemplateByApplication :one
+SELECT t.id, t.coach_id, t.name, t.description, t.weeks, t.created_at, t.updated_at
+FROM plan_template t
+JOIN plan_template_application ap ON ap.template_id = t.id
+WHERE ap.id = $1 AND t.coach_id = $2;
+
+-- name: ListPlanTemplatesByCoach :many
+-- Drafts still being reviewed are reached from the athlete, not listed as
+-- templates.
+SELECT t.id, t.name, t.description, t.weeks, t.updated_at,
+       (SELECT count(*) FROM plan_template_session s WHERE s.template_id = t.id) AS sessions
+FROM plan_template t
+WHERE t.coach_id = $1
+  AND NOT EXISTS (SELECT 1 FROM plan_draft d WHERE d.template_id = t.id AND d.status <> 'published')
+ORDER BY t.name;
+
+-- name: UpdatePlanTemplate :execrows
+UPDATE plan_template
+SET name = $3, description = $4, weeks = $5, updated_at = now()
+WHERE id = $1 AND coach_id = $2;
+
+-- name: DeletePlanTemplate :execrows
+DELETE FROM plan_template
+WHERE id = $1 AND coach_id = $2;
+
+-- name: CreatePlanTemplateSession :one
+INSERT INTO plan_template_session (
+    template_id, week, day, sport, title, description,
+    target_duration_sec, target_distance_m, target_load, target_zone, structure
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING *;
+
+-- name: GetPlanTemplateSession :one
+SELECT * FROM plan_template_session
+WHERE id = $1 AND template_id = $2;
+
+-- name: ListPlanTemplateSessions :many
+SELECT * FROM plan_template_session
+WHERE template_id = $1
+ORDER BY week, day, created_at;
+
+-- name: UpdatePlanTemplateSession :execrows
+UPDATE plan_template_session
+SET week = $3, day = $4, sport = $5, title = $6, description = $7,
+    target_duration_sec = $8, target_distance_m = $9, target_load = $10,
+    target_zone = $11, structure = $12, updated_at = now()
+WHERE id = $1 AND template_id = $2;
+
+-- name: DeletePlanTemplateSession :execrows
+DELETE FROM plan_template_session
+WHERE id = $1 AND template_id = $2;
+
+-- name: CreatePlanTemplateApplication :one
+INSERT INTO plan_template_application (template_id, athlete_id, start_day, scale)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListPlanTemplateApplications :many
+SELECT ap.id, ap.athlete_id, a.name AS athlete_name, a.tz, ap.start_day, ap.scale, ap.created_at
+FROM plan_template_application ap
+JOIN athlete a ON a.id = ap.athlete_id
+WHERE ap.template_id = $1
+ORDER BY ap.start_day DESC, ap.created_at DESC;
+
+-- name: ListTemplatePlannedWorkouts :many
+-- Planned sessions still linked to one of the template's sessions.
+SELECT p.id, p.application_id, p.template_session_id, p.day, p.workout_id
+FROM planned_workout p
+JOIN plan_template_application ap ON ap.id = p.application_id
+WHERE ap.template_id = $1 AND p.template_session_id IS NOT NULL;
+
+-- name: DeleteUpcomingFromTemplateSession :execrows
+-- Removes unmatched sessions from today on, in each athlete's time zone.
+DELETE FROM planned_workout p
+USING athlete a
+WHERE a.id = p.athlete_id
+  AND p.template_session_id = $1
+  AND p.workout_id IS NULL
+  AND p.day >= (now() AT TIME ZONE a.tz)::date;
+
+-- name: CreateAthleteEvent :one
+INSERT INTO athlete_event (athlete_id, name, day, priority, sport, distance_m, goal_time_sec, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListAthleteEvents :many
+SELECT * FROM athlete_event
+WHERE athlete_id = $1
+ORDER BY day, priority;
+
+-- name: DeleteAthleteEvent :execrows
+DELETE FROM athlete_event
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: CreateTrainingPhase :one
+INSERT INTO training_phase (athlete_id, kind, start_day, end_day, ramp_per_week, notes)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListTrainingPhases :many
+SELECT * FROM training_phase
+WHERE athlete_id = $1
+ORDER BY start_day;
+
+-- name: DeleteTrainingPhase :execrows
+DELETE FROM training_phase
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: GetCalendarFeed :one
+SELECT * FROM calendar_feed
+WHERE token = $1;
+
+-- name: GetAthleteCalendarFeed :one
+SELECT * FROM calendar_feed
+WHERE athlete_id = $1;
+
+-- name: GetCoachCalendarFeed :one
+SELECT * FROM calendar_feed
+WHERE coach_id = $1 AND athlete_id IS NULL;
+
+-- name: UpsertAthleteCalendarFeed :one
+-- A new token replaces the old one, so the previous URL stops working.
+INSERT INTO calendar_feed (token, coach_id, athlete_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (athlete_id) WHERE athlete_id IS NOT NULL
+DO UPDATE SET token = EXCLUDED.token, created_at = now()
+RETURNING *;
+
+-- name: UpsertCoachCalendarFeed :one
+INSERT INTO calendar_feed (token, coach_id)
+VALUES ($1, $2)
+ON CONFLICT (coach_id) WHERE athlete_id IS NULL
+DO UPDATE SET token = EXCLUDED.token, created_at = now()
+RETURNING *;
+
+-- name: DeleteAthleteCalendarFeed :exec
+DELETE FROM calendar_feed
+WHERE athlete_id = $1;
+
+-- name: DeleteCoachCalendarFeed :exec
+DELETE FROM calendar_feed
+WHERE coach_id = $1 AND athlete_id IS NULL;
+
+-- name: ListCoachPlannedWorkoutsBetween :many
+SELECT pw.id, pw.athlete_id, pw.day, pw.sport, pw.title, pw.description, pw.target_duration_sec, pw.target_distance_m, pw.target_load, pw.created_at, pw.updated_at, pw.target_zone, pw.workout_id, pw.status, pw.compliance, pw.structure, pw.template_session_id, pw.application_id, a.name AS athlete_name
+FROM planned_workout pw
+JOIN athlete a ON a.id = pw.athlete_id
+WHERE a.coach_id = $1 AND pw.day BETWEEN $2::date AND $3::date
+ORDER BY pw.day, a.name, pw.created_at;
+
+-- name: ListCoachAthleteEventsBetween :many
+SELECT e.id, e.athlete_id, e.name, e.day, e.priority, e.sport, e.distance_m, e.goal_time_sec, e.notes, e.created_at, a.name AS athlete_name
+FROM athlete_event e
+JOIN athlete a ON a.id = e.athlete_id
+WHERE a.coach_id = $1 AND e.day BETWEEN $2::date AND $3::date
+ORDER BY e.day, a.name;
+
+-- name: ListAthleteEventsBetween :many
+SELECT * FROM athlete_event
+WHERE athlete_id = $1 AND day BETWEEN $2::date AND $3::date
+ORDER BY day, priority;
+
+-- name: CreatePlanOperation :one
+INSERT INTO plan_operation (coach_id, kind, summary, undo)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListPlanOperations :many
+SELECT * FROM plan_operation
+WHERE coach_id = $1
+ORDER BY created_at DESC
+LIMIT 10;
+
+-- name: GetLatestPlanOperation :one
+-- Only the most recent operation still in effect can be undone, so undo
+-- works like a stack.
+SELECT * FROM plan_operation
+WHERE coach_id = $1 AND undone_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1
+FOR UPDATE;
+
+-- name: MarkPlanOperationUndone :exec
+UPDATE plan_operation
+SET undone_at = now()
+WHERE id = $1;
+
+-- name: RestorePlannedWorkout :execrows
+-- Puts back a session as an undone operation found it, template link
+-- included. The caller re-runs matching for the affected days.
+UPDATE planned_workout
+SET day = $3, sport = $4, title = $5, description = $6,
+    target_duration_sec = $7, target_distance_m = $8, target_load = $9,
+    target_zone = $10, structure = $11, template_session_id = $12,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    updated_at = now()
+WHERE id = $1 AND athlete_id = $2;
+
+-- name: RecordLLMUsage :exec
+INSERT INTO llm_usage (coach_id, feature, model, prompt_tokens, completion_tokens, estimated)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: SumCoachLLMTokensSince :one
+SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0)::bigint AS tokens
+FROM llm_usage
+WHERE coach_id = $1 AND created_at >= $2;
+
+-- name: ListCoachLLMUsageSince :many
+SELECT feature, model, COUNT(*)::int AS calls,
+       COALESCE(SUM(prompt_tokens), 0)::bigint AS prompt_tokens,
+       COALESCE(SUM(completion_tokens), 0)::bigint AS completion_tokens
+FROM llm_usage
+WHERE coach_id = $1 AND created_at >= $2
+GROUP BY feature, model
+ORDER BY feature, model;
+
+-- name: ListCoachesWithAthletes :many
+SELECT * FROM coach c
+WHERE EXISTS (SELECT 1 FROM athlete a WHERE a.coach_id = c.id)
+ORDER BY c.created_at;
+
+-- name: ListAthleteAlertsBetween :many
+SELECT * FROM athlete_alert
+WHERE athlete_id = @athlete_id AND day BETWEEN @from_day::date AND @to_day::date
+ORDER BY day, created_at;
+
+-- name: CreateWeeklyReport :one
+INSERT INTO weekly_report (coach_id, week_start, facts, summary, highlights, concerns, model, prompt_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetWeeklyReportForWeek :one
+SELECT * FROM weekly_report
+WHERE coach_id = $1 AND week_start = $2;
+
+-- name: GetWeeklyReport :one
+SELECT * FROM weekly_report
+WHERE id = $1 AND coach_id = $2;
+
+-- name: ListWeeklyReports :many
+SELECT id, week_start, summary, created_at
+FROM weekly_report
+WHERE coach_id = $1
+ORDER BY week_start DESC
+LIMIT 52;
+
+-- name: MarkWeeklyReportEmailed :exec
+UPDATE weekly_report
+SET emailed_at = now()
+WHERE id = $1;
+
+-- name: CreateChatConversation :one
+INSERT INTO chat_conversation (coach_id, athlete_id, title)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetChatConversation :one
+SELECT * FROM chat_conversation
+WHERE id = $1 AND coach_id = $2;
+
+-- name: ListChatConversations :many
+SELECT * FROM chat_conversation
+WHERE athlete_id = $1 AND coach_id = $2
+ORDER BY updated_at DESC
+LIMIT 20;
+
+-- name: ClaimChatReply :execrows
+-- Marks a reply as in progress. A claim older than five minutes is taken to
+-- be from a request that died and can be taken over.
+UPDATE chat_conversation
+SET replying_at = now()
+WHERE id = $1 AND (replying_at IS NULL OR replying_at < now() - interval '5 minutes');
+
+-- name: ReleaseChatReply :exec
+UPDATE chat_conversation
+SET replying_at = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: CreateChatMessage :one
+INSERT INTO chat_message (conversation_id, role, content, tool_calls, tool_call_id, model, prompt_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListChatMessages :many
+SELECT * FROM chat_message
+WHERE conversation_id = $1
+ORDER BY id;
+
+-- name: LogChatToolCall :exec
+INSERT INTO chat_tool_call (conversation_id, coach_id, athlete_id, tool, arguments, result, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListCoachAthleteWorkoutsBetween :many
+-- The assistant's workout lookup. Scoped to the coach in the query itself,
+-- like every query the assistant can reach.
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec, w.distance_m, w.elev_gain_m,
+       w.avg_hr, w.load, w.review_status, m.gap_speed, m.intensity_factor
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE a.coach_id = @coach_id AND w.athlete_id = @athlete_id
+  AND w.started_at >= @from_time::timestamptz AND w.started_at < @to_time::timestamptz
+  AND (@sport::text = '' OR w.sport = @sport::text)
+ORDER BY w.started_at
+LIMIT 200;
+
+-- name: GetCoachAthleteWorkout :one
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec, w.distance_m, w.elev_gain_m,
+       w.avg_hr, w.load, w.review_status, w.review_flags,
+       m.moving_sec, m.decoupling_pct, m.efficiency_factor, m.gap_speed, m.avg_power,
+       m.normalized_power, m.variability_index, m.intensity_factor, m.zone_sec
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.id = @id AND w.athlete_id = @athlete_id AND a.coach_id = @coach_id;
+
+-- name: ListCoachAthleteWorkoutLoadsBetween :many
+SELECT w.started_at, w.duration_sec, w.distance_m, w.avg_hr, w.load, m.intensity_factor
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE a.coach_id = @coach_id AND w.athlete_id = @athlete_id
+  AND w.started_at >= @from_time::timestamptz AND w.started_at < @to_time::timestamptz
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at;
+
+-- name: DeleteWorkoutLaps :exec
+DELETE FROM workout_lap
+WHERE workout_id = $1;
+
+-- name: CreateWorkoutLap :exec
+INSERT INTO workout_lap (workout_id, lap_index, elapsed_sec, moving_sec, distance_m, avg_speed, avg_hr, max_hr, avg_watts)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListWorkoutLaps :many
+SELECT * FROM workout_lap
+WHERE workout_id = $1
+ORDER BY lap_index;
+
+-- name: GetWorkoutForCommentary :one
+SELECT w.id, w.athlete_id, a.coach_id, w.name, w.sport, w.started_at, w.duration_sec,
+       w.distance_m, w.elev_gain_m, w.avg_hr, w.load, w.review_status,
+       m.moving_sec, m.decoupling_basis, m.decoupling_pct, m.efficiency_factor, m.gap_speed,
+       m.avg_power, m.normalized_power, m.variability_index, m.intensity_factor, m.zone_sec
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.id = $1;
+
+-- name: GetPlannedWorkoutForWorkout :one
+SELECT * FROM planned_workout
+WHERE workout_id = $1
+ORDER BY created_at
+LIMIT 1;
+
+-- name: CreateWorkoutCommentary :execrows
+-- Keeps an existing note, so a retried job never overwrites one the coach
+-- has already reviewed.
+INSERT INTO workout_commentary (workout_id, draft, body, facts, model, prompt_version)
+VALUES (@workout_id, @draft, @draft, @facts, @model, @prompt_version)
+ON CONFLICT (workout_id) DO NOTHING;
+
+-- name: GetWorkoutCommentary :one
+SELECT * FROM workout_commentary
+WHERE workout_id = $1;
+
+-- name: ReviewWorkoutCommentary :execrows
+UPDATE workout_commentary
+SET body = $2, status = $3, reviewed_at = now()
+WHERE workout_id = $1;
+
+-- name: ListCommentaryDraftsByCoach :many
+SELECT c.workout_id, w.athlete_id, a.name AS athlete_name, w.name, w.sport, w.started_at
+FROM workout_commentary c
+JOIN workout w ON w.id = c.workout_id
+JOIN athlete a ON a.id = w.athlete_id
+WHERE a.coach_id = $1 AND c.status = 'draft'
+ORDER BY w.started_at DESC
+LIMIT 50;
+
+-- name: CreatePlanDraft :one
+INSERT INTO plan_draft (coach_id, athlete_id, event_id, start_day, weeks, days, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetPlanDraft :one
+SELECT * FROM plan_draft
+WHERE id = $1 AND coach_id = $2;
+
+-- name: GetPlanDraftByID :one
+SELECT * FROM plan_draft
+WHERE id = $1;
+
+-- name: GetPlanDraftByTemplate :one
+SELECT * FROM plan_draft
+WHERE template_id = $1 AND coach_id = $2;
+
+-- name: ListPlanDrafts :many
+SELECT * FROM plan_draft
+WHERE athlete_id = $1 AND coach_id = $2
+ORDER BY created_at DESC
+LIMIT 10;
+
+-- name: SetPlanDraftReady :exec
+UPDATE plan_draft
+SET status = 'ready', template_id = $2, warnings = $3, model = $4, prompt_version = $5, error = NULL
+WHERE id = $1;
+
+-- name: SetPlanDraftError :exec
+-- Records why generation failed. status stays generating while the job
+-- will be retried.
+UPDATE plan_draft
+SET status = $2, error = $3
+WHERE id = $1;
+
+-- name: RetryPlanDraft :execrows
+UPDATE plan_draft
+SET status = 'generating', error = NULL
+WHERE id = $1 AND coach_id = $2 AND status = 'failed';
+
+-- name: PublishPlanDraft :execrows
+UPDATE plan_draft
+SET status = 'published', published_at = now()
+WHERE id = $1 AND coach_id = $2 AND status = 'ready';
+
+-- name: DeletePlanDraft :execrows
+DELETE FROM plan_draft
+WHERE id = $1 AND coach_id = $2 AND status <> 'published';
+
+-- name: EnsureWorkoutCommentThread :one
+-- Returns the workout's thread, starting it with the given tokens if there
+-- is none yet.
+INSERT INTO comment_thread (athlete_id, workout_id, coach_token, athlete_token)
+VALUES (@athlete_id, @workout_id, @coach_token, @athlete_token)
+ON CONFLICT (workout_id) DO UPDATE SET workout_id = EXCLUDED.workout_id
+RETURNING *;
+
+-- name: EnsurePlannedWorkoutCommentThread :one
+INSERT INTO comment_thread (athlete_id, planned_workout_id, coach_token, athlete_token)
+VALUES (@athlete_id, @planned_workout_id, @coach_token, @athlete_token)
+ON CONFLICT (planned_workout_id) DO UPDATE SET planned_workout_id = EXCLUDED.planned_workout_id
+RETURNING *;
+
+-- name: GetWorkoutCommentThread :one
+SELECT * FROM comment_thread
+WHERE workout_id = $1;
+
+-- name: GetPlannedWorkoutCommentThread :one
+SELECT * FROM comment_thread
+WHERE planned_workout_id = $1;
+
+-- name: GetCommentThreadByToken :one
+-- Finds the thread either side's token belongs to; compare the tokens to
+-- tell which side it is.
+SELECT * FROM comment_thread
+WHERE coach_token = @token OR athlete_token = @token;
+
+-- name: CreateThreadComment :one
+INSERT INTO thread_comment (thread_id, author, body, via)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListThreadComments :many
+SELECT * FROM thread_comment
+WHERE thread_id = $1
+ORDER BY id;
+
+-- name: GetAthleteByEmail :one
+-- Finds the athlete signing in to the portal. Addresses are matched without
+-- regard to case, and uniq_athlete_email_lower keeps that to one athlete.
+SELECT * FROM athlete
+WHERE lower(email) = lower(@email);
+
+-- name: UpdateAthleteProfile :exec
+UPDATE athlete
+SET name = $2,
+    tz = $3
+WHERE id = $1;
+
+-- name: UpsertWorkoutFeedback :exec
+INSERT INTO workout_feedback (workout_id, athlete_id, rpe, notes, pain)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (workout_id) DO UPDATE
+SET rpe = EXCLUDED.rpe, notes = EXCLUDED.notes, pain = EXCLUDED.pain, updated_at = now();
+
+-- name: GetWorkoutFeedback :one
+SELECT * FROM workout_feedback
+WHERE workout_id = $1;
+
+-- name: ListSessionRPE :many
+-- Rated workouts in a time range, for session-RPE load. Like
+-- ListWorkoutLoadsSince it skips workouts held back by review.
+SELECT w.started_at, w.duration_sec, f.rpe
+FROM workout_feedback f
+JOIN workout w ON w.id = f.workout_id
+WHERE f.athlete_id = @athlete_id
+  AND w.started_at >= @from_time AND w.started_at < @to_time
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at;
+
+-- name: ListWorkoutsAwaitingFeedback :many
+-- The athlete's recent workouts they haven't rated yet, newest first.
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec
+FROM workout w
+LEFT JOIN workout_feedback f ON f.workout_id = w.id
+WHERE w.athlete_id = @athlete_id AND w.started_at >= @since AND f.workout_id IS NULL
+ORDER BY w.started_at DESC
+LIMIT 5;
+
+-- name: UpsertWellnessCheckin :exec
+-- Saves the day's check-in, replacing an earlier one for the same day.
+INSERT INTO wellness_checkin (athlete_id, day, sleep_hours, sleep_quality, soreness, stress, mood, resting_hr, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (athlete_id, day) DO UPDATE
+SET sleep_hours = EXCLUDED.sleep_hours,
+    sleep_quality = EXCLUDED.sleep_quality,
+    soreness = EXCLUDED.soreness,
+    stress = EXCLUDED.stress,
+    mood = EXCLUDED.mood,
+    resting_hr = EXCLUDED.resting_hr,
+    notes = EXCLUDED.notes,
+    updated_at = now();
+
+-- name: GetWellnessCheckin :one
+SELECT * FROM wellness_checkin
+WHERE athlete_id = $1 AND day = $2;
+
+-- name: ListWellnessCheckins :many
+SELECT * FROM wellness_checkin
+WHERE athlete_id = @athlete_id AND day BETWEEN @from_day AND @to_day
+ORDER BY day;
+
+-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (kind, coach_id, athlete_id, to_addr, reply_to, subject, html, text_body, list_unsubscribe)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetOutboxEmail :one
+SELECT * FROM email_outbox
+WHERE id = $1;
+
+-- name: MarkOutboxEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = now(), updated_at = now()
+WHERE id = $1;
+
+-- name: MarkOutboxEmailAttempt :exec
+-- Records a failed send. status stays pending while retries remain.
+UPDATE email_outbox
+SET status = @status, attempts = attempts + 1, last_error = @last_error, updated_at = now()
+WHERE id = @id;
+
+-- name: ListStalePendingEmails :many
+-- Pending emails nothing has touched since before, whose send task was
+-- lost, for the sweep to queue again.
+SELECT id FROM email_outbox
+WHERE status = 'pending' AND updated_at < @before
+ORDER BY created_at
+LIMIT 100;
+
+-- name: ListOutboxEmailsByCoach :many
+SELECT * FROM email_outbox
+WHERE coach_id = $1
+ORDER BY created_at DESC
+LIMIT 50;
+
+-- name: ListLatestInvitesByCoach :many
+-- The newest invite email to each of the coach's athletes.
+SELECT DISTINCT ON (athlete_id) athlete_id, status, last_error, created_at
+FROM email_outbox
+WHERE coach_id = @coach_id AND kind = 'invite' AND athlete_id IS NOT NULL
+ORDER BY athlete_id, created_at DESC;
+
+-- name: SuppressEmail :exec
+INSERT INTO email_suppression (address, kind)
+VALUES (lower(@address), @kind)
+ON CONFLICT (address, kind) DO NOTHING;
+
+-- name: UnsuppressEmail :exec
+DELETE FROM email_suppression
+WHERE address = lower(@address) AND kind = @kind;
+
+-- name: IsEmailSuppressed :one
+SELECT EXISTS (
+  SELECT 1 FROM email_suppression
+  WHERE address = lower(@address) AND kind = @kind
+) AS suppressed;
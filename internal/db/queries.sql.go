@@ -12,10 +12,26 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimChatReply = `-- name: ClaimChatReply :execrows
+UPDATE chat_conversation
+SET replying_at = now()
+WHERE id = $1 AND (replying_at IS NULL OR replying_at < now() - interval '5 minutes')
+`
+
+// Marks a reply as in progress. A claim older than five minutes is taken to
+// be from a request that died and can be taken over.
+func (q *Queries) ClaimChatReply(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, claimChatReply, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createAthlete = `-- name: CreateAthlete :one
 INSERT INTO athlete (coach_id, name, email, tz)
 VALUES ($1, $2, $3, $4)
-RETURNING id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync
+RETURNING id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync, max_hr, resting_hr, ftp_watts, threshold_pace_sec
 `
 
 type CreateAthleteParams struct {
@@ -45,6 +61,168 @@ func (q *Queries) CreateAthlete(ctx context.Context, arg CreateAthleteParams) (A
 		&i.StravaTokenExpiry,
 		&i.CreatedAt,
 		&i.LastStravaSync,
+		&i.MaxHr,
+		&i.RestingHr,
+		&i.FtpWatts,
+		&i.ThresholdPaceSec,
+	)
+	return i, err
+}
+
+const createAthleteAlert = `-- name: CreateAthleteAlert :one
+INSERT INTO athlete_alert (athlete_id, kind, day, value, threshold, message)
+SELECT $1::uuid, $2::text, $3::date, $4::float8, $5::float8, $6::text
+WHERE NOT EXISTS (
+    SELECT 1 FROM athlete_alert
+    WHERE athlete_id = $1::uuid AND kind = $2::text AND dismissed_at IS NULL
+)
+ON CONFLICT (athlete_id, kind, day) DO NOTHING
+RETURNING id, athlete_id, kind, day, value, threshold, message, dismissed_at, created_at
+`
+
+type CreateAthleteAlertParams struct {
+	AthleteID uuid.UUID
+	Kind      string
+	Day       pgtype.Date
+	Value     float64
+	Threshold float64
+	Message   string
+}
+
+// Skips the insert while an undismissed alert of the same kind is open, so a
+// condition that persists for days doesn't re-alert the coach every sync.
+func (q *Queries) CreateAthleteAlert(ctx context.Context, arg CreateAthleteAlertParams) (AthleteAlert, error) {
+	row := q.db.QueryRow(ctx, createAthleteAlert,
+		arg.AthleteID,
+		arg.Kind,
+		arg.Day,
+		arg.Value,
+		arg.Threshold,
+		arg.Message,
+	)
+	var i AthleteAlert
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Kind,
+		&i.Day,
+		&i.Value,
+		&i.Threshold,
+		&i.Message,
+		&i.DismissedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAthleteEvent = `-- name: CreateAthleteEvent :one
+INSERT INTO athlete_event (athlete_id, name, day, priority, sport, distance_m, goal_time_sec, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, athlete_id, name, day, priority, sport, distance_m, goal_time_sec, notes, created_at
+`
+
+type CreateAthleteEventParams struct {
+	AthleteID   uuid.UUID
+	Name        string
+	Day         pgtype.Date
+	Priority    string
+	Sport       string
+	DistanceM   pgtype.Float8
+	GoalTimeSec pgtype.Int4
+	Notes       pgtype.Text
+}
+
+func (q *Queries) CreateAthleteEvent(ctx context.Context, arg CreateAthleteEventParams) (AthleteEvent, error) {
+	row := q.db.QueryRow(ctx, createAthleteEvent,
+		arg.AthleteID,
+		arg.Name,
+		arg.Day,
+		arg.Priority,
+		arg.Sport,
+		arg.DistanceM,
+		arg.GoalTimeSec,
+		arg.Notes,
+	)
+	var i AthleteEvent
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Name,
+		&i.Day,
+		&i.Priority,
+		&i.Sport,
+		&i.DistanceM,
+		&i.GoalTimeSec,
+		&i.Notes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createChatConversation = `-- name: CreateChatConversation :one
+INSERT INTO chat_conversation (coach_id, athlete_id, title)
+VALUES ($1, $2, $3)
+RETURNING id, coach_id, athlete_id, title, replying_at, created_at, updated_at
+`
+
+type CreateChatConversationParams struct {
+	CoachID   uuid.UUID
+	AthleteID uuid.UUID
+	Title     string
+}
+
+func (q *Queries) CreateChatConversation(ctx context.Context, arg CreateChatConversationParams) (ChatConversation, error) {
+	row := q.db.QueryRow(ctx, createChatConversation, arg.CoachID, arg.AthleteID, arg.Title)
+	var i ChatConversation
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.Title,
+		&i.ReplyingAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createChatMessage = `-- name: CreateChatMessage :one
+INSERT INTO chat_message (conversation_id, role, content, tool_calls, tool_call_id, model, prompt_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, conversation_id, role, content, tool_calls, tool_call_id, created_at, model, prompt_version
+`
+
+type CreateChatMessageParams struct {
+	ConversationID uuid.UUID
+	Role           string
+	Content        string
+	ToolCalls      []byte
+	ToolCallID     pgtype.Text
+	Model          pgtype.Text
+	PromptVersion  pgtype.Text
+}
+
+func (q *Queries) CreateChatMessage(ctx context.Context, arg CreateChatMessageParams) (ChatMessage, error) {
+	row := q.db.QueryRow(ctx, createChatMessage,
+		arg.ConversationID,
+		arg.Role,
+		arg.Content,
+		arg.ToolCalls,
+		arg.ToolCallID,
+		arg.Model,
+		arg.PromptVersion,
+	)
+	var i ChatMessage
+	err := row.Scan(
+		&i.ID,
+		&i.ConversationID,
+		&i.Role,
+		&i.Content,
+		&i.ToolCalls,
+		&i.ToolCallID,
+		&i.CreatedAt,
+		&i.Model,
+		&i.PromptVersion,
 	)
 	return i, err
 }
@@ -74,144 +252,3730 @@ func (q *Queries) CreateCoach(ctx context.Context, arg CreateCoachParams) (Coach
 	return i, err
 }
 
-const getAthlete = `-- name: GetAthlete :one
-SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync FROM athlete WHERE id = $1 LIMIT 1
+const createOutboxEmail = `-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (kind, coach_id, athlete_id, to_addr, reply_to, subject, html, text_body, list_unsubscribe)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, kind, coach_id, athlete_id, to_addr, reply_to, subject, html, status, attempts, last_error, created_at, updated_at, sent_at, text_body, list_unsubscribe
 `
 
-func (q *Queries) GetAthlete(ctx context.Context, id uuid.UUID) (Athlete, error) {
-	row := q.db.QueryRow(ctx, getAthlete, id)
-	var i Athlete
+type CreateOutboxEmailParams struct {
+	Kind            string
+	CoachID         pgtype.UUID
+	AthleteID       pgtype.UUID
+	ToAddr          string
+	ReplyTo         pgtype.Text
+	Subject         string
+	Html            string
+	TextBody        string
+	ListUnsubscribe pgtype.Text
+}
+
+func (q *Queries) CreateOutboxEmail(ctx context.Context, arg CreateOutboxEmailParams) (EmailOutbox, error) {
+	row := q.db.QueryRow(ctx, createOutboxEmail,
+		arg.Kind,
+		arg.CoachID,
+		arg.AthleteID,
+		arg.ToAddr,
+		arg.ReplyTo,
+		arg.Subject,
+		arg.Html,
+		arg.TextBody,
+		arg.ListUnsubscribe,
+	)
+	var i EmailOutbox
 	err := row.Scan(
 		&i.ID,
+		&i.Kind,
 		&i.CoachID,
-		&i.Name,
-		&i.Email,
-		&i.Tz,
-		&i.StravaAthleteID,
-		&i.StravaAccessToken,
-		&i.StravaRefreshToken,
-		&i.StravaTokenExpiry,
+		&i.AthleteID,
+		&i.ToAddr,
+		&i.ReplyTo,
+		&i.Subject,
+		&i.Html,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
 		&i.CreatedAt,
-		&i.LastStravaSync,
+		&i.UpdatedAt,
+		&i.SentAt,
+		&i.TextBody,
+		&i.ListUnsubscribe,
 	)
 	return i, err
 }
 
-const getCoachByEmail = `-- name: GetCoachByEmail :one
-SELECT id, email, name, tz, created_at FROM coach WHERE email = $1 LIMIT 1
+const createPlanDraft = `-- name: CreatePlanDraft :one
+INSERT INTO plan_draft (coach_id, athlete_id, event_id, start_day, weeks, days, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, coach_id, athlete_id, event_id, template_id, start_day, weeks, days, notes, status, error, warnings, model, created_at, published_at, prompt_version
 `
 
-func (q *Queries) GetCoachByEmail(ctx context.Context, email string) (Coach, error) {
-	row := q.db.QueryRow(ctx, getCoachByEmail, email)
-	var i Coach
+type CreatePlanDraftParams struct {
+	CoachID   uuid.UUID
+	AthleteID uuid.UUID
+	EventID   pgtype.UUID
+	StartDay  pgtype.Date
+	Weeks     int32
+	Days      []int32
+	Notes     pgtype.Text
+}
+
+func (q *Queries) CreatePlanDraft(ctx context.Context, arg CreatePlanDraftParams) (PlanDraft, error) {
+	row := q.db.QueryRow(ctx, createPlanDraft,
+		arg.CoachID,
+		arg.AthleteID,
+		arg.EventID,
+		arg.StartDay,
+		arg.Weeks,
+		arg.Days,
+		arg.Notes,
+	)
+	var i PlanDraft
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.EventID,
+		&i.TemplateID,
+		&i.StartDay,
+		&i.Weeks,
+		&i.Days,
+		&i.Notes,
+		&i.Status,
+		&i.Error,
+		&i.Warnings,
+		&i.Model,
+		&i.CreatedAt,
+		&i.PublishedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const createPlanOperation = `-- name: CreatePlanOperation :one
+INSERT INTO plan_operation (coach_id, kind, summary, undo)
+VALUES ($1, $2, $3, $4)
+RETURNING id, coach_id, kind, summary, undo, created_at, undone_at
+`
+
+type CreatePlanOperationParams struct {
+	CoachID uuid.UUID
+	Kind    string
+	Summary string
+	Undo    []byte
+}
+
+func (q *Queries) CreatePlanOperation(ctx context.Context, arg CreatePlanOperationParams) (PlanOperation, error) {
+	row := q.db.QueryRow(ctx, createPlanOperation,
+		arg.CoachID,
+		arg.Kind,
+		arg.Summary,
+		arg.Undo,
+	)
+	var i PlanOperation
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Kind,
+		&i.Summary,
+		&i.Undo,
+		&i.CreatedAt,
+		&i.UndoneAt,
+	)
+	return i, err
+}
+
+const createPlanTemplate = `-- name: CreatePlanTemplate :one
+INSERT INTO plan_template (coach_id, name, description, weeks)
+VALUES ($1, $2, $3, $4)
+RETURNING id, coach_id, name, description, weeks, created_at, updated_at
+`
+
+type CreatePlanTemplateParams struct {
+	CoachID     uuid.UUID
+	Name        string
+	Description pgtype.Text
+	Weeks       int32
+}
+
+func (q *Queries) CreatePlanTemplate(ctx context.Context, arg CreatePlanTemplateParams) (PlanTemplate, error) {
+	row := q.db.QueryRow(ctx, createPlanTemplate,
+		arg.CoachID,
+		arg.Name,
+		arg.Description,
+		arg.Weeks,
+	)
+	var i PlanTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
 		&i.Name,
-		&i.Tz,
+		&i.Description,
+		&i.Weeks,
 		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const listAthletesByCoach = `-- name: ListAthletesByCoach :many
-SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync FROM athlete WHERE coach_id = $1 ORDER BY created_at DESC
+const createPlanTemplateApplication = `-- name: CreatePlanTemplateApplication :one
+INSERT INTO plan_template_application (template_id, athlete_id, start_day, scale)
+VALUES ($1, $2, $3, $4)
+RETURNING id, template_id, athlete_id, start_day, scale, created_at
 `
 
-func (q *Queries) ListAthletesByCoach(ctx context.Context, coachID uuid.UUID) ([]Athlete, error) {
-	rows, err := q.db.Query(ctx, listAthletesByCoach, coachID)
+type CreatePlanTemplateApplicationParams struct {
+	TemplateID uuid.UUID
+	AthleteID  uuid.UUID
+	StartDay   pgtype.Date
+	Scale      float64
+}
+
+func (q *Queries) CreatePlanTemplateApplication(ctx context.Context, arg CreatePlanTemplateApplicationParams) (PlanTemplateApplication, error) {
+	row := q.db.QueryRow(ctx, createPlanTemplateApplication,
+		arg.TemplateID,
+		arg.AthleteID,
+		arg.StartDay,
+		arg.Scale,
+	)
+	var i PlanTemplateApplication
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.AthleteID,
+		&i.StartDay,
+		&i.Scale,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPlanTemplateSession = `-- name: CreatePlanTemplateSession :one
+INSERT INTO plan_template_session (
+    template_id, week, day, sport, title, description,
+    target_duration_sec, target_distance_m, target_load, target_zone, structure
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, template_id, week, day, sport, title, description, target_duration_sec, target_distance_m, target_load, target_zone, structure, created_at, updated_at
+`
+
+type CreatePlanTemplateSessionParams struct {
+	TemplateID        uuid.UUID
+	Week              int32
+	Day               int32
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+}
+
+func (q *Queries) CreatePlanTemplateSession(ctx context.Context, arg CreatePlanTemplateSessionParams) (PlanTemplateSession, error) {
+	row := q.db.QueryRow(ctx, createPlanTemplateSession,
+		arg.TemplateID,
+		arg.Week,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+	)
+	var i PlanTemplateSession
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Week,
+		&i.Day,
+		&i.Sport,
+		&i.Title,
+		&i.Description,
+		&i.TargetDurationSec,
+		&i.TargetDistanceM,
+		&i.TargetLoad,
+		&i.TargetZone,
+		&i.Structure,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createPlannedWorkout = `-- name: CreatePlannedWorkout :one
+INSERT INTO planned_workout (
+    athlete_id, day, sport, title, description,
+    target_duration_sec, target_distance_m, target_load, target_zone, structure,
+    template_session_id, application_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id, athlete_id, day, sport, title, description, target_duration_sec, target_distance_m, target_load, created_at, updated_at, target_zone, workout_id, status, compliance, structure, template_session_id, application_id
+`
+
+type CreatePlannedWorkoutParams struct {
+	AthleteID         uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+	TemplateSessionID pgtype.UUID
+	ApplicationID     pgtype.UUID
+}
+
+func (q *Queries) CreatePlannedWorkout(ctx context.Context, arg CreatePlannedWorkoutParams) (PlannedWorkout, error) {
+	row := q.db.QueryRow(ctx, createPlannedWorkout,
+		arg.AthleteID,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+		arg.TemplateSessionID,
+		arg.ApplicationID,
+	)
+	var i PlannedWorkout
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Day,
+		&i.Sport,
+		&i.Title,
+		&i.Description,
+		&i.TargetDurationSec,
+		&i.TargetDistanceM,
+		&i.TargetLoad,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TargetZone,
+		&i.WorkoutID,
+		&i.Status,
+		&i.Compliance,
+		&i.Structure,
+		&i.TemplateSessionID,
+		&i.ApplicationID,
+	)
+	return i, err
+}
+
+const createThreadComment = `-- name: CreateThreadComment :one
+INSERT INTO thread_comment (thread_id, author, body, via)
+VALUES ($1, $2, $3, $4)
+RETURNING id, thread_id, author, body, via, created_at
+`
+
+type CreateThreadCommentParams struct {
+	ThreadID uuid.UUID
+	Author   string
+	Body     string
+	Via      string
+}
+
+func (q *Queries) CreateThreadComment(ctx context.Context, arg CreateThreadCommentParams) (ThreadComment, error) {
+	row := q.db.QueryRow(ctx, createThreadComment,
+		arg.ThreadID,
+		arg.Author,
+		arg.Body,
+		arg.Via,
+	)
+	var i ThreadComment
+	err := row.Scan(
+		&i.ID,
+		&i.ThreadID,
+		&i.Author,
+		&i.Body,
+		&i.Via,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createTrainingPhase = `-- name: CreateTrainingPhase :one
+INSERT INTO training_phase (athlete_id, kind, start_day, end_day, ramp_per_week, notes)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, athlete_id, kind, start_day, end_day, ramp_per_week, notes, created_at
+`
+
+type CreateTrainingPhaseParams struct {
+	AthleteID   uuid.UUID
+	Kind        string
+	StartDay    pgtype.Date
+	EndDay      pgtype.Date
+	RampPerWeek pgtype.Float8
+	Notes       pgtype.Text
+}
+
+func (q *Queries) CreateTrainingPhase(ctx context.Context, arg CreateTrainingPhaseParams) (TrainingPhase, error) {
+	row := q.db.QueryRow(ctx, createTrainingPhase,
+		arg.AthleteID,
+		arg.Kind,
+		arg.StartDay,
+		arg.EndDay,
+		arg.RampPerWeek,
+		arg.Notes,
+	)
+	var i TrainingPhase
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Kind,
+		&i.StartDay,
+		&i.EndDay,
+		&i.RampPerWeek,
+		&i.Notes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWeeklyReport = `-- name: CreateWeeklyReport :one
+INSERT INTO weekly_report (coach_id, week_start, facts, summary, highlights, concerns, model, prompt_version)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, coach_id, week_start, facts, summary, highlights, concerns, model, created_at, emailed_at, prompt_version
+`
+
+type CreateWeeklyReportParams struct {
+	CoachID       uuid.UUID
+	WeekStart     pgtype.Date
+	Facts         []byte
+	Summary       string
+	Highlights    []string
+	Concerns      []string
+	Model         string
+	PromptVersion string
+}
+
+func (q *Queries) CreateWeeklyReport(ctx context.Context, arg CreateWeeklyReportParams) (WeeklyReport, error) {
+	row := q.db.QueryRow(ctx, createWeeklyReport,
+		arg.CoachID,
+		arg.WeekStart,
+		arg.Facts,
+		arg.Summary,
+		arg.Highlights,
+		arg.Concerns,
+		arg.Model,
+		arg.PromptVersion,
+	)
+	var i WeeklyReport
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.WeekStart,
+		&i.Facts,
+		&i.Summary,
+		&i.Highlights,
+		&i.Concerns,
+		&i.Model,
+		&i.CreatedAt,
+		&i.EmailedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const createWorkoutCommentary = `-- name: CreateWorkoutCommentary :execrows
+INSERT INTO workout_commentary (workout_id, draft, body, facts, model, prompt_version)
+VALUES ($1, $2, $2, $3, $4, $5)
+ON CONFLICT (workout_id) DO NOTHING
+`
+
+type CreateWorkoutCommentaryParams struct {
+	WorkoutID     uuid.UUID
+	Draft         string
+	Facts         []byte
+	Model         string
+	PromptVersion string
+}
+
+// Keeps an existing note, so a retried job never overwrites one the coach
+// has already reviewed.
+func (q *Queries) CreateWorkoutCommentary(ctx context.Context, arg CreateWorkoutCommentaryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createWorkoutCommentary,
+		arg.WorkoutID,
+		arg.Draft,
+		arg.Facts,
+		arg.Model,
+		arg.PromptVersion,
+	)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Athlete
-	for rows.Next() {
-		var i Athlete
-		if err := rows.Scan(
-			&i.ID,
-			&i.CoachID,
-			&i.Name,
-			&i.Email,
-			&i.Tz,
-			&i.StravaAthleteID,
-			&i.StravaAccessToken,
-			&i.StravaRefreshToken,
-			&i.StravaTokenExpiry,
-			&i.CreatedAt,
-			&i.LastStravaSync,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected(), nil
+}
+
+const createWorkoutLap = `-- name: CreateWorkoutLap :exec
+INSERT INTO workout_lap (workout_id, lap_index, elapsed_sec, moving_sec, distance_m, avg_speed, avg_hr, max_hr, avg_watts)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+
+type CreateWorkoutLapParams struct {
+	WorkoutID  uuid.UUID
+	LapIndex   int32
+	ElapsedSec int32
+	MovingSec  int32
+	DistanceM  float64
+	AvgSpeed   pgtype.Float8
+	AvgHr      pgtype.Float8
+	MaxHr      pgtype.Float8
+	AvgWatts   pgtype.Float8
+}
+
+func (q *Queries) CreateWorkoutLap(ctx context.Context, arg CreateWorkoutLapParams) error {
+	_, err := q.db.Exec(ctx, createWorkoutLap,
+		arg.WorkoutID,
+		arg.LapIndex,
+		arg.ElapsedSec,
+		arg.MovingSec,
+		arg.DistanceM,
+		arg.AvgSpeed,
+		arg.AvgHr,
+		arg.MaxHr,
+		arg.AvgWatts,
+	)
+	return err
+}
+
+const deleteAthleteCalendarFeed = `-- name: DeleteAthleteCalendarFeed :exec
+DELETE FROM calendar_feed
+WHERE athlete_id = $1
+`
+
+func (q *Queries) DeleteAthleteCalendarFeed(ctx context.Context, athleteID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteAthleteCalendarFeed, athleteID)
+	return err
+}
+
+const deleteAthleteEvent = `-- name: DeleteAthleteEvent :execrows
+DELETE FROM athlete_event
+WHERE id = $1 AND athlete_id = $2
+`
+
+type DeleteAthleteEventParams struct {
+	ID        uuid.UUID
+	AthleteID uuid.UUID
+}
+
+func (q *Queries) DeleteAthleteEvent(ctx context.Context, arg DeleteAthleteEventParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteAthleteEvent, arg.ID, arg.AthleteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCoachCalendarFeed = `-- name: DeleteCoachCalendarFeed :exec
+DELETE FROM calendar_feed
+WHERE coach_id = $1 AND athlete_id IS NULL
+`
+
+func (q *Queries) DeleteCoachCalendarFeed(ctx context.Context, coachID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteCoachCalendarFeed, coachID)
+	return err
+}
+
+const deletePlanDraft = `-- name: DeletePlanDraft :execrows
+DELETE FROM plan_draft
+WHERE id = $1 AND coach_id = $2 AND status <> 'published'
+`
+
+type DeletePlanDraftParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) DeletePlanDraft(ctx context.Context, arg DeletePlanDraftParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePlanDraft, arg.ID, arg.CoachID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePlanTemplate = `-- name: DeletePlanTemplate :execrows
+DELETE FROM plan_template
+WHERE id = $1 AND coach_id = $2
+`
+
+type DeletePlanTemplateParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) DeletePlanTemplate(ctx context.Context, arg DeletePlanTemplateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePlanTemplate, arg.ID, arg.CoachID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePlanTemplateSession = `-- name: DeletePlanTemplateSession :execrows
+DELETE FROM plan_template_session
+WHERE id = $1 AND template_id = $2
+`
+
+type DeletePlanTemplateSessionParams struct {
+	ID         uuid.UUID
+	TemplateID uuid.UUID
+}
+
+func (q *Queries) DeletePlanTemplateSession(ctx context.Context, arg DeletePlanTemplateSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePlanTemplateSession, arg.ID, arg.TemplateID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePlannedWorkout = `-- name: DeletePlannedWorkout :execrows
+DELETE FROM planned_workout
+WHERE id = $1 AND athlete_id = $2
+`
+
+type DeletePlannedWorkoutParams struct {
+	ID        uuid.UUID
+	AthleteID uuid.UUID
+}
+
+func (q *Queries) DeletePlannedWorkout(ctx context.Context, arg DeletePlannedWorkoutParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePlannedWorkout, arg.ID, arg.AthleteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTrainingPhase = `-- name: DeleteTrainingPhase :execrows
+DELETE FROM training_phase
+WHERE id = $1 AND athlete_id = $2
+`
+
+type DeleteTrainingPhaseParams struct {
+	ID        uuid.UUID
+	AthleteID uuid.UUID
+}
+
+func (q *Queries) DeleteTrainingPhase(ctx context.Context, arg DeleteTrainingPhaseParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTrainingPhase, arg.ID, arg.AthleteID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUpcomingFromTemplateSession = `-- name: DeleteUpcomingFromTemplateSession :execrows
+DELETE FROM planned_workout p
+USING athlete a
+WHERE a.id = p.athlete_id
+  AND p.template_session_id = $1
+  AND p.workout_id IS NULL
+  AND p.day >= (now() AT TIME ZONE a.tz)::date
+`
+
+// Removes unmatched sessions from today on, in each athlete's time zone.
+func (q *Queries) DeleteUpcomingFromTemplateSession(ctx context.Context, templateSessionID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUpcomingFromTemplateSession, templateSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteWorkoutLaps = `-- name: DeleteWorkoutLaps :exec
+DELETE FROM workout_lap
+WHERE workout_id = $1
+`
+
+func (q *Queries) DeleteWorkoutLaps(ctx context.Context, workoutID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteWorkoutLaps, workoutID)
+	return err
+}
+
+const dismissAthleteAlert = `-- name: DismissAthleteAlert :execrows
+UPDATE athlete_alert
+SET dismissed_at = now()
+WHERE athlete_alert.id = $1
+  AND athlete_id IN (SELECT id FROM athlete WHERE coach_id = $2)
+`
+
+type DismissAthleteAlertParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) DismissAthleteAlert(ctx context.Context, arg DismissAthleteAlertParams) (int64, error) {
+	result, err := q.db.Exec(ctx, dismissAthleteAlert, arg.ID, arg.CoachID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const ensurePlannedWorkoutCommentThread = `-- name: EnsurePlannedWorkoutCommentThread :one
+INSERT INTO comment_thread (athlete_id, planned_workout_id, coach_token, athlete_token)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (planned_workout_id) DO UPDATE SET planned_workout_id = EXCLUDED.planned_workout_id
+RETURNING id, athlete_id, workout_id, planned_workout_id, coach_token, athlete_token, created_at
+`
+
+type EnsurePlannedWorkoutCommentThreadParams struct {
+	AthleteID        uuid.UUID
+	PlannedWorkoutID pgtype.UUID
+	CoachToken       string
+	AthleteToken     string
+}
+
+func (q *Queries) EnsurePlannedWorkoutCommentThread(ctx context.Context, arg EnsurePlannedWorkoutCommentThreadParams) (CommentThread, error) {
+	row := q.db.QueryRow(ctx, ensurePlannedWorkoutCommentThread,
+		arg.AthleteID,
+		arg.PlannedWorkoutID,
+		arg.CoachToken,
+		arg.AthleteToken,
+	)
+	var i CommentThread
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.WorkoutID,
+		&i.PlannedWorkoutID,
+		&i.CoachToken,
+		&i.AthleteToken,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ensureWorkoutCommentThread = `-- name: EnsureWorkoutCommentThread :one
+INSERT INTO comment_thread (athlete_id, workout_id, coach_token, athlete_token)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (workout_id) DO UPDATE SET workout_id = EXCLUDED.workout_id
+RETURNING id, athlete_id, workout_id, planned_workout_id, coach_token, athlete_token, created_at
+`
+
+type EnsureWorkoutCommentThreadParams struct {
+	AthleteID    uuid.UUID
+	WorkoutID    pgtype.UUID
+	CoachToken   string
+	AthleteToken string
+}
+
+// Returns the workout's thread, starting it with the given tokens if there
+// is none yet.
+func (q *Queries) EnsureWorkoutCommentThread(ctx context.Context, arg EnsureWorkoutCommentThreadParams) (CommentThread, error) {
+	row := q.db.QueryRow(ctx, ensureWorkoutCommentThread,
+		arg.AthleteID,
+		arg.WorkoutID,
+		arg.CoachToken,
+		arg.AthleteToken,
+	)
+	var i CommentThread
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.WorkoutID,
+		&i.PlannedWorkoutID,
+		&i.CoachToken,
+		&i.AthleteToken,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAthlete = `-- name: GetAthlete :one
+SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync, max_hr, resting_hr, ftp_watts, threshold_pace_sec FROM athlete WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAthlete(ctx context.Context, id uuid.UUID) (Athlete, error) {
+	row := q.db.QueryRow(ctx, getAthlete, id)
+	var i Athlete
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Name,
+		&i.Email,
+		&i.Tz,
+		&i.StravaAthleteID,
+		&i.StravaAccessToken,
+		&i.StravaRefreshToken,
+		&i.StravaTokenExpiry,
+		&i.CreatedAt,
+		&i.LastStravaSync,
+		&i.MaxHr,
+		&i.RestingHr,
+		&i.FtpWatts,
+		&i.ThresholdPaceSec,
+	)
+	return i, err
+}
+
+const getAthleteByEmail = `-- name: GetAthleteByEmail :one
+SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync, max_hr, resting_hr, ftp_watts, threshold_pace_sec FROM athlete
+WHERE lower(email) = lower($1)
+`
+
+// Finds the athlete signing in to the portal. Addresses are matched without
+// regard to case, and uniq_athlete_email_lower keeps that to one athlete.
+func (q *Queries) GetAthleteByEmail(ctx context.Context, email string) (Athlete, error) {
+	row := q.db.QueryRow(ctx, getAthleteByEmail, email)
+	var i Athlete
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Name,
+		&i.Email,
+		&i.Tz,
+		&i.StravaAthleteID,
+		&i.StravaAccessToken,
+		&i.StravaRefreshToken,
+		&i.StravaTokenExpiry,
+		&i.CreatedAt,
+		&i.LastStravaSync,
+		&i.MaxHr,
+		&i.RestingHr,
+		&i.FtpWatts,
+		&i.ThresholdPaceSec,
+	)
+	return i, err
+}
+
+const getAthleteCalendarFeed = `-- name: GetAthleteCalendarFeed :one
+SELECT token, coach_id, athlete_id, created_at FROM calendar_feed
+WHERE athlete_id = $1
+`
+
+func (q *Queries) GetAthleteCalendarFeed(ctx context.Context, athleteID pgtype.UUID) (CalendarFeed, error) {
+	row := q.db.QueryRow(ctx, getAthleteCalendarFeed, athleteID)
+	var i CalendarFeed
+	err := row.Scan(
+		&i.Token,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCalendarFeed = `-- name: GetCalendarFeed :one
+SELECT token, coach_id, athlete_id, created_at FROM calendar_feed
+WHERE token = $1
+`
+
+func (q *Queries) GetCalendarFeed(ctx context.Context, token string) (CalendarFeed, error) {
+	row := q.db.QueryRow(ctx, getCalendarFeed, token)
+	var i CalendarFeed
+	err := row.Scan(
+		&i.Token,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getChatConversation = `-- name: GetChatConversation :one
+SELECT id, coach_id, athlete_id, title, replying_at, created_at, updated_at FROM chat_conversation
+WHERE id = $1 AND coach_id = $2
+`
+
+type GetChatConversationParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) GetChatConversation(ctx context.Context, arg GetChatConversationParams) (ChatConversation, error) {
+	row := q.db.QueryRow(ctx, getChatConversation, arg.ID, arg.CoachID)
+	var i ChatConversation
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.Title,
+		&i.ReplyingAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCoach = `-- name: GetCoach :one
+SELECT id, email, name, tz, created_at FROM coach WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetCoach(ctx context.Context, id uuid.UUID) (Coach, error) {
+	row := q.db.QueryRow(ctx, getCoach, id)
+	var i Coach
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Tz,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCoachAthleteWorkout = `-- name: GetCoachAthleteWorkout :one
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec, w.distance_m, w.elev_gain_m,
+       w.avg_hr, w.load, w.review_status, w.review_flags,
+       m.moving_sec, m.decoupling_pct, m.efficiency_factor, m.gap_speed, m.avg_power,
+       m.normalized_power, m.variability_index, m.intensity_factor, m.zone_sec
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.id = $1 AND w.athlete_id = $2 AND a.coach_id = $3
+`
+
+type GetCoachAthleteWorkoutParams struct {
+	ID        uuid.UUID
+	AthleteID uuid.UUID
+	CoachID   uuid.UUID
+}
+
+type GetCoachAthleteWorkoutRow struct {
+	ID               uuid.UUID
+	Name             pgtype.Text
+	Sport            string
+	StartedAt        pgtype.Timestamptz
+	DurationSec      int32
+	DistanceM        pgtype.Float8
+	ElevGainM        pgtype.Float8
+	AvgHr            pgtype.Int4
+	Load             pgtype.Float8
+	ReviewStatus     string
+	ReviewFlags      []string
+	MovingSec        pgtype.Int4
+	DecouplingPct    pgtype.Float8
+	EfficiencyFactor pgtype.Float8
+	GapSpeed         pgtype.Float8
+	AvgPower         pgtype.Float8
+	NormalizedPower  pgtype.Float8
+	VariabilityIndex pgtype.Float8
+	IntensityFactor  pgtype.Float8
+	ZoneSec          []int32
+}
+
+func (q *Queries) GetCoachAthleteWorkout(ctx context.Context, arg GetCoachAthleteWorkoutParams) (GetCoachAthleteWorkoutRow, error) {
+	row := q.db.QueryRow(ctx, getCoachAthleteWorkout, arg.ID, arg.AthleteID, arg.CoachID)
+	var i GetCoachAthleteWorkoutRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Sport,
+		&i.StartedAt,
+		&i.DurationSec,
+		&i.DistanceM,
+		&i.ElevGainM,
+		&i.AvgHr,
+		&i.Load,
+		&i.ReviewStatus,
+		&i.ReviewFlags,
+		&i.MovingSec,
+		&i.DecouplingPct,
+		&i.EfficiencyFactor,
+		&i.GapSpeed,
+		&i.AvgPower,
+		&i.NormalizedPower,
+		&i.VariabilityIndex,
+		&i.IntensityFactor,
+		&i.ZoneSec,
+	)
+	return i, err
+}
+
+const getCoachByEmail = `-- name: GetCoachByEmail :one
+SELECT id, email, name, tz, created_at FROM coach WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetCoachByEmail(ctx context.Context, email string) (Coach, error) {
+	row := q.db.QueryRow(ctx, getCoachByEmail, email)
+	var i Coach
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Tz,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCoachCalendarFeed = `-- name: GetCoachCalendarFeed :one
+SELECT token, coach_id, athlete_id, created_at FROM calendar_feed
+WHERE coach_id = $1 AND athlete_id IS NULL
+`
+
+func (q *Queries) GetCoachCalendarFeed(ctx context.Context, coachID uuid.UUID) (CalendarFeed, error) {
+	row := q.db.QueryRow(ctx, getCoachCalendarFeed, coachID)
+	var i CalendarFeed
+	err := row.Scan(
+		&i.Token,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getCommentThreadByToken = `-- name: GetCommentThreadByToken :one
+SELECT id, athlete_id, workout_id, planned_workout_id, coach_token, athlete_token, created_at FROM comment_thread
+WHERE coach_token = $1 OR athlete_token = $1
+`
+
+// Finds the thread either side's token belongs to; compare the tokens to
+// tell which side it is.
+func (q *Queries) GetCommentThreadByToken(ctx context.Context, token string) (CommentThread, error) {
+	row := q.db.QueryRow(ctx, getCommentThreadByToken, token)
+	var i CommentThread
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.WorkoutID,
+		&i.PlannedWorkoutID,
+		&i.CoachToken,
+		&i.AthleteToken,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestPlanOperation = `-- name: GetLatestPlanOperation :one
+SELECT id, coach_id, kind, summary, undo, created_at, undone_at FROM plan_operation
+WHERE coach_id = $1 AND undone_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1
+FOR UPDATE
+`
+
+// Only the most recent operation still in effect can be undone, so undo
+// works like a stack.
+func (q *Queries) GetLatestPlanOperation(ctx context.Context, coachID uuid.UUID) (PlanOperation, error) {
+	row := q.db.QueryRow(ctx, getLatestPlanOperation, coachID)
+	var i PlanOperation
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Kind,
+		&i.Summary,
+		&i.Undo,
+		&i.CreatedAt,
+		&i.UndoneAt,
+	)
+	return i, err
+}
+
+const getOutboxEmail = `-- name: GetOutboxEmail :one
+SELECT id, kind, coach_id, athlete_id, to_addr, reply_to, subject, html, status, attempts, last_error, created_at, updated_at, sent_at, text_body, list_unsubscribe FROM email_outbox
+WHERE id = $1
+`
+
+func (q *Queries) GetOutboxEmail(ctx context.Context, id uuid.UUID) (EmailOutbox, error) {
+	row := q.db.QueryRow(ctx, getOutboxEmail, id)
+	var i EmailOutbox
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.ToAddr,
+		&i.ReplyTo,
+		&i.Subject,
+		&i.Html,
+		&i.Status,
+		&i.Attempts,
+		&i.LastError,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SentAt,
+		&i.TextBody,
+		&i.ListUnsubscribe,
+	)
+	return i, err
+}
+
+const getPlanDraft = `-- name: GetPlanDraft :one
+SELECT id, coach_id, athlete_id, event_id, template_id, start_day, weeks, days, notes, status, error, warnings, model, created_at, published_at, prompt_version FROM plan_draft
+WHERE id = $1 AND coach_id = $2
+`
+
+type GetPlanDraftParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) GetPlanDraft(ctx context.Context, arg GetPlanDraftParams) (PlanDraft, error) {
+	row := q.db.QueryRow(ctx, getPlanDraft, arg.ID, arg.CoachID)
+	var i PlanDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.EventID,
+		&i.TemplateID,
+		&i.StartDay,
+		&i.Weeks,
+		&i.Days,
+		&i.Notes,
+		&i.Status,
+		&i.Error,
+		&i.Warnings,
+		&i.Model,
+		&i.CreatedAt,
+		&i.PublishedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getPlanDraftByID = `-- name: GetPlanDraftByID :one
+SELECT id, coach_id, athlete_id, event_id, template_id, start_day, weeks, days, notes, status, error, warnings, model, created_at, published_at, prompt_version FROM plan_draft
+WHERE id = $1
+`
+
+func (q *Queries) GetPlanDraftByID(ctx context.Context, id uuid.UUID) (PlanDraft, error) {
+	row := q.db.QueryRow(ctx, getPlanDraftByID, id)
+	var i PlanDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.EventID,
+		&i.TemplateID,
+		&i.StartDay,
+		&i.Weeks,
+		&i.Days,
+		&i.Notes,
+		&i.Status,
+		&i.Error,
+		&i.Warnings,
+		&i.Model,
+		&i.CreatedAt,
+		&i.PublishedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getPlanDraftByTemplate = `-- name: GetPlanDraftByTemplate :one
+SELECT id, coach_id, athlete_id, event_id, template_id, start_day, weeks, days, notes, status, error, warnings, model, created_at, published_at, prompt_version FROM plan_draft
+WHERE template_id = $1 AND coach_id = $2
+`
+
+type GetPlanDraftByTemplateParams struct {
+	TemplateID pgtype.UUID
+	CoachID    uuid.UUID
+}
+
+func (q *Queries) GetPlanDraftByTemplate(ctx context.Context, arg GetPlanDraftByTemplateParams) (PlanDraft, error) {
+	row := q.db.QueryRow(ctx, getPlanDraftByTemplate, arg.TemplateID, arg.CoachID)
+	var i PlanDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.EventID,
+		&i.TemplateID,
+		&i.StartDay,
+		&i.Weeks,
+		&i.Days,
+		&i.Notes,
+		&i.Status,
+		&i.Error,
+		&i.Warnings,
+		&i.Model,
+		&i.CreatedAt,
+		&i.PublishedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getPlanTemplate = `-- name: GetPlanTemplate :one
+SELECT id, coach_id, name, description, weeks, created_at, updated_at FROM plan_template
+WHERE id = $1 AND coach_id = $2
+`
+
+type GetPlanTemplateParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) GetPlanTemplate(ctx context.Context, arg GetPlanTemplateParams) (PlanTemplate, error) {
+	row := q.db.QueryRow(ctx, getPlanTemplate, arg.ID, arg.CoachID)
+	var i PlanTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Name,
+		&i.Description,
+		&i.Weeks,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPlanTemplateByApplication = `-- name: GetPlanTemplateByApplication :one
+SELECT t.id, t.coach_id, t.name, t.description, t.weeks, t.created_at, t.updated_at
+FROM plan_template t
+JOIN plan_template_application ap ON ap.template_id = t.id
+WHERE ap.id = $1 AND t.coach_id = $2
+`
+
+type GetPlanTemplateByApplicationParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) GetPlanTemplateByApplication(ctx context.Context, arg GetPlanTemplateByApplicationParams) (PlanTemplate, error) {
+	row := q.db.QueryRow(ctx, getPlanTemplateByApplication, arg.ID, arg.CoachID)
+	var i PlanTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.Name,
+		&i.Description,
+		&i.Weeks,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPlanTemplateSession = `-- name: GetPlanTemplateSession :one
+SELECT id, template_id, week, day, sport, title, description, target_duration_sec, target_distance_m, target_load, target_zone, structure, created_at, updated_at FROM plan_template_session
+WHERE id = $1 AND template_id = $2
+`
+
+type GetPlanTemplateSessionParams struct {
+	ID         uuid.UUID
+	TemplateID uuid.UUID
+}
+
+func (q *Queries) GetPlanTemplateSession(ctx context.Context, arg GetPlanTemplateSessionParams) (PlanTemplateSession, error) {
+	row := q.db.QueryRow(ctx, getPlanTemplateSession, arg.ID, arg.TemplateID)
+	var i PlanTemplateSession
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Week,
+		&i.Day,
+		&i.Sport,
+		&i.Title,
+		&i.Description,
+		&i.TargetDurationSec,
+		&i.TargetDistanceM,
+		&i.TargetLoad,
+		&i.TargetZone,
+		&i.Structure,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPlannedWorkout = `-- name: GetPlannedWorkout :one
+SELECT id, athlete_id, day, sport, title, description, target_duration_sec, target_distance_m, target_load, created_at, updated_at, target_zone, workout_id, status, compliance, structure, template_session_id, application_id FROM planned_workout
+WHERE id = $1 AND athlete_id = $2
+`
+
+type GetPlannedWorkoutParams struct {
+	ID        uuid.UUID
+	AthleteID uuid.UUID
+}
+
+func (q *Queries) GetPlannedWorkout(ctx context.Context, arg GetPlannedWorkoutParams) (PlannedWorkout, error) {
+	row := q.db.QueryRow(ctx, getPlannedWorkout, arg.ID, arg.AthleteID)
+	var i PlannedWorkout
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Day,
+		&i.Sport,
+		&i.Title,
+		&i.Description,
+		&i.TargetDurationSec,
+		&i.TargetDistanceM,
+		&i.TargetLoad,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TargetZone,
+		&i.WorkoutID,
+		&i.Status,
+		&i.Compliance,
+		&i.Structure,
+		&i.TemplateSessionID,
+		&i.ApplicationID,
+	)
+	return i, err
+}
+
+const getPlannedWorkoutCommentThread = `-- name: GetPlannedWorkoutCommentThread :one
+SELECT id, athlete_id, workout_id, planned_workout_id, coach_token, athlete_token, created_at FROM comment_thread
+WHERE planned_workout_id = $1
+`
+
+func (q *Queries) GetPlannedWorkoutCommentThread(ctx context.Context, plannedWorkoutID pgtype.UUID) (CommentThread, error) {
+	row := q.db.QueryRow(ctx, getPlannedWorkoutCommentThread, plannedWorkoutID)
+	var i CommentThread
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.WorkoutID,
+		&i.PlannedWorkoutID,
+		&i.CoachToken,
+		&i.AthleteToken,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPlannedWorkoutForWorkout = `-- name: GetPlannedWorkoutForWorkout :one
+SELECT id, athlete_id, day, sport, title, description, target_duration_sec, target_distance_m, target_load, created_at, updated_at, target_zone, workout_id, status, compliance, structure, template_session_id, application_id FROM planned_workout
+WHERE workout_id = $1
+ORDER BY created_at
+LIMIT 1
+`
+
+func (q *Queries) GetPlannedWorkoutForWorkout(ctx context.Context, workoutID pgtype.UUID) (PlannedWorkout, error) {
+	row := q.db.QueryRow(ctx, getPlannedWorkoutForWorkout, workoutID)
+	var i PlannedWorkout
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.Day,
+		&i.Sport,
+		&i.Title,
+		&i.Description,
+		&i.TargetDurationSec,
+		&i.TargetDistanceM,
+		&i.TargetLoad,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TargetZone,
+		&i.WorkoutID,
+		&i.Status,
+		&i.Compliance,
+		&i.Structure,
+		&i.TemplateSessionID,
+		&i.ApplicationID,
+	)
+	return i, err
+}
+
+const getWeeklyReport = `-- name: GetWeeklyReport :one
+SELECT id, coach_id, week_start, facts, summary, highlights, concerns, model, created_at, emailed_at, prompt_version FROM weekly_report
+WHERE id = $1 AND coach_id = $2
+`
+
+type GetWeeklyReportParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) GetWeeklyReport(ctx context.Context, arg GetWeeklyReportParams) (WeeklyReport, error) {
+	row := q.db.QueryRow(ctx, getWeeklyReport, arg.ID, arg.CoachID)
+	var i WeeklyReport
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.WeekStart,
+		&i.Facts,
+		&i.Summary,
+		&i.Highlights,
+		&i.Concerns,
+		&i.Model,
+		&i.CreatedAt,
+		&i.EmailedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getWeeklyReportForWeek = `-- name: GetWeeklyReportForWeek :one
+SELECT id, coach_id, week_start, facts, summary, highlights, concerns, model, created_at, emailed_at, prompt_version FROM weekly_report
+WHERE coach_id = $1 AND week_start = $2
+`
+
+type GetWeeklyReportForWeekParams struct {
+	CoachID   uuid.UUID
+	WeekStart pgtype.Date
+}
+
+func (q *Queries) GetWeeklyReportForWeek(ctx context.Context, arg GetWeeklyReportForWeekParams) (WeeklyReport, error) {
+	row := q.db.QueryRow(ctx, getWeeklyReportForWeek, arg.CoachID, arg.WeekStart)
+	var i WeeklyReport
+	err := row.Scan(
+		&i.ID,
+		&i.CoachID,
+		&i.WeekStart,
+		&i.Facts,
+		&i.Summary,
+		&i.Highlights,
+		&i.Concerns,
+		&i.Model,
+		&i.CreatedAt,
+		&i.EmailedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getWellnessCheckin = `-- name: GetWellnessCheckin :one
+SELECT athlete_id, day, sleep_hours, sleep_quality, soreness, stress, mood, resting_hr, notes, created_at, updated_at FROM wellness_checkin
+WHERE athlete_id = $1 AND day = $2
+`
+
+type GetWellnessCheckinParams struct {
+	AthleteID uuid.UUID
+	Day       pgtype.Date
+}
+
+func (q *Queries) GetWellnessCheckin(ctx context.Context, arg GetWellnessCheckinParams) (WellnessCheckin, error) {
+	row := q.db.QueryRow(ctx, getWellnessCheckin, arg.AthleteID, arg.Day)
+	var i WellnessCheckin
+	err := row.Scan(
+		&i.AthleteID,
+		&i.Day,
+		&i.SleepHours,
+		&i.SleepQuality,
+		&i.Soreness,
+		&i.Stress,
+		&i.Mood,
+		&i.RestingHr,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWorkoutCommentThread = `-- name: GetWorkoutCommentThread :one
+SELECT id, athlete_id, workout_id, planned_workout_id, coach_token, athlete_token, created_at FROM comment_thread
+WHERE workout_id = $1
+`
+
+func (q *Queries) GetWorkoutCommentThread(ctx context.Context, workoutID pgtype.UUID) (CommentThread, error) {
+	row := q.db.QueryRow(ctx, getWorkoutCommentThread, workoutID)
+	var i CommentThread
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.WorkoutID,
+		&i.PlannedWorkoutID,
+		&i.CoachToken,
+		&i.AthleteToken,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWorkoutCommentary = `-- name: GetWorkoutCommentary :one
+SELECT workout_id, draft, body, status, facts, model, created_at, reviewed_at, prompt_version FROM workout_commentary
+WHERE workout_id = $1
+`
+
+func (q *Queries) GetWorkoutCommentary(ctx context.Context, workoutID uuid.UUID) (WorkoutCommentary, error) {
+	row := q.db.QueryRow(ctx, getWorkoutCommentary, workoutID)
+	var i WorkoutCommentary
+	err := row.Scan(
+		&i.WorkoutID,
+		&i.Draft,
+		&i.Body,
+		&i.Status,
+		&i.Facts,
+		&i.Model,
+		&i.CreatedAt,
+		&i.ReviewedAt,
+		&i.PromptVersion,
+	)
+	return i, err
+}
+
+const getWorkoutFeedback = `-- name: GetWorkoutFeedback :one
+SELECT workout_id, athlete_id, rpe, notes, pain, created_at, updated_at FROM workout_feedback
+WHERE workout_id = $1
+`
+
+func (q *Queries) GetWorkoutFeedback(ctx context.Context, workoutID uuid.UUID) (WorkoutFeedback, error) {
+	row := q.db.QueryRow(ctx, getWorkoutFeedback, workoutID)
+	var i WorkoutFeedback
+	err := row.Scan(
+		&i.WorkoutID,
+		&i.AthleteID,
+		&i.Rpe,
+		&i.Notes,
+		&i.Pain,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWorkoutForCommentary = `-- name: GetWorkoutForCommentary :one
+SELECT w.id, w.athlete_id, a.coach_id, w.name, w.sport, w.started_at, w.duration_sec,
+       w.distance_m, w.elev_gain_m, w.avg_hr, w.load, w.review_status,
+       m.moving_sec, m.decoupling_basis, m.decoupling_pct, m.efficiency_factor, m.gap_speed,
+       m.avg_power, m.normalized_power, m.variability_index, m.intensity_factor, m.zone_sec
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.id = $1
+`
+
+type GetWorkoutForCommentaryRow struct {
+	ID               uuid.UUID
+	AthleteID        uuid.UUID
+	CoachID          uuid.UUID
+	Name             pgtype.Text
+	Sport            string
+	StartedAt        pgtype.Timestamptz
+	DurationSec      int32
+	DistanceM        pgtype.Float8
+	ElevGainM        pgtype.Float8
+	AvgHr            pgtype.Int4
+	Load             pgtype.Float8
+	ReviewStatus     string
+	MovingSec        pgtype.Int4
+	DecouplingBasis  pgtype.Text
+	DecouplingPct    pgtype.Float8
+	EfficiencyFactor pgtype.Float8
+	GapSpeed         pgtype.Float8
+	AvgPower         pgtype.Float8
+	NormalizedPower  pgtype.Float8
+	VariabilityIndex pgtype.Float8
+	IntensityFactor  pgtype.Float8
+	ZoneSec          []int32
+}
+
+func (q *Queries) GetWorkoutForCommentary(ctx context.Context, id uuid.UUID) (GetWorkoutForCommentaryRow, error) {
+	row := q.db.QueryRow(ctx, getWorkoutForCommentary, id)
+	var i GetWorkoutForCommentaryRow
+	err := row.Scan(
+		&i.ID,
+		&i.AthleteID,
+		&i.CoachID,
+		&i.Name,
+		&i.Sport,
+		&i.StartedAt,
+		&i.DurationSec,
+		&i.DistanceM,
+		&i.ElevGainM,
+		&i.AvgHr,
+		&i.Load,
+		&i.ReviewStatus,
+		&i.MovingSec,
+		&i.DecouplingBasis,
+		&i.DecouplingPct,
+		&i.EfficiencyFactor,
+		&i.GapSpeed,
+		&i.AvgPower,
+		&i.NormalizedPower,
+		&i.VariabilityIndex,
+		&i.IntensityFactor,
+		&i.ZoneSec,
+	)
+	return i, err
+}
+
+const isEmailSuppressed = `-- name: IsEmailSuppressed :one
+SELECT EXISTS (
+  SELECT 1 FROM email_suppression
+  WHERE address = lower($1) AND kind = $2
+) AS suppressed
+`
+
+type IsEmailSuppressedParams struct {
+	Address string
+	Kind    string
+}
+
+func (q *Queries) IsEmailSuppressed(ctx context.Context, arg IsEmailSuppressedParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isEmailSuppressed, arg.Address, arg.Kind)
+	var suppressed bool
+	err := row.Scan(&suppressed)
+	return suppressed, err
+}
+
+const listAthleteAlertsBetween = `-- name: ListAthleteAlertsBetween :many
+SELECT id, athlete_id, kind, day, value, threshold, message, dismissed_at, created_at FROM athlete_alert
+WHERE athlete_id = $1 AND day BETWEEN $2::date AND $3::date
+ORDER BY day, created_at
+`
+
+type ListAthleteAlertsBetweenParams struct {
+	AthleteID uuid.UUID
+	FromDay   pgtype.Date
+	ToDay     pgtype.Date
+}
+
+func (q *Queries) ListAthleteAlertsBetween(ctx context.Context, arg ListAthleteAlertsBetweenParams) ([]AthleteAlert, error) {
+	rows, err := q.db.Query(ctx, listAthleteAlertsBetween, arg.AthleteID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AthleteAlert
+	for rows.Next() {
+		var i AthleteAlert
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Kind,
+			&i.Day,
+			&i.Value,
+			&i.Threshold,
+			&i.Message,
+			&i.DismissedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAthleteEvents = `-- name: ListAthleteEvents :many
+SELECT id, athlete_id, name, day, priority, sport, distance_m, goal_time_sec, notes, created_at FROM athlete_event
+WHERE athlete_id = $1
+ORDER BY day, priority
+`
+
+func (q *Queries) ListAthleteEvents(ctx context.Context, athleteID uuid.UUID) ([]AthleteEvent, error) {
+	rows, err := q.db.Query(ctx, listAthleteEvents, athleteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AthleteEvent
+	for rows.Next() {
+		var i AthleteEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Name,
+			&i.Day,
+			&i.Priority,
+			&i.Sport,
+			&i.DistanceM,
+			&i.GoalTimeSec,
+			&i.Notes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAthleteEventsBetween = `-- name: ListAthleteEventsBetween :many
+SELECT id, athlete_id, name, day, priority, sport, distance_m, goal_time_sec, notes, created_at FROM athlete_event
+WHERE athlete_id = $1 AND day BETWEEN $2::date AND $3::date
+ORDER BY day, priority
+`
+
+type ListAthleteEventsBetweenParams struct {
+	AthleteID uuid.UUID
+	FromDay   pgtype.Date
+	ToDay     pgtype.Date
+}
+
+func (q *Queries) ListAthleteEventsBetween(ctx context.Context, arg ListAthleteEventsBetweenParams) ([]AthleteEvent, error) {
+	rows, err := q.db.Query(ctx, listAthleteEventsBetween, arg.AthleteID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AthleteEvent
+	for rows.Next() {
+		var i AthleteEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Name,
+			&i.Day,
+			&i.Priority,
+			&i.Sport,
+			&i.DistanceM,
+			&i.GoalTimeSec,
+			&i.Notes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAthletesByCoach = `-- name: ListAthletesByCoach :many
+SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync, max_hr, resting_hr, ftp_watts, threshold_pace_sec FROM athlete WHERE coach_id = $1 ORDER BY created_at DESC
+`
+
+func (q *Queries) ListAthletesByCoach(ctx context.Context, coachID uuid.UUID) ([]Athlete, error) {
+	rows, err := q.db.Query(ctx, listAthletesByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Athlete
+	for rows.Next() {
+		var i Athlete
+		if err := rows.Scan(
+			&i.ID,
+			&i.CoachID,
+			&i.Name,
+			&i.Email,
+			&i.Tz,
+			&i.StravaAthleteID,
+			&i.StravaAccessToken,
+			&i.StravaRefreshToken,
+			&i.StravaTokenExpiry,
+			&i.CreatedAt,
+			&i.LastStravaSync,
+			&i.MaxHr,
+			&i.RestingHr,
+			&i.FtpWatts,
+			&i.ThresholdPaceSec,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChatConversations = `-- name: ListChatConversations :many
+SELECT id, coach_id, athlete_id, title, replying_at, created_at, updated_at FROM chat_conversation
+WHERE athlete_id = $1 AND coach_id = $2
+ORDER BY updated_at DESC
+LIMIT 20
+`
+
+type ListChatConversationsParams struct {
+	AthleteID uuid.UUID
+	CoachID   uuid.UUID
+}
+
+func (q *Queries) ListChatConversations(ctx context.Context, arg ListChatConversationsParams) ([]ChatConversation, error) {
+	rows, err := q.db.Query(ctx, listChatConversations, arg.AthleteID, arg.CoachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatConversation
+	for rows.Next() {
+		var i ChatConversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CoachID,
+			&i.AthleteID,
+			&i.Title,
+			&i.ReplyingAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChatMessages = `-- name: ListChatMessages :many
+SELECT id, conversation_id, role, content, tool_calls, tool_call_id, created_at, model, prompt_version FROM chat_message
+WHERE conversation_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListChatMessages(ctx context.Context, conversationID uuid.UUID) ([]ChatMessage, error) {
+	rows, err := q.db.Query(ctx, listChatMessages, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatMessage
+	for rows.Next() {
+		var i ChatMessage
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConversationID,
+			&i.Role,
+			&i.Content,
+			&i.ToolCalls,
+			&i.ToolCallID,
+			&i.CreatedAt,
+			&i.Model,
+			&i.PromptVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachAthleteEventsBetween = `-- name: ListCoachAthleteEventsBetween :many
+SELECT e.id, e.athlete_id, e.name, e.day, e.priority, e.sport, e.distance_m, e.goal_time_sec, e.notes, e.created_at, a.name AS athlete_name
+FROM athlete_event e
+JOIN athlete a ON a.id = e.athlete_id
+WHERE a.coach_id = $1 AND e.day BETWEEN $2::date AND $3::date
+ORDER BY e.day, a.name
+`
+
+type ListCoachAthleteEventsBetweenParams struct {
+	CoachID uuid.UUID
+	FromDay pgtype.Date
+	ToDay   pgtype.Date
+}
+
+type ListCoachAthleteEventsBetweenRow struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	Name        string
+	Day         pgtype.Date
+	Priority    string
+	Sport       string
+	DistanceM   pgtype.Float8
+	GoalTimeSec pgtype.Int4
+	Notes       pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+	AthleteName string
+}
+
+func (q *Queries) ListCoachAthleteEventsBetween(ctx context.Context, arg ListCoachAthleteEventsBetweenParams) ([]ListCoachAthleteEventsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, listCoachAthleteEventsBetween, arg.CoachID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoachAthleteEventsBetweenRow
+	for rows.Next() {
+		var i ListCoachAthleteEventsBetweenRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Name,
+			&i.Day,
+			&i.Priority,
+			&i.Sport,
+			&i.DistanceM,
+			&i.GoalTimeSec,
+			&i.Notes,
+			&i.CreatedAt,
+			&i.AthleteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachAthleteWorkoutLoadsBetween = `-- name: ListCoachAthleteWorkoutLoadsBetween :many
+SELECT w.started_at, w.duration_sec, w.distance_m, w.avg_hr, w.load, m.intensity_factor
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE a.coach_id = $1 AND w.athlete_id = $2
+  AND w.started_at >= $3::timestamptz AND w.started_at < $4::timestamptz
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at
+`
+
+type ListCoachAthleteWorkoutLoadsBetweenParams struct {
+	CoachID   uuid.UUID
+	AthleteID uuid.UUID
+	FromTime  pgtype.Timestamptz
+	ToTime    pgtype.Timestamptz
+}
+
+type ListCoachAthleteWorkoutLoadsBetweenRow struct {
+	StartedAt       pgtype.Timestamptz
+	DurationSec     int32
+	DistanceM       pgtype.Float8
+	AvgHr           pgtype.Int4
+	Load            pgtype.Float8
+	IntensityFactor pgtype.Float8
+}
+
+func (q *Queries) ListCoachAthleteWorkoutLoadsBetween(ctx context.Context, arg ListCoachAthleteWorkoutLoadsBetweenParams) ([]ListCoachAthleteWorkoutLoadsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, listCoachAthleteWorkoutLoadsBetween,
+		arg.CoachID,
+		arg.AthleteID,
+		arg.FromTime,
+		arg.ToTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoachAthleteWorkoutLoadsBetweenRow
+	for rows.Next() {
+		var i ListCoachAthleteWorkoutLoadsBetweenRow
+		if err := rows.Scan(
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.AvgHr,
+			&i.Load,
+			&i.IntensityFactor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachAthleteWorkoutsBetween = `-- name: ListCoachAthleteWorkoutsBetween :many
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec, w.distance_m, w.elev_gain_m,
+       w.avg_hr, w.load, w.review_status, m.gap_speed, m.intensity_factor
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE a.coach_id = $1 AND w.athlete_id = $2
+  AND w.started_at >= $3::timestamptz AND w.started_at < $4::timestamptz
+  AND ($5::text = '' OR w.sport = $5::text)
+ORDER BY w.started_at
+LIMIT 200
+`
+
+type ListCoachAthleteWorkoutsBetweenParams struct {
+	CoachID   uuid.UUID
+	AthleteID uuid.UUID
+	FromTime  pgtype.Timestamptz
+	ToTime    pgtype.Timestamptz
+	Sport     string
+}
+
+type ListCoachAthleteWorkoutsBetweenRow struct {
+	ID              uuid.UUID
+	Name            pgtype.Text
+	Sport           string
+	StartedAt       pgtype.Timestamptz
+	DurationSec     int32
+	DistanceM       pgtype.Float8
+	ElevGainM       pgtype.Float8
+	AvgHr           pgtype.Int4
+	Load            pgtype.Float8
+	ReviewStatus    string
+	GapSpeed        pgtype.Float8
+	IntensityFactor pgtype.Float8
+}
+
+// The assistant's workout lookup. Scoped to the coach in the query itself,
+// like every query the assistant can reach.
+func (q *Queries) ListCoachAthleteWorkoutsBetween(ctx context.Context, arg ListCoachAthleteWorkoutsBetweenParams) ([]ListCoachAthleteWorkoutsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, listCoachAthleteWorkoutsBetween,
+		arg.CoachID,
+		arg.AthleteID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Sport,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoachAthleteWorkoutsBetweenRow
+	for rows.Next() {
+		var i ListCoachAthleteWorkoutsBetweenRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.ElevGainM,
+			&i.AvgHr,
+			&i.Load,
+			&i.ReviewStatus,
+			&i.GapSpeed,
+			&i.IntensityFactor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachLLMUsageSince = `-- name: ListCoachLLMUsageSince :many
+SELECT feature, model, COUNT(*)::int AS calls,
+       COALESCE(SUM(prompt_tokens), 0)::bigint AS prompt_tokens,
+       COALESCE(SUM(completion_tokens), 0)::bigint AS completion_tokens
+FROM llm_usage
+WHERE coach_id = $1 AND created_at >= $2
+GROUP BY feature, model
+ORDER BY feature, model
+`
+
+type ListCoachLLMUsageSinceParams struct {
+	CoachID   uuid.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
+type ListCoachLLMUsageSinceRow struct {
+	Feature          string
+	Model            string
+	Calls            int32
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+func (q *Queries) ListCoachLLMUsageSince(ctx context.Context, arg ListCoachLLMUsageSinceParams) ([]ListCoachLLMUsageSinceRow, error) {
+	rows, err := q.db.Query(ctx, listCoachLLMUsageSince, arg.CoachID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoachLLMUsageSinceRow
+	for rows.Next() {
+		var i ListCoachLLMUsageSinceRow
+		if err := rows.Scan(
+			&i.Feature,
+			&i.Model,
+			&i.Calls,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachPlannedWorkoutsBetween = `-- name: ListCoachPlannedWorkoutsBetween :many
+SELECT pw.id, pw.athlete_id, pw.day, pw.sport, pw.title, pw.description, pw.target_duration_sec, pw.target_distance_m, pw.target_load, pw.created_at, pw.updated_at, pw.target_zone, pw.workout_id, pw.status, pw.compliance, pw.structure, pw.template_session_id, pw.application_id, a.name AS athlete_name
+FROM planned_workout pw
+JOIN athlete a ON a.id = pw.athlete_id
+WHERE a.coach_id = $1 AND pw.day BETWEEN $2::date AND $3::date
+ORDER BY pw.day, a.name, pw.created_at
+`
+
+type ListCoachPlannedWorkoutsBetweenParams struct {
+	CoachID uuid.UUID
+	FromDay pgtype.Date
+	ToDay   pgtype.Date
+}
+
+type ListCoachPlannedWorkoutsBetweenRow struct {
+	ID                uuid.UUID
+	AthleteID         uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	CreatedAt         pgtype.Timestamptz
+	UpdatedAt         pgtype.Timestamptz
+	TargetZone        pgtype.Int4
+	WorkoutID         pgtype.UUID
+	Status            string
+	Compliance        pgtype.Float8
+	Structure         []byte
+	TemplateSessionID pgtype.UUID
+	ApplicationID     pgtype.UUID
+	AthleteName       string
+}
+
+func (q *Queries) ListCoachPlannedWorkoutsBetween(ctx context.Context, arg ListCoachPlannedWorkoutsBetweenParams) ([]ListCoachPlannedWorkoutsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, listCoachPlannedWorkoutsBetween, arg.CoachID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCoachPlannedWorkoutsBetweenRow
+	for rows.Next() {
+		var i ListCoachPlannedWorkoutsBetweenRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Day,
+			&i.Sport,
+			&i.Title,
+			&i.Description,
+			&i.TargetDurationSec,
+			&i.TargetDistanceM,
+			&i.TargetLoad,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TargetZone,
+			&i.WorkoutID,
+			&i.Status,
+			&i.Compliance,
+			&i.Structure,
+			&i.TemplateSessionID,
+			&i.ApplicationID,
+			&i.AthleteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCoachesWithAthletes = `-- name: ListCoachesWithAthletes :many
+SELECT id, email, name, tz, created_at FROM coach c
+WHERE EXISTS (SELECT 1 FROM athlete a WHERE a.coach_id = c.id)
+ORDER BY c.created_at
+`
+
+func (q *Queries) ListCoachesWithAthletes(ctx context.Context) ([]Coach, error) {
+	rows, err := q.db.Query(ctx, listCoachesWithAthletes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Coach
+	for rows.Next() {
+		var i Coach
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Tz,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCommentaryDraftsByCoach = `-- name: ListCommentaryDraftsByCoach :many
+SELECT c.workout_id, w.athlete_id, a.name AS athlete_name, w.name, w.sport, w.started_at
+FROM workout_commentary c
+JOIN workout w ON w.id = c.workout_id
+JOIN athlete a ON a.id = w.athlete_id
+WHERE a.coach_id = $1 AND c.status = 'draft'
+ORDER BY w.started_at DESC
+LIMIT 50
+`
+
+type ListCommentaryDraftsByCoachRow struct {
+	WorkoutID   uuid.UUID
+	AthleteID   uuid.UUID
+	AthleteName string
+	Name        pgtype.Text
+	Sport       string
+	StartedAt   pgtype.Timestamptz
+}
+
+func (q *Queries) ListCommentaryDraftsByCoach(ctx context.Context, coachID uuid.UUID) ([]ListCommentaryDraftsByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listCommentaryDraftsByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCommentaryDraftsByCoachRow
+	for rows.Next() {
+		var i ListCommentaryDraftsByCoachRow
+		if err := rows.Scan(
+			&i.WorkoutID,
+			&i.AthleteID,
+			&i.AthleteName,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConnectedAthletes = `-- name: ListConnectedAthletes :many
+SELECT id, coach_id, name, email, tz, strava_athlete_id, strava_access_token, strava_refresh_token, strava_token_expiry, created_at, last_strava_sync, max_hr, resting_hr, ftp_watts, threshold_pace_sec FROM athlete WHERE strava_access_token IS NOT NULL ORDER BY created_at
+`
+
+func (q *Queries) ListConnectedAthletes(ctx context.Context) ([]Athlete, error) {
+	rows, err := q.db.Query(ctx, listConnectedAthletes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Athlete
+	for rows.Next() {
+		var i Athlete
+		if err := rows.Scan(
+			&i.ID,
+			&i.CoachID,
+			&i.Name,
+			&i.Email,
+			&i.Tz,
+			&i.StravaAthleteID,
+			&i.StravaAccessToken,
+			&i.StravaRefreshToken,
+			&i.StravaTokenExpiry,
+			&i.CreatedAt,
+			&i.LastStravaSync,
+			&i.MaxHr,
+			&i.RestingHr,
+			&i.FtpWatts,
+			&i.ThresholdPaceSec,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDecouplingSince = `-- name: ListDecouplingSince :many
+SELECT w.id, w.name, w.sport, w.started_at, m.moving_sec,
+       m.decoupling_basis, m.decoupling_pct, m.efficiency_factor
+FROM workout w
+JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = $1 AND w.started_at >= $2
+  AND m.decoupling_pct IS NOT NULL
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at
+`
+
+type ListDecouplingSinceParams struct {
+	AthleteID uuid.UUID
+	StartedAt pgtype.Timestamptz
+}
+
+type ListDecouplingSinceRow struct {
+	ID               uuid.UUID
+	Name             pgtype.Text
+	Sport            string
+	StartedAt        pgtype.Timestamptz
+	MovingSec        int32
+	DecouplingBasis  pgtype.Text
+	DecouplingPct    pgtype.Float8
+	EfficiencyFactor pgtype.Float8
+}
+
+func (q *Queries) ListDecouplingSince(ctx context.Context, arg ListDecouplingSinceParams) ([]ListDecouplingSinceRow, error) {
+	rows, err := q.db.Query(ctx, listDecouplingSince, arg.AthleteID, arg.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDecouplingSinceRow
+	for rows.Next() {
+		var i ListDecouplingSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.MovingSec,
+			&i.DecouplingBasis,
+			&i.DecouplingPct,
+			&i.EfficiencyFactor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFlaggedWorkoutsByCoach = `-- name: ListFlaggedWorkoutsByCoach :many
+SELECT w.id, w.athlete_id, a.name AS athlete_name, w.name, w.sport,
+       w.started_at, w.review_flags
+FROM workout w
+JOIN athlete a ON a.id = w.athlete_id
+WHERE a.coach_id = $1 AND w.review_status = 'flagged'
+ORDER BY w.started_at DESC
+LIMIT 50
+`
+
+type ListFlaggedWorkoutsByCoachRow struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	AthleteName string
+	Name        pgtype.Text
+	Sport       string
+	StartedAt   pgtype.Timestamptz
+	ReviewFlags []string
+}
+
+func (q *Queries) ListFlaggedWorkoutsByCoach(ctx context.Context, coachID uuid.UUID) ([]ListFlaggedWorkoutsByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listFlaggedWorkoutsByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFlaggedWorkoutsByCoachRow
+	for rows.Next() {
+		var i ListFlaggedWorkoutsByCoachRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.AthleteName,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.ReviewFlags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLatestInvitesByCoach = `-- name: ListLatestInvitesByCoach :many
+SELECT DISTINCT ON (athlete_id) athlete_id, status, last_error, created_at
+FROM email_outbox
+WHERE coach_id = $1 AND kind = 'invite' AND athlete_id IS NOT NULL
+ORDER BY athlete_id, created_at DESC
+`
+
+type ListLatestInvitesByCoachRow struct {
+	AthleteID pgtype.UUID
+	Status    string
+	LastError pgtype.Text
+	CreatedAt pgtype.Timestamptz
+}
+
+// The newest invite email to each of the coach's athletes.
+func (q *Queries) ListLatestInvitesByCoach(ctx context.Context, coachID pgtype.UUID) ([]ListLatestInvitesByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listLatestInvitesByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListLatestInvitesByCoachRow
+	for rows.Next() {
+		var i ListLatestInvitesByCoachRow
+		if err := rows.Scan(
+			&i.AthleteID,
+			&i.Status,
+			&i.LastError,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenAlertsByCoach = `-- name: ListOpenAlertsByCoach :many
+SELECT al.id, al.athlete_id, a.name AS athlete_name, al.kind, al.day,
+       al.value, al.threshold, al.message, al.created_at
+FROM athlete_alert al
+JOIN athlete a ON a.id = al.athlete_id
+WHERE a.coach_id = $1 AND al.dismissed_at IS NULL
+ORDER BY al.day DESC, al.created_at DESC
+LIMIT 50
+`
+
+type ListOpenAlertsByCoachRow struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	AthleteName string
+	Kind        string
+	Day         pgtype.Date
+	Value       float64
+	Threshold   float64
+	Message     string
+	CreatedAt   pgtype.Timestamptz
+}
+
+func (q *Queries) ListOpenAlertsByCoach(ctx context.Context, coachID uuid.UUID) ([]ListOpenAlertsByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listOpenAlertsByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOpenAlertsByCoachRow
+	for rows.Next() {
+		var i ListOpenAlertsByCoachRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.AthleteName,
+			&i.Kind,
+			&i.Day,
+			&i.Value,
+			&i.Threshold,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOutboxEmailsByCoach = `-- name: ListOutboxEmailsByCoach :many
+SELECT id, kind, coach_id, athlete_id, to_addr, reply_to, subject, html, status, attempts, last_error, created_at, updated_at, sent_at, text_body, list_unsubscribe FROM email_outbox
+WHERE coach_id = $1
+ORDER BY created_at DESC
+LIMIT 50
+`
+
+func (q *Queries) ListOutboxEmailsByCoach(ctx context.Context, coachID pgtype.UUID) ([]EmailOutbox, error) {
+	rows, err := q.db.Query(ctx, listOutboxEmailsByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EmailOutbox
+	for rows.Next() {
+		var i EmailOutbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.CoachID,
+			&i.AthleteID,
+			&i.ToAddr,
+			&i.ReplyTo,
+			&i.Subject,
+			&i.Html,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SentAt,
+			&i.TextBody,
+			&i.ListUnsubscribe,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanComplianceByCoach = `-- name: ListPlanComplianceByCoach :many
+SELECT p.athlete_id, p.day, p.status, p.compliance
+FROM planned_workout p
+JOIN athlete a ON a.id = p.athlete_id
+WHERE a.coach_id = $1 AND p.day BETWEEN $2::date AND $3::date
+ORDER BY p.athlete_id, p.day
+`
+
+type ListPlanComplianceByCoachParams struct {
+	CoachID uuid.UUID
+	FromDay pgtype.Date
+	ToDay   pgtype.Date
+}
+
+type ListPlanComplianceByCoachRow struct {
+	AthleteID  uuid.UUID
+	Day        pgtype.Date
+	Status     string
+	Compliance pgtype.Float8
+}
+
+func (q *Queries) ListPlanComplianceByCoach(ctx context.Context, arg ListPlanComplianceByCoachParams) ([]ListPlanComplianceByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listPlanComplianceByCoach, arg.CoachID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPlanComplianceByCoachRow
+	for rows.Next() {
+		var i ListPlanComplianceByCoachRow
+		if err := rows.Scan(
+			&i.AthleteID,
+			&i.Day,
+			&i.Status,
+			&i.Compliance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanDrafts = `-- name: ListPlanDrafts :many
+SELECT id, coach_id, athlete_id, event_id, template_id, start_day, weeks, days, notes, status, error, warnings, model, created_at, published_at, prompt_version FROM plan_draft
+WHERE athlete_id = $1 AND coach_id = $2
+ORDER BY created_at DESC
+LIMIT 10
+`
+
+type ListPlanDraftsParams struct {
+	AthleteID uuid.UUID
+	CoachID   uuid.UUID
+}
+
+func (q *Queries) ListPlanDrafts(ctx context.Context, arg ListPlanDraftsParams) ([]PlanDraft, error) {
+	rows, err := q.db.Query(ctx, listPlanDrafts, arg.AthleteID, arg.CoachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlanDraft
+	for rows.Next() {
+		var i PlanDraft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CoachID,
+			&i.AthleteID,
+			&i.EventID,
+			&i.TemplateID,
+			&i.StartDay,
+			&i.Weeks,
+			&i.Days,
+			&i.Notes,
+			&i.Status,
+			&i.Error,
+			&i.Warnings,
+			&i.Model,
+			&i.CreatedAt,
+			&i.PublishedAt,
+			&i.PromptVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanOperations = `-- name: ListPlanOperations :many
+SELECT id, coach_id, kind, summary, undo, created_at, undone_at FROM plan_operation
+WHERE coach_id = $1
+ORDER BY created_at DESC
+LIMIT 10
+`
+
+func (q *Queries) ListPlanOperations(ctx context.Context, coachID uuid.UUID) ([]PlanOperation, error) {
+	rows, err := q.db.Query(ctx, listPlanOperations, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlanOperation
+	for rows.Next() {
+		var i PlanOperation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CoachID,
+			&i.Kind,
+			&i.Summary,
+			&i.Undo,
+			&i.CreatedAt,
+			&i.UndoneAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanTemplateApplications = `-- name: ListPlanTemplateApplications :many
+SELECT ap.id, ap.athlete_id, a.name AS athlete_name, a.tz, ap.start_day, ap.scale, ap.created_at
+FROM plan_template_application ap
+JOIN athlete a ON a.id = ap.athlete_id
+WHERE ap.template_id = $1
+ORDER BY ap.start_day DESC, ap.created_at DESC
+`
+
+type ListPlanTemplateApplicationsRow struct {
+	ID          uuid.UUID
+	AthleteID   uuid.UUID
+	AthleteName string
+	Tz          string
+	StartDay    pgtype.Date
+	Scale       float64
+	CreatedAt   pgtype.Timestamptz
+}
+
+func (q *Queries) ListPlanTemplateApplications(ctx context.Context, templateID uuid.UUID) ([]ListPlanTemplateApplicationsRow, error) {
+	rows, err := q.db.Query(ctx, listPlanTemplateApplications, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPlanTemplateApplicationsRow
+	for rows.Next() {
+		var i ListPlanTemplateApplicationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.AthleteName,
+			&i.Tz,
+			&i.StartDay,
+			&i.Scale,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanTemplateSessions = `-- name: ListPlanTemplateSessions :many
+SELECT id, template_id, week, day, sport, title, description, target_duration_sec, target_distance_m, target_load, target_zone, structure, created_at, updated_at FROM plan_template_session
+WHERE template_id = $1
+ORDER BY week, day, created_at
+`
+
+func (q *Queries) ListPlanTemplateSessions(ctx context.Context, templateID uuid.UUID) ([]PlanTemplateSession, error) {
+	rows, err := q.db.Query(ctx, listPlanTemplateSessions, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlanTemplateSession
+	for rows.Next() {
+		var i PlanTemplateSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Week,
+			&i.Day,
+			&i.Sport,
+			&i.Title,
+			&i.Description,
+			&i.TargetDurationSec,
+			&i.TargetDistanceM,
+			&i.TargetLoad,
+			&i.TargetZone,
+			&i.Structure,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlanTemplatesByCoach = `-- name: ListPlanTemplatesByCoach :many
+SELECT t.id, t.name, t.description, t.weeks, t.updated_at,
+       (SELECT count(*) FROM plan_template_session s WHERE s.template_id = t.id) AS sessions
+FROM plan_template t
+WHERE t.coach_id = $1
+  AND NOT EXISTS (SELECT 1 FROM plan_draft d WHERE d.template_id = t.id AND d.status <> 'published')
+ORDER BY t.name
+`
+
+type ListPlanTemplatesByCoachRow struct {
+	ID          uuid.UUID
+	Name        string
+	Description pgtype.Text
+	Weeks       int32
+	UpdatedAt   pgtype.Timestamptz
+	Sessions    int64
+}
+
+// Drafts still being reviewed are reached from the athlete, not listed as
+// templates.
+func (q *Queries) ListPlanTemplatesByCoach(ctx context.Context, coachID uuid.UUID) ([]ListPlanTemplatesByCoachRow, error) {
+	rows, err := q.db.Query(ctx, listPlanTemplatesByCoach, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPlanTemplatesByCoachRow
+	for rows.Next() {
+		var i ListPlanTemplatesByCoachRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Weeks,
+			&i.UpdatedAt,
+			&i.Sessions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPlannedWorkoutsBetween = `-- name: ListPlannedWorkoutsBetween :many
+SELECT id, athlete_id, day, sport, title, description, target_duration_sec, target_distance_m, target_load, created_at, updated_at, target_zone, workout_id, status, compliance, structure, template_session_id, application_id FROM planned_workout
+WHERE athlete_id = $1 AND day BETWEEN $2::date AND $3::date
+ORDER BY day, created_at
+`
+
+type ListPlannedWorkoutsBetweenParams struct {
+	AthleteID uuid.UUID
+	FromDay   pgtype.Date
+	ToDay     pgtype.Date
+}
+
+func (q *Queries) ListPlannedWorkoutsBetween(ctx context.Context, arg ListPlannedWorkoutsBetweenParams) ([]PlannedWorkout, error) {
+	rows, err := q.db.Query(ctx, listPlannedWorkoutsBetween, arg.AthleteID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PlannedWorkout
+	for rows.Next() {
+		var i PlannedWorkout
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Day,
+			&i.Sport,
+			&i.Title,
+			&i.Description,
+			&i.TargetDurationSec,
+			&i.TargetDistanceM,
+			&i.TargetLoad,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TargetZone,
+			&i.WorkoutID,
+			&i.Status,
+			&i.Compliance,
+			&i.Structure,
+			&i.TemplateSessionID,
+			&i.ApplicationID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRunEffortsSince = `-- name: ListRunEffortsSince :many
+SELECT started_at, duration_sec, distance_m, avg_hr
+FROM workout
+WHERE athlete_id = $1 AND started_at >= $2
+  AND sport IN ('Run', 'TrailRun', 'VirtualRun')
+  AND distance_m IS NOT NULL
+  AND review_status IN ('ok', 'accepted')
+ORDER BY started_at
+`
+
+type ListRunEffortsSinceParams struct {
+	AthleteID uuid.UUID
+	StartedAt pgtype.Timestamptz
+}
+
+type ListRunEffortsSinceRow struct {
+	StartedAt   pgtype.Timestamptz
+	DurationSec int32
+	DistanceM   pgtype.Float8
+	AvgHr       pgtype.Int4
+}
+
+func (q *Queries) ListRunEffortsSince(ctx context.Context, arg ListRunEffortsSinceParams) ([]ListRunEffortsSinceRow, error) {
+	rows, err := q.db.Query(ctx, listRunEffortsSince, arg.AthleteID, arg.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRunEffortsSinceRow
+	for rows.Next() {
+		var i ListRunEffortsSinceRow
+		if err := rows.Scan(
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.AvgHr,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSessionRPE = `-- name: ListSessionRPE :many
+SELECT w.started_at, w.duration_sec, f.rpe
+FROM workout_feedback f
+JOIN workout w ON w.id = f.workout_id
+WHERE f.athlete_id = $1
+  AND w.started_at >= $2 AND w.started_at < $3
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at
+`
+
+type ListSessionRPEParams struct {
+	AthleteID uuid.UUID
+	FromTime  pgtype.Timestamptz
+	ToTime    pgtype.Timestamptz
+}
+
+type ListSessionRPERow struct {
+	StartedAt   pgtype.Timestamptz
+	DurationSec int32
+	Rpe         int32
+}
+
+// Rated workouts in a time range, for session-RPE load. Like
+// ListWorkoutLoadsSince it skips workouts held back by review.
+func (q *Queries) ListSessionRPE(ctx context.Context, arg ListSessionRPEParams) ([]ListSessionRPERow, error) {
+	rows, err := q.db.Query(ctx, listSessionRPE, arg.AthleteID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSessionRPERow
+	for rows.Next() {
+		var i ListSessionRPERow
+		if err := rows.Scan(&i.StartedAt, &i.DurationSec, &i.Rpe); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStalePendingEmails = `-- name: ListStalePendingEmails :many
+SELECT id FROM email_outbox
+WHERE status = 'pending' AND updated_at < $1
+ORDER BY created_at
+LIMIT 100
+`
+
+// Pending emails nothing has touched since before, whose send task was
+// lost, for the sweep to queue again.
+func (q *Queries) ListStalePendingEmails(ctx context.Context, before pgtype.Timestamptz) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listStalePendingEmails, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTemplatePlannedWorkouts = `-- name: ListTemplatePlannedWorkouts :many
+SELECT p.id, p.application_id, p.template_session_id, p.day, p.workout_id
+FROM planned_workout p
+JOIN plan_template_application ap ON ap.id = p.application_id
+WHERE ap.template_id = $1 AND p.template_session_id IS NOT NULL
+`
+
+type ListTemplatePlannedWorkoutsRow struct {
+	ID                uuid.UUID
+	ApplicationID     pgtype.UUID
+	TemplateSessionID pgtype.UUID
+	Day               pgtype.Date
+	WorkoutID         pgtype.UUID
+}
+
+// Planned sessions still linked to one of the template's sessions.
+func (q *Queries) ListTemplatePlannedWorkouts(ctx context.Context, templateID uuid.UUID) ([]ListTemplatePlannedWorkoutsRow, error) {
+	rows, err := q.db.Query(ctx, listTemplatePlannedWorkouts, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTemplatePlannedWorkoutsRow
+	for rows.Next() {
+		var i ListTemplatePlannedWorkoutsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ApplicationID,
+			&i.TemplateSessionID,
+			&i.Day,
+			&i.WorkoutID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listThreadComments = `-- name: ListThreadComments :many
+SELECT id, thread_id, author, body, via, created_at FROM thread_comment
+WHERE thread_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListThreadComments(ctx context.Context, threadID uuid.UUID) ([]ThreadComment, error) {
+	rows, err := q.db.Query(ctx, listThreadComments, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ThreadComment
+	for rows.Next() {
+		var i ThreadComment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ThreadID,
+			&i.Author,
+			&i.Body,
+			&i.Via,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrainingPhases = `-- name: ListTrainingPhases :many
+SELECT id, athlete_id, kind, start_day, end_day, ramp_per_week, notes, created_at FROM training_phase
+WHERE athlete_id = $1
+ORDER BY start_day
+`
+
+func (q *Queries) ListTrainingPhases(ctx context.Context, athleteID uuid.UUID) ([]TrainingPhase, error) {
+	rows, err := q.db.Query(ctx, listTrainingPhases, athleteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TrainingPhase
+	for rows.Next() {
+		var i TrainingPhase
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Kind,
+			&i.StartDay,
+			&i.EndDay,
+			&i.RampPerWeek,
+			&i.Notes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWeeklyReports = `-- name: ListWeeklyReports :many
+SELECT id, week_start, summary, created_at
+FROM weekly_report
+WHERE coach_id = $1
+ORDER BY week_start DESC
+LIMIT 52
+`
+
+type ListWeeklyReportsRow struct {
+	ID        uuid.UUID
+	WeekStart pgtype.Date
+	Summary   string
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) ListWeeklyReports(ctx context.Context, coachID uuid.UUID) ([]ListWeeklyReportsRow, error) {
+	rows, err := q.db.Query(ctx, listWeeklyReports, coachID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWeeklyReportsRow
+	for rows.Next() {
+		var i ListWeeklyReportsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WeekStart,
+			&i.Summary,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWellnessCheckins = `-- name: ListWellnessCheckins :many
+SELECT athlete_id, day, sleep_hours, sleep_quality, soreness, stress, mood, resting_hr, notes, created_at, updated_at FROM wellness_checkin
+WHERE athlete_id = $1 AND day BETWEEN $2 AND $3
+ORDER BY day
+`
+
+type ListWellnessCheckinsParams struct {
+	AthleteID uuid.UUID
+	FromDay   pgtype.Date
+	ToDay     pgtype.Date
+}
+
+func (q *Queries) ListWellnessCheckins(ctx context.Context, arg ListWellnessCheckinsParams) ([]WellnessCheckin, error) {
+	rows, err := q.db.Query(ctx, listWellnessCheckins, arg.AthleteID, arg.FromDay, arg.ToDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WellnessCheckin
+	for rows.Next() {
+		var i WellnessCheckin
+		if err := rows.Scan(
+			&i.AthleteID,
+			&i.Day,
+			&i.SleepHours,
+			&i.SleepQuality,
+			&i.Soreness,
+			&i.Stress,
+			&i.Mood,
+			&i.RestingHr,
+			&i.Notes,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutHistory = `-- name: ListWorkoutHistory :many
+SELECT duration_sec, distance_m, avg_hr
+FROM workout
+WHERE athlete_id = $1 AND sport = $2 AND started_at < $3
+  AND review_status IN ('ok', 'accepted')
+ORDER BY started_at DESC
+LIMIT 60
+`
+
+type ListWorkoutHistoryParams struct {
+	AthleteID uuid.UUID
+	Sport     string
+	StartedAt pgtype.Timestamptz
+}
+
+type ListWorkoutHistoryRow struct {
+	DurationSec int32
+	DistanceM   pgtype.Float8
+	AvgHr       pgtype.Int4
+}
+
+func (q *Queries) ListWorkoutHistory(ctx context.Context, arg ListWorkoutHistoryParams) ([]ListWorkoutHistoryRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutHistory, arg.AthleteID, arg.Sport, arg.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutHistoryRow
+	for rows.Next() {
+		var i ListWorkoutHistoryRow
+		if err := rows.Scan(&i.DurationSec, &i.DistanceM, &i.AvgHr); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutLaps = `-- name: ListWorkoutLaps :many
+SELECT workout_id, lap_index, elapsed_sec, moving_sec, distance_m, avg_speed, avg_hr, max_hr, avg_watts FROM workout_lap
+WHERE workout_id = $1
+ORDER BY lap_index
+`
+
+func (q *Queries) ListWorkoutLaps(ctx context.Context, workoutID uuid.UUID) ([]WorkoutLap, error) {
+	rows, err := q.db.Query(ctx, listWorkoutLaps, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WorkoutLap
+	for rows.Next() {
+		var i WorkoutLap
+		if err := rows.Scan(
+			&i.WorkoutID,
+			&i.LapIndex,
+			&i.ElapsedSec,
+			&i.MovingSec,
+			&i.DistanceM,
+			&i.AvgSpeed,
+			&i.AvgHr,
+			&i.MaxHr,
+			&i.AvgWatts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutLoadsSince = `-- name: ListWorkoutLoadsSince :many
+SELECT w.started_at, w.duration_sec, w.avg_hr, w.load, m.intensity_factor
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = $1 AND w.started_at >= $2
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at
+`
+
+type ListWorkoutLoadsSinceParams struct {
+	AthleteID uuid.UUID
+	StartedAt pgtype.Timestamptz
+}
+
+type ListWorkoutLoadsSinceRow struct {
+	StartedAt       pgtype.Timestamptz
+	DurationSec     int32
+	AvgHr           pgtype.Int4
+	Load            pgtype.Float8
+	IntensityFactor pgtype.Float8
+}
+
+func (q *Queries) ListWorkoutLoadsSince(ctx context.Context, arg ListWorkoutLoadsSinceParams) ([]ListWorkoutLoadsSinceRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutLoadsSince, arg.AthleteID, arg.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutLoadsSinceRow
+	for rows.Next() {
+		var i ListWorkoutLoadsSinceRow
+		if err := rows.Scan(
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.AvgHr,
+			&i.Load,
+			&i.IntensityFactor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutsAwaitingFeedback = `-- name: ListWorkoutsAwaitingFeedback :many
+SELECT w.id, w.name, w.sport, w.started_at, w.duration_sec
+FROM workout w
+LEFT JOIN workout_feedback f ON f.workout_id = w.id
+WHERE w.athlete_id = $1 AND w.started_at >= $2 AND f.workout_id IS NULL
+ORDER BY w.started_at DESC
+LIMIT 5
+`
+
+type ListWorkoutsAwaitingFeedbackParams struct {
+	AthleteID uuid.UUID
+	Since     pgtype.Timestamptz
+}
+
+type ListWorkoutsAwaitingFeedbackRow struct {
+	ID          uuid.UUID
+	Name        pgtype.Text
+	Sport       string
+	StartedAt   pgtype.Timestamptz
+	DurationSec int32
+}
+
+// The athlete's recent workouts they haven't rated yet, newest first.
+func (q *Queries) ListWorkoutsAwaitingFeedback(ctx context.Context, arg ListWorkoutsAwaitingFeedbackParams) ([]ListWorkoutsAwaitingFeedbackRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutsAwaitingFeedback, arg.AthleteID, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutsAwaitingFeedbackRow
+	for rows.Next() {
+		var i ListWorkoutsAwaitingFeedbackRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.DurationSec,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutsBetween = `-- name: ListWorkoutsBetween :many
+SELECT id, name, sport, started_at, duration_sec, distance_m, load, review_status
+FROM workout
+WHERE athlete_id = $1 AND started_at >= $2::timestamptz AND started_at < $3::timestamptz
+ORDER BY started_at
+`
+
+type ListWorkoutsBetweenParams struct {
+	AthleteID uuid.UUID
+	FromTime  pgtype.Timestamptz
+	ToTime    pgtype.Timestamptz
+}
+
+type ListWorkoutsBetweenRow struct {
+	ID           uuid.UUID
+	Name         pgtype.Text
+	Sport        string
+	StartedAt    pgtype.Timestamptz
+	DurationSec  int32
+	DistanceM    pgtype.Float8
+	Load         pgtype.Float8
+	ReviewStatus string
+}
+
+func (q *Queries) ListWorkoutsBetween(ctx context.Context, arg ListWorkoutsBetweenParams) ([]ListWorkoutsBetweenRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutsBetween, arg.AthleteID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutsBetweenRow
+	for rows.Next() {
+		var i ListWorkoutsBetweenRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.Load,
+			&i.ReviewStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutsByAthlete = `-- name: ListWorkoutsByAthlete :many
+SELECT w.id, w.athlete_id, w.source, w.source_id, w.name, w.sport, w.started_at,
+       w.duration_sec, w.distance_m, w.elev_gain_m, w.avg_hr, w.created_at, w.updated_at,
+       w.load, m.gap_speed, m.normalized_power, m.variability_index, m.intensity_factor,
+       w.review_status, w.review_flags, c.status AS commentary_status
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+LEFT JOIN workout_commentary c ON c.workout_id = w.id
+WHERE w.athlete_id = $1
+ORDER BY w.started_at DESC
+LIMIT $2
+`
+
+type ListWorkoutsByAthleteParams struct {
+	AthleteID uuid.UUID
+	Limit     int32
+}
+
+type ListWorkoutsByAthleteRow struct {
+	ID               uuid.UUID
+	AthleteID        uuid.UUID
+	Source           string
+	SourceID         int64
+	Name             pgtype.Text
+	Sport            string
+	StartedAt        pgtype.Timestamptz
+	DurationSec      int32
+	DistanceM        pgtype.Float8
+	ElevGainM        pgtype.Float8
+	AvgHr            pgtype.Int4
+	CreatedAt        pgtype.Timestamptz
+	UpdatedAt        pgtype.Timestamptz
+	Load             pgtype.Float8
+	GapSpeed         pgtype.Float8
+	NormalizedPower  pgtype.Float8
+	VariabilityIndex pgtype.Float8
+	IntensityFactor  pgtype.Float8
+	ReviewStatus     string
+	ReviewFlags      []string
+	CommentaryStatus pgtype.Text
+}
+
+func (q *Queries) ListWorkoutsByAthlete(ctx context.Context, arg ListWorkoutsByAthleteParams) ([]ListWorkoutsByAthleteRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutsByAthlete, arg.AthleteID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutsByAthleteRow
+	for rows.Next() {
+		var i ListWorkoutsByAthleteRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AthleteID,
+			&i.Source,
+			&i.SourceID,
+			&i.Name,
+			&i.Sport,
+			&i.StartedAt,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.ElevGainM,
+			&i.AvgHr,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Load,
+			&i.GapSpeed,
+			&i.NormalizedPower,
+			&i.VariabilityIndex,
+			&i.IntensityFactor,
+			&i.ReviewStatus,
+			&i.ReviewFlags,
+			&i.CommentaryStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkoutsForMatching = `-- name: ListWorkoutsForMatching :many
+SELECT w.id, w.sport, w.duration_sec, w.distance_m, w.load, m.zone_sec
+FROM workout w
+LEFT JOIN workout_metrics m ON m.workout_id = w.id
+WHERE w.athlete_id = $1
+  AND w.started_at >= $2::timestamptz AND w.started_at < $3::timestamptz
+  AND w.review_status IN ('ok', 'accepted')
+ORDER BY w.started_at
+`
+
+type ListWorkoutsForMatchingParams struct {
+	AthleteID uuid.UUID
+	FromTime  pgtype.Timestamptz
+	ToTime    pgtype.Timestamptz
+}
+
+type ListWorkoutsForMatchingRow struct {
+	ID          uuid.UUID
+	Sport       string
+	DurationSec int32
+	DistanceM   pgtype.Float8
+	Load        pgtype.Float8
+	ZoneSec     []int32
+}
+
+func (q *Queries) ListWorkoutsForMatching(ctx context.Context, arg ListWorkoutsForMatchingParams) ([]ListWorkoutsForMatchingRow, error) {
+	rows, err := q.db.Query(ctx, listWorkoutsForMatching, arg.AthleteID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkoutsForMatchingRow
+	for rows.Next() {
+		var i ListWorkoutsForMatchingRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Sport,
+			&i.DurationSec,
+			&i.DistanceM,
+			&i.Load,
+			&i.ZoneSec,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const logChatToolCall = `-- name: LogChatToolCall :exec
+INSERT INTO chat_tool_call (conversation_id, coach_id, athlete_id, tool, arguments, result, error, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`
+
+type LogChatToolCallParams struct {
+	ConversationID uuid.UUID
+	CoachID        uuid.UUID
+	AthleteID      uuid.UUID
+	Tool           string
+	Arguments      string
+	Result         string
+	Error          pgtype.Text
+	DurationMs     int32
+}
+
+func (q *Queries) LogChatToolCall(ctx context.Context, arg LogChatToolCallParams) error {
+	_, err := q.db.Exec(ctx, logChatToolCall,
+		arg.ConversationID,
+		arg.CoachID,
+		arg.AthleteID,
+		arg.Tool,
+		arg.Arguments,
+		arg.Result,
+		arg.Error,
+		arg.DurationMs,
+	)
+	return err
+}
+
+const markOutboxEmailAttempt = `-- name: MarkOutboxEmailAttempt :exec
+UPDATE email_outbox
+SET status = $1, attempts = attempts + 1, last_error = $2, updated_at = now()
+WHERE id = $3
+`
+
+type MarkOutboxEmailAttemptParams struct {
+	Status    string
+	LastError pgtype.Text
+	ID        uuid.UUID
+}
+
+// Records a failed send. status stays pending while retries remain.
+func (q *Queries) MarkOutboxEmailAttempt(ctx context.Context, arg MarkOutboxEmailAttemptParams) error {
+	_, err := q.db.Exec(ctx, markOutboxEmailAttempt, arg.Status, arg.LastError, arg.ID)
+	return err
+}
+
+const markOutboxEmailSent = `-- name: MarkOutboxEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = now(), updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEmailSent(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markOutboxEmailSent, id)
+	return err
+}
+
+const markPlanOperationUndone = `-- name: MarkPlanOperationUndone :exec
+UPDATE plan_operation
+SET undone_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkPlanOperationUndone(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markPlanOperationUndone, id)
+	return err
+}
+
+const markWeeklyReportEmailed = `-- name: MarkWeeklyReportEmailed :exec
+UPDATE weekly_report
+SET emailed_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) MarkWeeklyReportEmailed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, markWeeklyReportEmailed, id)
+	return err
+}
+
+const publishPlanDraft = `-- name: PublishPlanDraft :execrows
+UPDATE plan_draft
+SET status = 'published', published_at = now()
+WHERE id = $1 AND coach_id = $2 AND status = 'ready'
+`
+
+type PublishPlanDraftParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) PublishPlanDraft(ctx context.Context, arg PublishPlanDraftParams) (int64, error) {
+	result, err := q.db.Exec(ctx, publishPlanDraft, arg.ID, arg.CoachID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordLLMUsage = `-- name: RecordLLMUsage :exec
+INSERT INTO llm_usage (coach_id, feature, model, prompt_tokens, completion_tokens, estimated)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type RecordLLMUsageParams struct {
+	CoachID          uuid.UUID
+	Feature          string
+	Model            string
+	PromptTokens     int32
+	CompletionTokens int32
+	Estimated        bool
+}
+
+func (q *Queries) RecordLLMUsage(ctx context.Context, arg RecordLLMUsageParams) error {
+	_, err := q.db.Exec(ctx, recordLLMUsage,
+		arg.CoachID,
+		arg.Feature,
+		arg.Model,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Estimated,
+	)
+	return err
+}
+
+const releaseChatReply = `-- name: ReleaseChatReply :exec
+UPDATE chat_conversation
+SET replying_at = NULL, updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) ReleaseChatReply(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, releaseChatReply, id)
+	return err
 }
 
-const listWorkoutsByAthlete = `-- name: ListWorkoutsByAthlete :many
-SELECT id, athlete_id, source, source_id, name, sport, started_at, 
-       duration_sec, distance_m, elev_gain_m, avg_hr, created_at, updated_at
-FROM workout 
-WHERE athlete_id = $1 
-ORDER BY started_at DESC 
-LIMIT $2
+const resetWorkoutLoad = `-- name: ResetWorkoutLoad :exec
+UPDATE workout
+SET load = $1, load_source = 'hr'
+WHERE id = $2 AND load_source = 'stream'
 `
 
-type ListWorkoutsByAthleteParams struct {
-	AthleteID uuid.UUID
-	Limit     int32
+type ResetWorkoutLoadParams struct {
+	Load pgtype.Float8
+	ID   uuid.UUID
 }
 
-type ListWorkoutsByAthleteRow struct {
-	ID          uuid.UUID
-	AthleteID   uuid.UUID
-	Source      string
-	SourceID    int64
-	Name        pgtype.Text
-	Sport       string
-	StartedAt   pgtype.Timestamptz
-	DurationSec int32
-	DistanceM   pgtype.Float8
-	ElevGainM   pgtype.Float8
-	AvgHr       pgtype.Int4
-	CreatedAt   pgtype.Timestamptz
-	UpdatedAt   pgtype.Timestamptz
+// Puts back the heart-rate estimate on a workout whose streams no longer
+// give a load, say after its thresholds were cleared.
+func (q *Queries) ResetWorkoutLoad(ctx context.Context, arg ResetWorkoutLoadParams) error {
+	_, err := q.db.Exec(ctx, resetWorkoutLoad, arg.Load, arg.ID)
+	return err
 }
 
-func (q *Queries) ListWorkoutsByAthlete(ctx context.Context, arg ListWorkoutsByAthleteParams) ([]ListWorkoutsByAthleteRow, error) {
-	rows, err := q.db.Query(ctx, listWorkoutsByAthlete, arg.AthleteID, arg.Limit)
+const restorePlannedWorkout = `-- name: RestorePlannedWorkout :execrows
+UPDATE planned_workout
+SET day = $3, sport = $4, title = $5, description = $6,
+    target_duration_sec = $7, target_distance_m = $8, target_load = $9,
+    target_zone = $10, structure = $11, template_session_id = $12,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    updated_at = now()
+WHERE id = $1 AND athlete_id = $2
+`
+
+type RestorePlannedWorkoutParams struct {
+	ID                uuid.UUID
+	AthleteID         uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+	TemplateSessionID pgtype.UUID
+}
+
+// Puts back a session as an undone operation found it, template link
+// included. The caller re-runs matching for the affected days.
+func (q *Queries) RestorePlannedWorkout(ctx context.Context, arg RestorePlannedWorkoutParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restorePlannedWorkout,
+		arg.ID,
+		arg.AthleteID,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+		arg.TemplateSessionID,
+	)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	var items []ListWorkoutsByAthleteRow
-	for rows.Next() {
-		var i ListWorkoutsByAthleteRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.AthleteID,
-			&i.Source,
-			&i.SourceID,
-			&i.Name,
-			&i.Sport,
-			&i.StartedAt,
-			&i.DurationSec,
-			&i.DistanceM,
-			&i.ElevGainM,
-			&i.AvgHr,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
+	return result.RowsAffected(), nil
+}
+
+const retryPlanDraft = `-- name: RetryPlanDraft :execrows
+UPDATE plan_draft
+SET status = 'generating', error = NULL
+WHERE id = $1 AND coach_id = $2 AND status = 'failed'
+`
+
+type RetryPlanDraftParams struct {
+	ID      uuid.UUID
+	CoachID uuid.UUID
+}
+
+func (q *Queries) RetryPlanDraft(ctx context.Context, arg RetryPlanDraftParams) (int64, error) {
+	result, err := q.db.Exec(ctx, retryPlanDraft, arg.ID, arg.CoachID)
+	if err != nil {
+		return 0, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result.RowsAffected(), nil
+}
+
+const reviewWorkout = `-- name: ReviewWorkout :one
+UPDATE workout
+SET review_status = $3, updated_at = now()
+WHERE id = $1 AND athlete_id = $2
+RETURNING started_at
+`
+
+type ReviewWorkoutParams struct {
+	ID           uuid.UUID
+	AthleteID    uuid.UUID
+	ReviewStatus string
+}
+
+func (q *Queries) ReviewWorkout(ctx context.Context, arg ReviewWorkoutParams) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, reviewWorkout, arg.ID, arg.AthleteID, arg.ReviewStatus)
+	var startedAt pgtype.Timestamptz
+	err := row.Scan(&startedAt)
+	return startedAt, err
+}
+
+const reviewWorkoutCommentary = `-- name: ReviewWorkoutCommentary :execrows
+UPDATE workout_commentary
+SET body = $2, status = $3, reviewed_at = now()
+WHERE workout_id = $1
+`
+
+type ReviewWorkoutCommentaryParams struct {
+	WorkoutID uuid.UUID
+	Body      string
+	Status    string
+}
+
+func (q *Queries) ReviewWorkoutCommentary(ctx context.Context, arg ReviewWorkoutCommentaryParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reviewWorkoutCommentary, arg.WorkoutID, arg.Body, arg.Status)
+	if err != nil {
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
 const setAthleteStravaTokens = `-- name: SetAthleteStravaTokens :exec
@@ -242,6 +4006,140 @@ func (q *Queries) SetAthleteStravaTokens(ctx context.Context, arg SetAthleteStra
 	return err
 }
 
+const setPlanDraftError = `-- name: SetPlanDraftError :exec
+UPDATE plan_draft
+SET status = $2, error = $3
+WHERE id = $1
+`
+
+type SetPlanDraftErrorParams struct {
+	ID     uuid.UUID
+	Status string
+	Error  pgtype.Text
+}
+
+// Records why generation failed. status stays generating while the job
+// will be retried.
+func (q *Queries) SetPlanDraftError(ctx context.Context, arg SetPlanDraftErrorParams) error {
+	_, err := q.db.Exec(ctx, setPlanDraftError, arg.ID, arg.Status, arg.Error)
+	return err
+}
+
+const setPlanDraftReady = `-- name: SetPlanDraftReady :exec
+UPDATE plan_draft
+SET status = 'ready', template_id = $2, warnings = $3, model = $4, prompt_version = $5, error = NULL
+WHERE id = $1
+`
+
+type SetPlanDraftReadyParams struct {
+	ID            uuid.UUID
+	TemplateID    pgtype.UUID
+	Warnings      []string
+	Model         string
+	PromptVersion string
+}
+
+func (q *Queries) SetPlanDraftReady(ctx context.Context, arg SetPlanDraftReadyParams) error {
+	_, err := q.db.Exec(ctx, setPlanDraftReady,
+		arg.ID,
+		arg.TemplateID,
+		arg.Warnings,
+		arg.Model,
+		arg.PromptVersion,
+	)
+	return err
+}
+
+const setPlannedWorkoutMatch = `-- name: SetPlannedWorkoutMatch :exec
+UPDATE planned_workout
+SET workout_id = $2, status = $3, compliance = $4
+WHERE id = $1
+`
+
+type SetPlannedWorkoutMatchParams struct {
+	ID         uuid.UUID
+	WorkoutID  pgtype.UUID
+	Status     string
+	Compliance pgtype.Float8
+}
+
+func (q *Queries) SetPlannedWorkoutMatch(ctx context.Context, arg SetPlannedWorkoutMatchParams) error {
+	_, err := q.db.Exec(ctx, setPlannedWorkoutMatch,
+		arg.ID,
+		arg.WorkoutID,
+		arg.Status,
+		arg.Compliance,
+	)
+	return err
+}
+
+const setWorkoutReviewFlags = `-- name: SetWorkoutReviewFlags :exec
+UPDATE workout
+SET review_flags = $1::text[],
+    review_status = CASE WHEN cardinality($1::text[]) > 0 THEN 'flagged' ELSE 'ok' END
+WHERE id = $2 AND review_status IN ('ok', 'flagged')
+`
+
+type SetWorkoutReviewFlagsParams struct {
+	Flags []string
+	ID    uuid.UUID
+}
+
+// Coach decisions (accepted/rejected) are never overwritten by a re-sync.
+func (q *Queries) SetWorkoutReviewFlags(ctx context.Context, arg SetWorkoutReviewFlagsParams) error {
+	_, err := q.db.Exec(ctx, setWorkoutReviewFlags, arg.Flags, arg.ID)
+	return err
+}
+
+const sumCoachLLMTokensSince = `-- name: SumCoachLLMTokensSince :one
+SELECT COALESCE(SUM(prompt_tokens + completion_tokens), 0)::bigint AS tokens
+FROM llm_usage
+WHERE coach_id = $1 AND created_at >= $2
+`
+
+type SumCoachLLMTokensSinceParams struct {
+	CoachID   uuid.UUID
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) SumCoachLLMTokensSince(ctx context.Context, arg SumCoachLLMTokensSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, sumCoachLLMTokensSince, arg.CoachID, arg.CreatedAt)
+	var tokens int64
+	err := row.Scan(&tokens)
+	return tokens, err
+}
+
+const suppressEmail = `-- name: SuppressEmail :exec
+INSERT INTO email_suppression (address, kind)
+VALUES (lower($1), $2)
+ON CONFLICT (address, kind) DO NOTHING
+`
+
+type SuppressEmailParams struct {
+	Address string
+	Kind    string
+}
+
+func (q *Queries) SuppressEmail(ctx context.Context, arg SuppressEmailParams) error {
+	_, err := q.db.Exec(ctx, suppressEmail, arg.Address, arg.Kind)
+	return err
+}
+
+const unsuppressEmail = `-- name: UnsuppressEmail :exec
+DELETE FROM email_suppression
+WHERE address = lower($1) AND kind = $2
+`
+
+type UnsuppressEmailParams struct {
+	Address string
+	Kind    string
+}
+
+func (q *Queries) UnsuppressEmail(ctx context.Context, arg UnsuppressEmailParams) error {
+	_, err := q.db.Exec(ctx, unsuppressEmail, arg.Address, arg.Kind)
+	return err
+}
+
 const updateAthleteLastStravaSync = `-- name: UpdateAthleteLastStravaSync :exec
 UPDATE athlete
 SET last_strava_sync = $2
@@ -258,6 +4156,24 @@ func (q *Queries) UpdateAthleteLastStravaSync(ctx context.Context, arg UpdateAth
 	return err
 }
 
+const updateAthleteProfile = `-- name: UpdateAthleteProfile :exec
+UPDATE athlete
+SET name = $2,
+    tz = $3
+WHERE id = $1
+`
+
+type UpdateAthleteProfileParams struct {
+	ID   uuid.UUID
+	Name string
+	Tz   string
+}
+
+func (q *Queries) UpdateAthleteProfile(ctx context.Context, arg UpdateAthleteProfileParams) error {
+	_, err := q.db.Exec(ctx, updateAthleteProfile, arg.ID, arg.Name, arg.Tz)
+	return err
+}
+
 const updateAthleteStravaTokens = `-- name: UpdateAthleteStravaTokens :exec
 UPDATE athlete
 SET strava_access_token = $2,
@@ -283,6 +4199,237 @@ func (q *Queries) UpdateAthleteStravaTokens(ctx context.Context, arg UpdateAthle
 	return err
 }
 
+const updateAthleteThresholds = `-- name: UpdateAthleteThresholds :exec
+UPDATE athlete
+SET max_hr = $2,
+    resting_hr = $3,
+    ftp_watts = $4,
+    threshold_pace_sec = $5
+WHERE id = $1
+`
+
+type UpdateAthleteThresholdsParams struct {
+	ID               uuid.UUID
+	MaxHr            pgtype.Int4
+	RestingHr        pgtype.Int4
+	FtpWatts         pgtype.Int4
+	ThresholdPaceSec pgtype.Int4
+}
+
+func (q *Queries) UpdateAthleteThresholds(ctx context.Context, arg UpdateAthleteThresholdsParams) error {
+	_, err := q.db.Exec(ctx, updateAthleteThresholds,
+		arg.ID,
+		arg.MaxHr,
+		arg.RestingHr,
+		arg.FtpWatts,
+		arg.ThresholdPaceSec,
+	)
+	return err
+}
+
+const updatePlanTemplate = `-- name: UpdatePlanTemplate :execrows
+UPDATE plan_template
+SET name = $3, description = $4, weeks = $5, updated_at = now()
+WHERE id = $1 AND coach_id = $2
+`
+
+type UpdatePlanTemplateParams struct {
+	ID          uuid.UUID
+	CoachID     uuid.UUID
+	Name        string
+	Description pgtype.Text
+	Weeks       int32
+}
+
+func (q *Queries) UpdatePlanTemplate(ctx context.Context, arg UpdatePlanTemplateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePlanTemplate,
+		arg.ID,
+		arg.CoachID,
+		arg.Name,
+		arg.Description,
+		arg.Weeks,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePlanTemplateSession = `-- name: UpdatePlanTemplateSession :execrows
+UPDATE plan_template_session
+SET week = $3, day = $4, sport = $5, title = $6, description = $7,
+    target_duration_sec = $8, target_distance_m = $9, target_load = $10,
+    target_zone = $11, structure = $12, updated_at = now()
+WHERE id = $1 AND template_id = $2
+`
+
+type UpdatePlanTemplateSessionParams struct {
+	ID                uuid.UUID
+	TemplateID        uuid.UUID
+	Week              int32
+	Day               int32
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+}
+
+func (q *Queries) UpdatePlanTemplateSession(ctx context.Context, arg UpdatePlanTemplateSessionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePlanTemplateSession,
+		arg.ID,
+		arg.TemplateID,
+		arg.Week,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePlannedWorkout = `-- name: UpdatePlannedWorkout :execrows
+UPDATE planned_workout
+SET day = $3, sport = $4, title = $5, description = $6,
+    target_duration_sec = $7, target_distance_m = $8, target_load = $9,
+    target_zone = $10, structure = $11,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    template_session_id = NULL,
+    updated_at = now()
+WHERE id = $1 AND athlete_id = $2
+`
+
+type UpdatePlannedWorkoutParams struct {
+	ID                uuid.UUID
+	AthleteID         uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+}
+
+// Clears the match; the caller re-runs matching for the affected days. A
+// hand edit also detaches the session from its template session so pushing
+// template changes won't overwrite it.
+func (q *Queries) UpdatePlannedWorkout(ctx context.Context, arg UpdatePlannedWorkoutParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updatePlannedWorkout,
+		arg.ID,
+		arg.AthleteID,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updatePlannedWorkoutFromTemplate = `-- name: UpdatePlannedWorkoutFromTemplate :exec
+UPDATE planned_workout
+SET day = $2, sport = $3, title = $4, description = $5,
+    target_duration_sec = $6, target_distance_m = $7, target_load = $8,
+    target_zone = $9, structure = $10,
+    workout_id = NULL, status = 'planned', compliance = NULL,
+    updated_at = now()
+WHERE id = $1
+`
+
+type UpdatePlannedWorkoutFromTemplateParams struct {
+	ID                uuid.UUID
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+}
+
+func (q *Queries) UpdatePlannedWorkoutFromTemplate(ctx context.Context, arg UpdatePlannedWorkoutFromTemplateParams) error {
+	_, err := q.db.Exec(ctx, updatePlannedWorkoutFromTemplate,
+		arg.ID,
+		arg.Day,
+		arg.Sport,
+		arg.Title,
+		arg.Description,
+		arg.TargetDurationSec,
+		arg.TargetDistanceM,
+		arg.TargetLoad,
+		arg.TargetZone,
+		arg.Structure,
+	)
+	return err
+}
+
+const updateWorkoutLoad = `-- name: UpdateWorkoutLoad :exec
+UPDATE workout
+SET load = $2, load_source = 'stream'
+WHERE id = $1
+`
+
+type UpdateWorkoutLoadParams struct {
+	ID   uuid.UUID
+	Load pgtype.Float8
+}
+
+// Sets the load from the workout's streams.
+func (q *Queries) UpdateWorkoutLoad(ctx context.Context, arg UpdateWorkoutLoadParams) error {
+	_, err := q.db.Exec(ctx, updateWorkoutLoad, arg.ID, arg.Load)
+	return err
+}
+
+const upsertAthleteCalendarFeed = `-- name: UpsertAthleteCalendarFeed :one
+INSERT INTO calendar_feed (token, coach_id, athlete_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (athlete_id) WHERE athlete_id IS NOT NULL
+DO UPDATE SET token = EXCLUDED.token, created_at = now()
+RETURNING token, coach_id, athlete_id, created_at
+`
+
+type UpsertAthleteCalendarFeedParams struct {
+	Token     string
+	CoachID   uuid.UUID
+	AthleteID pgtype.UUID
+}
+
+// A new token replaces the old one, so the previous URL stops working.
+func (q *Queries) UpsertAthleteCalendarFeed(ctx context.Context, arg UpsertAthleteCalendarFeedParams) (CalendarFeed, error) {
+	row := q.db.QueryRow(ctx, upsertAthleteCalendarFeed, arg.Token, arg.CoachID, arg.AthleteID)
+	var i CalendarFeed
+	err := row.Scan(
+		&i.Token,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const upsertCoachByEmail = `-- name: UpsertCoachByEmail :one
 INSERT INTO coach (email, name, tz)
 VALUES ($1, $2, $3)
@@ -310,15 +4457,84 @@ func (q *Queries) UpsertCoachByEmail(ctx context.Context, arg UpsertCoachByEmail
 	return i, err
 }
 
-const upsertWorkout = `-- name: UpsertWorkout :exec
+const upsertCoachCalendarFeed = `-- name: UpsertCoachCalendarFeed :one
+INSERT INTO calendar_feed (token, coach_id)
+VALUES ($1, $2)
+ON CONFLICT (coach_id) WHERE athlete_id IS NULL
+DO UPDATE SET token = EXCLUDED.token, created_at = now()
+RETURNING token, coach_id, athlete_id, created_at
+`
+
+type UpsertCoachCalendarFeedParams struct {
+	Token   string
+	CoachID uuid.UUID
+}
+
+func (q *Queries) UpsertCoachCalendarFeed(ctx context.Context, arg UpsertCoachCalendarFeedParams) (CalendarFeed, error) {
+	row := q.db.QueryRow(ctx, upsertCoachCalendarFeed, arg.Token, arg.CoachID)
+	var i CalendarFeed
+	err := row.Scan(
+		&i.Token,
+		&i.CoachID,
+		&i.AthleteID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertWellnessCheckin = `-- name: UpsertWellnessCheckin :exec
+INSERT INTO wellness_checkin (athlete_id, day, sleep_hours, sleep_quality, soreness, stress, mood, resting_hr, notes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (athlete_id, day) DO UPDATE
+SET sleep_hours = EXCLUDED.sleep_hours,
+    sleep_quality = EXCLUDED.sleep_quality,
+    soreness = EXCLUDED.soreness,
+    stress = EXCLUDED.stress,
+    mood = EXCLUDED.mood,
+    resting_hr = EXCLUDED.resting_hr,
+    notes = EXCLUDED.notes,
+    updated_at = now()
+`
+
+type UpsertWellnessCheckinParams struct {
+	AthleteID    uuid.UUID
+	Day          pgtype.Date
+	SleepHours   pgtype.Float8
+	SleepQuality pgtype.Int4
+	Soreness     pgtype.Int4
+	Stress       pgtype.Int4
+	Mood         pgtype.Int4
+	RestingHr    pgtype.Int4
+	Notes        pgtype.Text
+}
+
+// Saves the day's check-in, replacing an earlier one for the same day.
+func (q *Queries) UpsertWellnessCheckin(ctx context.Context, arg UpsertWellnessCheckinParams) error {
+	_, err := q.db.Exec(ctx, upsertWellnessCheckin,
+		arg.AthleteID,
+		arg.Day,
+		arg.SleepHours,
+		arg.SleepQuality,
+		arg.Soreness,
+		arg.Stress,
+		arg.Mood,
+		arg.RestingHr,
+		arg.Notes,
+	)
+	return err
+}
+
+const upsertWorkout = `-- name: UpsertWorkout :one
 INSERT INTO workout (
     athlete_id, source, source_id, name, sport, started_at,
-    duration_sec, distance_m, elev_gain_m, avg_hr, raw_json
-) VALUES ($1, 'strava', $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    duration_sec, distance_m, elev_gain_m, avg_hr, raw_json, load
+) VALUES ($1, 'strava', $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     ON CONFLICT (athlete_id, source, source_id) DO UPDATE
 SET name = $3, sport=$4, started_at=$5,
     duration_sec=$6, distance_m=$7, elev_gain_m=$8, avg_hr=$9,
-    raw_json=$10, updated_at=now()
+    raw_json=$10, updated_at=now(),
+    load = CASE WHEN workout.load_source = 'stream' THEN workout.load ELSE $11 END
+RETURNING id
 `
 
 type UpsertWorkoutParams struct {
@@ -332,10 +4548,12 @@ type UpsertWorkoutParams struct {
 	ElevGainM   pgtype.Float8
 	AvgHr       pgtype.Int4
 	RawJson     []byte
+	Load        pgtype.Float8
 }
 
-func (q *Queries) UpsertWorkout(ctx context.Context, arg UpsertWorkoutParams) error {
-	_, err := q.db.Exec(ctx, upsertWorkout,
+// load is the heart-rate estimate; it doesn't replace a load from streams.
+func (q *Queries) UpsertWorkout(ctx context.Context, arg UpsertWorkoutParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, upsertWorkout,
 		arg.AthleteID,
 		arg.SourceID,
 		arg.Name,
@@ -346,6 +4564,79 @@ func (q *Queries) UpsertWorkout(ctx context.Context, arg UpsertWorkoutParams) er
 		arg.ElevGainM,
 		arg.AvgHr,
 		arg.RawJson,
+		arg.Load,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const upsertWorkoutFeedback = `-- name: UpsertWorkoutFeedback :exec
+INSERT INTO workout_feedback (workout_id, athlete_id, rpe, notes, pain)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (workout_id) DO UPDATE
+SET rpe = EXCLUDED.rpe, notes = EXCLUDED.notes, pain = EXCLUDED.pain, updated_at = now()
+`
+
+type UpsertWorkoutFeedbackParams struct {
+	WorkoutID uuid.UUID
+	AthleteID uuid.UUID
+	Rpe       int32
+	Notes     pgtype.Text
+	Pain      []string
+}
+
+func (q *Queries) UpsertWorkoutFeedback(ctx context.Context, arg UpsertWorkoutFeedbackParams) error {
+	_, err := q.db.Exec(ctx, upsertWorkoutFeedback,
+		arg.WorkoutID,
+		arg.AthleteID,
+		arg.Rpe,
+		arg.Notes,
+		arg.Pain,
+	)
+	return err
+}
+
+const upsertWorkoutMetrics = `-- name: UpsertWorkoutMetrics :exec
+INSERT INTO workout_metrics (
+    workout_id, moving_sec, decoupling_basis, decoupling_pct, efficiency_factor,
+    gap_speed, avg_power, normalized_power, variability_index, intensity_factor,
+    zone_sec
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    ON CONFLICT (workout_id) DO UPDATE
+SET moving_sec = $2, decoupling_basis = $3, decoupling_pct = $4,
+    efficiency_factor = $5, gap_speed = $6, avg_power = $7,
+    normalized_power = $8, variability_index = $9, intensity_factor = $10,
+    zone_sec = $11, computed_at = now()
+`
+
+type UpsertWorkoutMetricsParams struct {
+	WorkoutID        uuid.UUID
+	MovingSec        int32
+	DecouplingBasis  pgtype.Text
+	DecouplingPct    pgtype.Float8
+	EfficiencyFactor pgtype.Float8
+	GapSpeed         pgtype.Float8
+	AvgPower         pgtype.Float8
+	NormalizedPower  pgtype.Float8
+	VariabilityIndex pgtype.Float8
+	IntensityFactor  pgtype.Float8
+	ZoneSec          []int32
+}
+
+func (q *Queries) UpsertWorkoutMetrics(ctx context.Context, arg UpsertWorkoutMetricsParams) error {
+	_, err := q.db.Exec(ctx, upsertWorkoutMetrics,
+		arg.WorkoutID,
+		arg.MovingSec,
+		arg.DecouplingBasis,
+		arg.DecouplingPct,
+		arg.EfficiencyFactor,
+		arg.GapSpeed,
+		arg.AvgPower,
+		arg.NormalizedPower,
+		arg.VariabilityIndex,
+		arg.IntensityFactor,
+		arg.ZoneSec,
 	)
 	return err
 }
@@ -0,0 +1,84 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpTimeout bounds each request to the email API.
+const httpTimeout = 30 * time.Second
+
+// HTTPSender sends emails through a transactional email service's HTTP
+// API rather than SMTP. It POSTs each message as JSON, in the shape most
+// such APIs take (Resend's, for one), with APIKey as a bearer token. The
+// service builds the MIME message, so headers Build would add go in
+// "headers".
+type HTTPSender struct {
+	URL    string // the API's send endpoint
+	APIKey string
+	From   string
+	Client *http.Client // nil uses http.DefaultClient
+}
+
+type wireEmail struct {
+	From    string            `json:"from"`
+	To      []string          `json:"to"`
+	ReplyTo string            `json:"reply_to,omitempty"`
+	Subject string            `json:"subject"`
+	HTML    string            `json:"html"`
+	Text    string            `json:"text,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (h *HTTPSender) Send(to, subject, html string) error {
+	return h.SendMessage(Message{To: to, Subject: subject, HTML: html})
+}
+
+func (h *HTTPSender) SendMessage(m Message) error {
+	if m.To == "" {
+		return fmt.Errorf("recipient empty")
+	}
+	e := wireEmail{From: h.From, To: []string{m.To}, ReplyTo: m.ReplyTo, Subject: m.Subject, HTML: m.HTML, Text: m.Text}
+	if m.Unsubscribe != "" {
+		e.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + m.Unsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("email api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("email api: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // so the connection can be reused
+	return nil
+}
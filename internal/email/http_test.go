@@ -0,0 +1,81 @@
+package email
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/briangreenhill/coachgpt/internal/config"
+)
+
+func TestHTTPSender(t *testing.T) {
+	var got wireEmail
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer srv.Close()
+
+	s := &HTTPSender{URL: srv.URL, APIKey: "key", From: "CoachGPT <no-reply@coachgpt.local>"}
+	err := s.SendMessage(Message{
+		To: "ana@example.com", ReplyTo: "reply+t@example.com", Subject: "Hi",
+		HTML: "<p>Hi</p>", Text: "Hi", Unsubscribe: "https://example.com/unsubscribe/checkin?token=t",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer key" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+	if got.From != s.From || len(got.To) != 1 || got.To[0] != "ana@example.com" || got.ReplyTo != "reply+t@example.com" ||
+		got.Subject != "Hi" || got.HTML != "<p>Hi</p>" || got.Text != "Hi" {
+		t.Fatalf("sent %+v", got)
+	}
+	if got.Headers["List-Unsubscribe"] != "<https://example.com/unsubscribe/checkin?token=t>" {
+		t.Fatalf("headers = %v", got.Headers)
+	}
+}
+
+func TestHTTPSenderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"domain not verified"}`, http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	err := (&HTTPSender{URL: srv.URL, From: "a@example.com"}).Send("b@example.com", "Hi", "x")
+	if err == nil || !strings.Contains(err.Error(), "422") || !strings.Contains(err.Error(), "domain not verified") {
+		t.Fatalf("err = %v", err)
+	}
+	if err := (&HTTPSender{URL: srv.URL}).Send("", "Hi", "x"); err == nil || strings.Contains(err.Error(), "422") {
+		t.Fatalf("posted an email with no recipient: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	s, err := New(config.EmailConfig{Provider: ProviderSMTP, From: "f@example.com", SMTPHost: "smtp.example.com", SMTPPort: 587, SMTPTLS: TLSStartTLS, SMTPUsername: "u", SMTPPassword: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if smtp := s.(*SMTPSender); smtp.Addr != "smtp.example.com:587" || smtp.TLS != TLSStartTLS || smtp.Username != "u" || smtp.From != "f@example.com" {
+		t.Fatalf("SMTP sender = %+v", smtp)
+	}
+	if s, err := New(config.EmailConfig{Provider: ProviderHTTP, APIURL: "https://api.example.com/emails", APIKey: "k"}); err != nil || s.(*HTTPSender).APIKey != "k" {
+		t.Fatalf("HTTP sender = %+v, %v", s, err)
+	}
+	for _, cfg := range []config.EmailConfig{
+		{Provider: "pigeon"},
+		{Provider: ProviderSMTP, SMTPTLS: "ssl"},
+		{Provider: ProviderHTTP},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) succeeded", cfg)
+		}
+	}
+}
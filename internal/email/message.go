@@ -0,0 +1,117 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Envelope is what Build adds to a Message that isn't part of it: when it
+// was sent and its unique ID.
+type Envelope struct {
+	From      string
+	Date      time.Time
+	MessageID string // without the angle brackets
+}
+
+// NewEnvelope dates a message now and gives it a random ID in the domain
+// of from.
+func NewEnvelope(from string) Envelope {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return Envelope{From: from, Date: time.Now(), MessageID: hex.EncodeToString(b[:]) + "@" + domain(from)}
+}
+
+func domain(addr string) string {
+	addr = address(addr)
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 && i < len(addr)-1 {
+		return addr[i+1:]
+	}
+	return "localhost"
+}
+
+// Build writes m as an RFC 5322 message. With a Text body it is
+// multipart/alternative, text first so clients that can show HTML prefer
+// it; without one it is a single HTML part. Bodies are quoted-printable,
+// the subject is RFC 2047 encoded when it isn't plain ASCII, and headers
+// come out in a fixed order.
+func Build(m Message, env Envelope) ([]byte, error) {
+	if m.To == "" {
+		return nil, fmt.Errorf("recipient empty")
+	}
+	var b bytes.Buffer
+	header := func(k, v string) {
+		// Values come from users and templates; a newline would start a
+		// header of their choosing.
+		v = strings.NewReplacer("\r", "", "\n", " ").Replace(v)
+		fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+	}
+	header("From", env.From)
+	header("To", m.To)
+	if m.ReplyTo != "" {
+		header("Reply-To", m.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", env.Date.Format(time.RFC1123Z))
+	header("Message-ID", "<"+env.MessageID+">")
+	if m.Unsubscribe != "" {
+		// RFC 8058: mail clients may unsubscribe with a POST, no page visit.
+		header("List-Unsubscribe", "<"+m.Unsubscribe+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	header("MIME-Version", "1.0")
+
+	if m.Text == "" {
+		header("Content-Type", `text/html; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, m.HTML); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&b)
+	sum := sha256.Sum256([]byte(env.MessageID))
+	if err := mw.SetBoundary("=_" + hex.EncodeToString(sum[:12])); err != nil {
+		return nil, err
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+mw.Boundary()+`"`)
+	b.WriteString("\r\n")
+	for _, part := range []struct{ typ, body string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.typ + `; charset="utf-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, s); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,192 @@
+package email
+
+import (
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden messages")
+
+var testEnvelope = Envelope{
+	From:      "CoachGPT <no-reply@coachgpt.local>",
+	Date:      time.Date(2025, 6, 2, 7, 0, 0, 0, time.UTC),
+	MessageID: "0123456789abcdef@coachgpt.local",
+}
+
+// goldenCases render every template, so a template change shows up as a
+// diff of the raw message.
+var goldenCases = []struct {
+	name string
+	data any
+}{
+	{"invite", map[string]any{"URL": "https://coachgpt.example/invite?token=abc.def%3D"}},
+	{"sign_in", map[string]any{"URL": "https://coachgpt.example/auth/callback?token=abc"}},
+	{"athlete_sign_in", map[string]any{"URL": "https://coachgpt.example/athlete/auth/callback?token=abc"}},
+	{"interest", map[string]any{"Email": "runner@example.com"}},
+	{"comment", map[string]any{
+		"Author": "Zoë Müller", "Title": "Tempo <intervals>", "Day": "Mon 2 Jun",
+		"Body": "Legs felt heavy.\nPace was 4:05/km — a bit slow.", "URL": "https://coachgpt.example/threads/tok", "CanReply": true,
+	}},
+	{"session", map[string]any{
+		"Title": "Threshold", "Day": "Tuesday 3 June", "Description": "Keep the recoveries easy.",
+		"Steps":     []string{"Warm-up 15 min", "3 × (10 min @ 4:00/km, Recovery 2 min)", "Cool-down 10 min"},
+		"Downloads": []map[string]string{{"Format": "ZWO", "URL": "https://coachgpt.example/plan/download/zwo?token=t"}},
+	}},
+	{"alert", map[string]any{"Athlete": "Ana", "Alerts": []string{"Acute:chronic load 1.7 is above 1.5"}, "URL": "https://coachgpt.example/dashboard"}},
+	{"weekly_report", map[string]any{
+		"Week": "Jun 2", "Summary": "A solid week for both athletes.", "Highlights": []string{"Ana ran a 10K PR"},
+		"Concerns": []string{}, "URL": "https://coachgpt.example/reports/r",
+	}},
+	{"checkin", map[string]any{
+		"Name": "Ana", "URL": "https://coachgpt.example/athlete/auth/callback?token=t&next=%2Fathlete%2Fcheckin",
+		"Unrated": []string{"Morning Run (Run)"}, "Unsubscribe": "https://coachgpt.example/unsubscribe/checkin?token=u",
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, c := range goldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := Render("Ana Runner <ana@example.com>", c.name, c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.name == "checkin" {
+				m.Unsubscribe = c.data.(map[string]any)["Unsubscribe"].(string)
+			}
+			raw, err := Build(m, testEnvelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", c.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, raw, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test ./internal/email -update to create it)", err)
+			}
+			if string(raw) != string(want) {
+				t.Errorf("message changed; rerun with -update if that's intended\n--- want\n%s\n--- got\n%s", want, raw)
+			}
+		})
+	}
+}
+
+func TestEveryTemplateHasAGolden(t *testing.T) {
+	covered := map[string]bool{}
+	for _, c := range goldenCases {
+		covered[c.name] = true
+	}
+	for name := range templates {
+		if !covered[name] {
+			t.Errorf("template %s has no golden case", name)
+		}
+	}
+}
+
+// TestBuildParses reads a built message back the way a mail client would.
+func TestBuildParses(t *testing.T) {
+	m, err := Render("ana@example.com", "comment", goldenCases[4].data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ReplyTo = "reply+tok@reply.example.com"
+	raw, err := Build(m, testEnvelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Zoë Müller commented on Tempo <intervals> (Mon 2 Jun)" {
+		t.Fatalf("Subject = %q, %v", subject, err)
+	}
+	if got := msg.Header.Get("Message-ID"); got != "<0123456789abcdef@coachgpt.local>" {
+		t.Fatalf("Message-ID = %q", got)
+	}
+	if d, err := msg.Header.Date(); err != nil || !d.Equal(testEnvelope.Date) {
+		t.Fatalf("Date = %v, %v", d, err)
+	}
+	if got := msg.Header.Get("Reply-To"); got != m.ReplyTo {
+		t.Fatalf("Reply-To = %q", got)
+	}
+
+	typ, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || typ != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", typ, err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		p, err := mr.NextPart() // decodes quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(p)
+		typ, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		types = append(types, typ)
+		bodies = append(bodies, string(b))
+	}
+	if strings.Join(types, ",") != "text/plain,text/html" {
+		t.Fatalf("parts = %v", types)
+	}
+	if !strings.Contains(bodies[0], "Pace was 4:05/km — a bit slow.") || !strings.Contains(bodies[0], "Or reply to this email") {
+		t.Fatalf("text part = %q", bodies[0])
+	}
+	if !strings.Contains(bodies[1], `<a href="https://coachgpt.example/threads/tok">`) {
+		t.Fatalf("html part = %q", bodies[1])
+	}
+}
+
+func TestBuildHTMLOnly(t *testing.T) {
+	raw, err := Build(Message{To: "a@example.com", Subject: "Hi", HTML: "<p>Hello</p>"}, testEnvelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Content-Type"); got != `text/html; charset="utf-8"` {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if msg.Header.Get("List-Unsubscribe") != "" {
+		t.Fatal("List-Unsubscribe without an unsubscribe URL")
+	}
+}
+
+func TestBuildHeaderInjection(t *testing.T) {
+	raw, err := Build(Message{To: "a@example.com\r\nBcc: victim@example.com", Subject: "Hi\r\nBcc: x@example.com", HTML: "x"}, testEnvelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Fatalf("injected a header:\n%s", raw)
+	}
+}
+
+func TestRenderUnknown(t *testing.T) {
+	if _, err := Render("a@example.com", "nope", nil); err == nil {
+		t.Fatal("rendered a template that doesn't exist")
+	}
+}
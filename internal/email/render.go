@@ -0,0 +1,68 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Each email is a pair of templates: name.txt defines "subject" and the
+// plain-text "body", name.html the HTML "body". layout.txt and layout.html
+// wrap the bodies.
+//
+//go:embed templates
+var files embed.FS
+
+type pair struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var templates = mustParse()
+
+func mustParse() map[string]pair {
+	names, err := files.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]pair)
+	for _, f := range names {
+		name, ok := strings.CutSuffix(f.Name(), ".txt")
+		if !ok || name == "layout" {
+			continue
+		}
+		out[name] = pair{
+			text: texttemplate.Must(texttemplate.ParseFS(files, "templates/layout.txt", path.Join("templates", name+".txt"))),
+			html: htmltemplate.Must(htmltemplate.ParseFS(files, "templates/layout.html", path.Join("templates", name+".html"))),
+		}
+	}
+	return out
+}
+
+// Render fills in the named email for to.
+func Render(to, name string, data any) (Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("no email template %q", name)
+	}
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -3,63 +3,78 @@ package email
 import (
 	"fmt"
 	"log"
-	"net/smtp"
-	"strings"
+	"net"
+	"net/mail"
+	"strconv"
+
+	"github.com/briangreenhill/coachgpt/internal/config"
 )
 
 type Sender interface {
 	Send(to, subject, html string) error
+	SendMessage(m Message) error
 }
 
-// StdoutSender prints emails to stdout (used in tests/dev)
-type StdoutSender struct{}
-
-func (StdoutSender) Send(to, subject, html string) error {
-	log.Printf("EMAIL to=%s subject=%s\n%s", to, subject, html)
-	return nil
+// Message is an email with the optional headers Send leaves out.
+type Message struct {
+	To          string
+	ReplyTo     string // where replies go instead of From; optional
+	Subject     string
+	HTML        string
+	Text        string // plain-text alternative; optional
+	Unsubscribe string // one-click unsubscribe URL, for List-Unsubscribe; optional
 }
 
-// SMTPSender sends emails over SMTP. MailHog listens on localhost:1025 by default.
-// This is a simple implementation without auth, suitable for local use with MailHog.
-// From should be a valid email address (e.g., "no-reply@coachgpt.local").
-type SMTPSender struct {
-	Addr string // e.g. "localhost:1025"
-	From string
-}
+// Provider names for config.EmailConfig.Provider.
+const (
+	ProviderSMTP   = "smtp"
+	ProviderHTTP   = "http"
+	ProviderStdout = "stdout"
+)
 
-func NewSMTPSender(addr, from string) *SMTPSender {
-	if addr == "" {
-		addr = "localhost:1025"
-	}
-	if from == "" {
-		from = "no-reply@coachgpt.local"
+// New builds the configured sender. The default is SMTP to MailHog on
+// localhost:1025.
+func New(cfg config.EmailConfig) (Sender, error) {
+	switch cfg.Provider {
+	case ProviderSMTP, "":
+		switch cfg.SMTPTLS {
+		case TLSNone, TLSStartTLS, TLSImplicit, "":
+		default:
+			return nil, fmt.Errorf("email: unknown SMTP TLS mode %q", cfg.SMTPTLS)
+		}
+		s := NewSMTPSender(net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)), cfg.From)
+		s.Username = cfg.SMTPUsername
+		s.Password = cfg.SMTPPassword
+		s.TLS = cfg.SMTPTLS
+		return s, nil
+	case ProviderHTTP:
+		if cfg.APIURL == "" {
+			return nil, fmt.Errorf("email: the http provider needs EMAIL_API_URL")
+		}
+		return &HTTPSender{URL: cfg.APIURL, APIKey: cfg.APIKey, From: cfg.From}, nil
+	case ProviderStdout:
+		return StdoutSender{}, nil
 	}
-	return &SMTPSender{Addr: addr, From: from}
+	return nil, fmt.Errorf("email: unknown provider %q", cfg.Provider)
 }
 
-func (s *SMTPSender) Send(to, subject, html string) error {
-	if to == "" {
-		return fmt.Errorf("recipient empty")
-	}
+// StdoutSender prints emails to stdout (used in tests/dev)
+type StdoutSender struct{}
 
-	// Build a minimal RFC 822 message with HTML body
-	header := make(map[string]string)
-	header["From"] = s.From
-	header["To"] = to
-	header["Subject"] = subject
-	header["MIME-Version"] = "1.0"
-	header["Content-Type"] = "text/html; charset=\"utf-8\""
+func (s StdoutSender) Send(to, subject, html string) error {
+	return s.SendMessage(Message{To: to, Subject: subject, HTML: html})
+}
 
-	var msg strings.Builder
-	for k, v := range header {
-		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	msg.WriteString("\r\n")
-	msg.WriteString(html)
+func (StdoutSender) SendMessage(m Message) error {
+	log.Printf("EMAIL to=%s reply-to=%s subject=%s\n%s", m.To, m.ReplyTo, m.Subject, m.HTML)
+	return nil
+}
 
-	// No auth, send directly to MailHog
-	if err := smtp.SendMail(s.Addr, nil, s.From, []string{to}, []byte(msg.String())); err != nil {
-		return fmt.Errorf("smtp send: %w", err)
+// address is the bare address of a header value like "Name <a@b>", for
+// the SMTP envelope.
+func address(v string) string {
+	if a, err := mail.ParseAddress(v); err == nil {
+		return a.Address
 	}
-	return nil
+	return v
 }
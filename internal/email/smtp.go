@@ -0,0 +1,211 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+// TLS modes for SMTPSender.TLS.
+const (
+	TLSNone     = "none"     // plain text, for MailHog and other local servers
+	TLSStartTLS = "starttls" // upgrade after connecting, usually on port 587
+	TLSImplicit = "implicit" // TLS from the first byte, usually on port 465
+)
+
+const (
+	smtpTimeout     = 30 * time.Second // bounds dialing and each send
+	smtpMaxIdle     = 2                // connections kept open between sends
+	smtpIdleTimeout = time.Minute      // well under what servers allow
+)
+
+// SMTPSender sends emails over SMTP. The zero TLS mode and no credentials
+// suit MailHog on localhost:1025. With a Username it authenticates with
+// PLAIN, which net/smtp only allows over TLS or to localhost.
+//
+// Connections are kept open for a while after a send, so a batch of emails
+// doesn't pay for a handshake each. Close hangs them up.
+type SMTPSender struct {
+	Addr      string // host:port, e.g. "localhost:1025"
+	From      string
+	Username  string
+	Password  string
+	TLS       string      // TLSNone (the default), TLSStartTLS or TLSImplicit
+	TLSConfig *tls.Config // nil verifies the server's certificate for its host name
+
+	mu   sync.Mutex
+	idle []*smtpConn
+}
+
+func NewSMTPSender(addr, from string) *SMTPSender {
+	if addr == "" {
+		addr = "localhost:1025"
+	}
+	if from == "" {
+		from = "no-reply@coachgpt.local"
+	}
+	return &SMTPSender{Addr: addr, From: from}
+}
+
+// smtpConn is an open, signed-in connection.
+type smtpConn struct {
+	client *smtp.Client
+	conn   net.Conn // under client, for deadlines
+	used   time.Time
+}
+
+func (s *SMTPSender) Send(to, subject, html string) error {
+	return s.SendMessage(Message{To: to, Subject: subject, HTML: html})
+}
+
+func (s *SMTPSender) SendMessage(m Message) error {
+	msg, err := Build(m, NewEnvelope(s.From))
+	if err != nil {
+		return err
+	}
+	c, err := s.conn()
+	if err != nil {
+		return fmt.Errorf("smtp connect: %w", err)
+	}
+	if err := s.send(c, address(m.To), msg); err != nil {
+		_ = c.client.Close()
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	s.release(c)
+	return nil
+}
+
+func (s *SMTPSender) send(c *smtpConn, to string, msg []byte) error {
+	if err := c.conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		return err
+	}
+	if err := c.client.Mail(address(s.From)); err != nil {
+		return err
+	}
+	if err := c.client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// conn takes an idle connection that still answers, or dials a new one.
+func (s *SMTPSender) conn() (*smtpConn, error) {
+	for {
+		s.mu.Lock()
+		if len(s.idle) == 0 {
+			s.mu.Unlock()
+			return s.dial()
+		}
+		c := s.idle[len(s.idle)-1]
+		s.idle = s.idle[:len(s.idle)-1]
+		s.mu.Unlock()
+
+		// RSET both clears any state and finds connections the server
+		// has hung up on while they sat idle.
+		if time.Since(c.used) < smtpIdleTimeout &&
+			c.conn.SetDeadline(time.Now().Add(smtpTimeout)) == nil &&
+			c.client.Reset() == nil {
+			return c, nil
+		}
+		_ = c.client.Close()
+	}
+}
+
+// release keeps a connection for the next send, or hangs up when enough
+// are kept already.
+func (s *SMTPSender) release(c *smtpConn) {
+	c.used = time.Now()
+	s.mu.Lock()
+	if len(s.idle) < smtpMaxIdle {
+		s.idle = append(s.idle, c)
+		c = nil
+	}
+	s.mu.Unlock()
+	if c != nil {
+		_ = c.client.Quit()
+	}
+}
+
+func (s *SMTPSender) dial() (*smtpConn, error) {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cfg := s.TLSConfig
+	if cfg == nil {
+		cfg = &tls.Config{ServerName: host}
+	}
+
+	d := net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	switch s.TLS {
+	case TLSNone, TLSStartTLS, "":
+		conn, err = d.Dial("tcp", s.Addr)
+	case TLSImplicit:
+		conn, err = tls.DialWithDialer(&d, "tcp", s.Addr, cfg)
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q", s.TLS)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err := s.hello(client, host, cfg); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &smtpConn{client: client, conn: conn}, nil
+}
+
+// hello upgrades to TLS and signs in, as configured.
+func (s *SMTPSender) hello(client *smtp.Client, host string, cfg *tls.Config) error {
+	if s.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server doesn't offer STARTTLS")
+		}
+		if err := client.StartTLS(cfg); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username == "" {
+		return nil
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return errors.New("server doesn't offer AUTH")
+	}
+	if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	return nil
+}
+
+// Close hangs up the connections kept open between sends.
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+	for _, c := range idle {
+		_ = c.client.Quit()
+	}
+	return nil
+}
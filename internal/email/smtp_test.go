@@ -0,0 +1,329 @@
+package email
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is an in-process SMTP server that speaks enough of the protocol
+// for net/smtp: EHLO, STARTTLS, AUTH PLAIN, MAIL, RCPT, DATA, RSET, NOOP
+// and QUIT.
+type fakeSMTP struct {
+	ln       net.Listener
+	tls      *tls.Config // offered with STARTTLS when set
+	implicit bool        // the listener is TLS already
+	hangUp   bool        // close the connection after each message
+
+	mu    sync.Mutex
+	conns int
+	mails []fakeMail
+}
+
+type fakeMail struct {
+	From, To string
+	Auth     string // "user:pass", empty without AUTH
+	TLS      bool
+	Data     string // with the line endings undone to \n
+}
+
+func newFakeSMTP(t *testing.T, cert *tls.Config, implicit bool) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicit {
+		ln = tls.NewListener(ln, cert)
+	}
+	f := &fakeSMTP{ln: ln, tls: cert, implicit: implicit}
+	go f.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+	return f
+}
+
+func (f *fakeSMTP) Addr() string { return f.ln.Addr().String() }
+
+func (f *fakeSMTP) received() (int, []fakeMail) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns, append([]fakeMail(nil), f.mails...)
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns++
+		f.mu.Unlock()
+		go f.session(conn)
+	}
+}
+
+func (f *fakeSMTP) session(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	secure := f.implicit
+	var auth string
+	var m fakeMail
+	reply := func(format string, args ...any) bool { return tp.PrintfLine(format, args...) == nil }
+	reply("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			ext := []string{"250-fake", "250-8BITMIME"}
+			if f.tls != nil && !secure {
+				ext = append(ext, "250-STARTTLS")
+			}
+			reply("%s\r\n250 AUTH PLAIN", strings.Join(ext, "\r\n"))
+		case "STARTTLS":
+			if f.tls == nil || secure {
+				reply("502 not now")
+				continue
+			}
+			reply("220 go ahead")
+			tc := tls.Server(conn, f.tls)
+			if tc.Handshake() != nil {
+				return
+			}
+			conn, tp, secure = tc, textproto.NewConn(tc), true
+		case "AUTH":
+			// AUTH PLAIN <base64 of \x00user\x00pass>
+			_, enc, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(enc)
+			parts := strings.Split(string(b), "\x00")
+			if len(parts) != 3 || parts[2] != "secret" {
+				reply("535 bad credentials")
+				continue
+			}
+			auth = parts[1] + ":" + parts[2]
+			reply("235 ok")
+		case "MAIL":
+			// FROM:<a@b> with parameters like BODY=8BITMIME after it
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			m = fakeMail{From: strings.Trim(from, "<>"), Auth: auth, TLS: secure}
+			reply("250 ok")
+		case "RCPT":
+			m.To = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = string(b)
+			f.mu.Lock()
+			f.mails = append(f.mails, m)
+			f.mu.Unlock()
+			reply("250 queued")
+			if f.hangUp {
+				return
+			}
+		case "RSET", "NOOP":
+			m = fakeMail{}
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("500 what")
+		}
+	}
+}
+
+// testCert is a self-signed certificate for 127.0.0.1, with the client
+// config that trusts it.
+func testCert(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+	return server, client
+}
+
+func TestSMTPSenderTLSModes(t *testing.T) {
+	serverTLS, clientTLS := testCert(t)
+	for _, c := range []struct {
+		mode     string
+		implicit bool
+		user     string
+	}{
+		{TLSNone, false, ""},
+		{TLSNone, false, "coach"}, // PLAIN over plain text is allowed to localhost only
+		{TLSStartTLS, false, "coach"},
+		{TLSImplicit, true, "coach"},
+	} {
+		t.Run(c.mode+"/"+c.user, func(t *testing.T) {
+			srv := newFakeSMTP(t, serverTLS, c.implicit)
+			s := NewSMTPSender(srv.Addr(), "CoachGPT <no-reply@coachgpt.local>")
+			s.TLS, s.TLSConfig = c.mode, clientTLS
+			if c.user != "" {
+				s.Username, s.Password = c.user, "secret"
+			}
+			defer s.Close()
+
+			if err := s.SendMessage(Message{To: "Ana <ana@example.com>", Subject: "Hi", HTML: "<p>Hi</p>", Text: "Hi"}); err != nil {
+				t.Fatal(err)
+			}
+			_, mails := srv.received()
+			if len(mails) != 1 {
+				t.Fatalf("got %d mails", len(mails))
+			}
+			m := mails[0]
+			if m.From != "no-reply@coachgpt.local" || m.To != "ana@example.com" {
+				t.Fatalf("envelope %s -> %s", m.From, m.To)
+			}
+			if m.TLS != (c.mode != TLSNone) {
+				t.Fatalf("TLS = %v", m.TLS)
+			}
+			if want := map[bool]string{true: c.user + ":secret", false: ""}[c.user != ""]; m.Auth != want {
+				t.Fatalf("auth = %q, want %q", m.Auth, want)
+			}
+			if !strings.Contains(m.Data, "\nSubject: Hi\n") || !strings.Contains(m.Data, "multipart/alternative") {
+				t.Fatalf("data:\n%s", m.Data)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderNeedsStartTLS(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	s.TLS = TLSStartTLS
+	if err := s.Send("a@example.com", "Hi", "x"); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("sent without the TLS it was told to use: %v", err)
+	}
+}
+
+func TestSMTPSenderBadPassword(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	s.Username, s.Password = "coach", "wrong"
+	if err := s.Send("a@example.com", "Hi", "x"); err == nil {
+		t.Fatal("sent with a rejected password")
+	}
+	if _, mails := srv.received(); len(mails) != 0 {
+		t.Fatalf("server got %d mails", len(mails))
+	}
+}
+
+func TestSMTPSenderReusesConnection(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	defer s.Close()
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := s.Send(to, "Hi", "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conns, mails := srv.received()
+	if conns != 1 || len(mails) != 3 {
+		t.Fatalf("%d mails over %d connections, want 3 over 1", len(mails), conns)
+	}
+	if mails[2].To != "c@example.com" {
+		t.Fatalf("third mail to %s", mails[2].To)
+	}
+}
+
+func TestSMTPSenderRedialsAfterHangUp(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	srv.hangUp = true
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	defer s.Close()
+	for range 2 {
+		if err := s.Send("a@example.com", "Hi", "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conns, mails := srv.received(); conns != 2 || len(mails) != 2 {
+		t.Fatalf("%d mails over %d connections, want 2 over 2", len(mails), conns)
+	}
+}
+
+func TestSMTPSenderConcurrent(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	defer s.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Send("a@example.com", "Hi", "x")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, mails := srv.received(); len(mails) != 8 {
+		t.Fatalf("got %d mails", len(mails))
+	}
+}
+
+// The fake speaks line by line; make sure a dot at the start of a body
+// line survives the SMTP dot-stuffing both ways.
+func TestSMTPSenderDotStuffing(t *testing.T) {
+	srv := newFakeSMTP(t, nil, false)
+	s := NewSMTPSender(srv.Addr(), "from@example.com")
+	defer s.Close()
+	if err := s.SendMessage(Message{To: "a@example.com", Subject: "Hi", HTML: "x", Text: "line\n.\nend"}); err != nil {
+		t.Fatal(err)
+	}
+	_, mails := srv.received()
+	r := bufio.NewScanner(strings.NewReader(mails[0].Data))
+	found := false
+	for r.Scan() {
+		found = found || r.Text() == "."
+	}
+	if !found {
+		t.Fatalf("lost the dot line:\n%s", mails[0].Data)
+	}
+}
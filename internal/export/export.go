@@ -0,0 +1,199 @@
+// Package export writes structured workouts in the file formats watches and
+// indoor-trainer apps import: Garmin FIT, Zwift ZWO and ERG/MRC.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Formats lists the supported exports by file extension.
+var Formats = []string{"fit", "zwo", "erg", "mrc"}
+
+// ErrNoFTP is returned by the trainer formats when watts are needed but the
+// athlete has no FTP on record.
+var ErrNoFTP = errors.New("athlete has no FTP set")
+
+// Thresholds are the athlete values relative targets resolve against. Zero
+// means unknown.
+type Thresholds struct {
+	FTP              float64 // watts
+	ThresholdPaceSec float64 // seconds per km
+	HR               training.HeartRate
+}
+
+// Resolved kinds.
+const (
+	KindOpen  = "open"
+	KindPower = "power" // watts
+	KindSpeed = "speed" // m/s
+	KindHR    = "hr"    // bpm
+)
+
+// Resolved is a target in absolute units, Low <= High.
+type Resolved struct {
+	Kind string
+	Low  float64
+	High float64
+}
+
+// Workout is what the exporters need besides the structure itself.
+type Workout struct {
+	Name      string
+	Sport     string
+	Structure structured.Workout
+	Athlete   Thresholds
+}
+
+func usesPower(sport string) bool {
+	return strings.HasSuffix(strings.ToLower(sport), "ride")
+}
+
+// Resolve turns a step target into absolute units for this athlete.
+// Percentages use FTP for rides and threshold pace otherwise, falling back
+// to whichever is known. Targets that can't be resolved, like RPE, are open.
+func (th Thresholds) Resolve(t *structured.Target, sport string) Resolved {
+	if t == nil {
+		return Resolved{Kind: KindOpen}
+	}
+	switch t.Kind {
+	case structured.TargetPower:
+		return Resolved{KindPower, t.Low, t.High}
+	case structured.TargetPace:
+		// Low is the faster pace, which is the higher speed.
+		return Resolved{KindSpeed, 1000 / t.High, 1000 / t.Low}
+	case structured.TargetHRZone:
+		lo, _ := th.zoneRange(int(t.Low))
+		_, hi := th.zoneRange(int(t.High))
+		return Resolved{KindHR, math.Round(lo), math.Round(hi)}
+	case structured.TargetThresholdPct:
+		power := th.FTP > 0 && (usesPower(sport) || th.ThresholdPaceSec <= 0)
+		switch {
+		case power:
+			return Resolved{KindPower, math.Round(th.FTP * t.Low / 100), math.Round(th.FTP * t.High / 100)}
+		case th.ThresholdPaceSec > 0:
+			speed := 1000 / th.ThresholdPaceSec
+			return Resolved{KindSpeed, speed * t.Low / 100, speed * t.High / 100}
+		}
+	}
+	return Resolved{Kind: KindOpen}
+}
+
+// zoneRange is the bpm span of a heart-rate zone, 1-5.
+func (th Thresholds) zoneRange(zone int) (float64, float64) {
+	hr := th.HR
+	if hr.Max <= hr.Rest {
+		hr = training.DefaultHeartRate
+	}
+	bounds := hr.ZoneBounds()
+	lo, hi := float64(hr.Rest), float64(hr.Max)
+	if zone > 1 && zone-2 < len(bounds) {
+		lo = bounds[zone-2]
+	}
+	if zone >= 1 && zone-1 < len(bounds) {
+		hi = bounds[zone-1]
+	}
+	return lo, hi
+}
+
+// zoneFTP approximates each heart-rate zone as a fraction of FTP, for
+// trainer formats that only understand power.
+var zoneFTP = map[int]float64{1: 0.55, 2: 0.68, 3: 0.83, 4: 0.98, 5: 1.13}
+
+// intentFTP is used for steps without a usable target.
+var intentFTP = map[string]float64{
+	structured.IntentWarmup:   0.5,
+	structured.IntentActive:   0.65,
+	structured.IntentRecovery: 0.5,
+	structured.IntentCooldown: 0.45,
+}
+
+// fraction expresses a step target relative to threshold (FTP for power,
+// threshold speed for pace), as ZWO and MRC files do.
+func (w Workout) fraction(s structured.Step) (float64, float64, error) {
+	if t := s.Target; t != nil && t.Kind == structured.TargetThresholdPct {
+		return t.Low / 100, t.High / 100, nil
+	}
+	th := w.Athlete
+	r := th.Resolve(s.Target, w.Sport)
+	switch r.Kind {
+	case KindPower:
+		if th.FTP <= 0 {
+			return 0, 0, ErrNoFTP
+		}
+		return r.Low / th.FTP, r.High / th.FTP, nil
+	case KindSpeed:
+		if th.ThresholdPaceSec > 0 {
+			speed := 1000 / th.ThresholdPaceSec
+			return r.Low / speed, r.High / speed, nil
+		}
+	}
+	if t := s.Target; t != nil {
+		switch t.Kind {
+		case structured.TargetHRZone:
+			return zoneFTP[int(t.Low)], zoneFTP[int(t.High)], nil
+		case structured.TargetRPE:
+			return 0.4 + 0.07*t.Low, 0.4 + 0.07*t.High, nil
+		}
+	}
+	f := intentFTP[s.Intent]
+	if f == 0 {
+		f = intentFTP[structured.IntentActive]
+	}
+	return f, f, nil
+}
+
+// seconds times a step for the time-based formats, estimating distance
+// steps from their resolved speed or a typical speed for the sport.
+func (w Workout) seconds(s structured.Step) float64 {
+	if s.DurationSec > 0 {
+		return s.DurationSec
+	}
+	if r := w.Athlete.Resolve(s.Target, w.Sport); r.Kind == KindSpeed && r.Low > 0 {
+		return math.Round(s.DistanceM / ((r.Low + r.High) / 2))
+	}
+	return math.Round(s.DistanceM / structured.TypicalSpeed(w.Sport))
+}
+
+var unsafeName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Filename builds a download name like "2025-03-04-threshold-intervals.fit".
+func Filename(day, title, format string) string {
+	slug := strings.Trim(unsafeName.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		slug = "workout"
+	}
+	return fmt.Sprintf("%s-%s.%s", day, slug, format)
+}
+
+// ContentType returns the MIME type served for a format.
+func ContentType(format string) string {
+	switch format {
+	case "fit":
+		return "application/vnd.ant.fit"
+	case "zwo":
+		return "application/xml"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Write renders the workout in the given format.
+func Write(w Workout, format string) ([]byte, error) {
+	switch format {
+	case "fit":
+		return FIT(w)
+	case "zwo":
+		return ZWO(w)
+	case "erg":
+		return ERG(w)
+	case "mrc":
+		return MRC(w)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
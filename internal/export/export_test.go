@@ -0,0 +1,150 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+func testWorkout(t *testing.T, src, sport string) Workout {
+	t.Helper()
+	s, err := structured.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Workout{
+		Name:      "Threshold intervals",
+		Sport:     sport,
+		Structure: s,
+		Athlete: Thresholds{
+			FTP:              250,
+			ThresholdPaceSec: 250,
+			HR:               training.HeartRate{Rest: 50, Max: 190},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	th := Thresholds{FTP: 200, ThresholdPaceSec: 250, HR: training.HeartRate{Rest: 50, Max: 190}}
+
+	r := th.Resolve(&structured.Target{Kind: structured.TargetThresholdPct, Low: 90, High: 100}, "Ride")
+	if r != (Resolved{KindPower, 180, 200}) {
+		t.Fatalf("ride %% = %+v", r)
+	}
+	r = th.Resolve(&structured.Target{Kind: structured.TargetThresholdPct, Low: 100, High: 100}, "Run")
+	if r.Kind != KindSpeed || r.Low != 4 {
+		t.Fatalf("run %% = %+v", r)
+	}
+	r = th.Resolve(&structured.Target{Kind: structured.TargetHRZone, Low: 2, High: 2}, "Run")
+	if r != (Resolved{KindHR, 134, 148}) {
+		t.Fatalf("z2 = %+v", r)
+	}
+	if r := th.Resolve(&structured.Target{Kind: structured.TargetRPE, Low: 6, High: 6}, "Run"); r.Kind != KindOpen {
+		t.Fatalf("rpe should be open, got %+v", r)
+	}
+}
+
+func TestFIT(t *testing.T) {
+	fitNow = func() time.Time { return time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC) }
+	defer func() { fitNow = time.Now }()
+
+	w := testWorkout(t, "wu 10min @z2, 3x(5min @95-105%, 2min rest), cd 10min", "Ride")
+	b, err := FIT(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 14 || string(b[8:12]) != ".FIT" {
+		t.Fatalf("bad header % x", b[:14])
+	}
+	if size := binary.LittleEndian.Uint32(b[4:8]); int(size) != len(b)-16 {
+		t.Fatalf("data size %d, file %d", size, len(b))
+	}
+	if fitCRC(b[:12]) != binary.LittleEndian.Uint16(b[12:14]) {
+		t.Fatal("header crc mismatch")
+	}
+	if fitCRC(b) != 0 {
+		t.Fatal("file crc does not verify")
+	}
+
+	var steps []fitStep
+	w.fitSteps(w.Structure.Steps, &steps)
+	if len(steps) != 5 {
+		t.Fatalf("steps = %d, want 5", len(steps))
+	}
+	rep := steps[3]
+	if rep.durationType != fitDurationRepeat || rep.durationValue != 1 || rep.targetValue != 3 {
+		t.Fatalf("repeat step = %+v", rep)
+	}
+	if s := steps[1]; s.targetType != fitTargetPower || s.low != 1000+238 || s.high != 1000+263 {
+		t.Fatalf("interval step = %+v", s)
+	}
+}
+
+func TestZWO(t *testing.T) {
+	w := testWorkout(t, "wu 10min @50-75%, 3x(5min @250w, 2min rest @50%), 20min, cd 5min", "Ride")
+	b, err := ZWO(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	for _, want := range []string{
+		`<sportType>bike</sportType>`,
+		`<Warmup Duration="600" PowerLow="0.5" PowerHigh="0.75">`,
+		`<IntervalsT Repeat="3" OnDuration="300" OffDuration="120" OnPower="1" OffPower="0.5">`,
+		`<FreeRide Duration="1200">`,
+		`<Cooldown Duration="300"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %s in\n%s", want, out)
+		}
+	}
+}
+
+func TestERG(t *testing.T) {
+	w := testWorkout(t, "wu 10min @50-70%, 2x(5min @100%, 5min @50%)", "Ride")
+	w.Athlete.FTP = 0
+	if _, err := ERG(w); !errors.Is(err, ErrNoFTP) {
+		t.Fatalf("err = %v, want ErrNoFTP", err)
+	}
+
+	w.Athlete.FTP = 200
+	b, err := ERG(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := b[bytes.Index(b, []byte("[COURSE DATA]\r\n"))+len("[COURSE DATA]\r\n") : bytes.Index(b, []byte("[END COURSE DATA]"))]
+	lines := strings.Split(strings.TrimSpace(string(data)), "\r\n")
+	want := []string{
+		"0.00\t100", "10.00\t140",
+		"10.00\t200", "15.00\t200",
+		"15.00\t100", "20.00\t100",
+		"20.00\t200", "25.00\t200",
+		"25.00\t100", "30.00\t100",
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("course data:\n%s", data)
+	}
+
+	mrc, err := MRC(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(mrc, []byte("MINUTES PERCENT")) || !bytes.Contains(mrc, []byte("10.00\t100\r\n")) {
+		t.Fatalf("unexpected mrc:\n%s", mrc)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	if got := Filename("2025-03-04", "Threshold: 3×10'", "fit"); got != "2025-03-04-threshold-3-10.fit" {
+		t.Fatalf("got %q", got)
+	}
+	if got := Filename("2025-03-04", "", "zwo"); got != "2025-03-04-workout.zwo" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,257 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// FIT global message numbers and field values from the FIT profile.
+const (
+	fitMesgFileID      = 0
+	fitMesgWorkout     = 26
+	fitMesgWorkoutStep = 27
+
+	fitFileWorkout      = 5
+	fitManufacturerDev  = 255
+	fitProtocolVersion  = 0x10 // 1.0
+	fitProfileVersion   = 2100 // 21.00
+	fitEpochOffset      = 631065600
+	fitStepNameSize     = 16
+	fitWorkoutNameSize  = 32
+	fitBaseEnum         = 0x00
+	fitBaseUint16       = 0x84
+	fitBaseUint32       = 0x86
+	fitBaseUint32z      = 0x8C
+	fitBaseString       = 0x07
+	fitInvalidEnum      = 0xFF
+	fitInvalidUint32    = 0xFFFFFFFF
+	fitDurationTime     = 0
+	fitDurationDistance = 1
+	fitDurationOpen     = 5
+	fitDurationRepeat   = 6 // repeat_until_steps_cmplt
+	fitTargetSpeed      = 0
+	fitTargetHR         = 1
+	fitTargetOpen       = 2
+	fitTargetPower      = 4
+	fitHROffset         = 100  // custom heart-rate values are bpm + 100
+	fitPowerOffset      = 1000 // custom power values are watts + 1000
+)
+
+var fitIntent = map[string]byte{
+	structured.IntentActive:   0,
+	structured.IntentRecovery: 1, // rest
+	structured.IntentWarmup:   2,
+	structured.IntentCooldown: 3,
+}
+
+func fitSport(sport string) byte {
+	s := strings.ToLower(sport)
+	switch {
+	case strings.HasSuffix(s, "run"):
+		return 1
+	case strings.HasSuffix(s, "ride"):
+		return 2
+	case strings.Contains(s, "swim"):
+		return 5
+	}
+	return 0 // generic
+}
+
+type fitField struct {
+	num  byte
+	size byte
+	base byte
+}
+
+var (
+	fitFileIDFields = []fitField{
+		{0, 1, fitBaseEnum},    // type
+		{1, 2, fitBaseUint16},  // manufacturer
+		{2, 2, fitBaseUint16},  // product
+		{3, 4, fitBaseUint32z}, // serial_number
+		{4, 4, fitBaseUint32},  // time_created
+	}
+	fitWorkoutFields = []fitField{
+		{4, 1, fitBaseEnum},                    // sport
+		{6, 2, fitBaseUint16},                  // num_valid_steps
+		{8, fitWorkoutNameSize, fitBaseString}, // wkt_name
+	}
+	fitStepFields = []fitField{
+		{254, 2, fitBaseUint16},             // message_index
+		{0, fitStepNameSize, fitBaseString}, // wkt_step_name
+		{1, 1, fitBaseEnum},                 // duration_type
+		{2, 4, fitBaseUint32},               // duration_value
+		{3, 1, fitBaseEnum},                 // target_type
+		{4, 4, fitBaseUint32},               // target_value
+		{5, 4, fitBaseUint32},               // custom_target_value_low
+		{6, 4, fitBaseUint32},               // custom_target_value_high
+		{7, 1, fitBaseEnum},                 // intensity
+	}
+)
+
+type fitStep struct {
+	name          string
+	durationType  byte
+	durationValue uint32
+	targetType    byte
+	targetValue   uint32
+	low, high     uint32
+	intensity     byte
+}
+
+// fitNow is overridden in tests for stable output.
+var fitNow = time.Now
+
+// FIT encodes the workout as a FIT workout file. Repeat blocks become
+// repeat_until_steps_cmplt steps, so nesting is preserved.
+func FIT(w Workout) ([]byte, error) {
+	var steps []fitStep
+	w.fitSteps(w.Structure.Steps, &steps)
+
+	var body bytes.Buffer
+	writeFITDefinition(&body, 0, fitMesgFileID, fitFileIDFields)
+	body.WriteByte(0)
+	body.WriteByte(fitFileWorkout)
+	le16(&body, fitManufacturerDev)
+	le16(&body, 0)
+	le32(&body, 1)
+	le32(&body, uint32(fitNow().Unix()-fitEpochOffset))
+
+	writeFITDefinition(&body, 1, fitMesgWorkout, fitWorkoutFields)
+	body.WriteByte(1)
+	body.WriteByte(fitSport(w.Sport))
+	le16(&body, uint16(len(steps)))
+	fitString(&body, w.Name, fitWorkoutNameSize)
+
+	writeFITDefinition(&body, 2, fitMesgWorkoutStep, fitStepFields)
+	for i, s := range steps {
+		body.WriteByte(2)
+		le16(&body, uint16(i))
+		fitString(&body, s.name, fitStepNameSize)
+		body.WriteByte(s.durationType)
+		le32(&body, s.durationValue)
+		body.WriteByte(s.targetType)
+		le32(&body, s.targetValue)
+		le32(&body, s.low)
+		le32(&body, s.high)
+		body.WriteByte(s.intensity)
+	}
+
+	var out bytes.Buffer
+	header := make([]byte, 12)
+	header[0] = 14
+	header[1] = fitProtocolVersion
+	binary.LittleEndian.PutUint16(header[2:], fitProfileVersion)
+	binary.LittleEndian.PutUint32(header[4:], uint32(body.Len()))
+	copy(header[8:], ".FIT")
+	out.Write(header)
+	le16(&out, fitCRC(header))
+	out.Write(body.Bytes())
+	le16(&out, fitCRC(out.Bytes()))
+	return out.Bytes(), nil
+}
+
+func (w Workout) fitSteps(in []structured.Step, out *[]fitStep) {
+	for _, s := range in {
+		if s.IsRepeat() {
+			from := len(*out)
+			w.fitSteps(s.Steps, out)
+			*out = append(*out, fitStep{
+				durationType:  fitDurationRepeat,
+				durationValue: uint32(from),
+				targetType:    fitInvalidEnum,
+				targetValue:   uint32(s.Repeat),
+				low:           fitInvalidUint32,
+				high:          fitInvalidUint32,
+				intensity:     fitInvalidEnum,
+			})
+			continue
+		}
+
+		fs := fitStep{
+			name:        s.Intent,
+			targetType:  fitTargetOpen,
+			low:         fitInvalidUint32,
+			high:        fitInvalidUint32,
+			intensity:   fitIntent[s.Intent],
+			targetValue: 0,
+		}
+		switch {
+		case s.DurationSec > 0:
+			fs.durationType = fitDurationTime
+			fs.durationValue = uint32(math.Round(s.DurationSec * 1000))
+		case s.DistanceM > 0:
+			fs.durationType = fitDurationDistance
+			fs.durationValue = uint32(math.Round(s.DistanceM * 100))
+		default:
+			fs.durationType = fitDurationOpen
+		}
+
+		r := w.Athlete.Resolve(s.Target, w.Sport)
+		switch r.Kind {
+		case KindHR:
+			fs.targetType = fitTargetHR
+			fs.low = uint32(r.Low) + fitHROffset
+			fs.high = uint32(r.High) + fitHROffset
+		case KindPower:
+			fs.targetType = fitTargetPower
+			fs.low = uint32(r.Low) + fitPowerOffset
+			fs.high = uint32(r.High) + fitPowerOffset
+		case KindSpeed:
+			fs.targetType = fitTargetSpeed
+			fs.low = uint32(math.Round(r.Low * 1000))
+			fs.high = uint32(math.Round(r.High * 1000))
+		}
+		*out = append(*out, fs)
+	}
+}
+
+func writeFITDefinition(b *bytes.Buffer, local byte, global uint16, fields []fitField) {
+	b.WriteByte(0x40 | local)
+	b.WriteByte(0) // reserved
+	b.WriteByte(0) // little endian
+	le16(b, global)
+	b.WriteByte(byte(len(fields)))
+	for _, f := range fields {
+		b.Write([]byte{f.num, f.size, f.base})
+	}
+}
+
+// fitString writes a null-terminated string padded to size bytes.
+func fitString(b *bytes.Buffer, s string, size int) {
+	buf := make([]byte, size)
+	copy(buf[:size-1], s)
+	b.Write(buf)
+}
+
+func le16(b *bytes.Buffer, v uint16) {
+	_ = binary.Write(b, binary.LittleEndian, v)
+}
+
+func le32(b *bytes.Buffer, v uint32) {
+	_ = binary.Write(b, binary.LittleEndian, v)
+}
+
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC is the CRC-16 defined by the FIT protocol.
+func fitCRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		tmp := fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[b&0xF]
+		tmp = fitCRCTable[crc&0xF]
+		crc = (crc >> 4) & 0x0FFF
+		crc = crc ^ tmp ^ fitCRCTable[(b>>4)&0xF]
+	}
+	return crc
+}
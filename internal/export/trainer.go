@@ -0,0 +1,200 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// zwoFile mirrors Zwift's workout XML. Power values are fractions of FTP;
+// Zwift reads the same attributes as fractions of threshold pace for runs.
+type zwoFile struct {
+	XMLName     xml.Name   `xml:"workout_file"`
+	Author      string     `xml:"author"`
+	Name        string     `xml:"name"`
+	Description string     `xml:"description"`
+	SportType   string     `xml:"sportType"`
+	Workout     zwoWorkout `xml:"workout"`
+}
+
+type zwoWorkout struct {
+	Segments []any
+}
+
+func (z zwoWorkout) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, s := range z.Segments {
+		if err := e.Encode(s); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+type zwoRamp struct {
+	XMLName   xml.Name
+	Duration  int     `xml:"Duration,attr"`
+	PowerLow  float64 `xml:"PowerLow,attr"`
+	PowerHigh float64 `xml:"PowerHigh,attr"`
+}
+
+type zwoSteady struct {
+	XMLName  xml.Name `xml:"SteadyState"`
+	Duration int      `xml:"Duration,attr"`
+	Power    float64  `xml:"Power,attr"`
+}
+
+type zwoIntervals struct {
+	XMLName     xml.Name `xml:"IntervalsT"`
+	Repeat      int      `xml:"Repeat,attr"`
+	OnDuration  int      `xml:"OnDuration,attr"`
+	OffDuration int      `xml:"OffDuration,attr"`
+	OnPower     float64  `xml:"OnPower,attr"`
+	OffPower    float64  `xml:"OffPower,attr"`
+}
+
+type zwoFree struct {
+	XMLName  xml.Name `xml:"FreeRide"`
+	Duration int      `xml:"Duration,attr"`
+}
+
+func round3(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+// ZWO encodes the workout for Zwift. Distance steps are converted to time,
+// a top-level repeat of one on and one off step becomes IntervalsT and
+// other repeats are unrolled.
+func ZWO(w Workout) ([]byte, error) {
+	f := zwoFile{
+		Author:      "CoachGPT",
+		Name:        w.Name,
+		Description: w.Structure.Text(),
+		SportType:   "bike",
+	}
+	if !usesPower(w.Sport) {
+		f.SportType = "run"
+	}
+
+	for _, s := range w.Structure.Steps {
+		if s.IsRepeat() && len(s.Steps) == 2 && !s.Steps[0].IsRepeat() && !s.Steps[1].IsRepeat() {
+			on, off := s.Steps[0], s.Steps[1]
+			onLo, onHi, err := w.fraction(on)
+			if err != nil {
+				return nil, err
+			}
+			offLo, offHi, err := w.fraction(off)
+			if err != nil {
+				return nil, err
+			}
+			f.Workout.Segments = append(f.Workout.Segments, zwoIntervals{
+				Repeat:      s.Repeat,
+				OnDuration:  int(w.seconds(on)),
+				OffDuration: int(w.seconds(off)),
+				OnPower:     round3((onLo + onHi) / 2),
+				OffPower:    round3((offLo + offHi) / 2),
+			})
+			continue
+		}
+		for _, st := range (structured.Workout{Steps: []structured.Step{s}}).Flatten() {
+			seg, err := w.zwoSegment(st)
+			if err != nil {
+				return nil, err
+			}
+			f.Workout.Segments = append(f.Workout.Segments, seg)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return nil, fmt.Errorf("encode zwo: %w", err)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (w Workout) zwoSegment(s structured.Step) (any, error) {
+	secs := int(w.seconds(s))
+	if s.Target == nil && s.Intent == structured.IntentActive {
+		return zwoFree{Duration: secs}, nil
+	}
+	lo, hi, err := w.fraction(s)
+	if err != nil {
+		return nil, err
+	}
+	switch s.Intent {
+	case structured.IntentWarmup:
+		if lo == hi {
+			lo = math.Min(lo, 0.45)
+		}
+		return zwoRamp{XMLName: xml.Name{Local: "Warmup"}, Duration: secs, PowerLow: round3(lo), PowerHigh: round3(hi)}, nil
+	case structured.IntentCooldown:
+		if lo == hi {
+			lo = math.Min(lo, 0.4)
+		}
+		return zwoRamp{XMLName: xml.Name{Local: "Cooldown"}, Duration: secs, PowerLow: round3(hi), PowerHigh: round3(lo)}, nil
+	}
+	return zwoSteady{Duration: secs, Power: round3((lo + hi) / 2)}, nil
+}
+
+// ERG writes the workout as absolute watts over minutes, which needs FTP.
+func ERG(w Workout) ([]byte, error) {
+	if w.Athlete.FTP <= 0 {
+		return nil, ErrNoFTP
+	}
+	return w.course("WATTS", w.Athlete.FTP)
+}
+
+// MRC writes the workout as percent of FTP over minutes.
+func MRC(w Workout) ([]byte, error) {
+	return w.course("PERCENT", 100)
+}
+
+// course writes the shared ERG/MRC layout: each step is a flat segment,
+// except warm-ups and cool-downs given as ranges, which ramp.
+func (w Workout) course(unit string, scale float64) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("[COURSE HEADER]\r\n")
+	b.WriteString("VERSION = 2\r\n")
+	b.WriteString("UNITS = ENGLISH\r\n")
+	fmt.Fprintf(&b, "DESCRIPTION = %s\r\n", oneLine(w.Structure.Text()))
+	fmt.Fprintf(&b, "FILE NAME = %s\r\n", oneLine(w.Name))
+	if unit == "WATTS" {
+		fmt.Fprintf(&b, "FTP = %.0f\r\n", w.Athlete.FTP)
+	}
+	fmt.Fprintf(&b, "MINUTES %s\r\n", unit)
+	b.WriteString("[END COURSE HEADER]\r\n")
+	b.WriteString("[COURSE DATA]\r\n")
+
+	var t float64
+	for _, s := range w.Structure.Flatten() {
+		lo, hi, err := w.fraction(s)
+		if err != nil {
+			return nil, err
+		}
+		start, end := (lo+hi)/2, (lo+hi)/2
+		switch s.Intent {
+		case structured.IntentWarmup:
+			start, end = lo, hi
+		case structured.IntentCooldown:
+			start, end = hi, lo
+		}
+		d := w.seconds(s) / 60
+		fmt.Fprintf(&b, "%.2f\t%.0f\r\n", t, start*scale)
+		t += d
+		fmt.Fprintf(&b, "%.2f\t%.0f\r\n", t, end*scale)
+	}
+	b.WriteString("[END COURSE DATA]\r\n")
+	return b.Bytes(), nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
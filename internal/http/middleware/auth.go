@@ -4,7 +4,10 @@ import "net/http"
 
 type contextKey string
 
-const CoachIDKey contextKey = "coach_id"
+const (
+	CoachIDKey   contextKey = "coach_id"
+	AthleteIDKey contextKey = "athlete_id"
+)
 
 func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -16,3 +19,17 @@ func RequireAuth(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireAthlete guards the athlete portal. It looks only at the athlete's
+// sign-in, so a coach session never opens an athlete's pages, nor the
+// reverse.
+func RequireAthlete(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		athleteID := r.Context().Value(AthleteIDKey)
+		if athleteID == nil || athleteID == "" {
+			http.Redirect(w, r, "/athlete/login", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
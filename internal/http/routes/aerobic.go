@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+	"github.com/briangreenhill/coachgpt/internal/metrics"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+const (
+	aerobicWeeks = 16
+	// Decoupling is only meaningful for long, steady sessions
+	minSteadySec = 45 * 60
+)
+
+type aerobicSection struct {
+	Basis    string
+	Label    string
+	Weeks    []metrics.AerobicWeek
+	Points   string
+	Workouts []db.ListDecouplingSinceRow
+}
+
+func (s *Server) handleAthleteAerobic(w http.ResponseWriter, r *http.Request) {
+	coachID := r.Context().Value(appmw.CoachIDKey).(string)
+	athleteID := chi.URLParam(r, "athleteID")
+
+	aid, err := uuid.Parse(athleteID)
+	if err != nil {
+		http.Error(w, "invalid athlete ID", http.StatusBadRequest)
+		return
+	}
+
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil {
+		http.Error(w, "athlete not found", http.StatusNotFound)
+		return
+	}
+	if athlete.CoachID.String() != coachID {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	loc := training.Location(athlete.Tz)
+	now := time.Now()
+	from := training.WeekStart(now, loc).AddDate(0, 0, -7*(aerobicWeeks-1))
+
+	rows, err := s.Q.ListDecouplingSince(r.Context(), db.ListDecouplingSinceParams{
+		AthleteID: aid,
+		StartedAt: pgtype.Timestamptz{Time: from, Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to list decoupling for athlete %s: %v", athleteID, err)
+		http.Error(w, "failed to load metrics", http.StatusInternalServerError)
+		return
+	}
+
+	sections := []*aerobicSection{
+		{Basis: metrics.BasisPace, Label: "Pace to heart rate (Pa:HR)"},
+		{Basis: metrics.BasisPower, Label: "Power to heart rate (Pw:HR)"},
+	}
+	samples := make(map[string][]metrics.AerobicSample)
+	for _, row := range rows {
+		if row.MovingSec < minSteadySec {
+			continue
+		}
+		basis := row.DecouplingBasis.String
+		samples[basis] = append(samples[basis], metrics.AerobicSample{
+			Start: row.StartedAt.Time,
+			Pct:   row.DecouplingPct.Float64,
+			EF:    row.EfficiencyFactor.Float64,
+		})
+		for _, sec := range sections {
+			if sec.Basis == basis {
+				sec.Workouts = append([]db.ListDecouplingSinceRow{row}, sec.Workouts...)
+			}
+		}
+	}
+
+	var shown []*aerobicSection
+	for _, sec := range sections {
+		if len(samples[sec.Basis]) == 0 {
+			continue
+		}
+		sec.Weeks = metrics.WeeklyAerobic(samples[sec.Basis], loc, from, now)
+		ef := make([]float64, len(sec.Weeks))
+		for i, wk := range sec.Weeks {
+			ef[i] = wk.AvgEF
+		}
+		sec.Points = sparkline(ef)
+		shown = append(shown, sec)
+	}
+
+	s.render(w, "aerobic", map[string]any{
+		"Title":    "Aerobic durability - " + athlete.Name,
+		"Athlete":  athlete,
+		"Sections": shown,
+		"MinMins":  minSteadySec / 60,
+	})
+}
@@ -0,0 +1,297 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/ical"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Feeds cover recent history and the season ahead.
+const (
+	feedPastDays   = 90
+	feedFutureDays = 365
+	feedTokenBytes = 24
+)
+
+// feedURL is the https subscription URL; calendar apps also accept it with
+// the webcal scheme.
+func (s *Server) feedURL(token string) string {
+	return s.BaseURL + "/calendar/" + token + ".ics"
+}
+
+func webcalURL(feed string) string {
+	if _, rest, ok := strings.Cut(feed, "://"); ok {
+		return "webcal://" + rest
+	}
+	return feed
+}
+
+// uidDomain qualifies event UIDs so they are unique across calendars.
+func (s *Server) uidDomain() string {
+	if u, err := url.Parse(s.BaseURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "coachgpt"
+}
+
+// handleCalendarFeed serves a feed by its secret token.
+func (s *Server) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := s.Q.GetCalendarFeed(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("get calendar feed failed: %v", err)
+		}
+		http.Error(w, "calendar not found", http.StatusNotFound)
+		return
+	}
+
+	var cal ical.Calendar
+	if feed.AthleteID.Valid {
+		cal, err = s.athleteCalendar(r, feed.AthleteID.Bytes)
+	} else {
+		cal, err = s.coachCalendar(r, feed.CoachID)
+	}
+	if err != nil {
+		log.Printf("build calendar feed for coach %s failed: %v", feed.CoachID, err)
+		http.Error(w, "could not load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	_, _ = w.Write(cal.Bytes())
+}
+
+func feedWindow(loc *time.Location) (pgtype.Date, pgtype.Date) {
+	today := training.Day(time.Now(), loc)
+	return pgDate(today.AddDate(0, 0, -feedPastDays)), pgDate(today.AddDate(0, 0, feedFutureDays))
+}
+
+func (s *Server) athleteCalendar(r *http.Request, athleteID uuid.UUID) (ical.Calendar, error) {
+	athlete, err := s.Q.GetAthlete(r.Context(), athleteID)
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("get athlete: %w", err)
+	}
+	from, to := feedWindow(training.Location(athlete.Tz))
+	planned, err := s.Q.ListPlannedWorkoutsBetween(r.Context(), db.ListPlannedWorkoutsBetweenParams{AthleteID: athlete.ID, FromDay: from, ToDay: to})
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("list planned workouts: %w", err)
+	}
+	events, err := s.Q.ListAthleteEventsBetween(r.Context(), db.ListAthleteEventsBetweenParams{AthleteID: athlete.ID, FromDay: from, ToDay: to})
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("list events: %w", err)
+	}
+
+	cal := ical.Calendar{Name: "Training plan - " + athlete.Name, TZ: athlete.Tz}
+	for _, e := range events {
+		cal.Events = append(cal.Events, s.raceEvent(e, ""))
+	}
+	for _, p := range planned {
+		cal.Events = append(cal.Events, s.plannedEvent(p, ""))
+	}
+	return cal, nil
+}
+
+func (s *Server) coachCalendar(r *http.Request, coachID uuid.UUID) (ical.Calendar, error) {
+	coach, err := s.Q.GetCoach(r.Context(), coachID)
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("get coach: %w", err)
+	}
+	from, to := feedWindow(training.Location(coach.Tz))
+	planned, err := s.Q.ListCoachPlannedWorkoutsBetween(r.Context(), db.ListCoachPlannedWorkoutsBetweenParams{CoachID: coach.ID, FromDay: from, ToDay: to})
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("list planned workouts: %w", err)
+	}
+	events, err := s.Q.ListCoachAthleteEventsBetween(r.Context(), db.ListCoachAthleteEventsBetweenParams{CoachID: coach.ID, FromDay: from, ToDay: to})
+	if err != nil {
+		return ical.Calendar{}, fmt.Errorf("list events: %w", err)
+	}
+
+	cal := ical.Calendar{Name: "CoachGPT - all athletes", TZ: coach.Tz}
+	for _, e := range events {
+		ev := s.raceEvent(db.AthleteEvent{
+			ID: e.ID, AthleteID: e.AthleteID, Name: e.Name, Day: e.Day, Priority: e.Priority, Sport: e.Sport,
+			DistanceM: e.DistanceM, GoalTimeSec: e.GoalTimeSec, Notes: e.Notes, CreatedAt: e.CreatedAt,
+		}, e.AthleteName)
+		ev.URL = s.BaseURL + seasonURL(e.AthleteID)
+		cal.Events = append(cal.Events, ev)
+	}
+	for _, p := range planned {
+		ev := s.plannedEvent(db.PlannedWorkout{
+			ID: p.ID, AthleteID: p.AthleteID, Day: p.Day, Sport: p.Sport, Title: p.Title, Description: p.Description,
+			TargetDurationSec: p.TargetDurationSec, TargetDistanceM: p.TargetDistanceM, TargetLoad: p.TargetLoad,
+			CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, TargetZone: p.TargetZone, WorkoutID: p.WorkoutID,
+			Status: p.Status, Compliance: p.Compliance, Structure: p.Structure,
+		}, p.AthleteName)
+		ev.URL = s.BaseURL + "/athletes/" + p.AthleteID.String() + "/plan/" + p.ID.String() + "/edit"
+		cal.Events = append(cal.Events, ev)
+	}
+	return cal, nil
+}
+
+// plannedEvent describes a session with its targets and structure. The
+// athlete name prefixes the summary in the coach's feed.
+func (s *Server) plannedEvent(p db.PlannedWorkout, athleteName string) ical.Event {
+	summary := p.Title
+	if athleteName != "" {
+		summary = athleteName + ": " + summary
+	}
+	if p.Status == "completed" {
+		summary = "✓ " + summary
+	}
+
+	targets := []string{p.Sport}
+	if p.TargetDurationSec.Valid {
+		targets = append(targets, formatClock(float64(p.TargetDurationSec.Int32)))
+	}
+	if p.TargetDistanceM.Valid {
+		targets = append(targets, fmt.Sprintf("%.1f km", p.TargetDistanceM.Float64/1000))
+	}
+	if p.TargetLoad.Valid {
+		targets = append(targets, fmt.Sprintf("load %.0f", p.TargetLoad.Float64))
+	}
+	if p.TargetZone.Valid {
+		targets = append(targets, fmt.Sprintf("Z%d", p.TargetZone.Int32))
+	}
+	parts := []string{strings.Join(targets, " · ")}
+	if p.Description.Valid && p.Description.String != "" {
+		parts = append(parts, p.Description.String)
+	}
+	if len(p.Structure) > 0 {
+		if sw, err := structured.Decode(p.Structure); err == nil {
+			parts = append(parts, sw.Text())
+		}
+	}
+
+	return ical.Event{
+		UID:         "planned-" + p.ID.String() + "@" + s.uidDomain(),
+		Day:         p.Day.Time,
+		Summary:     summary,
+		Description: strings.Join(parts, "\n\n"),
+		Categories:  []string{p.Sport},
+		Modified:    p.UpdatedAt.Time,
+	}
+}
+
+func (s *Server) raceEvent(e db.AthleteEvent, athleteName string) ical.Event {
+	summary := "🏁 " + e.Name + " (" + e.Priority + " race)"
+	if athleteName != "" {
+		summary = athleteName + ": " + summary
+	}
+	details := []string{e.Sport}
+	if e.DistanceM.Valid {
+		details = append(details, fmt.Sprintf("%.1f km", e.DistanceM.Float64/1000))
+	}
+	if e.GoalTimeSec.Valid {
+		details = append(details, "goal "+formatClock(float64(e.GoalTimeSec.Int32)))
+	}
+	desc := strings.Join(details, " · ")
+	if e.Notes.Valid && e.Notes.String != "" {
+		desc += "\n\n" + e.Notes.String
+	}
+	return ical.Event{
+		UID:         "race-" + e.ID.String() + "@" + s.uidDomain(),
+		Day:         e.Day.Time,
+		Summary:     summary,
+		Description: desc,
+		Categories:  []string{"Race"},
+		Modified:    e.CreatedAt.Time,
+	}
+}
+
+// handleRegenerateAthleteFeed creates the athlete's feed URL, replacing any
+// existing one.
+func (s *Server) handleRegenerateAthleteFeed(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	tok, err := auth.RandomToken(feedTokenBytes)
+	if err != nil {
+		log.Printf("generate feed token failed: %v", err)
+		http.Error(w, "could not create feed", http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.Q.UpsertAthleteCalendarFeed(r.Context(), db.UpsertAthleteCalendarFeedParams{
+		Token:     tok,
+		CoachID:   athlete.CoachID,
+		AthleteID: pgtype.UUID{Bytes: athlete.ID, Valid: true},
+	}); err != nil {
+		log.Printf("save feed for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not create feed", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/athletes/"+athlete.ID.String()+"/plan", http.StatusSeeOther)
+}
+
+func (s *Server) handleRevokeAthleteFeed(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	if err := s.Q.DeleteAthleteCalendarFeed(r.Context(), pgtype.UUID{Bytes: athlete.ID, Valid: true}); err != nil {
+		log.Printf("revoke feed for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not revoke feed", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/athletes/"+athlete.ID.String()+"/plan", http.StatusSeeOther)
+}
+
+func (s *Server) handleRegenerateCoachFeed(w http.ResponseWriter, r *http.Request) {
+	tok, err := auth.RandomToken(feedTokenBytes)
+	if err != nil {
+		log.Printf("generate feed token failed: %v", err)
+		http.Error(w, "could not create feed", http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.Q.UpsertCoachCalendarFeed(r.Context(), db.UpsertCoachCalendarFeedParams{Token: tok, CoachID: coachUUID(r)}); err != nil {
+		log.Printf("save coach feed failed: %v", err)
+		http.Error(w, "could not create feed", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (s *Server) handleRevokeCoachFeed(w http.ResponseWriter, r *http.Request) {
+	if err := s.Q.DeleteCoachCalendarFeed(r.Context(), coachUUID(r)); err != nil {
+		log.Printf("revoke coach feed failed: %v", err)
+		http.Error(w, "could not revoke feed", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// feedView is what the pages show about a feed: where to manage it and,
+// when one exists, its URL.
+type feedView struct {
+	URL    string
+	Webcal string
+	Action string
+	Scope  string
+}
+
+func (s *Server) feedView(feed db.CalendarFeed, err error) feedView {
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("get calendar feed failed: %v", err)
+		}
+		return feedView{}
+	}
+	u := s.feedURL(feed.Token)
+	return feedView{URL: u, Webcal: webcalURL(u)}
+}
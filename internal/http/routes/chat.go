@@ -0,0 +1,333 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/assistant"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+// chatEntry is a message as shown on the chat page. Tool traffic is folded
+// into the assistant turn that asked for it.
+type chatEntry struct {
+	Role    string
+	Content string
+	Tools   []string
+}
+
+func chatURL(athleteID, conversationID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/chat/" + conversationID.String()
+}
+
+// ownedConversation loads the conversation from the URL, scoped to the
+// signed-in coach and the athlete, writing the error response itself when
+// it can't.
+func (s *Server) ownedConversation(w http.ResponseWriter, r *http.Request, athlete db.Athlete) (db.ChatConversation, bool) {
+	cid, err := uuid.Parse(chi.URLParam(r, "conversationID"))
+	if err != nil {
+		http.Error(w, "invalid conversation ID", http.StatusBadRequest)
+		return db.ChatConversation{}, false
+	}
+	c, err := s.Q.GetChatConversation(r.Context(), db.GetChatConversationParams{ID: cid, CoachID: coachUUID(r)})
+	if err == nil && c.AthleteID != athlete.ID {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "conversation not found", http.StatusNotFound)
+		} else {
+			log.Printf("get chat conversation %s failed: %v", cid, err)
+			http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		}
+		return db.ChatConversation{}, false
+	}
+	return c, true
+}
+
+func chatQuestion(w http.ResponseWriter, r *http.Request) (string, bool) {
+	q := strings.TrimSpace(r.FormValue("content"))
+	switch {
+	case q == "":
+		http.Error(w, "ask a question", http.StatusBadRequest)
+		return "", false
+	case len(q) > assistant.MaxQuestion:
+		http.Error(w, "question is too long", http.StatusBadRequest)
+		return "", false
+	}
+	return q, true
+}
+
+func (s *Server) handleCreateChat(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	question, ok := chatQuestion(w, r)
+	if !ok {
+		return
+	}
+	var conv db.ChatConversation
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		var err error
+		conv, err = q.CreateChatConversation(r.Context(), db.CreateChatConversationParams{
+			CoachID:   coachUUID(r),
+			AthleteID: athlete.ID,
+			Title:     assistant.Title(question),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateChatMessage(r.Context(), db.CreateChatMessageParams{ConversationID: conv.ID, Role: llm.RoleUser, Content: question})
+		return err
+	})
+	if err != nil {
+		log.Printf("create chat for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not start conversation", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, chatURL(athlete.ID, conv.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleChatMessage(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	conv, ok := s.ownedConversation(w, r, athlete)
+	if !ok {
+		return
+	}
+	question, ok := chatQuestion(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.Q.CreateChatMessage(r.Context(), db.CreateChatMessageParams{ConversationID: conv.ID, Role: llm.RoleUser, Content: question}); err != nil {
+		log.Printf("add chat message to %s failed: %v", conv.ID, err)
+		http.Error(w, "could not send message", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, chatURL(athlete.ID, conv.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	conv, ok := s.ownedConversation(w, r, athlete)
+	if !ok {
+		return
+	}
+	rows, err := s.Q.ListChatMessages(r.Context(), conv.ID)
+	if err != nil {
+		log.Printf("list chat messages for %s failed: %v", conv.ID, err)
+		http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		return
+	}
+	conversations, err := s.Q.ListChatConversations(r.Context(), db.ListChatConversationsParams{AthleteID: athlete.ID, CoachID: coachUUID(r)})
+	if err != nil {
+		log.Printf("list chat conversations for athlete %s failed: %v", athlete.ID, err)
+	}
+
+	var entries []chatEntry
+	var tools []string
+	for _, m := range rows {
+		switch {
+		case m.Role == llm.RoleTool:
+			continue
+		case m.Role == llm.RoleAssistant && len(m.ToolCalls) > 0:
+			var calls []struct{ Name string }
+			if err := json.Unmarshal(m.ToolCalls, &calls); err == nil {
+				for _, c := range calls {
+					tools = append(tools, c.Name)
+				}
+			}
+			if strings.TrimSpace(m.Content) == "" {
+				continue
+			}
+		}
+		e := chatEntry{Role: m.Role, Content: m.Content}
+		if m.Role == llm.RoleAssistant {
+			e.Tools, tools = tools, nil
+		}
+		entries = append(entries, e)
+	}
+
+	s.render(w, "chat", map[string]any{
+		"Title":         "Assistant - " + athlete.Name,
+		"Athlete":       athlete,
+		"Conversation":  conv,
+		"Conversations": conversations,
+		"Entries":       entries,
+		"Pending":       assistant.Pending(rows),
+		"URL":           chatURL(athlete.ID, conv.ID),
+	})
+}
+
+// handleChatStream answers a pending question as server-sent events:
+// "tool" names each lookup as it starts, "delta" carries JSON-encoded
+// text, and the stream ends with "done" or "error". The reply is only
+// stored once complete, but it is completed even if the client
+// disconnects; tool calls are logged either way.
+func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	conv, ok := s.ownedConversation(w, r, athlete)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	rows, err := s.Q.ListChatMessages(ctx, conv.ID)
+	if err != nil {
+		log.Printf("list chat messages for %s failed: %v", conv.ID, err)
+		http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	send := func(event, data string) error {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	// relay passes the reply's progress on while the coach is watching. Once
+	// they have gone it drops it, so the reply still finishes and is saved.
+	var gone bool
+	relay := func(event string, v any) error {
+		if gone {
+			return nil
+		}
+		b, _ := json.Marshal(v)
+		if err := send(event, string(b)); err != nil {
+			log.Printf("chat stream %s: client gone, finishing reply: %v", conv.ID, err)
+			gone = true
+		}
+		return nil
+	}
+
+	if !assistant.Pending(rows) {
+		_ = send("done", "{}")
+		return
+	}
+	n, err := s.Q.ClaimChatReply(ctx, conv.ID)
+	if err != nil || n == 0 {
+		if err != nil {
+			log.Printf("claim chat reply %s failed: %v", conv.ID, err)
+		}
+		_ = send("error", `"Another reply is already being written. Reload in a moment."`)
+		return
+	}
+	defer func() {
+		if err := s.Q.ReleaseChatReply(context.WithoutCancel(ctx), conv.ID); err != nil {
+			log.Printf("release chat reply %s failed: %v", conv.ID, err)
+		}
+	}()
+	// Re-read under the claim: a reply that finished since the first read
+	// has already answered the question.
+	if rows, err = s.Q.ListChatMessages(ctx, conv.ID); err != nil {
+		log.Printf("list chat messages for %s failed: %v", conv.ID, err)
+		_ = send("error", `"Could not load the conversation."`)
+		return
+	}
+	if !assistant.Pending(rows) {
+		_ = send("done", "{}")
+		return
+	}
+
+	system, err := assistant.SystemPrompt(athlete, time.Now())
+	if err != nil {
+		log.Printf("chat system prompt for %s failed: %v", conv.ID, err)
+		_ = send("error", `"Could not load the conversation."`)
+		return
+	}
+	history, err := assistant.History(system, rows)
+	if err != nil {
+		log.Printf("load chat history %s failed: %v", conv.ID, err)
+		_ = send("error", `"Could not load the conversation."`)
+		return
+	}
+	// The reply outlives the request, so the answer (and its usage) is kept
+	// even if the coach leaves mid-stream.
+	replyCtx := context.WithoutCancel(ctx)
+	tools := assistant.Tools{Q: s.Q, CoachID: coachUUID(r), Athlete: athlete}
+	turn, err := assistant.Reply(llm.ForCoach(replyCtx, conv.CoachID, assistant.Feature), s.LLM, tools, history, assistant.Events{
+		OnDelta: func(text string) error { return relay("delta", text) },
+		OnTool:  func(c llm.ToolCall) error { return relay("tool", c.Name) },
+	})
+	s.logToolCalls(replyCtx, conv, turn.Calls)
+	if err != nil {
+		log.Printf("chat reply %s failed: %v", conv.ID, err)
+		msg := `"The assistant couldn't answer just now. Reload to try again."`
+		if errors.Is(err, llm.ErrQuotaExceeded) {
+			msg = `"You've used this month's AI allowance."`
+		}
+		_ = send("error", msg)
+		return
+	}
+
+	// Stored even if the coach has gone, so the answer is there next time
+	err = s.inTx(replyCtx, func(q *db.Queries) error {
+		for _, m := range turn.Messages {
+			arg, err := assistant.CreateParams(conv.ID, m)
+			if err != nil {
+				return err
+			}
+			if m.Role == llm.RoleAssistant {
+				arg.Model = pgtype.Text{String: turn.Model, Valid: true}
+				arg.PromptVersion = pgtype.Text{String: assistant.Prompt.ID(), Valid: true}
+			}
+			if _, err := q.CreateChatMessage(replyCtx, arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("save chat reply %s failed: %v", conv.ID, err)
+		_ = send("error", `"The reply couldn't be saved. Reload to try again."`)
+		return
+	}
+	_ = send("done", "{}")
+}
+
+// logToolCalls writes the audit trail. A failure loses only the log, so
+// it doesn't fail the reply.
+func (s *Server) logToolCalls(ctx context.Context, conv db.ChatConversation, calls []assistant.Call) {
+	for _, c := range calls {
+		arg := db.LogChatToolCallParams{
+			ConversationID: conv.ID,
+			CoachID:        conv.CoachID,
+			AthleteID:      conv.AthleteID,
+			Tool:           c.Name,
+			Arguments:      c.Arguments,
+			Result:         c.Result,
+			DurationMs:     int32(c.Duration.Milliseconds()),
+		}
+		if c.Err != nil {
+			arg.Error = pgtype.Text{String: c.Err.Error(), Valid: true}
+		}
+		if err := s.Q.LogChatToolCall(ctx, arg); err != nil {
+			log.Printf("log chat tool call %s in %s failed: %v", c.Name, conv.ID, err)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package routes
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/comments"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// commentsView is the data for the comments partial.
+type commentsView struct {
+	Comments []db.ThreadComment
+	Action   string // where the form posts
+	Viewer   string // the author whose comments read as "You"
+	Other    string // the other side's name
+}
+
+// commentsView lists a thread for the coach's pages.
+func (s *Server) commentsView(ctx context.Context, t db.CommentThread, err error, athlete db.Athlete, action string) commentsView {
+	return commentsView{
+		Comments: s.threadComments(ctx, t, err),
+		Action:   action,
+		Viewer:   comments.AuthorCoach,
+		Other:    athlete.Name,
+	}
+}
+
+// threadComments lists the comments on a thread as it was loaded. A thread
+// that doesn't exist yet is just empty.
+func (s *Server) threadComments(ctx context.Context, t db.CommentThread, err error) []db.ThreadComment {
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("get comment thread failed: %v", err)
+		}
+		return nil
+	}
+	list, err := s.Q.ListThreadComments(ctx, t.ID)
+	if err != nil {
+		log.Printf("list comments on thread %s failed: %v", t.ID, err)
+	}
+	return list
+}
+
+func plannedURL(athleteID, planID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/plan/" + planID.String() + "/edit"
+}
+
+// threadSubject is what a thread is about.
+type threadSubject struct {
+	Title    string
+	Day      time.Time // athlete-local
+	CoachURL string
+	Workout  *db.GetCoachAthleteWorkoutRow
+	Planned  *db.PlannedWorkout
+}
+
+func (s *Server) threadSubject(ctx context.Context, t db.CommentThread, athlete db.Athlete) (threadSubject, error) {
+	if t.WorkoutID.Valid {
+		wo, err := s.Q.GetCoachAthleteWorkout(ctx, db.GetCoachAthleteWorkoutParams{
+			ID:        t.WorkoutID.Bytes,
+			AthleteID: athlete.ID,
+			CoachID:   athlete.CoachID,
+		})
+		if err != nil {
+			return threadSubject{}, fmt.Errorf("get workout: %w", err)
+		}
+		title := wo.Name.String
+		if title == "" {
+			title = wo.Sport
+		}
+		return threadSubject{
+			Title:    title,
+			Day:      wo.StartedAt.Time.In(training.Location(athlete.Tz)),
+			CoachURL: workoutURL(athlete.ID, wo.ID),
+			Workout:  &wo,
+		}, nil
+	}
+	p, err := s.Q.GetPlannedWorkout(ctx, db.GetPlannedWorkoutParams{ID: t.PlannedWorkoutID.Bytes, AthleteID: athlete.ID})
+	if err != nil {
+		return threadSubject{}, fmt.Errorf("get planned session: %w", err)
+	}
+	return threadSubject{Title: p.Title, Day: p.Day.Time, CoachURL: plannedURL(athlete.ID, p.ID), Planned: &p}, nil
+}
+
+// replyAddress is the Reply-To for a side's notifications, empty unless
+// replies can be received.
+func (s *Server) replyAddress(token string) string {
+	if s.Inbound.InboundSecret == "" {
+		return ""
+	}
+	return comments.ReplyAddress(token, s.Inbound.ReplyDomain)
+}
+
+// postComment saves a comment and, in the same transaction, the email
+// telling the other side. Failing to build the email only loses the
+// notification.
+func (s *Server) postComment(ctx context.Context, t db.CommentThread, athlete db.Athlete, author, body, via string) error {
+	var sent db.EmailOutbox
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		c, err := q.CreateThreadComment(ctx, db.CreateThreadCommentParams{ThreadID: t.ID, Author: author, Body: body, Via: via})
+		if err != nil {
+			return err
+		}
+		m, ok, err := s.commentEmail(ctx, t, athlete, c)
+		if err != nil {
+			log.Printf("notify comment %d on thread %s failed: %v", c.ID, t.ID, err)
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+		sent, err = outbox.Add(ctx, q, outbox.KindComment, m, outbox.Owner{CoachID: pgUUID(athlete.CoachID), AthleteID: pgUUID(athlete.ID)})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if sent.ID != uuid.Nil {
+		s.enqueueEmail(ctx, sent.ID)
+	}
+	return nil
+}
+
+// commentEmail is the email telling the other side about c, or false when
+// they have no address to send it to.
+func (s *Server) commentEmail(ctx context.Context, t db.CommentThread, athlete db.Athlete, c db.ThreadComment) (email.Message, bool, error) {
+	subj, err := s.threadSubject(ctx, t, athlete)
+	if err != nil {
+		return email.Message{}, false, err
+	}
+	coach, err := s.Q.GetCoach(ctx, athlete.CoachID)
+	if err != nil {
+		return email.Message{}, false, fmt.Errorf("get coach: %w", err)
+	}
+
+	var to, replyTo, author, link string
+	if c.Author == comments.AuthorCoach {
+		if !athlete.Email.Valid || athlete.Email.String == "" {
+			return email.Message{}, false, nil
+		}
+		to, replyTo = athlete.Email.String, s.replyAddress(t.AthleteToken)
+		author = coachName(coach)
+		link = s.BaseURL + "/threads/" + t.AthleteToken
+	} else {
+		to, replyTo = coach.Email, s.replyAddress(t.CoachToken)
+		author = athlete.Name
+		link = s.BaseURL + subj.CoachURL + "#comments"
+	}
+	m, err := email.Render(to, outbox.KindComment, map[string]any{
+		"Author":   author,
+		"Title":    subj.Title,
+		"Day":      subj.Day.Format("Mon 2 Jan"),
+		"Body":     c.Body,
+		"URL":      link,
+		"CanReply": replyTo != "",
+	})
+	if err != nil {
+		return email.Message{}, false, err
+	}
+	m.ReplyTo = replyTo
+	return m, true, nil
+}
+
+func coachName(c db.Coach) string {
+	if c.Name.Valid && c.Name.String != "" {
+		return c.Name.String
+	}
+	return "Your coach"
+}
+
+// readComment cleans the posted comment, writing the error response itself
+// when it's unusable.
+func readComment(w http.ResponseWriter, r *http.Request) (string, bool) {
+	body, err := comments.Clean(r.FormValue("body"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	return body, true
+}
+
+func (s *Server) handleWorkoutComment(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.ownedWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	body, ok := readComment(w, r)
+	if !ok {
+		return
+	}
+	t, err := comments.WorkoutThread(r.Context(), s.Q, athlete.ID, wo.ID)
+	if err == nil {
+		err = s.postComment(r.Context(), t, athlete, comments.AuthorCoach, body, comments.ViaWeb)
+	}
+	if err != nil {
+		log.Printf("comment on workout %s failed: %v", wo.ID, err)
+		http.Error(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, workoutURL(athlete.ID, wo.ID)+"#comments", http.StatusSeeOther)
+}
+
+func (s *Server) handlePlannedComment(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	body, ok := readComment(w, r)
+	if !ok {
+		return
+	}
+	t, err := comments.PlannedThread(r.Context(), s.Q, athlete.ID, p.ID)
+	if err == nil {
+		err = s.postComment(r.Context(), t, athlete, comments.AuthorCoach, body, comments.ViaWeb)
+	}
+	if err != nil {
+		log.Printf("comment on planned session %s failed: %v", p.ID, err)
+		http.Error(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, plannedURL(athlete.ID, p.ID)+"#comments", http.StatusSeeOther)
+}
+
+// athleteThread loads the thread from the athlete's token in the URL,
+// writing the error response itself when it can't. The coach's token
+// doesn't open the page: the coach has the app.
+func (s *Server) athleteThread(w http.ResponseWriter, r *http.Request) (db.CommentThread, db.Athlete, bool) {
+	token := chi.URLParam(r, "token")
+	t, err := s.Q.GetCommentThreadByToken(r.Context(), token)
+	if err == nil && comments.Author(t, token) != comments.AuthorAthlete {
+		err = pgx.ErrNoRows
+	}
+	var athlete db.Athlete
+	if err == nil {
+		athlete, err = s.Q.GetAthlete(r.Context(), t.AthleteID)
+	}
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("get comment thread failed: %v", err)
+		}
+		http.Error(w, "conversation not found", http.StatusNotFound)
+		return db.CommentThread{}, db.Athlete{}, false
+	}
+	return t, athlete, true
+}
+
+// handleAthleteThread is the athlete's view of a thread, opened from the
+// link in their notification email.
+func (s *Server) handleAthleteThread(w http.ResponseWriter, r *http.Request) {
+	t, athlete, ok := s.athleteThread(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	subj, err := s.threadSubject(ctx, t, athlete)
+	if err != nil {
+		log.Printf("load subject of thread %s failed: %v", t.ID, err)
+		http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		return
+	}
+	coach, err := s.Q.GetCoach(ctx, athlete.CoachID)
+	if err != nil {
+		log.Printf("get coach %s failed: %v", athlete.CoachID, err)
+		http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		return
+	}
+	list, err := s.Q.ListThreadComments(ctx, t.ID)
+	if err != nil {
+		log.Printf("list comments on thread %s failed: %v", t.ID, err)
+		http.Error(w, "could not load conversation", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "thread", map[string]any{
+		"Title":   subj.Title,
+		"Athlete": athlete,
+		"Subject": subj,
+		"Comments": commentsView{
+			Comments: list,
+			Action:   "/threads/" + t.AthleteToken,
+			Viewer:   comments.AuthorAthlete,
+			Other:    coachName(coach),
+		},
+	})
+}
+
+func (s *Server) handleAthleteComment(w http.ResponseWriter, r *http.Request) {
+	t, athlete, ok := s.athleteThread(w, r)
+	if !ok {
+		return
+	}
+	body, ok := readComment(w, r)
+	if !ok {
+		return
+	}
+	if err := s.postComment(r.Context(), t, athlete, comments.AuthorAthlete, body, comments.ViaWeb); err != nil {
+		log.Printf("athlete comment on thread %s failed: %v", t.ID, err)
+		http.Error(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/threads/"+t.AthleteToken+"#comments", http.StatusSeeOther)
+}
+
+// handleInboundEmail takes replies to notifications from the inbound mail
+// service, which posts each message as a form: Mailgun's field names, or
+// the to/from/text of SendGrid's Inbound Parse. Mail that can't be matched
+// to a thread and its sender is acknowledged and dropped, so the service
+// doesn't retry it.
+func (s *Server) handleInboundEmail(w http.ResponseWriter, r *http.Request) {
+	secret := s.Inbound.InboundSecret
+	if secret == "" || s.Inbound.ReplyDomain == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(secret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	ctx := r.Context()
+	to := formValue(r, "recipient", "to")
+	from := formValue(r, "sender", "from")
+	text := formValue(r, "stripped-text", "body-plain", "text")
+
+	token, ok := comments.TokenFromAddress(to, s.Inbound.ReplyDomain)
+	if !ok {
+		log.Printf("[inbound] no reply address in %q, dropped", to)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	t, err := s.Q.GetCommentThreadByToken(ctx, token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[inbound] unknown reply token, dropped")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		log.Printf("[inbound] get comment thread failed: %v", err)
+		http.Error(w, "could not load thread", http.StatusInternalServerError)
+		return
+	}
+	athlete, err := s.Q.GetAthlete(ctx, t.AthleteID)
+	if err != nil {
+		log.Printf("[inbound] get athlete %s failed: %v", t.AthleteID, err)
+		http.Error(w, "could not load thread", http.StatusInternalServerError)
+		return
+	}
+
+	// The token is in every copy of the notification, so only take the
+	// reply from the address it was sent to.
+	author := comments.Author(t, token)
+	want := athlete.Email.String
+	if author == comments.AuthorCoach {
+		coach, err := s.Q.GetCoach(ctx, athlete.CoachID)
+		if err != nil {
+			log.Printf("[inbound] get coach %s failed: %v", athlete.CoachID, err)
+			http.Error(w, "could not load thread", http.StatusInternalServerError)
+			return
+		}
+		want = coach.Email
+	}
+	if !comments.SameAddress(from, want) {
+		log.Printf("[inbound] reply to thread %s from %q, not the %s, dropped", t.ID, from, author)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	reply := comments.StripQuoted(text)
+	if len(reply) > comments.MaxBody {
+		reply = strings.ToValidUTF8(reply[:comments.MaxBody], "")
+	}
+	body, err := comments.Clean(reply)
+	if err != nil {
+		log.Printf("[inbound] reply to thread %s dropped: %v", t.ID, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if err := s.postComment(ctx, t, athlete, author, body, comments.ViaEmail); err != nil {
+		log.Printf("[inbound] save reply to thread %s failed: %v", t.ID, err)
+		http.Error(w, "could not save reply", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[inbound] %s replied on thread %s", author, t.ID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// formValue is the first of the named fields that is set.
+func formValue(r *http.Request, names ...string) string {
+	for _, n := range names {
+		if v := r.FormValue(n); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// pgUUID wraps an ID for a nullable column.
+func pgUUID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
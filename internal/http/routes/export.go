@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/export"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// downloadTTL is how long emailed download links stay valid.
+const downloadTTL = 60 * 24 * time.Hour
+
+// downloadLink signs download tokens in a scope of their own, so a token
+// signed for anything else, like the Strava OAuth state, doesn't open a
+// session.
+func (s *Server) downloadLink() auth.MagicLink {
+	return auth.MagicLink{Secret: []byte(s.StateSecret), Scope: "plan-download"}
+}
+
+// exportWorkout resolves a planned session against the athlete's current
+// thresholds. ok is false when the session has no structure to export.
+func exportWorkout(athlete db.Athlete, p db.PlannedWorkout) (export.Workout, bool, error) {
+	if len(p.Structure) == 0 {
+		return export.Workout{}, false, nil
+	}
+	sw, err := structured.Decode(p.Structure)
+	if err != nil {
+		return export.Workout{}, false, err
+	}
+	return export.Workout{
+		Name:      p.Title,
+		Sport:     p.Sport,
+		Structure: sw,
+		Athlete: export.Thresholds{
+			FTP:              float64(athlete.FtpWatts.Int32),
+			ThresholdPaceSec: float64(athlete.ThresholdPaceSec.Int32),
+			HR:               training.HeartRateFor(int(athlete.MaxHr.Int32), int(athlete.RestingHr.Int32)),
+		},
+	}, true, nil
+}
+
+// serveExport writes the session as a file download in the format named in
+// the URL.
+func (s *Server) serveExport(w http.ResponseWriter, r *http.Request, athlete db.Athlete, p db.PlannedWorkout) {
+	format := chi.URLParam(r, "format")
+	if !slices.Contains(export.Formats, format) {
+		http.Error(w, "unknown format", http.StatusNotFound)
+		return
+	}
+	ew, ok, err := exportWorkout(athlete, p)
+	if err != nil {
+		log.Printf("decode structure for session %s failed: %v", p.ID, err)
+		http.Error(w, "could not read session structure", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "session has no structured steps to export", http.StatusBadRequest)
+		return
+	}
+
+	b, err := export.Write(ew, format)
+	if errors.Is(err, export.ErrNoFTP) {
+		http.Error(w, "set the athlete's FTP to export this session", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		log.Printf("export session %s as %s failed: %v", p.ID, format, err)
+		http.Error(w, "could not export session", http.StatusInternalServerError)
+		return
+	}
+
+	name := export.Filename(p.Day.Time.Format(time.DateOnly), p.Title, format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	_, _ = w.Write(b)
+}
+
+func (s *Server) handleExportPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	s.serveExport(w, r, athlete, p)
+}
+
+// downloadURL is a signed link the athlete can open without signing in.
+func (s *Server) downloadURL(p db.PlannedWorkout, format string) string {
+	tok := s.downloadLink().Sign(p.AthleteID.String()+":"+p.ID.String(), time.Now().Add(downloadTTL))
+	return s.BaseURL + "/plan/download/" + format + "?token=" + url.QueryEscape(tok)
+}
+
+// handleDownloadPlannedWorkout serves the signed links from session emails.
+func (s *Server) handleDownloadPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	ids, err := s.downloadLink().Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+	athleteStr, planStr, _ := strings.Cut(ids, ":")
+	aid, err1 := uuid.Parse(athleteStr)
+	pid, err2 := uuid.Parse(planStr)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	p, err := s.Q.GetPlannedWorkout(r.Context(), db.GetPlannedWorkoutParams{ID: pid, AthleteID: aid})
+	if err != nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	s.serveExport(w, r, athlete, p)
+}
+
+// handleSendPlannedWorkout emails the session and its download links to
+// the athlete.
+func (s *Server) handleSendPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	if !athlete.Email.Valid || athlete.Email.String == "" {
+		http.Error(w, "athlete has no email address", http.StatusBadRequest)
+		return
+	}
+
+	ew, structuredOK, err := exportWorkout(athlete, p)
+	if err != nil {
+		log.Printf("decode structure for session %s failed: %v", p.ID, err)
+	}
+
+	type download struct{ Format, URL string }
+	var steps []string
+	var downloads []download
+	if structuredOK {
+		steps = ew.Structure.Lines()
+		for _, format := range export.Formats {
+			if format == "erg" && ew.Athlete.FTP <= 0 {
+				continue
+			}
+			downloads = append(downloads, download{strings.ToUpper(format), s.downloadURL(p, format)})
+		}
+	}
+	m, err := email.Render(athlete.Email.String, outbox.KindSession, map[string]any{
+		"Title":       p.Title,
+		"Day":         p.Day.Time.Format("Monday 2 January"),
+		"Description": p.Description.String,
+		"Steps":       steps,
+		"Downloads":   downloads,
+	})
+	if err == nil {
+		err = s.queueEmail(r.Context(), outbox.KindSession, m, outbox.Owner{CoachID: pgUUID(athlete.CoachID), AthleteID: pgUUID(athlete.ID)})
+	}
+	if err != nil {
+		log.Printf("queue session %s for %s failed: %v", p.ID, athlete.Email.String, err)
+		http.Error(w, "could not send email", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, planURL(athlete.ID, p.Day), http.StatusSeeOther)
+}
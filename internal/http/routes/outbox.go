@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+)
+
+// inviteTTL is how long an athlete's invite link works.
+const inviteTTL = 7 * 24 * time.Hour
+
+// queueEmail stores an email and queues it for sending, for an email that is
+// the request's only write. One that goes with other writes is added with
+// outbox.Add in their transaction and enqueued after it commits.
+func (s *Server) queueEmail(ctx context.Context, kind string, m email.Message, owner outbox.Owner) error {
+	row, err := outbox.Add(ctx, s.Q, kind, m, owner)
+	if err != nil {
+		return err
+	}
+	s.enqueueEmail(ctx, row.ID)
+	return nil
+}
+
+// enqueueEmail queues a stored email once its transaction has committed. A
+// failure only delays it: the worker's sweep picks up pending emails.
+func (s *Server) enqueueEmail(ctx context.Context, id uuid.UUID) {
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: s.RedisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	if err := outbox.Enqueue(ctx, client, id); err != nil {
+		log.Printf("[email] enqueue %s failed, leaving it to the sweep: %v", id, err)
+	}
+}
+
+// queueInvite stores an invite for the athlete with q, returning the
+// email and the link in it.
+func (s *Server) queueInvite(ctx context.Context, q *db.Queries, a db.Athlete) (db.EmailOutbox, string, error) {
+	invite := s.Invite.URL(a.CoachID.String(), a.ID.String(), inviteTTL)
+	m, err := email.Render(a.Email.String, outbox.KindInvite, map[string]any{"URL": invite})
+	if err != nil {
+		return db.EmailOutbox{}, "", err
+	}
+	row, err := outbox.Add(ctx, q, outbox.KindInvite, m, outbox.Owner{
+		CoachID:   pgUUID(a.CoachID),
+		AthleteID: pgUUID(a.ID),
+	})
+	return row, invite, err
+}
+
+// handleResendInvite sends the athlete a fresh invite, for one that never
+// arrived or has expired.
+func (s *Server) handleResendInvite(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	if !athlete.Email.Valid || athlete.Email.String == "" {
+		http.Error(w, "athlete has no email address", http.StatusBadRequest)
+		return
+	}
+	row, _, err := s.queueInvite(r.Context(), s.Q, athlete)
+	if err != nil {
+		log.Printf("queue invite for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not send invite", http.StatusInternalServerError)
+		return
+	}
+	s.enqueueEmail(r.Context(), row.ID)
+	http.Redirect(w, r, "/emails", http.StatusSeeOther)
+}
+
+// handleEmails lists the coach's recent emails and how their delivery
+// went.
+func (s *Server) handleEmails(w http.ResponseWriter, r *http.Request) {
+	cid := coachUUID(r)
+	emails, err := s.Q.ListOutboxEmailsByCoach(r.Context(), pgUUID(cid))
+	if err != nil {
+		log.Printf("list emails for coach %s failed: %v", cid, err)
+		http.Error(w, "could not load emails", http.StatusInternalServerError)
+		return
+	}
+	athletes, err := s.Q.ListAthletesByCoach(r.Context(), cid)
+	if err != nil {
+		log.Printf("list athletes for coach %s failed: %v", cid, err)
+	}
+	byID := make(map[uuid.UUID]db.Athlete, len(athletes))
+	for _, a := range athletes {
+		byID[a.ID] = a
+	}
+	views := make([]emailView, len(emails))
+	for i, e := range emails {
+		views[i].EmailOutbox = e
+		if a, ok := byID[e.AthleteID.Bytes]; ok && e.AthleteID.Valid {
+			views[i].Athlete = &a
+		}
+	}
+	s.render(w, "emails", map[string]any{
+		"Title":  "Emails",
+		"Emails": views,
+	})
+}
+
+// emailView is an outbox email with the athlete it concerns, if any.
+type emailView struct {
+	db.EmailOutbox
+	Athlete *db.Athlete
+}
@@ -0,0 +1,690 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/compliance"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/export"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+	"github.com/briangreenhill/coachgpt/internal/season"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// planSports are the Strava sport types offered when planning a session.
+var planSports = []string{"Run", "TrailRun", "Ride", "VirtualRide", "Swim", "WeightTraining", "Workout"}
+
+var planZones = []string{"1", "2", "3", "4", "5"}
+
+type calendarDay struct {
+	Date      time.Time
+	Key       string
+	InRange   bool
+	Today     bool
+	Events    []db.AthleteEvent
+	Planned   []plannedEntry
+	Completed []completedEntry
+}
+
+// plannedEntry carries the displayed status, which turns missed once the
+// day has passed.
+type plannedEntry struct {
+	db.PlannedWorkout
+	State string
+}
+
+type completedEntry struct {
+	db.ListWorkoutsBetweenRow
+	Unplanned bool
+}
+
+type calendarWeek struct {
+	Start       time.Time
+	Phase       string
+	Days        []*calendarDay
+	PlannedSec  int32
+	PlannedLoad float64
+	DoneSec     int32
+	DoneLoad    float64
+}
+
+// ownedAthlete loads the athlete from the URL and checks that it belongs to
+// the signed-in coach, writing the error response itself when it doesn't.
+func (s *Server) ownedAthlete(w http.ResponseWriter, r *http.Request) (db.Athlete, bool) {
+	coachID := r.Context().Value(appmw.CoachIDKey).(string)
+
+	aid, err := uuid.Parse(chi.URLParam(r, "athleteID"))
+	if err != nil {
+		http.Error(w, "invalid athlete ID", http.StatusBadRequest)
+		return db.Athlete{}, false
+	}
+
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil {
+		http.Error(w, "athlete not found", http.StatusNotFound)
+		return db.Athlete{}, false
+	}
+	if athlete.CoachID.String() != coachID {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return db.Athlete{}, false
+	}
+	return athlete, true
+}
+
+func (s *Server) handleAthletePlan(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	data, ok := s.calendarData(w, r, athlete)
+	if !ok {
+		return
+	}
+
+	feed := s.feedView(s.Q.GetAthleteCalendarFeed(r.Context(), pgtype.UUID{Bytes: athlete.ID, Valid: true}))
+	feed.Action, feed.Scope = "/athletes/"+athlete.ID.String()+"/calendar-feed", "athlete's plan"
+
+	data["Title"] = "Plan - " + athlete.Name
+	data["Formats"] = export.Formats
+	data["Feed"] = feed
+	s.render(w, "plan", data)
+}
+
+// calendarData lays out the athlete's plan and workouts for the month or
+// week in the query, writing the error response itself when it can't. The
+// coach's plan page and the athlete's portal both show it.
+func (s *Server) calendarData(w http.ResponseWriter, r *http.Request, athlete db.Athlete) (map[string]any, bool) {
+	loc := training.Location(athlete.Tz)
+	today := training.Day(time.Now(), loc)
+	anchor := today
+	if v := r.URL.Query().Get("date"); v != "" {
+		t, err := time.ParseInLocation(time.DateOnly, v, loc)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return nil, false
+		}
+		anchor = t
+	}
+
+	view := r.URL.Query().Get("view")
+	var from, to, prev, next time.Time // to is exclusive
+	var label string
+	if view == "week" {
+		from = training.WeekStart(anchor, loc)
+		to = from.AddDate(0, 0, 7)
+		prev, next = from.AddDate(0, 0, -7), to
+		label = "Week of " + from.Format("Jan 2, 2006")
+	} else {
+		view = "month"
+		first := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, loc)
+		last := first.AddDate(0, 1, -1)
+		from = training.WeekStart(first, loc)
+		to = training.WeekStart(last, loc).AddDate(0, 0, 7)
+		prev, next = first.AddDate(0, -1, 0), first.AddDate(0, 1, 0)
+		label = first.Format("January 2006")
+	}
+
+	planned, err := s.Q.ListPlannedWorkoutsBetween(r.Context(), db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: athlete.ID,
+		FromDay:   pgDate(from),
+		ToDay:     pgDate(to.AddDate(0, 0, -1)),
+	})
+	if err != nil {
+		log.Printf("list planned workouts for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "failed to load plan", http.StatusInternalServerError)
+		return nil, false
+	}
+	completed, err := s.Q.ListWorkoutsBetween(r.Context(), db.ListWorkoutsBetweenParams{
+		AthleteID: athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		log.Printf("list workouts for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "failed to load workouts", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	events, err := s.Q.ListAthleteEvents(r.Context(), athlete.ID)
+	if err != nil {
+		log.Printf("list events for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "failed to load season", http.StatusInternalServerError)
+		return nil, false
+	}
+	phases, err := s.Q.ListTrainingPhases(r.Context(), athlete.ID)
+	if err != nil {
+		log.Printf("list phases for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "failed to load season", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	weeks := buildCalendar(from, to, today, func(d time.Time) bool {
+		return view == "week" || d.Month() == anchor.Month()
+	})
+	byKey := map[string]*calendarDay{}
+	for _, wk := range weeks {
+		for _, d := range wk.Days {
+			byKey[d.Key] = d
+		}
+		// Label the week with the phase covering most of it.
+		if p, ok := season.At(phasesOf(phases), calendarDate(wk.Start.AddDate(0, 0, 3))); ok {
+			wk.Phase = p.Kind
+		}
+	}
+	for _, e := range events {
+		if d, ok := byKey[e.Day.Time.Format(time.DateOnly)]; ok {
+			d.Events = append(d.Events, e)
+		}
+	}
+	matched := map[uuid.UUID]bool{}
+	for _, p := range planned {
+		if d, ok := byKey[p.Day.Time.Format(time.DateOnly)]; ok {
+			d.Planned = append(d.Planned, plannedEntry{
+				PlannedWorkout: p,
+				State:          compliance.Effective(p.Status, d.Date, today),
+			})
+		}
+		if p.WorkoutID.Valid {
+			matched[p.WorkoutID.Bytes] = true
+		}
+	}
+	for _, c := range completed {
+		if d, ok := byKey[c.StartedAt.Time.In(loc).Format(time.DateOnly)]; ok {
+			d.Completed = append(d.Completed, completedEntry{
+				ListWorkoutsBetweenRow: c,
+				Unplanned:              !matched[c.ID],
+			})
+		}
+	}
+	for _, wk := range weeks {
+		wk.total()
+	}
+
+	return map[string]any{
+		"Athlete": athlete,
+		"View":    view,
+		"Label":   label,
+		"Weeks":   weeks,
+		"Prev":    prev.Format(time.DateOnly),
+		"Next":    next.Format(time.DateOnly),
+		"Anchor":  anchor.Format(time.DateOnly),
+	}, true
+}
+
+// buildCalendar lays out whole weeks from..to (exclusive); both must be
+// local midnights on a Monday.
+func buildCalendar(from, to, today time.Time, inRange func(time.Time) bool) []*calendarWeek {
+	var weeks []*calendarWeek
+	for ws := from; ws.Before(to); ws = ws.AddDate(0, 0, 7) {
+		wk := &calendarWeek{Start: ws}
+		for i := 0; i < 7; i++ {
+			d := ws.AddDate(0, 0, i)
+			wk.Days = append(wk.Days, &calendarDay{
+				Date:    d,
+				Key:     d.Format(time.DateOnly),
+				InRange: inRange(d),
+				Today:   d.Equal(today),
+			})
+		}
+		weeks = append(weeks, wk)
+	}
+	return weeks
+}
+
+func (wk *calendarWeek) total() {
+	for _, d := range wk.Days {
+		for _, p := range d.Planned {
+			if p.TargetDurationSec.Valid {
+				wk.PlannedSec += p.TargetDurationSec.Int32
+			}
+			if p.TargetLoad.Valid {
+				wk.PlannedLoad += p.TargetLoad.Float64
+			}
+		}
+		for _, c := range d.Completed {
+			if c.ReviewStatus == "flagged" || c.ReviewStatus == "rejected" {
+				continue
+			}
+			wk.DoneSec += c.DurationSec
+			if c.Load.Valid {
+				wk.DoneLoad += c.Load.Float64
+			}
+		}
+	}
+}
+
+// weeklyCompliance rolls up each athlete's current local week.
+func weeklyCompliance(athletes []db.Athlete, rows []db.ListPlanComplianceByCoachRow, now time.Time) map[uuid.UUID]compliance.Week {
+	byAthlete := map[uuid.UUID][]db.ListPlanComplianceByCoachRow{}
+	for _, r := range rows {
+		byAthlete[r.AthleteID] = append(byAthlete[r.AthleteID], r)
+	}
+
+	out := make(map[uuid.UUID]compliance.Week, len(athletes))
+	for _, a := range athletes {
+		loc := training.Location(a.Tz)
+		today := training.Day(now, loc)
+		from := training.WeekStart(now, loc)
+		to := from.AddDate(0, 0, 7)
+
+		var sessions []compliance.Session
+		for _, r := range byAthlete[a.ID] {
+			d := r.Day.Time
+			day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+			if day.Before(from) || !day.Before(to) {
+				continue
+			}
+			sessions = append(sessions, compliance.Session{Day: day, Status: r.Status, Score: r.Compliance.Float64})
+		}
+		out[a.ID] = compliance.Summarize(sessions, today)
+	}
+	return out
+}
+
+func (s *Server) handleNewPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+
+	day := r.URL.Query().Get("day")
+	if day == "" {
+		day = training.Day(time.Now(), training.Location(athlete.Tz)).Format(time.DateOnly)
+	}
+
+	s.render(w, "plan_form", map[string]any{
+		"Title":      "Plan a session - " + athlete.Name,
+		"Athlete":    athlete,
+		"Sports":     planSports,
+		"Zones":      planZones,
+		"Form":       plannedForm{Day: day, Sport: "Run"},
+		"PreviewURL": "/athletes/" + athlete.ID.String() + "/plan/preview",
+	})
+}
+
+func (s *Server) handleCreatePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+
+	_ = r.ParseForm()
+	p, msg := plannedFormFrom(r).parse()
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	created, err := s.Q.CreatePlannedWorkout(r.Context(), db.CreatePlannedWorkoutParams{
+		AthleteID:         athlete.ID,
+		Day:               p.Day,
+		Sport:             p.Sport,
+		Title:             p.Title,
+		Description:       p.Description,
+		TargetDurationSec: p.TargetDurationSec,
+		TargetDistanceM:   p.TargetDistanceM,
+		TargetLoad:        p.TargetLoad,
+		TargetZone:        p.TargetZone,
+		Structure:         p.Structure,
+	})
+	if err != nil {
+		log.Printf("create planned workout for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save session", http.StatusInternalServerError)
+		return
+	}
+	s.rematchDays(r, athlete, created.Day)
+
+	http.Redirect(w, r, planURL(athlete.ID, created.Day), http.StatusSeeOther)
+}
+
+func (s *Server) handleEditPlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+
+	data := map[string]any{
+		"Title":      "Edit session - " + athlete.Name,
+		"Athlete":    athlete,
+		"Sports":     planSports,
+		"Zones":      planZones,
+		"Planned":    p,
+		"Form":       plannedFormOf(p),
+		"Structure":  newStructureView(p.Structure, p.Sport),
+		"Formats":    export.Formats,
+		"PreviewURL": "/athletes/" + athlete.ID.String() + "/plan/preview",
+	}
+	if p.ApplicationID.Valid {
+		coachID := uuid.MustParse(r.Context().Value(appmw.CoachIDKey).(string))
+		if t, err := s.Q.GetPlanTemplateByApplication(r.Context(), db.GetPlanTemplateByApplicationParams{ID: p.ApplicationID.Bytes, CoachID: coachID}); err == nil {
+			data["FromTemplate"] = t
+		}
+	}
+	t, err := s.Q.GetPlannedWorkoutCommentThread(r.Context(), pgUUID(p.ID))
+	data["Comments"] = s.commentsView(r.Context(), t, err, athlete, "/athletes/"+athlete.ID.String()+"/plan/"+p.ID.String()+"/comments")
+	s.render(w, "plan_form", data)
+}
+
+// structureView is the rendered form of a structured workout: readable
+// lines plus a step chart.
+type structureView struct {
+	Lines   []string
+	Bars    []structured.Bar
+	Seconds int32
+	Err     string
+}
+
+const chartWidth, chartHeight = 600, 80
+
+func newStructureView(raw []byte, sport string) *structureView {
+	if len(raw) == 0 {
+		return nil
+	}
+	sw, err := structured.Decode(raw)
+	if err != nil {
+		return &structureView{Err: err.Error()}
+	}
+	return viewOf(sw, sport)
+}
+
+func viewOf(sw structured.Workout, sport string) *structureView {
+	speed := structured.TypicalSpeed(sport)
+	return &structureView{
+		Lines:   sw.Lines(),
+		Bars:    sw.Chart(chartWidth, chartHeight, speed),
+		Seconds: int32(sw.Seconds(speed)),
+	}
+}
+
+// handlePreviewStructure validates the structure field as the coach types
+// and returns the rendered preview fragment.
+func (s *Server) handlePreviewStructure(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.ownedAthlete(w, r); !ok {
+		return
+	}
+	s.previewStructure(w, r)
+}
+
+func (s *Server) previewStructure(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	src := strings.TrimSpace(r.Form.Get("structure"))
+	var view *structureView
+	if src != "" {
+		sw, err := structured.Parse(src)
+		if err != nil {
+			view = &structureView{Err: err.Error()}
+		} else {
+			view = viewOf(sw, r.Form.Get("sport"))
+		}
+	}
+	s.render(w, "structure_preview", view)
+}
+
+func (s *Server) handleUpdatePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	old, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+
+	_ = r.ParseForm()
+	p, msg := plannedFormFrom(r).parse()
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	n, err := s.Q.UpdatePlannedWorkout(r.Context(), db.UpdatePlannedWorkoutParams{
+		ID:                old.ID,
+		AthleteID:         athlete.ID,
+		Day:               p.Day,
+		Sport:             p.Sport,
+		Title:             p.Title,
+		Description:       p.Description,
+		TargetDurationSec: p.TargetDurationSec,
+		TargetDistanceM:   p.TargetDistanceM,
+		TargetLoad:        p.TargetLoad,
+		TargetZone:        p.TargetZone,
+		Structure:         p.Structure,
+	})
+	if err != nil {
+		log.Printf("update planned workout %s failed: %v", old.ID, err)
+		http.Error(w, "could not save session", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	s.rematchDays(r, athlete, old.Day, p.Day)
+
+	http.Redirect(w, r, planURL(athlete.ID, p.Day), http.StatusSeeOther)
+}
+
+func (s *Server) handleDeletePlannedWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+
+	if _, err := s.Q.DeletePlannedWorkout(r.Context(), db.DeletePlannedWorkoutParams{
+		ID:        p.ID,
+		AthleteID: athlete.ID,
+	}); err != nil {
+		log.Printf("delete planned workout %s failed: %v", p.ID, err)
+		http.Error(w, "could not delete session", http.StatusInternalServerError)
+		return
+	}
+	s.rematchDays(r, athlete, p.Day)
+
+	http.Redirect(w, r, planURL(athlete.ID, p.Day), http.StatusSeeOther)
+}
+
+// rematchDays re-runs plan matching after an edit. Failures only leave a
+// stale status behind, so they are logged rather than surfaced.
+func (s *Server) rematchDays(r *http.Request, athlete db.Athlete, days ...pgtype.Date) {
+	loc := training.Location(athlete.Tz)
+	for _, d := range days {
+		day := time.Date(d.Time.Year(), d.Time.Month(), d.Time.Day(), 0, 0, 0, 0, loc)
+		if err := compliance.MatchDay(r.Context(), s.Q, athlete.ID, day, loc); err != nil {
+			log.Printf("match plan for athlete %s on %s failed: %v", athlete.ID, day.Format(time.DateOnly), err)
+		}
+	}
+}
+
+// plannedWorkout loads the session named in the URL, scoped to the athlete.
+func (s *Server) plannedWorkout(w http.ResponseWriter, r *http.Request, athleteID uuid.UUID) (db.PlannedWorkout, bool) {
+	pid, err := uuid.Parse(chi.URLParam(r, "planID"))
+	if err != nil {
+		http.Error(w, "invalid session ID", http.StatusBadRequest)
+		return db.PlannedWorkout{}, false
+	}
+
+	p, err := s.Q.GetPlannedWorkout(r.Context(), db.GetPlannedWorkoutParams{ID: pid, AthleteID: athleteID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "session not found", http.StatusNotFound)
+		} else {
+			log.Printf("get planned workout %s failed: %v", pid, err)
+			http.Error(w, "could not load session", http.StatusInternalServerError)
+		}
+		return db.PlannedWorkout{}, false
+	}
+	return p, true
+}
+
+// plannedForm holds the raw form values so the edit page can be prefilled.
+type plannedForm struct {
+	Day         string
+	Sport       string
+	Title       string
+	Description string
+	Duration    string // h:mm:ss or mm:ss
+	DistanceKm  string
+	Load        string
+	Zone        string
+	Structure   string // compact syntax, see package structured
+}
+
+type plannedValues struct {
+	Day               pgtype.Date
+	Sport             string
+	Title             string
+	Description       pgtype.Text
+	TargetDurationSec pgtype.Int4
+	TargetDistanceM   pgtype.Float8
+	TargetLoad        pgtype.Float8
+	TargetZone        pgtype.Int4
+	Structure         []byte
+}
+
+func plannedFormFrom(r *http.Request) plannedForm {
+	return plannedForm{
+		Day:         strings.TrimSpace(r.Form.Get("day")),
+		Sport:       strings.TrimSpace(r.Form.Get("sport")),
+		Title:       strings.TrimSpace(r.Form.Get("title")),
+		Description: strings.TrimSpace(r.Form.Get("description")),
+		Duration:    strings.TrimSpace(r.Form.Get("duration")),
+		DistanceKm:  strings.TrimSpace(r.Form.Get("distance_km")),
+		Load:        strings.TrimSpace(r.Form.Get("load")),
+		Zone:        strings.TrimSpace(r.Form.Get("zone")),
+		Structure:   strings.TrimSpace(r.Form.Get("structure")),
+	}
+}
+
+func plannedFormOf(p db.PlannedWorkout) plannedForm {
+	f := plannedForm{
+		Day:         p.Day.Time.Format(time.DateOnly),
+		Sport:       p.Sport,
+		Title:       p.Title,
+		Description: p.Description.String,
+	}
+	if p.TargetDurationSec.Valid {
+		f.Duration = formatClock(float64(p.TargetDurationSec.Int32))
+	}
+	if p.TargetDistanceM.Valid {
+		f.DistanceKm = strconv.FormatFloat(p.TargetDistanceM.Float64/1000, 'f', -1, 64)
+	}
+	if p.TargetLoad.Valid {
+		f.Load = strconv.FormatFloat(p.TargetLoad.Float64, 'f', -1, 64)
+	}
+	if p.TargetZone.Valid {
+		f.Zone = strconv.Itoa(int(p.TargetZone.Int32))
+	}
+	if len(p.Structure) > 0 {
+		if sw, err := structured.Decode(p.Structure); err == nil {
+			f.Structure = sw.DSL()
+		}
+	}
+	return f
+}
+
+// parse validates the form, returning a user-facing message on failure.
+func (f plannedForm) parse() (plannedValues, string) {
+	day, err := time.Parse(time.DateOnly, f.Day)
+	if err != nil {
+		return plannedValues{}, "day must be YYYY-MM-DD"
+	}
+	v, msg := f.parseTargets()
+	v.Day = pgtype.Date{Time: day, Valid: true}
+	return v, msg
+}
+
+// parseTargets validates everything but the day, which template sessions
+// give as a week and day of the block instead.
+func (f plannedForm) parseTargets() (plannedValues, string) {
+	var v plannedValues
+
+	if f.Sport == "" {
+		return v, "sport required"
+	}
+	v.Sport = f.Sport
+	if f.Title == "" {
+		return v, "title required"
+	}
+	v.Title = f.Title
+	if f.Description != "" {
+		v.Description = pgtype.Text{String: f.Description, Valid: true}
+	}
+
+	if f.Duration != "" {
+		secs, ok := parseClock(f.Duration)
+		if !ok || secs > 24*3600 {
+			return v, "duration must be h:mm:ss or mm:ss"
+		}
+		v.TargetDurationSec = pgtype.Int4{Int32: int32(secs), Valid: true}
+	}
+	if f.DistanceKm != "" {
+		km, err := strconv.ParseFloat(f.DistanceKm, 64)
+		if err != nil || km <= 0 || km > 1000 {
+			return v, "invalid distance"
+		}
+		v.TargetDistanceM = pgtype.Float8{Float64: math.Round(km * 1000), Valid: true}
+	}
+	if f.Load != "" {
+		load, err := strconv.ParseFloat(f.Load, 64)
+		if err != nil || load < 0 || load > 1000 {
+			return v, "invalid load"
+		}
+		v.TargetLoad = pgtype.Float8{Float64: load, Valid: true}
+	}
+	zone, ok := formInt(f.Zone, 1, 5)
+	if !ok {
+		return v, "zone must be 1-5"
+	}
+	v.TargetZone = zone
+
+	if f.Structure != "" {
+		sw, err := structured.Parse(f.Structure)
+		if err != nil {
+			return v, "structure: " + err.Error()
+		}
+		if v.Structure, err = sw.Encode(); err != nil {
+			return v, "structure: " + err.Error()
+		}
+		// Fill totals the structure pins down exactly.
+		var timed, measured bool
+		for _, st := range sw.Flatten() {
+			timed = timed || st.DurationSec > 0
+			measured = measured || st.DistanceM > 0
+		}
+		if !v.TargetDurationSec.Valid && !measured {
+			v.TargetDurationSec = pgtype.Int4{Int32: int32(sw.Seconds(0)), Valid: true}
+		}
+		if !v.TargetDistanceM.Valid && !timed {
+			v.TargetDistanceM = pgtype.Float8{Float64: sw.Meters(), Valid: true}
+		}
+	}
+	return v, ""
+}
+
+func pgDate(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func planURL(athleteID uuid.UUID, day pgtype.Date) string {
+	return "/athletes/" + athleteID.String() + "/plan?date=" + day.Time.Format(time.DateOnly)
+}
@@ -0,0 +1,354 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/plandraft"
+	"github.com/briangreenhill/coachgpt/internal/plantemplate"
+)
+
+// weekday is a day offered on the draft form, numbered from 1 for Monday.
+type weekday struct {
+	Number int32
+	Name   string
+}
+
+var weekdays = []weekday{{1, "Mon"}, {2, "Tue"}, {3, "Wed"}, {4, "Thu"}, {5, "Fri"}, {6, "Sat"}, {7, "Sun"}}
+
+// dayNames lists the draft's training days, as in "Tue, Thu, Sat".
+func dayNames(days []int32) string {
+	names := make([]string, 0, len(days))
+	for _, d := range days {
+		if d >= 1 && int(d) <= len(weekdays) {
+			names = append(names, weekdays[d-1].Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func planDraftURL(athleteID, draftID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/plan/drafts/" + draftID.String()
+}
+
+// ownedDraft loads the draft from the URL, scoped to the signed-in coach
+// and the athlete, writing the error response itself when it can't.
+func (s *Server) ownedDraft(w http.ResponseWriter, r *http.Request, athlete db.Athlete) (db.PlanDraft, bool) {
+	did, err := uuid.Parse(chi.URLParam(r, "draftID"))
+	if err != nil {
+		http.Error(w, "invalid draft ID", http.StatusBadRequest)
+		return db.PlanDraft{}, false
+	}
+	d, err := s.Q.GetPlanDraft(r.Context(), db.GetPlanDraftParams{ID: did, CoachID: coachUUID(r)})
+	if err == nil && d.AthleteID != athlete.ID {
+		err = pgx.ErrNoRows
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "draft not found", http.StatusNotFound)
+		} else {
+			log.Printf("get plan draft %s failed: %v", did, err)
+			http.Error(w, "could not load draft", http.StatusInternalServerError)
+		}
+		return db.PlanDraft{}, false
+	}
+	return d, true
+}
+
+// enqueuePlanDraft queues generation on the model queue.
+func (s *Server) enqueuePlanDraft(ctx context.Context, id uuid.UUID) error {
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: s.RedisAddr})
+	defer func() {
+		if closeErr := client.Close(); closeErr != nil {
+			log.Printf("Error closing asynq client: %v", closeErr)
+		}
+	}()
+	payload, err := json.Marshal(jobs.GeneratePlanDraftPayload{DraftID: id.String()})
+	if err != nil {
+		return err
+	}
+	_, err = client.EnqueueContext(ctx, asynq.NewTask(jobs.TaskGeneratePlanDraft, payload),
+		asynq.Queue(jobs.QueueCommentary),
+		asynq.MaxRetry(2),
+		asynq.Timeout(5*time.Minute),
+	)
+	return err
+}
+
+// handleCreatePlanDraft asks for a plan: the goal race, the Monday it
+// starts, how many weeks and which days the athlete can train.
+func (s *Server) handleCreatePlanDraft(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	ctx := r.Context()
+
+	var event *db.AthleteEvent
+	if v := r.Form.Get("event_id"); v != "" {
+		eid, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "invalid race", http.StatusBadRequest)
+			return
+		}
+		events, err := s.Q.ListAthleteEvents(ctx, athlete.ID)
+		if err != nil {
+			log.Printf("list events for athlete %s failed: %v", athlete.ID, err)
+			http.Error(w, "could not load races", http.StatusInternalServerError)
+			return
+		}
+		i := slices.IndexFunc(events, func(e db.AthleteEvent) bool { return e.ID == eid })
+		if i < 0 {
+			http.Error(w, "race not found", http.StatusNotFound)
+			return
+		}
+		event = &events[i]
+	}
+
+	start, err := time.Parse(time.DateOnly, r.Form.Get("start"))
+	if err != nil {
+		http.Error(w, "start must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7) // back to Monday
+
+	n, ok := formInt(r.Form.Get("weeks"), 1, plandraft.MaxWeeks)
+	if !ok {
+		http.Error(w, fmt.Sprintf("weeks must be 1-%d", plandraft.MaxWeeks), http.StatusBadRequest)
+		return
+	}
+	weeks := n.Int32
+	if !n.Valid {
+		if event == nil || event.Day.Time.Before(start) {
+			http.Error(w, "give the number of weeks, or a race after the start", http.StatusBadRequest)
+			return
+		}
+		weeks = draftWeeks(start, event.Day.Time)
+	}
+
+	var days []int32
+	for _, v := range r.Form["days"] {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 || d > 7 {
+			http.Error(w, "invalid day", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(days, int32(d)) {
+			days = append(days, int32(d))
+		}
+	}
+	if len(days) == 0 {
+		http.Error(w, "choose at least one training day", http.StatusBadRequest)
+		return
+	}
+	slices.Sort(days)
+
+	notes := strings.TrimSpace(strings.ReplaceAll(r.Form.Get("notes"), "\r\n", "\n"))
+	if len(notes) > plandraft.MaxNotes {
+		http.Error(w, "notes are too long", http.StatusBadRequest)
+		return
+	}
+
+	params := db.CreatePlanDraftParams{
+		CoachID:   athlete.CoachID,
+		AthleteID: athlete.ID,
+		StartDay:  pgDate(start),
+		Weeks:     weeks,
+		Days:      days,
+		Notes:     pgtype.Text{String: notes, Valid: notes != ""},
+	}
+	if event != nil {
+		params.EventID = pgtype.UUID{Bytes: event.ID, Valid: true}
+	}
+	d, err := s.Q.CreatePlanDraft(ctx, params)
+	if err != nil {
+		log.Printf("create plan draft for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save draft", http.StatusInternalServerError)
+		return
+	}
+	if err := s.enqueuePlanDraft(ctx, d.ID); err != nil {
+		log.Printf("enqueue plan draft %s failed: %v", d.ID, err)
+		if err := s.Q.SetPlanDraftError(ctx, db.SetPlanDraftErrorParams{
+			ID:     d.ID,
+			Status: plandraft.StatusFailed,
+			Error:  pgtype.Text{String: "Could not be queued.", Valid: true},
+		}); err != nil {
+			log.Printf("record plan draft %s error failed: %v", d.ID, err)
+		}
+	}
+	http.Redirect(w, r, planDraftURL(athlete.ID, d.ID), http.StatusSeeOther)
+}
+
+// handlePlanDraft shows a draft's progress, and once it's ready the
+// template to edit and publish.
+func (s *Server) handlePlanDraft(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	d, ok := s.ownedDraft(w, r, athlete)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	data := map[string]any{
+		"Title":   "Plan draft - " + athlete.Name,
+		"Athlete": athlete,
+		"Draft":   d,
+		"Days":    dayNames(d.Days),
+		"End":     d.StartDay.Time.AddDate(0, 0, int(d.Weeks)*7-1),
+	}
+
+	if d.EventID.Valid {
+		events, err := s.Q.ListAthleteEvents(ctx, athlete.ID)
+		if err != nil {
+			log.Printf("list events for athlete %s failed: %v", athlete.ID, err)
+		}
+		for _, e := range events {
+			if e.ID == d.EventID.Bytes {
+				data["Event"] = e
+			}
+		}
+	}
+	if d.TemplateID.Valid {
+		t, err := s.Q.GetPlanTemplate(ctx, db.GetPlanTemplateParams{ID: d.TemplateID.Bytes, CoachID: d.CoachID})
+		switch {
+		case err == nil:
+			sessions, err := s.Q.ListPlanTemplateSessions(ctx, t.ID)
+			if err != nil {
+				log.Printf("list template sessions for %s failed: %v", t.ID, err)
+			}
+			data["Template"] = t
+			data["Weeks"] = templateGrid(t.Weeks, sessions)
+		case !errors.Is(err, pgx.ErrNoRows):
+			log.Printf("get plan template %s failed: %v", d.TemplateID.Bytes, err)
+		}
+	}
+	s.render(w, "plan_draft", data)
+}
+
+// handleRetryPlanDraft queues a failed draft again.
+func (s *Server) handleRetryPlanDraft(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	d, ok := s.ownedDraft(w, r, athlete)
+	if !ok {
+		return
+	}
+	n, err := s.Q.RetryPlanDraft(r.Context(), db.RetryPlanDraftParams{ID: d.ID, CoachID: d.CoachID})
+	if err != nil {
+		log.Printf("retry plan draft %s failed: %v", d.ID, err)
+		http.Error(w, "could not retry draft", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "only a failed draft can be retried", http.StatusConflict)
+		return
+	}
+	if err := s.enqueuePlanDraft(r.Context(), d.ID); err != nil {
+		log.Printf("enqueue plan draft %s failed: %v", d.ID, err)
+		http.Error(w, "could not queue draft", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, planDraftURL(athlete.ID, d.ID), http.StatusSeeOther)
+}
+
+// handlePublishPlanDraft applies the draft's template, as the coach has
+// edited it, to the athlete's calendar from the draft's start.
+func (s *Server) handlePublishPlanDraft(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	d, ok := s.ownedDraft(w, r, athlete)
+	if !ok {
+		return
+	}
+	if d.Status != plandraft.StatusReady || !d.TemplateID.Valid {
+		http.Error(w, "only a ready draft can be published", http.StatusConflict)
+		return
+	}
+
+	var days []pgtype.Date
+	errNotReady := errors.New("draft not ready")
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		n, err := q.PublishPlanDraft(r.Context(), db.PublishPlanDraftParams{ID: d.ID, CoachID: d.CoachID})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errNotReady // published by another request
+		}
+		days, err = plantemplate.Apply(r.Context(), q, d.TemplateID.Bytes, athlete.ID, d.StartDay.Time, 1)
+		return err
+	})
+	if errors.Is(err, errNotReady) {
+		http.Error(w, "only a ready draft can be published", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("publish plan draft %s failed: %v", d.ID, err)
+		http.Error(w, "could not publish draft", http.StatusInternalServerError)
+		return
+	}
+
+	s.rematchPast(r, athlete, days)
+
+	http.Redirect(w, r, planURL(athlete.ID, d.StartDay), http.StatusSeeOther)
+}
+
+// handleDeletePlanDraft discards an unpublished draft with its template.
+func (s *Server) handleDeletePlanDraft(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	d, ok := s.ownedDraft(w, r, athlete)
+	if !ok {
+		return
+	}
+	if d.Status == plandraft.StatusPublished {
+		http.Error(w, "a published draft is a template now; delete that instead", http.StatusConflict)
+		return
+	}
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		if d.TemplateID.Valid {
+			if _, err := q.DeletePlanTemplate(r.Context(), db.DeletePlanTemplateParams{ID: d.TemplateID.Bytes, CoachID: d.CoachID}); err != nil {
+				return err
+			}
+		}
+		_, err := q.DeletePlanDraft(r.Context(), db.DeletePlanDraftParams{ID: d.ID, CoachID: d.CoachID})
+		return err
+	})
+	if err != nil {
+		log.Printf("delete plan draft %s failed: %v", d.ID, err)
+		http.Error(w, "could not delete draft", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, seasonURL(athlete.ID), http.StatusSeeOther)
+}
+
+// draftWeeks is the default length of a draft starting on start: up to
+// and including the week of the goal race, capped to what a draft can hold.
+func draftWeeks(start, race time.Time) int32 {
+	return int32(max(1, min(plandraft.MaxWeeks, daysBetween(start, race)/7+1)))
+}
@@ -0,0 +1,334 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/planops"
+	"github.com/briangreenhill/coachgpt/internal/plantemplate"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// errNothingToUndo means the coach has no operation left in effect, or not
+// the one they asked to undo.
+var errNothingToUndo = errors.New("only the most recent bulk edit can be undone")
+
+func bulkURL(athleteID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/plan/bulk"
+}
+
+func (s *Server) handlePlanBulk(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	athletes, err := s.Q.ListAthletesByCoach(r.Context(), athlete.CoachID)
+	if err != nil {
+		log.Printf("list athletes failed: %v", err)
+		http.Error(w, "could not load athletes", http.StatusInternalServerError)
+		return
+	}
+	ops, err := s.Q.ListPlanOperations(r.Context(), athlete.CoachID)
+	if err != nil {
+		log.Printf("list plan operations failed: %v", err)
+		http.Error(w, "could not load history", http.StatusInternalServerError)
+		return
+	}
+	// Undo works as a stack: only the newest operation still in effect.
+	var undoable uuid.UUID
+	for _, op := range ops {
+		if !op.UndoneAt.Valid {
+			undoable = op.ID
+			break
+		}
+	}
+
+	loc := training.Location(athlete.Tz)
+	week := training.WeekStart(time.Now(), loc)
+	s.render(w, "plan_bulk", map[string]any{
+		"Title":        "Bulk edit - " + athlete.Name,
+		"Athlete":      athlete,
+		"Athletes":     athletes,
+		"Operations":   ops,
+		"Undoable":     undoable,
+		"Today":        training.Day(time.Now(), loc).Format(time.DateOnly),
+		"ThisWeek":     week.Format(time.DateOnly),
+		"NextWeek":     week.AddDate(0, 0, 7).Format(time.DateOnly),
+		"MaxShiftDays": planops.MaxShiftDays,
+		"MinPct":       int(plantemplate.MinScale * 100),
+		"MaxPct":       int(plantemplate.MaxScale * 100),
+	})
+}
+
+// runPlanOperation applies op in a transaction together with its undo
+// entry, then re-runs matching for the days it touched. Operations that
+// matched no sessions leave no entry.
+func (s *Server) runPlanOperation(r *http.Request, coachID uuid.UUID, kind string, athletes []db.Athlete, op func(q *db.Queries) (planops.Undo, planops.Touched, string, error)) error {
+	var touched planops.Touched
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		u, t, summary, err := op(q)
+		if err != nil || u.Sessions() == 0 {
+			return err
+		}
+		b, err := u.Encode()
+		if err != nil {
+			return err
+		}
+		if _, err := q.CreatePlanOperation(r.Context(), db.CreatePlanOperationParams{
+			CoachID: coachID,
+			Kind:    kind,
+			Summary: summary,
+			Undo:    b,
+		}); err != nil {
+			return fmt.Errorf("record operation: %w", err)
+		}
+		touched = t
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, a := range athletes {
+		s.rematchPast(r, a, touched[a.ID])
+	}
+	return nil
+}
+
+// rematchPast re-runs matching for the days up to today, the only ones
+// that can already have workouts.
+func (s *Server) rematchPast(r *http.Request, athlete db.Athlete, days []pgtype.Date) {
+	today := pgDate(training.Day(time.Now(), training.Location(athlete.Tz))).Time
+	var past []pgtype.Date
+	for _, d := range days {
+		if !d.Time.After(today) {
+			past = append(past, d)
+		}
+	}
+	s.rematchDays(r, athlete, past...)
+}
+
+func sessions(n int) string {
+	if n == 1 {
+		return "1 session"
+	}
+	return fmt.Sprintf("%d sessions", n)
+}
+
+func (s *Server) handleShiftPlan(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	from, ok := formDate(r.Form.Get("from"))
+	if !ok {
+		http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	n, ok := formInt(r.Form.Get("days"), -planops.MaxShiftDays, planops.MaxShiftDays)
+	if !ok || !n.Valid || n.Int32 == 0 {
+		http.Error(w, fmt.Sprintf("days must be between -%d and %d and not 0", planops.MaxShiftDays, planops.MaxShiftDays), http.StatusBadRequest)
+		return
+	}
+
+	err := s.runPlanOperation(r, athlete.CoachID, planops.KindShift, []db.Athlete{athlete}, func(q *db.Queries) (planops.Undo, planops.Touched, string, error) {
+		u, t, err := planops.Shift(r.Context(), q, athlete.ID, from.Time, int(n.Int32))
+		summary := fmt.Sprintf("Shifted %s for %s from %s by %+d days", sessions(len(u.Before)), athlete.Name, from.Time.Format("Jan 2"), n.Int32)
+		return u, t, summary, err
+	})
+	if err != nil {
+		log.Printf("shift plan for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not shift sessions", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, planURL(athlete.ID, from), http.StatusSeeOther)
+}
+
+// formWeek parses a date field and returns the Monday of its week in the
+// athlete's zone.
+func formWeek(v string, athlete db.Athlete) (time.Time, bool) {
+	loc := training.Location(athlete.Tz)
+	t, err := time.ParseInLocation(time.DateOnly, v, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return training.WeekStart(t, loc), true
+}
+
+func (s *Server) handleCopyPlanWeek(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	src, ok1 := formWeek(r.Form.Get("from_week"), athlete)
+	dst, ok2 := formWeek(r.Form.Get("to_week"), athlete)
+	if !ok1 || !ok2 {
+		http.Error(w, "weeks must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if src.Equal(dst) {
+		http.Error(w, "choose a different week to copy to", http.StatusBadRequest)
+		return
+	}
+
+	err := s.runPlanOperation(r, athlete.CoachID, planops.KindCopyWeek, []db.Athlete{athlete}, func(q *db.Queries) (planops.Undo, planops.Touched, string, error) {
+		u, t, err := planops.CopyWeek(r.Context(), q, athlete.ID, src, []uuid.UUID{athlete.ID}, dst)
+		summary := fmt.Sprintf("Copied %s for %s from the week of %s to the week of %s", sessions(len(u.Created)), athlete.Name, src.Format("Jan 2"), dst.Format("Jan 2"))
+		return u, t, summary, err
+	})
+	if err != nil {
+		log.Printf("copy week for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not copy week", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, planURL(athlete.ID, pgDate(dst))+"&view=week", http.StatusSeeOther)
+}
+
+func (s *Server) handleCopyPlanToAthletes(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	src, ok1 := formWeek(r.Form.Get("from_week"), athlete)
+	dst, ok2 := formWeek(r.Form.Get("to_week"), athlete)
+	if !ok1 || !ok2 {
+		http.Error(w, "weeks must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	var targets []db.Athlete
+	var ids []uuid.UUID
+	for _, v := range r.Form["athlete_id"] {
+		aid, err := uuid.Parse(v)
+		if err != nil || aid == athlete.ID {
+			continue
+		}
+		a, err := s.Q.GetAthlete(r.Context(), aid)
+		if err != nil || a.CoachID != athlete.CoachID {
+			http.Error(w, "athlete not found", http.StatusNotFound)
+			return
+		}
+		targets = append(targets, a)
+		ids = append(ids, a.ID)
+	}
+	if len(targets) == 0 {
+		http.Error(w, "choose at least one other athlete", http.StatusBadRequest)
+		return
+	}
+
+	err := s.runPlanOperation(r, athlete.CoachID, planops.KindCopyAthletes, targets, func(q *db.Queries) (planops.Undo, planops.Touched, string, error) {
+		u, t, err := planops.CopyWeek(r.Context(), q, athlete.ID, src, ids, dst)
+		summary := fmt.Sprintf("Copied %s's week of %s to %d athletes (%s)", athlete.Name, src.Format("Jan 2"), len(ids), sessions(len(u.Created)))
+		return u, t, summary, err
+	})
+	if err != nil {
+		log.Printf("copy week from athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not copy week", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, bulkURL(athlete.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleScalePlan(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	from, ok1 := formDate(r.Form.Get("from"))
+	to, ok2 := formDate(r.Form.Get("to"))
+	if !ok1 || !ok2 {
+		http.Error(w, "dates must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if to.Time.Before(from.Time) {
+		http.Error(w, "range ends before it starts", http.StatusBadRequest)
+		return
+	}
+	minPct, maxPct := int(plantemplate.MinScale*100), int(plantemplate.MaxScale*100)
+	pct, ok := formInt(r.Form.Get("scale_pct"), minPct, maxPct)
+	if !ok || !pct.Valid {
+		http.Error(w, fmt.Sprintf("volume must be %d-%d%%", minPct, maxPct), http.StatusBadRequest)
+		return
+	}
+
+	err := s.runPlanOperation(r, athlete.CoachID, planops.KindScale, []db.Athlete{athlete}, func(q *db.Queries) (planops.Undo, planops.Touched, string, error) {
+		u, t, err := planops.Scale(r.Context(), q, athlete.ID, from.Time, to.Time, float64(pct.Int32)/100)
+		summary := fmt.Sprintf("Scaled %s for %s, %s to %s, to %d%%", sessions(len(u.Before)), athlete.Name, from.Time.Format("Jan 2"), to.Time.Format("Jan 2"), pct.Int32)
+		return u, t, summary, err
+	})
+	if err != nil {
+		log.Printf("scale plan for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not scale sessions", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, planURL(athlete.ID, from), http.StatusSeeOther)
+}
+
+// handleUndoPlanOperation reverts the coach's most recent bulk edit.
+func (s *Server) handleUndoPlanOperation(w http.ResponseWriter, r *http.Request) {
+	opID, err := uuid.Parse(chi.URLParam(r, "operationID"))
+	if err != nil {
+		http.Error(w, "invalid operation ID", http.StatusBadRequest)
+		return
+	}
+	coachID := coachUUID(r)
+
+	var touched planops.Touched
+	err = s.inTx(r.Context(), func(q *db.Queries) error {
+		op, err := q.GetLatestPlanOperation(r.Context(), coachID)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && op.ID != opID) {
+			return errNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+		u, err := planops.DecodeUndo(op.Undo)
+		if err != nil {
+			return err
+		}
+		if touched, err = planops.Revert(r.Context(), q, u, op.CreatedAt.Time); err != nil {
+			return err
+		}
+		return q.MarkPlanOperationUndone(r.Context(), op.ID)
+	})
+	if errors.Is(err, errNothingToUndo) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, planops.ErrEditedSince) {
+		http.Error(w, planops.ErrEditedSince.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("undo plan operation %s failed: %v", opID, err)
+		http.Error(w, "could not undo", http.StatusInternalServerError)
+		return
+	}
+
+	for aid, days := range touched {
+		a, err := s.Q.GetAthlete(r.Context(), aid)
+		if err != nil {
+			continue
+		}
+		s.rematchPast(r, a, days)
+	}
+
+	// Return to the bulk page the undo was clicked on.
+	if aid, err := uuid.Parse(r.FormValue("athlete_id")); err == nil {
+		http.Redirect(w, r, bulkURL(aid), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
@@ -0,0 +1,542 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+	"github.com/briangreenhill/coachgpt/internal/plandraft"
+	"github.com/briangreenhill/coachgpt/internal/plantemplate"
+)
+
+// templateWeek is one row of the template grid.
+type templateWeek struct {
+	Number int
+	Days   [plantemplate.DaysPerWeek]templateDay
+	Sec    int32
+	Load   float64
+}
+
+type templateDay struct {
+	Number   int
+	Sessions []db.PlanTemplateSession
+}
+
+func templateGrid(weeks int32, sessions []db.PlanTemplateSession) []templateWeek {
+	grid := make([]templateWeek, weeks)
+	for i := range grid {
+		grid[i].Number = i + 1
+		for d := range grid[i].Days {
+			grid[i].Days[d].Number = d + 1
+		}
+	}
+	for _, s := range sessions {
+		if s.Week < 1 || int(s.Week) > len(grid) || s.Day < 1 || s.Day > plantemplate.DaysPerWeek {
+			continue
+		}
+		wk := &grid[s.Week-1]
+		day := &wk.Days[s.Day-1]
+		day.Sessions = append(day.Sessions, s)
+		wk.Sec += s.TargetDurationSec.Int32
+		wk.Load += s.TargetLoad.Float64
+	}
+	return grid
+}
+
+func coachUUID(r *http.Request) uuid.UUID {
+	return uuid.MustParse(r.Context().Value(appmw.CoachIDKey).(string))
+}
+
+func templateURL(id uuid.UUID) string {
+	return "/plan-templates/" + id.String()
+}
+
+// ownedTemplate loads the template from the URL, scoped to the signed-in
+// coach, writing the error response itself when it can't.
+func (s *Server) ownedTemplate(w http.ResponseWriter, r *http.Request) (db.PlanTemplate, bool) {
+	tid, err := uuid.Parse(chi.URLParam(r, "templateID"))
+	if err != nil {
+		http.Error(w, "invalid template ID", http.StatusBadRequest)
+		return db.PlanTemplate{}, false
+	}
+	t, err := s.Q.GetPlanTemplate(r.Context(), db.GetPlanTemplateParams{ID: tid, CoachID: coachUUID(r)})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "template not found", http.StatusNotFound)
+		} else {
+			log.Printf("get plan template %s failed: %v", tid, err)
+			http.Error(w, "could not load template", http.StatusInternalServerError)
+		}
+		return db.PlanTemplate{}, false
+	}
+	return t, true
+}
+
+// templateSession loads the session named in the URL, scoped to the template.
+func (s *Server) templateSession(w http.ResponseWriter, r *http.Request, templateID uuid.UUID) (db.PlanTemplateSession, bool) {
+	sid, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		http.Error(w, "invalid session ID", http.StatusBadRequest)
+		return db.PlanTemplateSession{}, false
+	}
+	ts, err := s.Q.GetPlanTemplateSession(r.Context(), db.GetPlanTemplateSessionParams{ID: sid, TemplateID: templateID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "session not found", http.StatusNotFound)
+		} else {
+			log.Printf("get template session %s failed: %v", sid, err)
+			http.Error(w, "could not load session", http.StatusInternalServerError)
+		}
+		return db.PlanTemplateSession{}, false
+	}
+	return ts, true
+}
+
+func (s *Server) handlePlanTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := s.Q.ListPlanTemplatesByCoach(r.Context(), coachUUID(r))
+	if err != nil {
+		log.Printf("list plan templates failed: %v", err)
+		http.Error(w, "could not load templates", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "plan_templates", map[string]any{
+		"Title":     "Plan templates",
+		"Templates": templates,
+		"MaxWeeks":  plantemplate.MaxWeeks,
+	})
+}
+
+// templateForm parses the name, description and weeks fields.
+func templateForm(r *http.Request) (name string, desc pgtype.Text, weeks int32, msg string) {
+	name = strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		return "", desc, 0, "name required"
+	}
+	if d := strings.TrimSpace(r.Form.Get("description")); d != "" {
+		desc = pgtype.Text{String: d, Valid: true}
+	}
+	n, ok := formInt(r.Form.Get("weeks"), 1, plantemplate.MaxWeeks)
+	if !ok || !n.Valid {
+		return "", desc, 0, fmt.Sprintf("weeks must be 1-%d", plantemplate.MaxWeeks)
+	}
+	return name, desc, n.Int32, ""
+}
+
+func (s *Server) handleCreatePlanTemplate(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	name, desc, weeks, msg := templateForm(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	t, err := s.Q.CreatePlanTemplate(r.Context(), db.CreatePlanTemplateParams{
+		CoachID:     coachUUID(r),
+		Name:        name,
+		Description: desc,
+		Weeks:       weeks,
+	})
+	if err != nil {
+		log.Printf("create plan template failed: %v", err)
+		http.Error(w, "could not save template", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, templateURL(t.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handlePlanTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := s.Q.ListPlanTemplateSessions(r.Context(), t.ID)
+	if err != nil {
+		log.Printf("list template sessions for %s failed: %v", t.ID, err)
+		http.Error(w, "could not load template", http.StatusInternalServerError)
+		return
+	}
+	apps, err := s.Q.ListPlanTemplateApplications(r.Context(), t.ID)
+	if err != nil {
+		log.Printf("list applications for %s failed: %v", t.ID, err)
+		http.Error(w, "could not load template", http.StatusInternalServerError)
+		return
+	}
+	athletes, err := s.Q.ListAthletesByCoach(r.Context(), t.CoachID)
+	if err != nil {
+		log.Printf("list athletes failed: %v", err)
+		http.Error(w, "could not load athletes", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	data := map[string]any{
+		"Title":        "Template - " + t.Name,
+		"Template":     t,
+		"Weeks":        templateGrid(t.Weeks, sessions),
+		"Applications": apps,
+		"Athletes":     athletes,
+		"Today":        time.Now().Format(time.DateOnly),
+		"Updated":      q.Get("updated"),
+		"Created":      q.Get("created"),
+		"MaxWeeks":     plantemplate.MaxWeeks,
+	}
+
+	// A template drafted for one athlete is published from here once edited.
+	d, err := s.Q.GetPlanDraftByTemplate(r.Context(), db.GetPlanDraftByTemplateParams{
+		TemplateID: pgtype.UUID{Bytes: t.ID, Valid: true},
+		CoachID:    t.CoachID,
+	})
+	switch {
+	case err == nil && d.Status != plandraft.StatusPublished:
+		data["Draft"] = d
+		for _, a := range athletes {
+			if a.ID == d.AthleteID {
+				data["DraftAthlete"] = a
+			}
+		}
+	case err != nil && !errors.Is(err, pgx.ErrNoRows):
+		log.Printf("get draft for template %s failed: %v", t.ID, err)
+	}
+	s.render(w, "plan_template", data)
+}
+
+func (s *Server) handleUpdatePlanTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	name, desc, weeks, msg := templateForm(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	sessions, err := s.Q.ListPlanTemplateSessions(r.Context(), t.ID)
+	if err != nil {
+		log.Printf("list template sessions for %s failed: %v", t.ID, err)
+		http.Error(w, "could not save template", http.StatusInternalServerError)
+		return
+	}
+	for _, ts := range sessions {
+		if ts.Week > weeks {
+			http.Error(w, fmt.Sprintf("week %d still has sessions; move or delete them first", ts.Week), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := s.Q.UpdatePlanTemplate(r.Context(), db.UpdatePlanTemplateParams{
+		ID:          t.ID,
+		CoachID:     t.CoachID,
+		Name:        name,
+		Description: desc,
+		Weeks:       weeks,
+	}); err != nil {
+		log.Printf("update plan template %s failed: %v", t.ID, err)
+		http.Error(w, "could not save template", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, templateURL(t.ID), http.StatusSeeOther)
+}
+
+// handleDeletePlanTemplate removes the template. Sessions already on
+// athletes' calendars stay and simply lose their link.
+func (s *Server) handleDeletePlanTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.Q.DeletePlanTemplate(r.Context(), db.DeletePlanTemplateParams{ID: t.ID, CoachID: t.CoachID}); err != nil {
+		log.Printf("delete plan template %s failed: %v", t.ID, err)
+		http.Error(w, "could not delete template", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/plan-templates", http.StatusSeeOther)
+}
+
+// templateSessionForm is a session form placed by week and day of the
+// block rather than by date.
+type templateSessionForm struct {
+	plannedForm
+	Week string
+	Day  string
+}
+
+func templateSessionFormFrom(r *http.Request) templateSessionForm {
+	return templateSessionForm{
+		plannedForm: plannedFormFrom(r),
+		Week:        strings.TrimSpace(r.Form.Get("week")),
+		Day:         strings.TrimSpace(r.Form.Get("day")),
+	}
+}
+
+func templateSessionFormOf(ts db.PlanTemplateSession) templateSessionForm {
+	f := templateSessionForm{
+		plannedForm: plannedFormOf(db.PlannedWorkout{
+			Sport:             ts.Sport,
+			Title:             ts.Title,
+			Description:       ts.Description,
+			TargetDurationSec: ts.TargetDurationSec,
+			TargetDistanceM:   ts.TargetDistanceM,
+			TargetLoad:        ts.TargetLoad,
+			TargetZone:        ts.TargetZone,
+			Structure:         ts.Structure,
+		}),
+		Week: strconv.Itoa(int(ts.Week)),
+		Day:  strconv.Itoa(int(ts.Day)),
+	}
+	f.plannedForm.Day = ""
+	return f
+}
+
+func (f templateSessionForm) parse(weeks int32) (week, day int32, v plannedValues, msg string) {
+	wk, ok := formInt(f.Week, 1, int(weeks))
+	if !ok || !wk.Valid {
+		return 0, 0, v, fmt.Sprintf("week must be 1-%d", weeks)
+	}
+	d, ok := formInt(f.Day, 1, plantemplate.DaysPerWeek)
+	if !ok || !d.Valid {
+		return 0, 0, v, "day must be 1-7"
+	}
+	v, msg = f.parseTargets()
+	return wk.Int32, d.Int32, v, msg
+}
+
+func (s *Server) renderTemplateSessionForm(w http.ResponseWriter, t db.PlanTemplate, ts *db.PlanTemplateSession, f templateSessionForm) {
+	title := "Add session - " + t.Name
+	var view *structureView
+	if ts != nil {
+		title = "Edit session - " + t.Name
+		view = newStructureView(ts.Structure, ts.Sport)
+	}
+	s.render(w, "plan_template_session", map[string]any{
+		"Title":      title,
+		"Template":   t,
+		"Session":    ts,
+		"Form":       f,
+		"Sports":     planSports,
+		"Zones":      planZones,
+		"Structure":  view,
+		"PreviewURL": templateURL(t.ID) + "/preview",
+	})
+}
+
+func (s *Server) handleNewTemplateSession(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	f := templateSessionForm{plannedForm: plannedForm{Sport: "Run"}, Week: "1", Day: "1"}
+	if v := r.URL.Query().Get("week"); v != "" {
+		f.Week = v
+	}
+	if v := r.URL.Query().Get("day"); v != "" {
+		f.Day = v
+	}
+	s.renderTemplateSessionForm(w, t, nil, f)
+}
+
+func (s *Server) handleCreateTemplateSession(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	week, day, v, msg := templateSessionFormFrom(r).parse(t.Weeks)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if _, err := s.Q.CreatePlanTemplateSession(r.Context(), db.CreatePlanTemplateSessionParams{
+		TemplateID:        t.ID,
+		Week:              week,
+		Day:               day,
+		Sport:             v.Sport,
+		Title:             v.Title,
+		Description:       v.Description,
+		TargetDurationSec: v.TargetDurationSec,
+		TargetDistanceM:   v.TargetDistanceM,
+		TargetLoad:        v.TargetLoad,
+		TargetZone:        v.TargetZone,
+		Structure:         v.Structure,
+	}); err != nil {
+		log.Printf("create template session for %s failed: %v", t.ID, err)
+		http.Error(w, "could not save session", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, templateURL(t.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleEditTemplateSession(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	ts, ok := s.templateSession(w, r, t.ID)
+	if !ok {
+		return
+	}
+	s.renderTemplateSessionForm(w, t, &ts, templateSessionFormOf(ts))
+}
+
+func (s *Server) handleUpdateTemplateSession(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	ts, ok := s.templateSession(w, r, t.ID)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	week, day, v, msg := templateSessionFormFrom(r).parse(t.Weeks)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if _, err := s.Q.UpdatePlanTemplateSession(r.Context(), db.UpdatePlanTemplateSessionParams{
+		ID:                ts.ID,
+		TemplateID:        t.ID,
+		Week:              week,
+		Day:               day,
+		Sport:             v.Sport,
+		Title:             v.Title,
+		Description:       v.Description,
+		TargetDurationSec: v.TargetDurationSec,
+		TargetDistanceM:   v.TargetDistanceM,
+		TargetLoad:        v.TargetLoad,
+		TargetZone:        v.TargetZone,
+		Structure:         v.Structure,
+	}); err != nil {
+		log.Printf("update template session %s failed: %v", ts.ID, err)
+		http.Error(w, "could not save session", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, templateURL(t.ID), http.StatusSeeOther)
+}
+
+// handleDeleteTemplateSession removes a session from the template and,
+// when asked, the upcoming unmatched copies on athletes' calendars.
+func (s *Server) handleDeleteTemplateSession(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	ts, ok := s.templateSession(w, r, t.ID)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	removeUpcoming := r.Form.Get("remove_upcoming") != ""
+
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		if removeUpcoming {
+			if _, err := q.DeleteUpcomingFromTemplateSession(r.Context(), ts.ID); err != nil {
+				return err
+			}
+		}
+		_, err := q.DeletePlanTemplateSession(r.Context(), db.DeletePlanTemplateSessionParams{ID: ts.ID, TemplateID: t.ID})
+		return err
+	})
+	if err != nil {
+		log.Printf("delete template session %s failed: %v", ts.ID, err)
+		http.Error(w, "could not delete session", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, templateURL(t.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handlePreviewTemplateStructure(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.ownedTemplate(w, r); !ok {
+		return
+	}
+	s.previewStructure(w, r)
+}
+
+// handleApplyPlanTemplate clones the template onto an athlete's calendar.
+func (s *Server) handleApplyPlanTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+
+	aid, err := uuid.Parse(r.Form.Get("athlete_id"))
+	if err != nil {
+		http.Error(w, "choose an athlete", http.StatusBadRequest)
+		return
+	}
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil || athlete.CoachID != t.CoachID {
+		http.Error(w, "athlete not found", http.StatusNotFound)
+		return
+	}
+
+	anchor := r.Form.Get("anchor")
+	if anchor != plantemplate.AnchorStart && anchor != plantemplate.AnchorRace {
+		http.Error(w, "anchor must be start or race", http.StatusBadRequest)
+		return
+	}
+	date, err := time.Parse(time.DateOnly, r.Form.Get("date"))
+	if err != nil {
+		http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	pct, ok := formInt(r.Form.Get("scale_pct"), int(plantemplate.MinScale*100), int(plantemplate.MaxScale*100))
+	if !ok {
+		http.Error(w, fmt.Sprintf("volume must be %.0f-%.0f%%", plantemplate.MinScale*100, plantemplate.MaxScale*100), http.StatusBadRequest)
+		return
+	}
+	scale := 1.0
+	if pct.Valid {
+		scale = float64(pct.Int32) / 100
+	}
+
+	start := plantemplate.Start(date, anchor, int(t.Weeks))
+	var days []pgtype.Date
+	err = s.inTx(r.Context(), func(q *db.Queries) error {
+		var err error
+		days, err = plantemplate.Apply(r.Context(), q, t.ID, athlete.ID, start, scale)
+		return err
+	})
+	if err != nil {
+		log.Printf("apply template %s to athlete %s failed: %v", t.ID, athlete.ID, err)
+		http.Error(w, "could not apply template", http.StatusInternalServerError)
+		return
+	}
+
+	s.rematchPast(r, athlete, days)
+
+	http.Redirect(w, r, planURL(athlete.ID, pgDate(start)), http.StatusSeeOther)
+}
+
+// handlePushPlanTemplate copies template changes onto the upcoming sessions
+// created from it.
+func (s *Server) handlePushPlanTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.ownedTemplate(w, r)
+	if !ok {
+		return
+	}
+	var updated, created int
+	err := s.inTx(r.Context(), func(q *db.Queries) error {
+		var err error
+		updated, created, err = plantemplate.Push(r.Context(), q, t.ID, time.Now())
+		return err
+	})
+	if err != nil {
+		log.Printf("push template %s failed: %v", t.ID, err)
+		http.Error(w, "could not update athletes' sessions", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s?updated=%d&created=%d", templateURL(t.ID), updated, created), http.StatusSeeOther)
+}
@@ -0,0 +1,420 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/briangreenhill/coachgpt/internal/commentary"
+	"github.com/briangreenhill/coachgpt/internal/comments"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/export"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/wellness"
+)
+
+// The athlete portal is the athlete's own view of their plan, workouts and
+// the conversation with their coach. Athletes sign in with a magic link
+// scoped to the athlete table and held under their own session key, and
+// every page is limited to the signed-in athlete's data.
+
+const maxAthleteName = 100
+
+func (s *Server) athleteSessionToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := s.Sess.GetString(r.Context(), "athlete_id"); id != "" {
+			r = r.WithContext(context.WithValue(r.Context(), appmw.AthleteIDKey, id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func athleteUUID(r *http.Request) uuid.UUID {
+	return uuid.MustParse(r.Context().Value(appmw.AthleteIDKey).(string))
+}
+
+// portalAthlete loads the signed-in athlete, signing them out when the
+// athlete has since been removed.
+func (s *Server) portalAthlete(w http.ResponseWriter, r *http.Request) (db.Athlete, bool) {
+	athlete, err := s.Q.GetAthlete(r.Context(), athleteUUID(r))
+	if errors.Is(err, pgx.ErrNoRows) {
+		s.Sess.Remove(r.Context(), "athlete_id")
+		http.Redirect(w, r, "/athlete/login", http.StatusSeeOther)
+		return db.Athlete{}, false
+	}
+	if err != nil {
+		log.Printf("get athlete %s failed: %v", athleteUUID(r), err)
+		http.Error(w, "could not load athlete", http.StatusInternalServerError)
+		return db.Athlete{}, false
+	}
+	return athlete, true
+}
+
+// portalWorkout loads the workout from the URL, scoped to the athlete.
+func (s *Server) portalWorkout(w http.ResponseWriter, r *http.Request, athlete db.Athlete) (db.GetCoachAthleteWorkoutRow, bool) {
+	wid, err := uuid.Parse(chi.URLParam(r, "workoutID"))
+	if err != nil {
+		http.Error(w, "invalid workout ID", http.StatusBadRequest)
+		return db.GetCoachAthleteWorkoutRow{}, false
+	}
+	wo, err := s.Q.GetCoachAthleteWorkout(r.Context(), db.GetCoachAthleteWorkoutParams{
+		ID:        wid,
+		AthleteID: athlete.ID,
+		CoachID:   athlete.CoachID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "workout not found", http.StatusNotFound)
+		} else {
+			log.Printf("get workout %s failed: %v", wid, err)
+			http.Error(w, "could not load workout", http.StatusInternalServerError)
+		}
+		return db.GetCoachAthleteWorkoutRow{}, false
+	}
+	return wo, true
+}
+
+func portalWorkoutURL(workoutID uuid.UUID) string {
+	return "/athlete/workouts/" + workoutID.String()
+}
+
+func portalPlannedURL(planID uuid.UUID) string {
+	return "/athlete/plan/" + planID.String()
+}
+
+// portalComments lists a thread for the athlete's pages.
+func (s *Server) portalComments(ctx context.Context, t db.CommentThread, err error, athlete db.Athlete, action string) commentsView {
+	v := commentsView{
+		Comments: s.threadComments(ctx, t, err),
+		Action:   action,
+		Viewer:   comments.AuthorAthlete,
+		Other:    "Your coach",
+	}
+	if coach, err := s.Q.GetCoach(ctx, athlete.CoachID); err == nil {
+		v.Other = coachName(coach)
+	} else {
+		log.Printf("get coach %s failed: %v", athlete.CoachID, err)
+	}
+	return v
+}
+
+// ---- Sign-in
+
+func (s *Server) handleAthleteLogin(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "athlete_login", map[string]any{"Title": "Athlete sign in"})
+}
+
+// handleAthleteMagicLink emails a sign-in link to the athlete with the
+// address. The page is the same whether or not there is one, so the form
+// can't be used to find out who is coached here. Only in development does
+// it show the link, to sign in without a mail server.
+func (s *Server) handleAthleteMagicLink(w http.ResponseWriter, r *http.Request) {
+	emailAddr := strings.TrimSpace(r.FormValue("email"))
+	if emailAddr == "" {
+		http.Error(w, "email required", http.StatusBadRequest)
+		return
+	}
+
+	data := map[string]any{"Title": "Magic Link Sent", "Email": emailAddr}
+	athlete, err := s.Q.GetAthleteByEmail(r.Context(), emailAddr)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		log.Printf("[auth] no athlete with email %s", emailAddr)
+	case err != nil:
+		log.Printf("[auth] athlete lookup failed for %s: %v", emailAddr, err)
+		http.Error(w, "could not issue link", http.StatusInternalServerError)
+		return
+	default:
+		link := s.AthleteMagic.URL(athlete.Email.String, 2*time.Hour)
+		m, err := email.Render(athlete.Email.String, outbox.KindAthleteSignIn, map[string]any{"URL": link})
+		if err == nil {
+			err = s.queueEmail(r.Context(), outbox.KindAthleteSignIn, m, outbox.Owner{CoachID: pgUUID(athlete.CoachID), AthleteID: pgUUID(athlete.ID)})
+		}
+		if err != nil {
+			log.Printf("queue athlete magic link email to %s failed: %v", emailAddr, err)
+		}
+		if s.Dev {
+			log.Printf("[auth] athlete magic link for %s: %s", emailAddr, link)
+			data["URL"] = link
+		}
+	}
+	s.render(w, "magic_sent", data)
+}
+
+func (s *Server) handleAthleteCallback(w http.ResponseWriter, r *http.Request) {
+	tok := r.URL.Query().Get("token")
+	if un, err := url.QueryUnescape(tok); err == nil {
+		tok = un
+	}
+
+	emailAddr, err := s.AthleteMagic.Verify(tok)
+	if err != nil {
+		log.Printf("[auth] athlete verify failed: %v", err)
+		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	athlete, err := s.Q.GetAthleteByEmail(r.Context(), emailAddr)
+	if err != nil {
+		log.Printf("[auth] athlete lookup failed for %s: %v", emailAddr, err)
+		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+	if err := s.Sess.RenewToken(r.Context()); err != nil {
+		log.Printf("[auth] renew session failed: %v", err)
+		http.Error(w, "could not sign in", http.StatusInternalServerError)
+		return
+	}
+	s.Sess.Put(r.Context(), "athlete_id", athlete.ID.String())
+	http.Redirect(w, r, portalNext(r.URL.Query().Get("next")), http.StatusFound)
+}
+
+// portalNext is where to land after signing in: next when it's a portal
+// path, so a link can't send the athlete off-site, and the calendar
+// otherwise.
+func portalNext(next string) string {
+	if next == "/athlete" || (strings.HasPrefix(next, "/athlete/") && !strings.Contains(next, "//") && !strings.Contains(next, `\`)) {
+		return next
+	}
+	return "/athlete"
+}
+
+func (s *Server) handleAthleteLogout(w http.ResponseWriter, r *http.Request) {
+	s.Sess.Remove(r.Context(), "athlete_id")
+	http.Redirect(w, r, "/athlete/login", http.StatusSeeOther)
+}
+
+// ---- Pages
+
+func (s *Server) handlePortalCalendar(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	data, ok := s.calendarData(w, r, athlete)
+	if !ok {
+		return
+	}
+	data["Title"] = "My plan"
+	data["Formats"] = export.Formats
+	s.render(w, "athlete_calendar", data)
+}
+
+func (s *Server) handlePortalWorkouts(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	workouts, err := s.Q.ListWorkoutsByAthlete(r.Context(), db.ListWorkoutsByAthleteParams{
+		AthleteID: athlete.ID,
+		Limit:     50,
+	})
+	if err != nil {
+		log.Printf("failed to list workouts for athlete %s: %v", athlete.ID, err)
+		http.Error(w, "failed to load workouts", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "athlete_workouts", map[string]any{
+		"Title":    "My workouts",
+		"Athlete":  athlete,
+		"Workouts": workouts,
+	})
+}
+
+// handlePortalWorkout shows a completed workout with the coach's note, once
+// it is approved, and the comments on it.
+func (s *Server) handlePortalWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.portalWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	laps, err := s.Q.ListWorkoutLaps(ctx, wo.ID)
+	if err != nil {
+		log.Printf("list laps for workout %s failed: %v", wo.ID, err)
+	}
+	data := map[string]any{
+		"Title":   "Workout",
+		"Athlete": athlete,
+		"Workout": wo,
+		"Laps":    commentary.Laps(laps, commentary.FootSport(wo.Sport)),
+	}
+
+	plan, err := s.Q.GetPlannedWorkoutForWorkout(ctx, pgUUID(wo.ID))
+	switch {
+	case err == nil:
+		data["Plan"] = plan
+	case !errors.Is(err, pgx.ErrNoRows):
+		log.Printf("get plan for workout %s failed: %v", wo.ID, err)
+	}
+
+	note, err := s.Q.GetWorkoutCommentary(ctx, wo.ID)
+	switch {
+	case err == nil && note.Status == commentary.StatusApproved:
+		data["Note"] = note.Body
+	case err != nil && !errors.Is(err, pgx.ErrNoRows):
+		log.Printf("get commentary for workout %s failed: %v", wo.ID, err)
+	}
+
+	data["Feedback"] = s.workoutFeedback(ctx, wo.ID, wo.DurationSec)
+	data["RPEScale"] = rpeScale
+	data["PainAreas"] = wellness.PainAreas
+
+	t, err := s.Q.GetWorkoutCommentThread(ctx, pgUUID(wo.ID))
+	data["Comments"] = s.portalComments(ctx, t, err, athlete, portalWorkoutURL(wo.ID)+"/comments")
+
+	s.render(w, "athlete_workout", data)
+}
+
+func (s *Server) handlePortalWorkoutComment(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.portalWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	body, ok := readComment(w, r)
+	if !ok {
+		return
+	}
+	t, err := comments.WorkoutThread(r.Context(), s.Q, athlete.ID, wo.ID)
+	if err == nil {
+		err = s.postComment(r.Context(), t, athlete, comments.AuthorAthlete, body, comments.ViaWeb)
+	}
+	if err != nil {
+		log.Printf("athlete comment on workout %s failed: %v", wo.ID, err)
+		http.Error(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, portalWorkoutURL(wo.ID)+"#comments", http.StatusSeeOther)
+}
+
+func (s *Server) handlePortalPlanned(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	data := map[string]any{
+		"Title":   p.Title,
+		"Athlete": athlete,
+		"Plan":    p,
+		"Formats": export.Formats,
+	}
+	if st, err := structured.Decode(p.Structure); len(p.Structure) > 0 && err == nil {
+		data["Steps"] = st.Lines()
+	}
+	t, err := s.Q.GetPlannedWorkoutCommentThread(ctx, pgUUID(p.ID))
+	data["Comments"] = s.portalComments(ctx, t, err, athlete, portalPlannedURL(p.ID)+"/comments")
+
+	s.render(w, "athlete_planned", data)
+}
+
+func (s *Server) handlePortalPlannedComment(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	body, ok := readComment(w, r)
+	if !ok {
+		return
+	}
+	t, err := comments.PlannedThread(r.Context(), s.Q, athlete.ID, p.ID)
+	if err == nil {
+		err = s.postComment(r.Context(), t, athlete, comments.AuthorAthlete, body, comments.ViaWeb)
+	}
+	if err != nil {
+		log.Printf("athlete comment on planned session %s failed: %v", p.ID, err)
+		http.Error(w, "could not save comment", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, portalPlannedURL(p.ID)+"#comments", http.StatusSeeOther)
+}
+
+func (s *Server) handlePortalExport(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.plannedWorkout(w, r, athlete.ID)
+	if !ok {
+		return
+	}
+	s.serveExport(w, r, athlete, p)
+}
+
+// ---- Profile
+
+func (s *Server) handlePortalProfile(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, "athlete_profile", map[string]any{
+		"Title":         "My profile",
+		"Athlete":       athlete,
+		"Feed":          s.feedView(s.Q.GetAthleteCalendarFeed(r.Context(), pgUUID(athlete.ID))),
+		"Subscriptions": s.subscriptions(r.Context(), athlete.Email.String),
+		"Saved":         r.URL.Query().Get("saved") != "",
+	})
+}
+
+// handleUpdatePortalProfile saves the athlete's name and time zone. Email
+// is how they sign in, and thresholds are the coach's to set.
+func (s *Server) handleUpdatePortalProfile(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	tz := strings.TrimSpace(r.FormValue("tz"))
+	switch {
+	case name == "":
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	case len(name) > maxAthleteName:
+		http.Error(w, "name is too long", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.LoadLocation(tz); err != nil || tz == "" {
+		http.Error(w, "time zone must be a name like Europe/Berlin", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Q.UpdateAthleteProfile(r.Context(), db.UpdateAthleteProfileParams{
+		ID:   athlete.ID,
+		Name: name,
+		Tz:   tz,
+	}); err != nil {
+		log.Printf("update profile of athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save profile", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/athlete/profile?saved=1", http.StatusSeeOther)
+}
@@ -0,0 +1,232 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+const (
+	trendWeeks  = 26
+	trendWindow = 6 * 7 * 24 * time.Hour
+)
+
+type predictionView struct {
+	Name       string
+	Time       string
+	Riegel     string
+	VDOTTime   string
+	Level      string
+	Percent    int
+	SourceDate string
+	SourceKm   float64
+}
+
+type goalView struct {
+	Distance     string
+	Time         string
+	RequiredVDOT float64
+	Gap          float64
+	Verdict      string
+	Weeks        int // weeks to close the gap at the current trend; 0 if n/a
+}
+
+func (s *Server) handleAthletePredictions(w http.ResponseWriter, r *http.Request) {
+	coachID := r.Context().Value(appmw.CoachIDKey).(string)
+	athleteID := chi.URLParam(r, "athleteID")
+
+	aid, err := uuid.Parse(athleteID)
+	if err != nil {
+		http.Error(w, "invalid athlete ID", http.StatusBadRequest)
+		return
+	}
+
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil {
+		http.Error(w, "athlete not found", http.StatusNotFound)
+		return
+	}
+	if athlete.CoachID.String() != coachID {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -7*trendWeeks)
+	rows, err := s.Q.ListRunEffortsSince(r.Context(), db.ListRunEffortsSinceParams{
+		AthleteID: aid,
+		StartedAt: pgtype.Timestamptz{Time: from.Add(-trendWindow), Valid: true},
+	})
+	if err != nil {
+		log.Printf("failed to list run efforts for athlete %s: %v", athleteID, err)
+		http.Error(w, "failed to load workouts", http.StatusInternalServerError)
+		return
+	}
+
+	efforts := make([]training.Effort, 0, len(rows))
+	var recent []training.Effort
+	for _, row := range rows {
+		e := training.Effort{
+			Start:     row.StartedAt.Time,
+			DistanceM: row.DistanceM.Float64,
+			Seconds:   float64(row.DurationSec),
+			AvgHR:     int(row.AvgHr.Int32),
+		}
+		efforts = append(efforts, e)
+		if e.Start.After(now.Add(-trendWindow)) {
+			recent = append(recent, e)
+		}
+	}
+
+	var predictions []predictionView
+	for _, d := range training.RaceDistances {
+		p, ok := training.Predict(recent, d, now)
+		if !ok {
+			continue
+		}
+		predictions = append(predictions, predictionView{
+			Name:       d.Name,
+			Time:       formatClock(p.Seconds),
+			Riegel:     formatClock(p.RiegelSeconds),
+			VDOTTime:   formatClock(p.VDOTSeconds),
+			Level:      p.Level,
+			Percent:    int(math.Round(p.Confidence * 100)),
+			SourceDate: p.Source.Start.Format("Jan 2"),
+			SourceKm:   p.Source.DistanceM / 1000,
+		})
+	}
+
+	hr := training.HeartRateFor(int(athlete.MaxHr.Int32), int(athlete.RestingHr.Int32))
+	trend := training.Trend(efforts, hr, from, now, trendWindow)
+	current := trend[len(trend)-1]
+	slope := training.VDOTSlope(trend)
+
+	var goal *goalView
+	if gd, gt := r.URL.Query().Get("goal_distance"), r.URL.Query().Get("goal_time"); gd != "" && gt != "" {
+		goal, err = evaluateGoal(gd, gt, current.VDOT, slope)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.render(w, "predictions", map[string]any{
+		"Title":       "Predictions - " + athlete.Name,
+		"Athlete":     athlete,
+		"Predictions": predictions,
+		"VDOT":        current.VDOT,
+		"VO2max":      current.VO2max,
+		"Slope":       slope,
+		"TrendPoints": sparkline(vdotValues(trend)),
+		"Distances":   training.RaceDistances,
+		"Goal":        goal,
+	})
+}
+
+func evaluateGoal(distance, clock string, vdot, slope float64) (*goalView, error) {
+	var target training.RaceDistance
+	for _, d := range training.RaceDistances {
+		if d.Name == distance {
+			target = d
+		}
+	}
+	if target.Meters == 0 {
+		return nil, fmt.Errorf("unknown goal distance")
+	}
+	secs, ok := parseClock(clock)
+	if !ok {
+		return nil, fmt.Errorf("goal time must be h:mm:ss or mm:ss")
+	}
+
+	g := &goalView{Distance: target.Name, Time: formatClock(secs), RequiredVDOT: training.VDOT(target.Meters, secs)}
+	g.Gap = g.RequiredVDOT - vdot
+	switch {
+	case vdot == 0:
+		g.Verdict = "Not enough recent runs to judge"
+	case g.Gap <= 0:
+		g.Verdict = "On track: current fitness already supports this time"
+	case g.Gap <= 1.5:
+		g.Verdict = "Within reach"
+	case g.Gap <= 3:
+		g.Verdict = "Stretch goal"
+	default:
+		g.Verdict = "Unrealistic on current fitness"
+	}
+	if vdot > 0 && g.Gap > 0 && slope > 0 {
+		g.Weeks = int(math.Ceil(g.Gap / slope))
+	}
+	return g, nil
+}
+
+func vdotValues(points []training.TrendPoint) []float64 {
+	out := make([]float64, len(points))
+	for i, p := range points {
+		out[i] = p.VDOT
+	}
+	return out
+}
+
+// sparkline maps evenly spaced values onto a 300x60 SVG polyline. Zero
+// values are gaps.
+func sparkline(values []float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if v > 0 {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if math.IsInf(lo, 1) || len(values) < 2 {
+		return ""
+	}
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	var b strings.Builder
+	for i, v := range values {
+		if v <= 0 {
+			continue
+		}
+		x := float64(i) / float64(len(values)-1) * 300
+		y := 55 - (v-lo)/(hi-lo)*50
+		fmt.Fprintf(&b, "%.1f,%.1f ", x, y)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// formatClock renders seconds as h:mm:ss, or m:ss under an hour.
+func formatClock(secs float64) string {
+	t := int(math.Round(secs))
+	if t >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", t/3600, t%3600/60, t%60)
+	}
+	return fmt.Sprintf("%d:%02d", t/60, t%60)
+}
+
+// parseClock accepts h:mm:ss or mm:ss.
+func parseClock(v string) (float64, bool) {
+	parts := strings.Split(strings.TrimSpace(v), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	total := 0
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return float64(total), total > 0
+}
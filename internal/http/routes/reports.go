@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/report"
+)
+
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := s.Q.ListWeeklyReports(r.Context(), coachUUID(r))
+	if err != nil {
+		log.Printf("list weekly reports failed: %v", err)
+		http.Error(w, "could not load reports", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "reports", map[string]any{"Reports": reports})
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	rid, err := uuid.Parse(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, "invalid report ID", http.StatusBadRequest)
+		return
+	}
+	row, err := s.Q.GetWeeklyReport(r.Context(), db.GetWeeklyReportParams{ID: rid, CoachID: coachUUID(r)})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "report not found", http.StatusNotFound)
+		} else {
+			log.Printf("get weekly report %s failed: %v", rid, err)
+			http.Error(w, "could not load report", http.StatusInternalServerError)
+		}
+		return
+	}
+	var facts report.Facts
+	if err := json.Unmarshal(row.Facts, &facts); err != nil {
+		// The narrative still stands without the numbers behind it
+		log.Printf("decode weekly report %s facts: %v", rid, err)
+	}
+	s.render(w, "report", map[string]any{"Report": row, "Facts": facts})
+}
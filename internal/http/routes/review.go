@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/briangreenhill/coachgpt/internal/compliance"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// reviewActions maps the form action to the stored review status.
+var reviewActions = map[string]string{
+	"accept": "accepted",
+	"reject": "rejected",
+}
+
+func (s *Server) handleReviewWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	wid, err := uuid.Parse(chi.URLParam(r, "workoutID"))
+	if err != nil {
+		http.Error(w, "invalid workout ID", http.StatusBadRequest)
+		return
+	}
+
+	_ = r.ParseForm()
+	status, ok := reviewActions[r.Form.Get("action")]
+	if !ok {
+		http.Error(w, "action must be accept or reject", http.StatusBadRequest)
+		return
+	}
+
+	startedAt, err := s.Q.ReviewWorkout(r.Context(), db.ReviewWorkoutParams{
+		ID:           wid,
+		AthleteID:    athlete.ID,
+		ReviewStatus: status,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "workout not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("review workout %s failed: %v", wid, err)
+		http.Error(w, "could not save review", http.StatusInternalServerError)
+		return
+	}
+
+	// Accepting or rejecting changes which workouts can satisfy the plan.
+	loc := training.Location(athlete.Tz)
+	if err := compliance.MatchDay(r.Context(), s.Q, athlete.ID, startedAt.Time.In(loc), loc); err != nil {
+		log.Printf("match plan after review of workout %s failed: %v", wid, err)
+	}
+
+	redirect := "/athletes/" + athlete.ID.String() + "/workouts"
+	if r.Form.Get("from") == "dashboard" {
+		redirect = "/dashboard"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/oauth2"
 
 	"github.com/briangreenhill/coachgpt/internal/auth"
@@ -28,30 +29,38 @@ import (
 	"github.com/briangreenhill/coachgpt/internal/email"
 	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
 	"github.com/briangreenhill/coachgpt/internal/jobs"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
 )
 
 type Server struct {
-	Router      *chi.Mux
-	Sess        *scs.SessionManager
-	Tmpl        *template.Template
-	Q           *db.Queries    // sqlc queries
-	Magic       auth.MagicLink // magic-link helper
-	BaseURL     string
-	Invite      auth.InviteLink // invite-link helper
-	StravaConf  *oauth2.Config
-	StateSecret string // for signing oauth2 state param
-	RedisAddr   string
-	Email       email.Sender
+	Router       *chi.Mux
+	Sess         *scs.SessionManager
+	Tmpl         *template.Template
+	Q            *db.Queries    // sqlc queries
+	DB           *pgxpool.Pool  // for transactions, see inTx
+	Magic        auth.MagicLink // magic-link helper
+	AthleteMagic auth.MagicLink // magic links for the athlete portal
+	BaseURL      string
+	Invite       auth.InviteLink // invite-link helper
+	StravaConf   *oauth2.Config
+	StateSecret  string // for signing oauth2 state param
+	RedisAddr    string
+	LLM          llm.Provider // metered per coach
+	Inbound      config.EmailConfig
+	Dev          bool // APP_ENV=dev: sign-in pages offer the emailed link too
 }
 
 type ServerOptions struct {
-	Sess   *scs.SessionManager
-	Tmpl   *template.Template
-	Q      *db.Queries
-	Magic  auth.MagicLink
-	Invite auth.InviteLink
-	Cfg    config.Config
-	Email  email.Sender
+	Sess         *scs.SessionManager
+	Tmpl         *template.Template
+	Q            *db.Queries
+	DB           *pgxpool.Pool
+	Magic        auth.MagicLink
+	AthleteMagic auth.MagicLink
+	Invite       auth.InviteLink
+	Cfg          config.Config
+	LLM          llm.Provider
 }
 
 func New(opts ServerOptions) *Server {
@@ -61,7 +70,7 @@ func New(opts ServerOptions) *Server {
 	r.Use(chimw.Logger)
 	r.Use(chimw.Recoverer)
 
-	s := &Server{Router: r, Sess: opts.Sess, Tmpl: opts.Tmpl, Q: opts.Q, Magic: opts.Magic, BaseURL: opts.Cfg.BaseURL, Invite: opts.Invite, StateSecret: opts.Cfg.JWTSecret, RedisAddr: opts.Cfg.RedisAddr, Email: opts.Email}
+	s := &Server{Router: r, Sess: opts.Sess, Tmpl: opts.Tmpl, Q: opts.Q, DB: opts.DB, Magic: opts.Magic, AthleteMagic: opts.AthleteMagic, BaseURL: opts.Cfg.BaseURL, Invite: opts.Invite, StateSecret: opts.Cfg.JWTSecret, RedisAddr: opts.Cfg.RedisAddr, LLM: opts.LLM, Inbound: opts.Cfg.Email, Dev: opts.Cfg.Env == "dev"}
 	s.StravaConf = &oauth2.Config{
 		ClientID:     opts.Cfg.Strava.ClientID,
 		ClientSecret: opts.Cfg.Strava.ClientSecret,
@@ -87,14 +96,104 @@ func New(opts ServerOptions) *Server {
 	r.Get("/oauth/strava/start", s.handleStravaStart)
 	r.Get("/oauth/strava/callback", s.handleStravaCallback)
 	r.Post("/interest", s.handleInterestSubmit)
+	r.Get("/plan/download/{format}", s.handleDownloadPlannedWorkout) // public, but needs token
+	r.Get("/calendar/{token}.ics", s.handleCalendarFeed)             // public, but needs token
+	r.Get("/threads/{token}", s.handleAthleteThread)                 // public, but needs token
+	r.Post("/threads/{token}", s.handleAthleteComment)               // public, but needs token
+	r.Post("/inbound/email", s.handleInboundEmail)                   // public, but needs secret
+	r.Get("/unsubscribe/{kind}", s.handleUnsubscribe)                // public, but needs token
+	r.Post("/unsubscribe/{kind}", s.handleConfirmUnsubscribe)        // public, but needs token
+	r.Get("/athlete/login", s.handleAthleteLogin)
+	r.Post("/athlete/auth/magic-link", s.handleAthleteMagicLink)
+	r.Get("/athlete/auth/callback", s.handleAthleteCallback)
+	r.Post("/athlete/logout", s.handleAthleteLogout)
+
+	// The athlete portal, signed in separately from coaches.
+	r.Group(func(ar chi.Router) {
+		ar.Use(s.athleteSessionToContext)
+		ar.Use(appmw.RequireAthlete)
+		ar.Get("/athlete", s.handlePortalCalendar)
+		ar.Get("/athlete/workouts", s.handlePortalWorkouts)
+		ar.Get("/athlete/workouts/{workoutID}", s.handlePortalWorkout)
+		ar.Post("/athlete/workouts/{workoutID}/comments", s.handlePortalWorkoutComment)
+		ar.Post("/athlete/workouts/{workoutID}/feedback", s.handlePortalWorkoutFeedback)
+		ar.Get("/athlete/checkin", s.handlePortalCheckin)
+		ar.Post("/athlete/checkin", s.handleSavePortalCheckin)
+		ar.Get("/athlete/plan/{planID}", s.handlePortalPlanned)
+		ar.Post("/athlete/plan/{planID}/comments", s.handlePortalPlannedComment)
+		ar.Get("/athlete/plan/{planID}/export/{format}", s.handlePortalExport)
+		ar.Get("/athlete/profile", s.handlePortalProfile)
+		ar.Post("/athlete/profile", s.handleUpdatePortalProfile)
+		ar.Post("/athlete/profile/emails", s.handlePortalEmails)
+	})
 
 	r.Group(func(pr chi.Router) {
 		pr.Use(s.sessionToContext)
 		pr.Use(appmw.RequireAuth)
 		pr.Post("/athletes", s.handleCreateAthlete)
+		pr.Post("/athletes/{athleteID}/invite", s.handleResendInvite)
+		pr.Get("/emails", s.handleEmails)
 		pr.Get("/dashboard", s.handleDashboard)
 		pr.Get("/athletes/{athleteID}/workouts", s.handleAthleteWorkouts)
 		pr.Post("/athletes/{athleteID}/sync", s.handleTriggerSync)
+		pr.Get("/athletes/{athleteID}/predictions", s.handleAthletePredictions)
+		pr.Get("/athletes/{athleteID}/aerobic", s.handleAthleteAerobic)
+		pr.Get("/athletes/{athleteID}/wellness", s.handleAthleteWellness)
+		pr.Post("/athletes/{athleteID}/thresholds", s.handleUpdateThresholds)
+		pr.Get("/athletes/{athleteID}/workouts/{workoutID}", s.handleWorkout)
+		pr.Post("/athletes/{athleteID}/workouts/{workoutID}/review", s.handleReviewWorkout)
+		pr.Post("/athletes/{athleteID}/workouts/{workoutID}/commentary", s.handleReviewCommentary)
+		pr.Post("/athletes/{athleteID}/workouts/{workoutID}/comments", s.handleWorkoutComment)
+		pr.Get("/athletes/{athleteID}/plan", s.handleAthletePlan)
+		pr.Post("/athletes/{athleteID}/chat", s.handleCreateChat)
+		pr.Get("/athletes/{athleteID}/chat/{conversationID}", s.handleChat)
+		pr.Post("/athletes/{athleteID}/chat/{conversationID}", s.handleChatMessage)
+		pr.Get("/athletes/{athleteID}/chat/{conversationID}/stream", s.handleChatStream)
+		pr.Get("/athletes/{athleteID}/plan/new", s.handleNewPlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan", s.handleCreatePlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan/preview", s.handlePreviewStructure)
+		pr.Get("/athletes/{athleteID}/plan/bulk", s.handlePlanBulk)
+		pr.Post("/athletes/{athleteID}/plan/drafts", s.handleCreatePlanDraft)
+		pr.Get("/athletes/{athleteID}/plan/drafts/{draftID}", s.handlePlanDraft)
+		pr.Post("/athletes/{athleteID}/plan/drafts/{draftID}/retry", s.handleRetryPlanDraft)
+		pr.Post("/athletes/{athleteID}/plan/drafts/{draftID}/publish", s.handlePublishPlanDraft)
+		pr.Post("/athletes/{athleteID}/plan/drafts/{draftID}/delete", s.handleDeletePlanDraft)
+		pr.Post("/athletes/{athleteID}/plan/bulk/shift", s.handleShiftPlan)
+		pr.Post("/athletes/{athleteID}/plan/bulk/copy-week", s.handleCopyPlanWeek)
+		pr.Post("/athletes/{athleteID}/plan/bulk/copy-athletes", s.handleCopyPlanToAthletes)
+		pr.Post("/athletes/{athleteID}/plan/bulk/scale", s.handleScalePlan)
+		pr.Get("/athletes/{athleteID}/plan/{planID}/edit", s.handleEditPlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan/{planID}", s.handleUpdatePlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan/{planID}/delete", s.handleDeletePlannedWorkout)
+		pr.Get("/athletes/{athleteID}/plan/{planID}/export/{format}", s.handleExportPlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan/{planID}/send", s.handleSendPlannedWorkout)
+		pr.Post("/athletes/{athleteID}/plan/{planID}/comments", s.handlePlannedComment)
+		pr.Post("/athletes/{athleteID}/calendar-feed", s.handleRegenerateAthleteFeed)
+		pr.Post("/athletes/{athleteID}/calendar-feed/revoke", s.handleRevokeAthleteFeed)
+		pr.Get("/athletes/{athleteID}/season", s.handleAthleteSeason)
+		pr.Post("/athletes/{athleteID}/season/events", s.handleCreateAthleteEvent)
+		pr.Post("/athletes/{athleteID}/season/events/{eventID}/delete", s.handleDeleteAthleteEvent)
+		pr.Post("/athletes/{athleteID}/season/phases", s.handleCreateTrainingPhase)
+		pr.Post("/athletes/{athleteID}/season/phases/{phaseID}/delete", s.handleDeleteTrainingPhase)
+		pr.Get("/reports", s.handleReports)
+		pr.Get("/reports/{reportID}", s.handleReport)
+		pr.Get("/plan-templates", s.handlePlanTemplates)
+		pr.Post("/plan-templates", s.handleCreatePlanTemplate)
+		pr.Get("/plan-templates/{templateID}", s.handlePlanTemplate)
+		pr.Post("/plan-templates/{templateID}", s.handleUpdatePlanTemplate)
+		pr.Post("/plan-templates/{templateID}/delete", s.handleDeletePlanTemplate)
+		pr.Post("/plan-templates/{templateID}/preview", s.handlePreviewTemplateStructure)
+		pr.Post("/plan-templates/{templateID}/apply", s.handleApplyPlanTemplate)
+		pr.Post("/plan-templates/{templateID}/push", s.handlePushPlanTemplate)
+		pr.Get("/plan-templates/{templateID}/sessions/new", s.handleNewTemplateSession)
+		pr.Post("/plan-templates/{templateID}/sessions", s.handleCreateTemplateSession)
+		pr.Get("/plan-templates/{templateID}/sessions/{sessionID}/edit", s.handleEditTemplateSession)
+		pr.Post("/plan-templates/{templateID}/sessions/{sessionID}", s.handleUpdateTemplateSession)
+		pr.Post("/plan-templates/{templateID}/sessions/{sessionID}/delete", s.handleDeleteTemplateSession)
+		pr.Post("/plan-operations/{operationID}/undo", s.handleUndoPlanOperation)
+		pr.Post("/calendar-feed", s.handleRegenerateCoachFeed)
+		pr.Post("/calendar-feed/revoke", s.handleRevokeCoachFeed)
+		pr.Post("/alerts/{alertID}/dismiss", s.handleDismissAlert)
 	})
 
 	return s
@@ -118,6 +217,21 @@ func (s *Server) render(w http.ResponseWriter, name string, data any) {
 	}
 }
 
+// inTx runs fn with queries bound to one transaction, committing only when
+// fn returns nil.
+func (s *Server) inTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	if err := fn(s.Q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	s.render(w, "login", map[string]any{"Title": "Login"})
 }
@@ -138,9 +252,62 @@ func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	alerts, err := s.Q.ListOpenAlertsByCoach(r.Context(), cid)
+	if err != nil {
+		log.Printf("list alerts failed: %v", err)
+		http.Error(w, "could not load alerts", 500)
+		return
+	}
+
+	flagged, err := s.Q.ListFlaggedWorkoutsByCoach(r.Context(), cid)
+	if err != nil {
+		log.Printf("list flagged workouts failed: %v", err)
+		http.Error(w, "could not load workouts", 500)
+		return
+	}
+
+	notes, err := s.Q.ListCommentaryDraftsByCoach(r.Context(), cid)
+	if err != nil {
+		log.Printf("list commentary drafts failed: %v", err)
+		http.Error(w, "could not load workouts", 500)
+		return
+	}
+
+	// Athletes' weeks start on different instants, so fetch a little wider
+	// and let weeklyCompliance cut each athlete's local week out of it.
+	now := time.Now()
+	plan, err := s.Q.ListPlanComplianceByCoach(r.Context(), db.ListPlanComplianceByCoachParams{
+		CoachID: cid,
+		FromDay: pgDate(now.AddDate(0, 0, -8)),
+		ToDay:   pgDate(now.AddDate(0, 0, 8)),
+	})
+	if err != nil {
+		log.Printf("list plan compliance failed: %v", err)
+		http.Error(w, "could not load plan", 500)
+		return
+	}
+
+	invites := make(map[uuid.UUID]db.ListLatestInvitesByCoachRow)
+	rows, err := s.Q.ListLatestInvitesByCoach(r.Context(), pgUUID(cid))
+	if err != nil {
+		log.Printf("list invites failed: %v", err)
+	}
+	for _, row := range rows {
+		invites[row.AthleteID.Bytes] = row
+	}
+
+	feed := s.feedView(s.Q.GetCoachCalendarFeed(r.Context(), cid))
+	feed.Action, feed.Scope = "/calendar-feed", "plans of all your athletes"
+
 	s.render(w, "dashboard", map[string]any{
-		"Title":    "Dashboard",
-		"Athletes": athletes,
+		"Title":      "Dashboard",
+		"Athletes":   athletes,
+		"Alerts":     alerts,
+		"Flagged":    flagged,
+		"Notes":      notes,
+		"Compliance": weeklyCompliance(athletes, plan, now),
+		"Invites":    invites,
+		"Feed":       feed,
 	})
 }
 
@@ -160,28 +327,30 @@ func (s *Server) handleCreateAthlete(w http.ResponseWriter, r *http.Request) {
 	coachID := s.Sess.GetString(r.Context(), "coach_id")
 	coachUUID := uuid.MustParse(coachID)
 
-	a, err := s.Q.CreateAthlete(r.Context(), db.CreateAthleteParams{
-		CoachID: coachUUID, // <- uuid.UUID, not pgtype.UUID
-		Name:    name,
-		Email:   pgtype.Text{String: emailAddr, Valid: true},
-		Tz:      "Europe/Berlin",
+	// The athlete and their invite are saved together, so the invite goes
+	// out even if the mail server is down right now.
+	var a db.Athlete
+	var invite string
+	var sent db.EmailOutbox
+	err := s.inTx(r.Context(), func(q *db.Queries) (err error) {
+		a, err = q.CreateAthlete(r.Context(), db.CreateAthleteParams{
+			CoachID: coachUUID, // <- uuid.UUID, not pgtype.UUID
+			Name:    name,
+			Email:   pgtype.Text{String: emailAddr, Valid: true},
+			Tz:      "Europe/Berlin",
+		})
+		if err != nil {
+			return err
+		}
+		sent, invite, err = s.queueInvite(r.Context(), q, a)
+		return err
 	})
 	if err != nil {
 		log.Printf("create athlete failed: %v", err)
 		http.Error(w, "could not create athlete", 500)
 		return
 	}
-
-	invite := s.Invite.URL(coachID, a.ID.String(), 7*24*time.Hour)
-
-	// Send invite email if sender is configured
-	if s.Email != nil {
-		inviteHTML := "<p>You have been invited to CoachGPT. Click the link below to connect to Strava:</p>" +
-			"<p><a href=\"" + invite + "\">Connect to CoachGPT</a></p>"
-		if err := s.Email.Send(emailAddr, "You're invited to CoachGPT", inviteHTML); err != nil {
-			log.Printf("failed to send invite email to %s: %v", emailAddr, err)
-		}
-	}
+	s.enqueueEmail(r.Context(), sent.ID)
 
 	s.render(w, "invite_created", map[string]any{
 		"Title":     "Invite Link",
@@ -209,6 +378,10 @@ func (s *Server) handleAthleteInvite(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleStravaStart(w http.ResponseWriter, r *http.Request) {
 	aid := r.URL.Query().Get("aid")
+	if _, err := uuid.Parse(aid); err != nil {
+		http.Error(w, "invalid athlete ID", http.StatusBadRequest)
+		return
+	}
 	state := s.signState(aid, time.Now().Add(30*time.Minute))
 
 	authURL := s.StravaConf.AuthCodeURL(
@@ -362,14 +535,6 @@ func (s *Server) handleMagicLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send email via configured sender
-	if s.Email != nil {
-		html := "<p>Click the link below to sign in:</p><p><a href=\"" + url + "\">Sign in</a></p>"
-		if err := s.Email.Send(emailAddr, "Your CoachGPT sign-in link", html); err != nil {
-			log.Printf("failed to send magic link email to %s: %v", emailAddr, err)
-		}
-	}
-
 	log.Printf("[auth] magic link for %s: %s", emailAddr, url)
 	s.render(w, "magic_sent", map[string]any{
 		"Title": "Magic Link Sent", "Email": emailAddr, "URL": url,
@@ -383,17 +548,32 @@ func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
 
 // ---- Magic link flow
 
-func (s *Server) issueMagicLink(ctx context.Context, email string) (string, error) {
-	// Upsert coach so callback can find them
-	_, err := s.Q.UpsertCoachByEmail(ctx, db.UpsertCoachByEmailParams{
-		Email: email,
-		Name:  pgtype.Text{String: "", Valid: false},
-		Tz:    "Europe/Berlin",
+// issueMagicLink upserts the coach, so the callback can find them, and
+// stores the sign-in email with them. It returns the link.
+func (s *Server) issueMagicLink(ctx context.Context, emailAddr string) (string, error) {
+	link := s.Magic.URL(emailAddr, 2*time.Hour) // long TTL while developing
+	var sent db.EmailOutbox
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		coach, err := q.UpsertCoachByEmail(ctx, db.UpsertCoachByEmailParams{
+			Email: emailAddr,
+			Name:  pgtype.Text{String: "", Valid: false},
+			Tz:    "Europe/Berlin",
+		})
+		if err != nil {
+			return err
+		}
+		m, err := email.Render(emailAddr, outbox.KindSignIn, map[string]any{"URL": link})
+		if err != nil {
+			return err
+		}
+		sent, err = outbox.Add(ctx, q, outbox.KindSignIn, m, outbox.Owner{CoachID: pgUUID(coach.ID)})
+		return err
 	})
 	if err != nil {
 		return "", err
 	}
-	return s.Magic.URL(email, 2*time.Hour), nil // long TTL while developing
+	s.enqueueEmail(ctx, sent.ID)
+	return link, nil
 }
 
 func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
@@ -455,14 +635,24 @@ func (s *Server) handleAthleteWorkouts(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("found workouts for athlete=%s: %d", athleteID, len(workouts))
 
+	conversations, err := s.Q.ListChatConversations(r.Context(), db.ListChatConversationsParams{
+		AthleteID: aid,
+		CoachID:   athlete.CoachID,
+	})
+	if err != nil {
+		log.Printf("failed to list chat conversations for athlete %s: %v", athleteID, err)
+	}
+
 	data := struct {
-		Title    string
-		Athlete  db.Athlete
-		Workouts []db.ListWorkoutsByAthleteRow
+		Title         string
+		Athlete       db.Athlete
+		Workouts      []db.ListWorkoutsByAthleteRow
+		Conversations []db.ChatConversation
 	}{
-		Title:    "Workouts - " + athlete.Name,
-		Athlete:  athlete,
-		Workouts: workouts,
+		Title:         "Workouts - " + athlete.Name,
+		Athlete:       athlete,
+		Workouts:      workouts,
+		Conversations: conversations,
 	}
 
 	s.render(w, "workouts", data)
@@ -542,9 +732,12 @@ func (s *Server) handleInterestSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send a notification email to the inbox you check (for now, send to the same address to capture in MailHog)
-	if s.Email != nil {
-		html := "<p>New interest sign-up: " + em + "</p>"
-		_ = s.Email.Send(em, "CoachGPT interest signup", html)
+	m, err := email.Render(em, outbox.KindInterest, map[string]any{"Email": em})
+	if err == nil {
+		err = s.queueEmail(r.Context(), outbox.KindInterest, m, outbox.Owner{})
+	}
+	if err != nil {
+		log.Printf("queue interest email for %s failed: %v", em, err)
 	}
 
 	s.render(w, "interest_submitted", map[string]any{"Title": "Thanks", "Email": em})
@@ -0,0 +1,453 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/plandraft"
+	"github.com/briangreenhill/coachgpt/internal/season"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Chart window around today when the season doesn't reach further, and the
+// history loaded before it so CTL has settled by the first drawn day.
+const (
+	seasonPastDays   = 56
+	seasonFutureDays = 28
+	seasonMaxDays    = 2 * 365
+	ctlWarmupDays    = 120
+)
+
+// Chart geometry in SVG user units.
+const (
+	chartW = 700.0
+	chartH = 200.0
+)
+
+// seasonChart is the CTL chart: actual and planned lines over phase bands,
+// with race markers and today.
+type seasonChart struct {
+	Actual  string
+	Planned string
+	Bands   []chartBand
+	Markers []chartMarker
+	TodayX  float64
+	Max     float64
+	From    time.Time
+	To      time.Time
+}
+
+type chartBand struct {
+	X, W  float64
+	Kind  string
+	Color string
+}
+
+type chartMarker struct {
+	X     float64
+	Label string
+	Color string
+}
+
+var phaseColors = map[string]string{
+	season.PhaseBase:     "#dbeafe",
+	season.PhaseBuild:    "#fde68a",
+	season.PhasePeak:     "#fecaca",
+	season.PhaseTaper:    "#d1fae5",
+	season.PhaseRecovery: "#e5e7eb",
+}
+
+var priorityColors = map[string]string{
+	season.PriorityA: "#dc2626",
+	season.PriorityB: "#ea580c",
+	season.PriorityC: "#6b7280",
+}
+
+// goalStatus is how the athlete tracks towards the next goal race.
+type goalStatus struct {
+	Event         db.AthleteEvent
+	Days          int
+	Status        string
+	PlannedNow    float64
+	ActualNow     float64
+	PlannedRace   float64
+	ProjectedRace float64
+}
+
+// calendarDate is the stored form of a local day: the same date at
+// midnight UTC.
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+func seasonURL(athleteID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/season"
+}
+
+func phasesOf(rows []db.TrainingPhase) []season.Phase {
+	out := make([]season.Phase, len(rows))
+	for i, p := range rows {
+		out[i] = season.Phase{Kind: p.Kind, Start: p.StartDay.Time, End: p.EndDay.Time, Ramp: p.RampPerWeek.Float64}
+	}
+	return out
+}
+
+func eventsOf(rows []db.AthleteEvent) []season.Event {
+	out := make([]season.Event, len(rows))
+	for i, e := range rows {
+		out[i] = season.Event{Name: e.Name, Day: e.Day.Time, Priority: e.Priority}
+	}
+	return out
+}
+
+func (s *Server) handleAthleteSeason(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := s.Q.ListAthleteEvents(r.Context(), athlete.ID)
+	if err != nil {
+		log.Printf("list events for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not load season", http.StatusInternalServerError)
+		return
+	}
+	rows, err := s.Q.ListTrainingPhases(r.Context(), athlete.ID)
+	if err != nil {
+		log.Printf("list phases for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not load season", http.StatusInternalServerError)
+		return
+	}
+	phases := phasesOf(rows)
+
+	loc := training.Location(athlete.Tz)
+	localToday := training.Day(time.Now(), loc)
+	today := calendarDate(localToday)
+
+	from, to := today.AddDate(0, 0, -seasonPastDays), today.AddDate(0, 0, seasonFutureDays)
+	for _, p := range phases {
+		from = minTime(from, p.Start)
+		to = maxTime(to, p.End)
+	}
+	for _, e := range events {
+		to = maxTime(to, e.Day.Time)
+	}
+	from = maxTime(from, today.AddDate(0, 0, -seasonMaxDays))
+	to = minTime(to, today.AddDate(0, 0, seasonMaxDays))
+
+	// Actual CTL from..today, warmed up on the history before from.
+	loads, err := training.DailyLoads(r.Context(), s.Q, athlete, localToday.AddDate(0, 0, -daysBetween(from, today)-ctlWarmupDays), localToday)
+	if err != nil {
+		log.Printf("daily loads for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not load training history", http.StatusInternalServerError)
+		return
+	}
+	ctl := training.CTL(loads, 0)
+	actual := ctl[max(0, len(ctl)-daysBetween(from, today)-1):]
+
+	// The plan starts from the actual CTL on the first phase day, or today
+	// when the season is still ahead.
+	anchor := today
+	if len(phases) > 0 {
+		anchor = maxTime(from, minTime(today, phases[0].Start))
+	}
+	seed := actual[min(len(actual)-1, daysBetween(from, anchor))]
+	planned := season.Project(phases, anchor, to, seed)
+
+	var goal *goalStatus
+	if e, ok := season.NextGoal(eventsOf(events), today); ok {
+		i := slices.IndexFunc(events, func(ev db.AthleteEvent) bool {
+			return ev.Name == e.Name && ev.Day.Time.Equal(e.Day)
+		})
+		goal = &goalStatus{Event: events[i], Days: daysBetween(today, e.Day)}
+		if now, race := daysBetween(anchor, today), daysBetween(anchor, e.Day); race < len(planned) {
+			goal.PlannedNow = planned[now]
+			goal.ActualNow = actual[len(actual)-1]
+			goal.Status = season.Compare(goal.PlannedNow, goal.ActualNow)
+			goal.PlannedRace = planned[race]
+			goal.ProjectedRace = math.Max(0, planned[race]+goal.ActualNow-goal.PlannedNow)
+		}
+	}
+
+	ramps := map[uuid.UUID]float64{}
+	for i, p := range phases {
+		ramps[rows[i].ID] = p.WeeklyRamp()
+	}
+
+	drafts, err := s.Q.ListPlanDrafts(r.Context(), db.ListPlanDraftsParams{AthleteID: athlete.ID, CoachID: athlete.CoachID})
+	if err != nil {
+		log.Printf("list plan drafts for athlete %s failed: %v", athlete.ID, err)
+	}
+	// A new draft starts next Monday and runs up to the goal race.
+	draftStart := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+	draftLen := int32(12)
+	if goal != nil {
+		draftLen = draftWeeks(draftStart, goal.Event.Day.Time)
+	}
+
+	s.render(w, "season", map[string]any{
+		"Title":      "Season - " + athlete.Name,
+		"Athlete":    athlete,
+		"Events":     events,
+		"Phases":     rows,
+		"Ramps":      ramps,
+		"Goal":       goal,
+		"Chart":      buildSeasonChart(from, to, today, anchor, actual, planned, phases, events),
+		"Today":      today.Format(time.DateOnly),
+		"Priorities": season.Priorities,
+		"PhaseKinds": season.PhaseKinds,
+		"Sports":     planSports,
+		"Drafts":     drafts,
+		"DraftStart": draftStart.Format(time.DateOnly),
+		"DraftWeeks": draftLen,
+		"MaxWeeks":   plandraft.MaxWeeks,
+		"Weekdays":   weekdays,
+	})
+}
+
+func buildSeasonChart(from, to, today, anchor time.Time, actual, planned []float64, phases []season.Phase, events []db.AthleteEvent) seasonChart {
+	span := float64(max(1, daysBetween(from, to)))
+	x := func(t time.Time) float64 {
+		return math.Round(float64(daysBetween(from, t))/span*chartW*10) / 10
+	}
+	c := seasonChart{From: from, To: to, TodayX: x(today), Max: 10}
+	for _, v := range actual {
+		c.Max = math.Max(c.Max, v)
+	}
+	for _, v := range planned {
+		c.Max = math.Max(c.Max, v)
+	}
+	c.Max = math.Ceil(c.Max/10) * 10
+
+	line := func(start time.Time, values []float64) string {
+		var b strings.Builder
+		for i, v := range values {
+			fmt.Fprintf(&b, "%.1f,%.1f ", x(start.AddDate(0, 0, i)), chartH-v/c.Max*(chartH-10))
+		}
+		return strings.TrimSpace(b.String())
+	}
+	c.Actual = line(from, actual)
+	c.Planned = line(anchor, planned)
+
+	for _, p := range phases {
+		if p.End.Before(from) || p.Start.After(to) {
+			continue
+		}
+		x0, x1 := x(maxTime(p.Start, from)), x(minTime(p.End, to).AddDate(0, 0, 1))
+		c.Bands = append(c.Bands, chartBand{X: x0, W: math.Min(chartW, x1) - x0, Kind: p.Kind, Color: phaseColors[p.Kind]})
+	}
+	for _, e := range events {
+		if e.Day.Time.Before(from) || e.Day.Time.After(to) {
+			continue
+		}
+		c.Markers = append(c.Markers, chartMarker{
+			X:     x(e.Day.Time),
+			Label: e.Priority + " · " + e.Name + " · " + e.Day.Time.Format("Jan 2"),
+			Color: priorityColors[e.Priority],
+		})
+	}
+	return c
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// formDate parses a required YYYY-MM-DD field.
+func formDate(v string) (pgtype.Date, bool) {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(v))
+	if err != nil {
+		return pgtype.Date{}, false
+	}
+	return pgDate(t), true
+}
+
+func formText(v string) pgtype.Text {
+	v = strings.TrimSpace(v)
+	return pgtype.Text{String: v, Valid: v != ""}
+}
+
+func (s *Server) handleCreateAthleteEvent(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+
+	p := db.CreateAthleteEventParams{
+		AthleteID: athlete.ID,
+		Name:      strings.TrimSpace(r.Form.Get("name")),
+		Priority:  r.Form.Get("priority"),
+		Sport:     r.Form.Get("sport"),
+		Notes:     formText(r.Form.Get("notes")),
+	}
+	if p.Name == "" {
+		http.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+	if p.Day, ok = formDate(r.Form.Get("day")); !ok {
+		http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(season.Priorities, p.Priority) {
+		http.Error(w, "priority must be A, B or C", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(planSports, p.Sport) {
+		http.Error(w, "invalid sport", http.StatusBadRequest)
+		return
+	}
+	if v := strings.TrimSpace(r.Form.Get("distance_km")); v != "" {
+		km, err := strconv.ParseFloat(v, 64)
+		if err != nil || km <= 0 || km > 1000 {
+			http.Error(w, "invalid distance", http.StatusBadRequest)
+			return
+		}
+		p.DistanceM = pgtype.Float8{Float64: math.Round(km * 1000), Valid: true}
+	}
+	if v := strings.TrimSpace(r.Form.Get("goal_time")); v != "" {
+		secs, ok := parseClock(v)
+		if !ok || secs <= 0 {
+			http.Error(w, "goal time must be h:mm:ss or mm:ss", http.StatusBadRequest)
+			return
+		}
+		p.GoalTimeSec = pgtype.Int4{Int32: int32(secs), Valid: true}
+	}
+
+	if _, err := s.Q.CreateAthleteEvent(r.Context(), p); err != nil {
+		log.Printf("create event for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save race", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, seasonURL(athlete.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteAthleteEvent(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	eid, err := uuid.Parse(chi.URLParam(r, "eventID"))
+	if err != nil {
+		http.Error(w, "invalid race ID", http.StatusBadRequest)
+		return
+	}
+	n, err := s.Q.DeleteAthleteEvent(r.Context(), db.DeleteAthleteEventParams{ID: eid, AthleteID: athlete.ID})
+	if err != nil {
+		log.Printf("delete event %s failed: %v", eid, err)
+		http.Error(w, "could not delete race", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "race not found", http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, seasonURL(athlete.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleCreateTrainingPhase(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+
+	p := db.CreateTrainingPhaseParams{
+		AthleteID: athlete.ID,
+		Kind:      r.Form.Get("kind"),
+		Notes:     formText(r.Form.Get("notes")),
+	}
+	if !slices.Contains(season.PhaseKinds, p.Kind) {
+		http.Error(w, "invalid phase", http.StatusBadRequest)
+		return
+	}
+	var okStart, okEnd bool
+	p.StartDay, okStart = formDate(r.Form.Get("start_day"))
+	p.EndDay, okEnd = formDate(r.Form.Get("end_day"))
+	if !okStart || !okEnd {
+		http.Error(w, "dates must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if p.EndDay.Time.Before(p.StartDay.Time) {
+		http.Error(w, "phase ends before it starts", http.StatusBadRequest)
+		return
+	}
+	if p.EndDay.Time.After(p.StartDay.Time.AddDate(0, 0, seasonMaxDays)) {
+		http.Error(w, "phase is too long", http.StatusBadRequest)
+		return
+	}
+	if v := strings.TrimSpace(r.Form.Get("ramp")); v != "" {
+		ramp, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.Abs(ramp) > 20 {
+			http.Error(w, "ramp must be between -20 and 20 CTL per week", http.StatusBadRequest)
+			return
+		}
+		p.RampPerWeek = pgtype.Float8{Float64: ramp, Valid: ramp != 0}
+	}
+
+	existing, err := s.Q.ListTrainingPhases(r.Context(), athlete.ID)
+	if err != nil {
+		log.Printf("list phases for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not load season", http.StatusInternalServerError)
+		return
+	}
+	if season.Overlaps(season.Phase{Start: p.StartDay.Time, End: p.EndDay.Time}, phasesOf(existing)) {
+		http.Error(w, "phase overlaps an existing phase", http.StatusConflict)
+		return
+	}
+
+	if _, err := s.Q.CreateTrainingPhase(r.Context(), p); err != nil {
+		log.Printf("create phase for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save phase", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, seasonURL(athlete.ID), http.StatusSeeOther)
+}
+
+func (s *Server) handleDeleteTrainingPhase(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	pid, err := uuid.Parse(chi.URLParam(r, "phaseID"))
+	if err != nil {
+		http.Error(w, "invalid phase ID", http.StatusBadRequest)
+		return
+	}
+	n, err := s.Q.DeleteTrainingPhase(r.Context(), db.DeleteTrainingPhaseParams{ID: pid, AthleteID: athlete.ID})
+	if err != nil {
+		log.Printf("delete phase %s failed: %v", pid, err)
+		http.Error(w, "could not delete phase", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "phase not found", http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, seasonURL(athlete.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	appmw "github.com/briangreenhill/coachgpt/internal/http/middleware"
+)
+
+func (s *Server) handleDismissAlert(w http.ResponseWriter, r *http.Request) {
+	coachID := r.Context().Value(appmw.CoachIDKey).(string)
+
+	alertID, err := uuid.Parse(chi.URLParam(r, "alertID"))
+	if err != nil {
+		http.Error(w, "invalid alert ID", http.StatusBadRequest)
+		return
+	}
+
+	// Ownership is enforced in the query itself
+	n, err := s.Q.DismissAthleteAlert(r.Context(), db.DismissAthleteAlertParams{
+		ID:      alertID,
+		CoachID: uuid.MustParse(coachID),
+	})
+	if err != nil {
+		log.Printf("dismiss alert %s failed: %v", alertID, err)
+		http.Error(w, "could not dismiss alert", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "alert not found", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (s *Server) handleUpdateThresholds(w http.ResponseWriter, r *http.Request) {
+	coachID := r.Context().Value(appmw.CoachIDKey).(string)
+	athleteID := chi.URLParam(r, "athleteID")
+
+	aid, err := uuid.Parse(athleteID)
+	if err != nil {
+		http.Error(w, "invalid athlete ID", http.StatusBadRequest)
+		return
+	}
+
+	athlete, err := s.Q.GetAthlete(r.Context(), aid)
+	if err != nil {
+		http.Error(w, "athlete not found", http.StatusNotFound)
+		return
+	}
+	if athlete.CoachID.String() != coachID {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+
+	_ = r.ParseForm()
+	maxHR, ok := formInt(r.Form.Get("max_hr"), 30, 250)
+	if !ok {
+		http.Error(w, "invalid max HR", http.StatusBadRequest)
+		return
+	}
+	restHR, ok := formInt(r.Form.Get("resting_hr"), 30, 250)
+	if !ok {
+		http.Error(w, "invalid resting HR", http.StatusBadRequest)
+		return
+	}
+	if maxHR.Valid && restHR.Valid && restHR.Int32 >= maxHR.Int32 {
+		http.Error(w, "resting HR must be below max HR", http.StatusBadRequest)
+		return
+	}
+	ftp, ok := formInt(r.Form.Get("ftp_watts"), 50, 600)
+	if !ok {
+		http.Error(w, "invalid FTP", http.StatusBadRequest)
+		return
+	}
+	var pace pgtype.Int4
+	if v := strings.TrimSpace(r.Form.Get("threshold_pace")); v != "" {
+		secs, ok := parseClock(v)
+		if !ok || secs < 120 || secs > 900 {
+			http.Error(w, "threshold pace must be m:ss per km", http.StatusBadRequest)
+			return
+		}
+		pace = pgtype.Int4{Int32: int32(secs), Valid: true}
+	}
+
+	if err := s.Q.UpdateAthleteThresholds(r.Context(), db.UpdateAthleteThresholdsParams{
+		ID:               aid,
+		MaxHr:            maxHR,
+		RestingHr:        restHR,
+		FtpWatts:         ftp,
+		ThresholdPaceSec: pace,
+	}); err != nil {
+		log.Printf("update thresholds for athlete %s failed: %v", athleteID, err)
+		http.Error(w, "could not save thresholds", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/athletes/"+athleteID+"/workouts", http.StatusSeeOther)
+}
+
+// formInt parses an optional integer field within [lo, hi]; blank clears
+// the value.
+func formInt(v string, lo, hi int) (pgtype.Int4, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return pgtype.Int4{}, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return pgtype.Int4{}, false
+	}
+	return pgtype.Int4{Int32: int32(n), Valid: true}, true
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/briangreenhill/coachgpt/internal/auth"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/outbox"
+)
+
+// unsubscribable are the kinds of email people can opt out of. The rest
+// are needed to use the app.
+var unsubscribable = []string{outbox.KindCheckin}
+
+// kindLabels name them on the unsubscribe page and the athlete's profile.
+var kindLabels = map[string]string{
+	outbox.KindCheckin: "daily check-in emails",
+}
+
+// unsubscribeAddress checks the link's token, writing the error response
+// itself when it's no good.
+func (s *Server) unsubscribeAddress(w http.ResponseWriter, r *http.Request) (kind, addr string, ok bool) {
+	kind = chi.URLParam(r, "kind")
+	if !slices.Contains(unsubscribable, kind) {
+		http.Error(w, "unknown kind of email", http.StatusNotFound)
+		return "", "", false
+	}
+	addr, err := auth.Unsubscribe([]byte(s.StateSecret), s.BaseURL, kind).Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, "invalid or expired link", http.StatusUnauthorized)
+		return "", "", false
+	}
+	return kind, addr, true
+}
+
+// handleUnsubscribe asks before unsubscribing, since mail scanners open
+// links too.
+func (s *Server) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	kind, addr, ok := s.unsubscribeAddress(w, r)
+	if !ok {
+		return
+	}
+	s.render(w, "unsubscribe", map[string]any{
+		"Title":  "Unsubscribe",
+		"Email":  addr,
+		"Label":  kindLabels[kind],
+		"Action": r.URL.RequestURI(),
+	})
+}
+
+// handleConfirmUnsubscribe unsubscribes, from the page's button or a mail
+// client's one-click POST (RFC 8058).
+func (s *Server) handleConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	kind, addr, ok := s.unsubscribeAddress(w, r)
+	if !ok {
+		return
+	}
+	if err := s.Q.SuppressEmail(r.Context(), db.SuppressEmailParams{Address: addr, Kind: kind}); err != nil {
+		log.Printf("unsubscribe %s from %s failed: %v", addr, kind, err)
+		http.Error(w, "could not unsubscribe", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[email] %s unsubscribed from %s", addr, kind)
+	s.render(w, "unsubscribe", map[string]any{
+		"Title": "Unsubscribed",
+		"Email": addr,
+		"Label": kindLabels[kind],
+		"Done":  true,
+	})
+}
+
+// subscription is whether an address gets a kind of email.
+type subscription struct {
+	Kind, Label string
+	On          bool
+}
+
+func (s *Server) subscriptions(ctx context.Context, addr string) []subscription {
+	if addr == "" {
+		return nil
+	}
+	var subs []subscription
+	for _, kind := range unsubscribable {
+		off, err := s.Q.IsEmailSuppressed(ctx, db.IsEmailSuppressedParams{Address: addr, Kind: kind})
+		if err != nil {
+			log.Printf("check %s suppression for %s failed: %v", kind, addr, err)
+		}
+		subs = append(subs, subscription{Kind: kind, Label: kindLabels[kind], On: !off})
+	}
+	return subs
+}
+
+// handlePortalEmails turns a kind of email on or off from the athlete's
+// profile.
+func (s *Server) handlePortalEmails(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	kind := r.FormValue("kind")
+	if !slices.Contains(unsubscribable, kind) || !athlete.Email.Valid {
+		http.Error(w, "unknown kind of email", http.StatusBadRequest)
+		return
+	}
+	params := db.SuppressEmailParams{Address: athlete.Email.String, Kind: kind}
+	var err error
+	if r.FormValue("on") == "1" {
+		err = s.Q.UnsuppressEmail(r.Context(), db.UnsuppressEmailParams(params))
+	} else {
+		err = s.Q.SuppressEmail(r.Context(), params)
+	}
+	if err != nil {
+		log.Printf("update %s emails for athlete %s failed: %v", kind, athlete.ID, err)
+		http.Error(w, "could not save", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/athlete/profile?saved=1", http.StatusSeeOther)
+}
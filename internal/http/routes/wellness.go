@@ -0,0 +1,288 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+	"github.com/briangreenhill/coachgpt/internal/wellness"
+)
+
+const (
+	wellnessDays  = 28 // days on the coach's wellness page
+	wellnessRecap = 7  // days in its summary
+	checkinBack   = 14 // how far back an athlete can fill in a check-in
+	feedbackBack  = 3  // days of unrated workouts offered on the check-in
+)
+
+var (
+	// checkinScores are the answers to each 1-5 check-in question.
+	checkinScores = []int32{1, 2, 3, 4, 5}
+	// rpeScale is the CR-10 scale, with the anchors athletes pick from.
+	rpeScale = []struct {
+		Value int32
+		Label string
+	}{
+		{1, "1 · very easy"}, {2, "2 · easy"}, {3, "3 · moderate"}, {4, "4 · somewhat hard"},
+		{5, "5 · hard"}, {6, "6"}, {7, "7 · very hard"}, {8, "8"}, {9, "9"}, {10, "10 · maximal"},
+	}
+)
+
+// checkinQuestion is one 1-5 question on the check-in form.
+type checkinQuestion struct {
+	Name, Label string
+	Low, High   string // what 1 and 5 mean
+	Value       pgtype.Int4
+}
+
+func checkinQuestions(c db.WellnessCheckin) []checkinQuestion {
+	return []checkinQuestion{
+		{"sleep_quality", "Sleep quality", "poor", "great", c.SleepQuality},
+		{"soreness", "Soreness", "none", "very sore", c.Soreness},
+		{"stress", "Stress", "relaxed", "very stressed", c.Stress},
+		{"mood", "Mood", "low", "great", c.Mood},
+	}
+}
+
+// feedbackView is an athlete's rating of a workout and the load it gives.
+type feedbackView struct {
+	db.WorkoutFeedback
+	Load float64
+}
+
+// workoutFeedback loads the athlete's feedback on a workout, nil if they
+// haven't given any.
+func (s *Server) workoutFeedback(ctx context.Context, workoutID uuid.UUID, durationSec int32) *feedbackView {
+	fb, err := s.Q.GetWorkoutFeedback(ctx, workoutID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("get feedback for workout %s failed: %v", workoutID, err)
+		}
+		return nil
+	}
+	return &feedbackView{WorkoutFeedback: fb, Load: wellness.SessionLoad(fb.Rpe, durationSec)}
+}
+
+// Flagged reports whether the athlete flagged pain in area; false with no
+// feedback, so the form can ask before there is any.
+func (f *feedbackView) Flagged(area string) bool {
+	return f != nil && slices.Contains(f.Pain, area)
+}
+
+func (s *Server) handlePortalWorkoutFeedback(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.portalWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+
+	rpe, ok := formInt(r.Form.Get("rpe"), wellness.MinRPE, wellness.MaxRPE)
+	if !ok || !rpe.Valid {
+		http.Error(w, "RPE must be 1-10", http.StatusBadRequest)
+		return
+	}
+	notes := formText(strings.ReplaceAll(r.Form.Get("notes"), "\r\n", "\n"))
+	if len(notes.String) > wellness.MaxNotes {
+		http.Error(w, "notes are too long", http.StatusBadRequest)
+		return
+	}
+	pain := r.Form["pain"]
+	if err := wellness.CheckPain(pain); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if pain == nil {
+		pain = []string{}
+	}
+
+	if err := s.Q.UpsertWorkoutFeedback(r.Context(), db.UpsertWorkoutFeedbackParams{
+		WorkoutID: wo.ID,
+		AthleteID: athlete.ID,
+		Rpe:       rpe.Int32,
+		Notes:     notes,
+		Pain:      pain,
+	}); err != nil {
+		log.Printf("save feedback for workout %s failed: %v", wo.ID, err)
+		http.Error(w, "could not save feedback", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, portalWorkoutURL(wo.ID)+"#feedback", http.StatusSeeOther)
+}
+
+// checkinDay is the local day a check-in form is for: ?day= or today, no
+// later than today and no more than checkinBack days ago.
+func checkinDay(v string, loc *time.Location) (time.Time, bool) {
+	today := training.Day(time.Now(), loc)
+	if v == "" {
+		return today, true
+	}
+	day, err := time.ParseInLocation(time.DateOnly, v, loc)
+	if err != nil || day.After(today) || day.Before(today.AddDate(0, 0, -checkinBack)) {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+func (s *Server) handlePortalCheckin(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	loc := training.Location(athlete.Tz)
+	day, ok := checkinDay(r.URL.Query().Get("day"), loc)
+	if !ok {
+		http.Error(w, "day must be a date in the last two weeks", http.StatusBadRequest)
+		return
+	}
+	today := training.Day(time.Now(), loc)
+	ctx := r.Context()
+
+	checkin, err := s.Q.GetWellnessCheckin(ctx, db.GetWellnessCheckinParams{AthleteID: athlete.ID, Day: pgDate(day)})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("get check-in for athlete %s failed: %v", athlete.ID, err)
+	}
+	unrated, err := s.Q.ListWorkoutsAwaitingFeedback(ctx, db.ListWorkoutsAwaitingFeedbackParams{
+		AthleteID: athlete.ID,
+		Since:     pgtype.Timestamptz{Time: today.AddDate(0, 0, -feedbackBack), Valid: true},
+	})
+	if err != nil {
+		log.Printf("list unrated workouts for athlete %s failed: %v", athlete.ID, err)
+	}
+
+	s.render(w, "athlete_checkin", map[string]any{
+		"Title":     "Check-in",
+		"Athlete":   athlete,
+		"Day":       day,
+		"Today":     day.Equal(today),
+		"Prev":      day.AddDate(0, 0, -1).Format(time.DateOnly),
+		"Next":      day.AddDate(0, 0, 1).Format(time.DateOnly),
+		"HasPrev":   day.After(today.AddDate(0, 0, -checkinBack)),
+		"CheckIn":   checkin,
+		"Scores":    checkinScores,
+		"Questions": checkinQuestions(checkin),
+		"Unrated":   unrated,
+		"Saved":     r.URL.Query().Get("saved") == "1",
+	})
+}
+
+func (s *Server) handleSavePortalCheckin(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.portalAthlete(w, r)
+	if !ok {
+		return
+	}
+	_ = r.ParseForm()
+	day, ok := checkinDay(r.Form.Get("day"), training.Location(athlete.Tz))
+	if !ok {
+		http.Error(w, "day must be a date in the last two weeks", http.StatusBadRequest)
+		return
+	}
+
+	c := db.WellnessCheckin{
+		AthleteID: athlete.ID,
+		Day:       pgDate(day),
+		Notes:     formText(strings.ReplaceAll(r.Form.Get("notes"), "\r\n", "\n")),
+	}
+	if v := strings.TrimSpace(r.Form.Get("sleep_hours")); v != "" {
+		h, err := strconv.ParseFloat(v, 64)
+		if err != nil || h < 0 || h > 24 {
+			http.Error(w, "sleep must be 0-24 hours", http.StatusBadRequest)
+			return
+		}
+		c.SleepHours = pgtype.Float8{Float64: h, Valid: true}
+	}
+	for _, f := range []struct {
+		name string
+		dst  *pgtype.Int4
+	}{
+		{"sleep_quality", &c.SleepQuality},
+		{"soreness", &c.Soreness},
+		{"stress", &c.Stress},
+		{"mood", &c.Mood},
+	} {
+		if *f.dst, ok = formInt(r.Form.Get(f.name), wellness.MinScore, wellness.MaxScore); !ok {
+			http.Error(w, strings.ReplaceAll(f.name, "_", " ")+" must be 1-5", http.StatusBadRequest)
+			return
+		}
+	}
+	if c.RestingHr, ok = formInt(r.Form.Get("resting_hr"), 25, 150); !ok {
+		http.Error(w, "resting heart rate must be 25-150 bpm", http.StatusBadRequest)
+		return
+	}
+	if len(c.Notes.String) > wellness.MaxNotes {
+		http.Error(w, "notes are too long", http.StatusBadRequest)
+		return
+	}
+	if err := wellness.CheckEmpty(c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Q.UpsertWellnessCheckin(r.Context(), db.UpsertWellnessCheckinParams{
+		AthleteID:    c.AthleteID,
+		Day:          c.Day,
+		SleepHours:   c.SleepHours,
+		SleepQuality: c.SleepQuality,
+		Soreness:     c.Soreness,
+		Stress:       c.Stress,
+		Mood:         c.Mood,
+		RestingHr:    c.RestingHr,
+		Notes:        c.Notes,
+	}); err != nil {
+		log.Printf("save check-in for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not save check-in", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/athlete/checkin?saved=1&day="+day.Format(time.DateOnly), http.StatusSeeOther)
+}
+
+// wellnessSummary is one labelled row of the wellness page's summary.
+type wellnessSummary struct {
+	Label   string
+	Summary wellness.Summary
+}
+
+// handleAthleteWellness shows the coach the athlete's check-ins and
+// session-RPE load next to the load measured from their workouts.
+func (s *Server) handleAthleteWellness(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	today := training.Day(time.Now(), training.Location(athlete.Tz))
+	days, err := wellness.Load(r.Context(), s.Q, athlete, today.AddDate(0, 0, 1-wellnessDays), today)
+	if err != nil {
+		log.Printf("load wellness for athlete %s failed: %v", athlete.ID, err)
+		http.Error(w, "could not load wellness", http.StatusInternalServerError)
+		return
+	}
+
+	// Newest first, as in the workout list.
+	recent := make([]wellness.Day, len(days))
+	for i, d := range days {
+		recent[len(days)-1-i] = d
+	}
+	s.render(w, "wellness", map[string]any{
+		"Title":   "Wellness - " + athlete.Name,
+		"Athlete": athlete,
+		"Days":    recent,
+		"Summaries": []wellnessSummary{
+			{"Last 7 days", wellness.Summarize(days[len(days)-wellnessRecap:])},
+			{"Last 28 days", wellness.Summarize(days)},
+		},
+	})
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/commentary"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+func workoutURL(athleteID, workoutID uuid.UUID) string {
+	return "/athletes/" + athleteID.String() + "/workouts/" + workoutID.String()
+}
+
+// ownedWorkout loads the workout from the URL, scoped to the signed-in coach
+// and the athlete, writing the error response itself when it can't.
+func (s *Server) ownedWorkout(w http.ResponseWriter, r *http.Request, athlete db.Athlete) (db.GetCoachAthleteWorkoutRow, bool) {
+	wid, err := uuid.Parse(chi.URLParam(r, "workoutID"))
+	if err != nil {
+		http.Error(w, "invalid workout ID", http.StatusBadRequest)
+		return db.GetCoachAthleteWorkoutRow{}, false
+	}
+	wo, err := s.Q.GetCoachAthleteWorkout(r.Context(), db.GetCoachAthleteWorkoutParams{
+		ID:        wid,
+		AthleteID: athlete.ID,
+		CoachID:   coachUUID(r),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "workout not found", http.StatusNotFound)
+		} else {
+			log.Printf("get workout %s failed: %v", wid, err)
+			http.Error(w, "could not load workout", http.StatusInternalServerError)
+		}
+		return db.GetCoachAthleteWorkoutRow{}, false
+	}
+	return wo, true
+}
+
+func (s *Server) handleWorkout(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.ownedWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+
+	laps, err := s.Q.ListWorkoutLaps(ctx, wo.ID)
+	if err != nil {
+		log.Printf("list laps for workout %s failed: %v", wo.ID, err)
+	}
+	data := map[string]any{
+		"Title":   "Workout - " + athlete.Name,
+		"Athlete": athlete,
+		"Workout": wo,
+		"Laps":    commentary.Laps(laps, commentary.FootSport(wo.Sport)),
+	}
+
+	plan, err := s.Q.GetPlannedWorkoutForWorkout(ctx, pgtype.UUID{Bytes: wo.ID, Valid: true})
+	switch {
+	case err == nil:
+		data["Plan"] = plan
+		if st, err := structured.Decode(plan.Structure); len(plan.Structure) > 0 && err == nil {
+			data["Steps"] = st.Lines()
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		log.Printf("get plan for workout %s failed: %v", wo.ID, err)
+	}
+
+	note, err := s.Q.GetWorkoutCommentary(ctx, wo.ID)
+	switch {
+	case err == nil:
+		data["Note"] = note
+	case !errors.Is(err, pgx.ErrNoRows):
+		log.Printf("get commentary for workout %s failed: %v", wo.ID, err)
+	}
+
+	data["Feedback"] = s.workoutFeedback(ctx, wo.ID, wo.DurationSec)
+
+	t, err := s.Q.GetWorkoutCommentThread(ctx, pgUUID(wo.ID))
+	data["Comments"] = s.commentsView(ctx, t, err, athlete, workoutURL(athlete.ID, wo.ID)+"/comments")
+
+	s.render(w, "workout", data)
+}
+
+// commentaryActions maps the form action to the stored note status.
+var commentaryActions = map[string]string{
+	"approve": commentary.StatusApproved,
+	"discard": commentary.StatusDiscarded,
+	"reopen":  commentary.StatusDraft,
+}
+
+// handleReviewCommentary approves a note, with the coach's edits, or
+// discards or reopens it. Only approved notes are ever shown to the
+// athlete.
+func (s *Server) handleReviewCommentary(w http.ResponseWriter, r *http.Request) {
+	athlete, ok := s.ownedAthlete(w, r)
+	if !ok {
+		return
+	}
+	wo, ok := s.ownedWorkout(w, r, athlete)
+	if !ok {
+		return
+	}
+	status, ok := commentaryActions[r.FormValue("action")]
+	if !ok {
+		http.Error(w, "action must be approve, discard or reopen", http.StatusBadRequest)
+		return
+	}
+	note, err := s.Q.GetWorkoutCommentary(r.Context(), wo.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "workout has no note", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("get commentary for workout %s failed: %v", wo.ID, err)
+		http.Error(w, "could not load note", http.StatusInternalServerError)
+		return
+	}
+
+	body := note.Body
+	if status == commentary.StatusApproved {
+		body = strings.TrimSpace(strings.ReplaceAll(r.FormValue("body"), "\r\n", "\n"))
+		switch {
+		case body == "":
+			http.Error(w, "the note is empty; discard it instead", http.StatusBadRequest)
+			return
+		case len(body) > commentary.MaxBody:
+			http.Error(w, "note is too long", http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := s.Q.ReviewWorkoutCommentary(r.Context(), db.ReviewWorkoutCommentaryParams{
+		WorkoutID: wo.ID,
+		Body:      body,
+		Status:    status,
+	}); err != nil {
+		log.Printf("review commentary for workout %s failed: %v", wo.ID, err)
+		http.Error(w, "could not save note", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, workoutURL(athlete.ID, wo.ID), http.StatusSeeOther)
+}
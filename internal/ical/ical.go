@@ -0,0 +1,111 @@
+// Package ical writes iCalendar (RFC 5545) feeds for calendar apps to
+// subscribe to. Training sessions and races are whole-day events, so every
+// event is dated rather than timed and the calendar's time zone only tells
+// the app which local days are meant.
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of a feed.
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLine is the longest content line in octets before it's folded.
+const maxLine = 75
+
+// Calendar is a feed of events.
+type Calendar struct {
+	Name   string
+	TZ     string // IANA zone, e.g. Europe/Berlin
+	Events []Event
+}
+
+// Event is one all-day entry. UID must stay the same for the life of the
+// item so subscribing apps replace it when it changes.
+type Event struct {
+	UID         string
+	Day         time.Time
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	Modified    time.Time
+}
+
+// Bytes renders the calendar with CRLF line endings.
+func (c Calendar) Bytes() []byte {
+	var b bytes.Buffer
+	line := func(name, value string) { writeLine(&b, name+":"+value) }
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//CoachGPT//Training plan//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	if c.TZ != "" {
+		line("X-WR-TIMEZONE", c.TZ)
+	}
+	// Ask subscribing apps to refresh a few times a day.
+	line("REFRESH-INTERVAL;VALUE=DURATION", "PT4H")
+	line("X-PUBLISHED-TTL", "PT4H")
+
+	for _, e := range c.Events {
+		stamp := e.Modified.UTC().Format("20060102T150405Z")
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		line("LAST-MODIFIED", stamp)
+		line("DTSTART;VALUE=DATE", e.Day.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if len(e.Categories) > 0 {
+			cats := make([]string, len(e.Categories))
+			for i, c := range e.Categories {
+				cats[i] = escape(c)
+			}
+			line("CATEGORIES", strings.Join(cats, ","))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return b.Bytes()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape quotes a TEXT value.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writeLine folds the line at maxLine octets without splitting a UTF-8
+// sequence; continuation lines start with a space.
+func writeLine(b *bytes.Buffer, s string) {
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLine - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
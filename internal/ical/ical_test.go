@@ -0,0 +1,67 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestBytes(t *testing.T) {
+	cal := Calendar{
+		Name: "Plan, Ana",
+		TZ:   "Europe/Berlin",
+		Events: []Event{{
+			UID:         "planned-1@example.com",
+			Day:         time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC),
+			Summary:     "Long run; easy",
+			Description: "Run · 1h30\nwu 10min, 80min @Z2",
+			Categories:  []string{"Run"},
+			Modified:    time.Date(2025, 3, 1, 8, 30, 0, 0, time.FixedZone("", 3600)),
+		}},
+	}
+	out := string(cal.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Plan\\, Ana\r\n",
+		"X-WR-TIMEZONE:Europe/Berlin\r\n",
+		"UID:planned-1@example.com\r\n",
+		"DTSTAMP:20250301T073000Z\r\n",
+		"DTSTART;VALUE=DATE:20250309\r\n",
+		"DTEND;VALUE=DATE:20250310\r\n",
+		"SUMMARY:Long run\\; easy\r\n",
+		"DESCRIPTION:Run · 1h30\\nwu 10min\\, 80min @Z2\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Fatalf("bare LF in output")
+	}
+}
+
+func TestFold(t *testing.T) {
+	long := strings.Repeat("é", 100) // two octets each
+	out := string(Calendar{Events: []Event{{UID: "x", Summary: long}}}.Bytes())
+
+	var unfolded strings.Builder
+	for i, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > maxLine {
+			t.Fatalf("line %d is %d octets", i, len(l))
+		}
+		if !utf8.ValidString(l) {
+			t.Fatalf("line %d splits a character: %q", i, l)
+		}
+		if strings.HasPrefix(l, " ") {
+			unfolded.WriteString(l[1:])
+		} else {
+			unfolded.WriteString("\n" + l)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+long+"\n") {
+		t.Fatalf("summary did not unfold to the original")
+	}
+}
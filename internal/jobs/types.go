@@ -1,8 +1,62 @@
 package jobs
 
-const TaskSyncStrava = "sync:strava_athlete"
+const (
+	TaskSyncStrava     = "sync:strava_athlete"
+	TaskEvaluateAlerts = "alerts:evaluate"
+)
 
 type SyncStravaPayload struct {
 	AthleteID string `json:"athlete_id"`
 	SinceUnix int64  `json:"since_unix,omitempty"`
 }
+
+// EvaluateAlertsPayload targets one athlete; an empty AthleteID sweeps every
+// connected athlete.
+type EvaluateAlertsPayload struct {
+	AthleteID string `json:"athlete_id,omitempty"`
+}
+
+const TaskWeeklyReport = "reports:weekly"
+
+// WeeklyReportPayload targets one coach; an empty CoachID fans out a task
+// per coach. WeekStart (YYYY-MM-DD, a Monday) defaults to last week in the
+// coach's time zone.
+type WeeklyReportPayload struct {
+	CoachID   string `json:"coach_id,omitempty"`
+	WeekStart string `json:"week_start,omitempty"`
+}
+
+const TaskWorkoutCommentary = "commentary:workout"
+
+// QueueCommentary has its own worker pool, so slow model calls never hold
+// up sync. Plan drafts run on it too.
+const QueueCommentary = "commentary"
+
+// WorkoutCommentaryPayload names the workout to draft a note on.
+type WorkoutCommentaryPayload struct {
+	WorkoutID string `json:"workout_id"`
+}
+
+const TaskGeneratePlanDraft = "plans:draft"
+
+// GeneratePlanDraftPayload names the draft to generate.
+type GeneratePlanDraftPayload struct {
+	DraftID string `json:"draft_id"`
+}
+
+const TaskCheckinEmail = "wellness:checkin_email"
+
+// CheckinEmailPayload targets one athlete's check-in for Day (YYYY-MM-DD);
+// an empty AthleteID sweeps for athletes whose check-in hour it is.
+type CheckinEmailPayload struct {
+	AthleteID string `json:"athlete_id,omitempty"`
+	Day       string `json:"day,omitempty"`
+}
+
+const TaskSendEmail = "email:send"
+
+// SendEmailPayload names the outbox email to send; an empty EmailID sweeps
+// for pending emails whose task was lost.
+type SendEmailPayload struct {
+	EmailID string `json:"email_id,omitempty"`
+}
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// Fake is a deterministic Provider for development and tests. It plays
+// Replies back in order; once they run out it asks Respond, and without
+// that it answers with a digest of the prompt and the start of the last
+// user message, so the same request always gets the same answer and any
+// change to the prompt shows. Usage is estimated from the text.
+type Fake struct {
+	Model   string
+	Replies []Message
+	Respond func(Request) Message
+
+	mu    sync.Mutex
+	calls []Request
+}
+
+// Calls returns the requests made so far.
+func (f *Fake) Calls() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.calls...)
+}
+
+func (f *Fake) reply(req Request) Message {
+	f.mu.Lock()
+	n := len(f.calls)
+	f.calls = append(f.calls, req)
+	f.mu.Unlock()
+
+	var m Message
+	switch {
+	case n < len(f.Replies):
+		m = f.Replies[n]
+	case f.Respond != nil:
+		m = f.Respond(req)
+	default:
+		m = Message{Content: Echo(req)}
+	}
+	m.Role = RoleAssistant
+	return m
+}
+
+// Echo is the Fake's default answer to req.
+func Echo(req Request) string {
+	h := sha256.New()
+	var last string
+	for _, m := range req.Messages {
+		h.Write([]byte(m.Role + "\x00" + m.Content + "\x00"))
+		if m.Role == RoleUser {
+			last = m.Content
+		}
+	}
+	last = strings.Join(strings.Fields(last), " ")
+	if len(last) > 200 {
+		last = last[:200] + "…"
+	}
+	return "[fake " + hex.EncodeToString(h.Sum(nil))[:8] + "] " + last
+}
+
+func (f *Fake) response(req Request, m Message) Response {
+	model := req.Model
+	if model == "" {
+		model = f.Model
+	}
+	if model == "" {
+		model = "fake"
+	}
+	finish := FinishStop
+	if len(m.ToolCalls) > 0 {
+		finish = FinishToolCalls
+	}
+	return Response{Model: model, Message: m, FinishReason: finish, Usage: estimateUsage(req, m)}
+}
+
+// Complete answers at once.
+func (f *Fake) Complete(ctx context.Context, req Request) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	return f.response(req, f.reply(req)), nil
+}
+
+// Stream delivers the answer a word at a time.
+func (f *Fake) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+	m := f.reply(req)
+	for _, piece := range words(m.Content) {
+		if err := ctx.Err(); err != nil {
+			return Response{}, err
+		}
+		if err := onDelta(piece); err != nil {
+			return Response{}, err
+		}
+	}
+	return f.response(req, m), nil
+}
+
+// words splits s after each run of spaces, keeping them, so the pieces
+// join back to s.
+func words(s string) []string {
+	var out []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if s[i-1] == ' ' && s[i] != ' ' {
+			out = append(out, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		out = append(out, s[start:])
+	}
+	return out
+}
@@ -0,0 +1,327 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps how long a Retry-After header can make us wait.
+const maxRetryAfter = 30 * time.Second
+
+// HTTP is a Provider for OpenAI-compatible /chat/completions endpoints.
+// Timeout bounds each attempt, streaming included. Rate limits, server
+// errors and network failures are retried up to MaxRetries times with
+// exponential backoff, but never once a stream has started delivering.
+type HTTP struct {
+	BaseURL    string // e.g. https://api.openai.com/v1
+	APIKey     string
+	Model      string
+	Timeout    time.Duration
+	MaxRetries int
+	Client     *http.Client // nil uses http.DefaultClient
+}
+
+// wire types for the OpenAI chat format
+
+type wireMessage struct {
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []wireToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type wireToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type wireTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type wireRequest struct {
+	Model         string        `json:"model"`
+	Messages      []wireMessage `json:"messages"`
+	Tools         []wireTool    `json:"tools,omitempty"`
+	Temperature   float64       `json:"temperature"`
+	MaxTokens     int           `json:"max_tokens,omitempty"`
+	Stream        bool          `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type wireUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type wireResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      wireMessage `json:"message"`
+		Delta        wireMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *wireUsage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (h *HTTP) body(req Request, stream bool) ([]byte, error) {
+	w := wireRequest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      stream,
+	}
+	if w.Model == "" {
+		w.Model = h.Model
+	}
+	if stream {
+		w.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{true}
+	}
+	for _, m := range req.Messages {
+		wm := wireMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for i, c := range m.ToolCalls {
+			wc := wireToolCall{Index: i, ID: c.ID, Type: "function"}
+			wc.Function.Name, wc.Function.Arguments = c.Name, c.Arguments
+			wm.ToolCalls = append(wm.ToolCalls, wc)
+		}
+		w.Messages = append(w.Messages, wm)
+	}
+	for _, t := range req.Tools {
+		wt := wireTool{Type: "function"}
+		wt.Function.Name, wt.Function.Description = t.Name, t.Description
+		if len(t.Parameters) > 0 {
+			wt.Function.Parameters = t.Parameters
+		}
+		w.Tools = append(w.Tools, wt)
+	}
+	return json.Marshal(w)
+}
+
+// do posts the request, retrying failures that may pass on a second try,
+// and returns the successful response with its cancel func.
+func (h *HTTP) do(ctx context.Context, body []byte) (*http.Response, context.CancelFunc, error) {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	url := strings.TrimRight(h.BaseURL, "/") + "/chat/completions"
+
+	for attempt := 0; ; attempt++ {
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if h.Timeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, h.Timeout)
+		}
+		req, err := http.NewRequestWithContext(actx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if h.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+h.APIKey)
+		}
+
+		resp, err := client.Do(req)
+		var wait time.Duration
+		switch {
+		case err != nil:
+			cancel()
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			err = fmt.Errorf("llm: %w", err)
+		case resp.StatusCode/100 == 2:
+			return resp, cancel, nil
+		default:
+			err = apiError(resp)
+			wait = retryAfter(resp.Header.Get("Retry-After"))
+			cancel()
+			if !retryable(resp.StatusCode) {
+				return nil, nil, err
+			}
+		}
+
+		if attempt >= h.MaxRetries {
+			return nil, nil, err
+		}
+		if wait == 0 {
+			wait = backoff(attempt + 1)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func retryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return min(time.Duration(secs)*time.Second, maxRetryAfter)
+}
+
+func apiError(resp *http.Response) error {
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(b))
+	var w wireResponse
+	if json.Unmarshal(b, &w) == nil && w.Error != nil {
+		msg = w.Error.Message
+	}
+	return &APIError{Status: resp.StatusCode, Message: msg}
+}
+
+func fromWire(m wireMessage) Message {
+	out := Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+	for _, c := range m.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: c.Function.Arguments})
+	}
+	return out
+}
+
+// Complete runs a completion and waits for the whole answer.
+func (h *HTTP) Complete(ctx context.Context, req Request) (Response, error) {
+	body, err := h.body(req, false)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, cancel, err := h.do(ctx, body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	var w wireResponse
+	if err := json.NewDecoder(resp.Body).Decode(&w); err != nil {
+		return Response{}, fmt.Errorf("llm: decode response: %w", err)
+	}
+	if len(w.Choices) == 0 {
+		return Response{}, errors.New("llm: response has no choices")
+	}
+	out := Response{
+		Model:        w.Model,
+		Message:      fromWire(w.Choices[0].Message),
+		FinishReason: w.Choices[0].FinishReason,
+	}
+	out.Message.Role = RoleAssistant
+	if w.Usage != nil {
+		out.Usage = Usage{PromptTokens: w.Usage.PromptTokens, CompletionTokens: w.Usage.CompletionTokens}
+	} else {
+		out.Usage = estimateUsage(req, out.Message)
+	}
+	return out, nil
+}
+
+// Stream runs a completion as server-sent events. Tool call fragments are
+// assembled by index and returned whole on the response.
+func (h *HTTP) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	body, err := h.body(req, true)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, cancel, err := h.do(ctx, body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	out := Response{Message: Message{Role: RoleAssistant}}
+	var content strings.Builder
+	var calls []ToolCall
+	var usage *wireUsage
+
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var w wireResponse
+		if err := json.Unmarshal([]byte(data), &w); err != nil {
+			return Response{}, fmt.Errorf("llm: decode stream chunk: %w", err)
+		}
+		if w.Error != nil {
+			return Response{}, &APIError{Status: resp.StatusCode, Message: w.Error.Message}
+		}
+		if w.Model != "" {
+			out.Model = w.Model
+		}
+		if w.Usage != nil {
+			usage = w.Usage
+		}
+		for _, c := range w.Choices {
+			if c.FinishReason != "" {
+				out.FinishReason = c.FinishReason
+			}
+			if d := c.Delta.Content; d != "" {
+				content.WriteString(d)
+				if err := onDelta(d); err != nil {
+					return Response{}, err
+				}
+			}
+			for _, tc := range c.Delta.ToolCalls {
+				for len(calls) <= tc.Index {
+					calls = append(calls, ToolCall{})
+				}
+				call := &calls[tc.Index]
+				if tc.ID != "" {
+					call.ID = tc.ID
+				}
+				call.Name += tc.Function.Name
+				call.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Response{}, fmt.Errorf("llm: read stream: %w", err)
+	}
+
+	out.Message.Content = content.String()
+	out.Message.ToolCalls = calls
+	if usage != nil {
+		out.Usage = Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+	} else {
+		out.Usage = estimateUsage(req, out.Message)
+	}
+	return out, nil
+}
@@ -0,0 +1,158 @@
+// Package llm talks to chat-completion language models. Features build a
+// Request and call a Provider; the HTTP provider speaks the OpenAI wire
+// format that most hosted and local model servers accept, and Fake answers
+// deterministically so everything on top can run offline and in tests.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/config"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Finish reasons.
+const (
+	FinishStop      = "stop"
+	FinishLength    = "length"
+	FinishToolCalls = "tool_calls"
+)
+
+// Message is one turn of a conversation. Assistant messages may carry tool
+// calls instead of content; tool messages answer the call named by
+// ToolCallID.
+type Message struct {
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall
+	ToolCallID string
+}
+
+// ToolCall is the model asking to run a tool. Arguments is a JSON object
+// as the model wrote it, which may not be valid.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// Tool describes a function the model may call. Parameters is a JSON
+// Schema object.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  []byte
+}
+
+// Request is one completion. An empty Model uses the provider's default.
+type Request struct {
+	Model       string
+	Messages    []Message
+	Tools       []Tool
+	Temperature float64
+	MaxTokens   int
+}
+
+// Usage counts tokens for one completion. Estimated is set when the
+// server didn't report counts and they were approximated from the text.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	Estimated        bool
+}
+
+// Total is prompt plus completion tokens.
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Response is a finished completion.
+type Response struct {
+	Model        string
+	Message      Message
+	FinishReason string
+	Usage        Usage
+}
+
+// Provider runs completions. Stream calls onDelta with each piece of
+// content as it arrives and returns the assembled response; an error from
+// onDelta aborts the stream and is returned.
+type Provider interface {
+	Complete(ctx context.Context, req Request) (Response, error)
+	Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error)
+}
+
+// ErrQuotaExceeded is returned when a coach has used their monthly tokens.
+var ErrQuotaExceeded = errors.New("monthly AI usage limit reached")
+
+// APIError is a non-2xx answer from the model server.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("llm: HTTP %d: %s", e.Status, e.Message)
+}
+
+// Provider names for config.LLMConfig.Provider.
+const (
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+// New builds the configured provider.
+func New(cfg config.LLMConfig) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		return &HTTP{
+			BaseURL:    cfg.BaseURL,
+			APIKey:     cfg.APIKey,
+			Model:      cfg.Model,
+			Timeout:    cfg.Timeout,
+			MaxRetries: cfg.MaxRetries,
+		}, nil
+	case ProviderFake, "":
+		return &Fake{Model: cfg.Model}, nil
+	}
+	return nil, fmt.Errorf("llm: unknown provider %q", cfg.Provider)
+}
+
+// EstimateTokens approximates a token count from text, at roughly four
+// characters per token for English.
+func EstimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	return (len(s) + 3) / 4
+}
+
+func estimateUsage(req Request, reply Message) Usage {
+	u := Usage{Estimated: true}
+	for _, m := range req.Messages {
+		u.PromptTokens += EstimateTokens(m.Content) + 4
+	}
+	for _, t := range req.Tools {
+		u.PromptTokens += EstimateTokens(t.Description) + EstimateTokens(string(t.Parameters))
+	}
+	u.CompletionTokens = EstimateTokens(reply.Content)
+	for _, c := range reply.ToolCalls {
+		u.CompletionTokens += EstimateTokens(c.Name) + EstimateTokens(c.Arguments)
+	}
+	return u
+}
+
+// backoff is the wait before retry attempt n, counted from 1.
+func backoff(n int) time.Duration {
+	return time.Duration(500<<(n-1)) * time.Millisecond
+}
@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+func chat(content string) Request {
+	return Request{Messages: []Message{
+		{Role: RoleSystem, Content: "You are a running coach."},
+		{Role: RoleUser, Content: content},
+	}}
+}
+
+func TestHTTPComplete(t *testing.T) {
+	var got wireRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"model":"m-1","choices":[{"message":{"role":"assistant","content":null,
+			"tool_calls":[{"id":"c1","type":"function","function":{"name":"list_workouts","arguments":"{\"days\":7}"}}]},
+			"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":12,"completion_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	h := &HTTP{BaseURL: srv.URL + "/v1/", APIKey: "k", Model: "m"}
+	req := chat("How was my week?")
+	req.Tools = []Tool{{Name: "list_workouts", Description: "List workouts", Parameters: []byte(`{"type":"object"}`)}}
+	resp, err := h.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Model != "m" || len(got.Messages) != 2 || len(got.Tools) != 1 || got.Tools[0].Function.Name != "list_workouts" {
+		t.Fatalf("request = %+v", got)
+	}
+	if resp.FinishReason != FinishToolCalls || len(resp.Message.ToolCalls) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if c := resp.Message.ToolCalls[0]; c.ID != "c1" || c.Arguments != `{"days":7}` {
+		t.Fatalf("tool call = %+v", c)
+	}
+	if resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 5}) {
+		t.Fatalf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestHTTPStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range []string{
+			`{"model":"m-1","choices":[{"delta":{"role":"assistant","content":"Easy "}}]}`,
+			`{"choices":[{"delta":{"content":"week."}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","function":{"name":"get_","arguments":"{\"a\""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"name":"plan","arguments":":1}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":20,"completion_tokens":9}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+	}))
+	defer srv.Close()
+
+	var deltas []string
+	resp, err := (&HTTP{BaseURL: srv.URL}).Stream(context.Background(), chat("hi"), func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "|") != "Easy |week." || resp.Message.Content != "Easy week." {
+		t.Fatalf("deltas = %q, content = %q", deltas, resp.Message.Content)
+	}
+	if len(resp.Message.ToolCalls) != 1 || resp.Message.ToolCalls[0] != (ToolCall{ID: "c1", Name: "get_plan", Arguments: `{"a":1}`}) {
+		t.Fatalf("tool calls = %+v", resp.Message.ToolCalls)
+	}
+	if resp.Model != "m-1" || resp.Usage.Total() != 29 || resp.FinishReason != FinishToolCalls {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestHTTPRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	h := &HTTP{BaseURL: srv.URL, MaxRetries: 2}
+	resp, err := h.Complete(context.Background(), chat("hi"))
+	if err != nil || resp.Message.Content != "ok" || calls.Load() != 3 {
+		t.Fatalf("resp = %+v, err = %v, calls = %d", resp, err, calls.Load())
+	}
+	if !resp.Usage.Estimated || resp.Usage.PromptTokens == 0 {
+		t.Fatalf("missing usage should be estimated, got %+v", resp.Usage)
+	}
+
+	calls.Store(1)
+	h.MaxRetries = 0
+	_, err = h.Complete(context.Background(), chat("hi"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusTooManyRequests || apiErr.Message != "slow down" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestHTTPNoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad model", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if _, err := (&HTTP{BaseURL: srv.URL, MaxRetries: 3}).Complete(context.Background(), chat("hi")); err == nil || calls.Load() != 1 {
+		t.Fatalf("err = %v, calls = %d", err, calls.Load())
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	_, err := (&HTTP{BaseURL: srv.URL, Timeout: 50 * time.Millisecond}).Complete(context.Background(), chat("hi"))
+	if err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("err = %v after %v", err, time.Since(start))
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := &Fake{Replies: []Message{{ToolCalls: []ToolCall{{ID: "1", Name: "get_plan", Arguments: "{}"}}}}}
+
+	first, err := f.Complete(context.Background(), chat("plan my week"))
+	if err != nil || first.FinishReason != FinishToolCalls || first.Message.ToolCalls[0].Name != "get_plan" {
+		t.Fatalf("scripted reply = %+v, %v", first, err)
+	}
+
+	var deltas []string
+	a, err := f.Stream(context.Background(), chat("plan my  week"), func(d string) error {
+		deltas = append(deltas, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(deltas, "") != a.Message.Content || len(deltas) < 2 {
+		t.Fatalf("deltas %q don't rebuild %q", deltas, a.Message.Content)
+	}
+	b, _ := (&Fake{}).Complete(context.Background(), chat("plan my  week"))
+	c, _ := (&Fake{}).Complete(context.Background(), chat("plan my week"))
+	if a.Message.Content != b.Message.Content || a.Message.Content == c.Message.Content {
+		t.Fatalf("echo not deterministic per prompt: %q %q %q", a.Message.Content, b.Message.Content, c.Message.Content)
+	}
+	if len(f.Calls()) != 2 || a.Usage.Total() == 0 {
+		t.Fatalf("calls = %d usage = %+v", len(f.Calls()), a.Usage)
+	}
+}
+
+type usageStore struct {
+	used    int64
+	records []db.RecordLLMUsageParams
+}
+
+func (s *usageStore) RecordLLMUsage(_ context.Context, arg db.RecordLLMUsageParams) error {
+	s.records = append(s.records, arg)
+	s.used += int64(arg.PromptTokens + arg.CompletionTokens)
+	return nil
+}
+
+func (s *usageStore) SumCoachLLMTokensSince(context.Context, db.SumCoachLLMTokensSinceParams) (int64, error) {
+	return s.used, nil
+}
+
+func TestMetered(t *testing.T) {
+	store := &usageStore{}
+	m := &Metered{Provider: &Fake{Model: "f"}, Store: store, MonthlyLimit: 30}
+	coach := uuid.New()
+	ctx := ForCoach(context.Background(), coach, "chat")
+
+	if _, err := m.Complete(context.Background(), chat("untracked")); err != nil || len(store.records) != 0 {
+		t.Fatalf("call without a coach was metered: %v %d", err, len(store.records))
+	}
+	if _, err := m.Stream(ctx, chat(strings.Repeat("long question ", 10)), func(string) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.records) != 1 || store.records[0].CoachID != coach || store.records[0].Feature != "chat" || !store.records[0].Estimated {
+		t.Fatalf("records = %+v", store.records)
+	}
+	if _, err := m.Complete(ctx, chat("again")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected quota error after %d tokens, got %v", store.used, err)
+	}
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+// UsageStore is the slice of db.Queries that metering needs.
+type UsageStore interface {
+	RecordLLMUsage(ctx context.Context, arg db.RecordLLMUsageParams) error
+	SumCoachLLMTokensSince(ctx context.Context, arg db.SumCoachLLMTokensSinceParams) (int64, error)
+}
+
+type meterKey struct{}
+
+type meter struct {
+	coachID uuid.UUID
+	feature string
+}
+
+// ForCoach marks calls made with ctx as on behalf of the coach, for the
+// named feature, so Metered can bill and limit them.
+func ForCoach(ctx context.Context, coachID uuid.UUID, feature string) context.Context {
+	return context.WithValue(ctx, meterKey{}, meter{coachID, feature})
+}
+
+// Metered wraps a Provider with per-coach usage tracking. Calls made with
+// a ForCoach context are refused with ErrQuotaExceeded once the coach has
+// used MonthlyLimit tokens since the start of the UTC month (0 means no
+// limit) and are recorded afterwards. Other calls pass straight through.
+type Metered struct {
+	Provider     Provider
+	Store        UsageStore
+	MonthlyLimit int64
+	Now          func() time.Time // nil uses time.Now
+}
+
+func (m *Metered) check(ctx context.Context) (meter, bool, error) {
+	who, ok := ctx.Value(meterKey{}).(meter)
+	if !ok || m.MonthlyLimit <= 0 {
+		return who, ok, nil
+	}
+	now := time.Now
+	if m.Now != nil {
+		now = m.Now
+	}
+	t := now().UTC()
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	used, err := m.Store.SumCoachLLMTokensSince(ctx, db.SumCoachLLMTokensSinceParams{
+		CoachID:   who.coachID,
+		CreatedAt: pgtype.Timestamptz{Time: month, Valid: true},
+	})
+	if err != nil {
+		return who, ok, err
+	}
+	if used >= m.MonthlyLimit {
+		return who, ok, ErrQuotaExceeded
+	}
+	return who, ok, nil
+}
+
+// record stores usage. A failure only loses accounting, so it is logged.
+func (m *Metered) record(ctx context.Context, who meter, resp Response) {
+	if err := m.Store.RecordLLMUsage(context.WithoutCancel(ctx), db.RecordLLMUsageParams{
+		CoachID:          who.coachID,
+		Feature:          who.feature,
+		Model:            resp.Model,
+		PromptTokens:     int32(resp.Usage.PromptTokens),
+		CompletionTokens: int32(resp.Usage.CompletionTokens),
+		Estimated:        resp.Usage.Estimated,
+	}); err != nil {
+		log.Printf("[llm] record usage coach=%s feature=%s: %v", who.coachID, who.feature, err)
+	}
+}
+
+// Complete implements Provider.
+func (m *Metered) Complete(ctx context.Context, req Request) (Response, error) {
+	who, ok, err := m.check(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := m.Provider.Complete(ctx, req)
+	if err == nil && ok {
+		m.record(ctx, who, resp)
+	}
+	return resp, err
+}
+
+// Stream implements Provider.
+func (m *Metered) Stream(ctx context.Context, req Request, onDelta func(string) error) (Response, error) {
+	who, ok, err := m.check(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+	resp, err := m.Provider.Stream(ctx, req, onDelta)
+	if err == nil && ok {
+		m.record(ctx, who, resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,86 @@
+package metrics
+
+// Decoupling bases: pace-to-HR for running, power-to-HR for cycling.
+const (
+	BasisPace  = "pace"
+	BasisPower = "power"
+)
+
+// minDecouplingSec is the shortest moving time worth splitting in halves.
+const minDecouplingSec = 20 * 60
+
+// Decoupling compares efficiency in the first and second halves of a
+// workout's moving time.
+type Decoupling struct {
+	Basis     string
+	FirstEF   float64
+	SecondEF  float64
+	EF        float64 // whole-workout efficiency factor
+	Pct       float64 // positive when HR drifted up relative to output
+	MovingSec float64
+}
+
+// BasisFor picks power when the activity has a power stream, pace otherwise.
+func BasisFor(s Streams) string {
+	if len(s.Watts) == len(s.Time) && len(s.Watts) > 0 {
+		return BasisPower
+	}
+	return BasisPace
+}
+
+// AerobicDecoupling computes Pa:HR (pace basis, speed in m/min) or Pw:HR
+// (power basis, watts) decoupling, ignoring stopped time. Pace uses
+// grade-adjusted speed when altitude is available so hills don't read as
+// drift.
+func AerobicDecoupling(s Streams, basis string) (Decoupling, bool) {
+	output := s.Velocity
+	if gav := s.GradeAdjustedVelocity(); gav != nil {
+		output = gav
+	}
+	scale := 60.0 // m/s -> m/min, the usual unit for running EF
+	if basis == BasisPower {
+		output, scale = s.Watts, 1
+	}
+	if len(s.HeartRate) != len(s.Time) || len(output) != len(s.Time) {
+		return Decoupling{}, false
+	}
+
+	samples := s.movingSamples()
+	var total float64
+	for _, m := range samples {
+		total += m.dt
+	}
+	if total < minDecouplingSec {
+		return Decoupling{}, false
+	}
+
+	var halves [2]struct{ out, hr, secs float64 }
+	var elapsed float64
+	for _, m := range samples {
+		h := 0
+		if elapsed >= total/2 {
+			h = 1
+		}
+		elapsed += m.dt
+		halves[h].out += output[m.idx] * scale * m.dt
+		halves[h].hr += s.HeartRate[m.idx] * m.dt
+		halves[h].secs += m.dt
+	}
+	for _, h := range halves {
+		if h.hr == 0 || h.out == 0 {
+			return Decoupling{}, false
+		}
+	}
+
+	// Time weights cancel in out/hr, so the ratio of sums is the EF.
+	first := halves[0].out / halves[0].hr
+	second := halves[1].out / halves[1].hr
+	return Decoupling{
+		Basis:     basis,
+		FirstEF:   first,
+		SecondEF:  second,
+		EF:        (halves[0].out + halves[1].out) / (halves[0].hr + halves[1].hr),
+		Pct:       (first - second) / first * 100,
+		MovingSec: total,
+	}, true
+}
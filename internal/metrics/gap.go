@@ -0,0 +1,67 @@
+package metrics
+
+// gradeWindowM is the trailing distance over which grade is measured, long
+// enough to smooth out barometric noise.
+const gradeWindowM = 20
+
+// maxGrade clamps grades to the range Minetti's model was fitted on.
+const maxGrade = 0.45
+
+// flatCost is Minetti's energy cost of running on the flat, in J/kg/m.
+const flatCost = 3.6
+
+// minettiCost is the energy cost of running at grade i (rise over run).
+func minettiCost(i float64) float64 {
+	return 155.4*i*i*i*i*i - 30.4*i*i*i*i - 43.3*i*i*i + 46.3*i*i + 19.5*i + flatCost
+}
+
+// GradeAdjustedVelocity converts each velocity sample to the flat-ground
+// speed of equal metabolic cost. Grade comes from the altitude stream over
+// distance integrated from velocity. It returns nil without both streams.
+func (s Streams) GradeAdjustedVelocity() []float64 {
+	n := len(s.Time)
+	if n == 0 || len(s.Altitude) != n || len(s.Velocity) != n {
+		return nil
+	}
+
+	dist := make([]float64, n)
+	for i := 1; i < n; i++ {
+		dist[i] = dist[i-1]
+		if dt := s.Time[i] - s.Time[i-1]; dt > 0 && dt <= maxSampleGap {
+			dist[i] += s.Velocity[i] * dt
+		}
+	}
+
+	out := make([]float64, n)
+	j := 0
+	for i := 0; i < n; i++ {
+		for j+1 < i && dist[i]-dist[j+1] >= gradeWindowM {
+			j++
+		}
+		grade := 0.0
+		if run := dist[i] - dist[j]; run >= gradeWindowM {
+			grade = (s.Altitude[i] - s.Altitude[j]) / run
+		}
+		grade = max(-maxGrade, min(maxGrade, grade))
+		out[i] = s.Velocity[i] * minettiCost(grade) / flatCost
+	}
+	return out
+}
+
+// GradeAdjustedSpeed is the average grade-adjusted speed in m/s over moving
+// time, the basis for grade-adjusted pace.
+func GradeAdjustedSpeed(s Streams) (float64, bool) {
+	gav := s.GradeAdjustedVelocity()
+	if gav == nil {
+		return 0, false
+	}
+	var dist, secs float64
+	for _, m := range s.movingSamples() {
+		dist += gav[m.idx] * m.dt
+		secs += m.dt
+	}
+	if secs == 0 || dist == 0 {
+		return 0, false
+	}
+	return dist / secs, true
+}
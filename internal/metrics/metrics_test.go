@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+// steady builds n one-second samples at constant speed, with HR rising
+// linearly from hr0 to hr1.
+func steady(n int, speed, hr0, hr1 float64) Streams {
+	s := Streams{}
+	for i := 0; i < n; i++ {
+		s.Time = append(s.Time, float64(i))
+		s.Velocity = append(s.Velocity, speed)
+		s.HeartRate = append(s.HeartRate, hr0+(hr1-hr0)*float64(i)/float64(n-1))
+		s.Moving = append(s.Moving, true)
+	}
+	return s
+}
+
+func TestAerobicDecoupling_Drift(t *testing.T) {
+	s := steady(3600, 3, 140, 154)
+	d, ok := AerobicDecoupling(s, BasisPace)
+	if !ok {
+		t.Fatalf("expected decoupling result")
+	}
+	// First half averages ~143.5 bpm, second ~150.5: ~4.65% drift.
+	if math.Abs(d.Pct-4.65) > 0.1 {
+		t.Fatalf("decoupling = %.2f%%, want ~4.65%%", d.Pct)
+	}
+	if math.Abs(d.EF-180/147.0) > 0.01 {
+		t.Fatalf("EF = %.3f, want ~%.3f", d.EF, 180/147.0)
+	}
+}
+
+func TestAerobicDecoupling_IgnoresStops(t *testing.T) {
+	s := steady(3600, 3, 140, 140)
+	// A 10-minute stop with HR falling must not change the result.
+	for i := 600; i < 1200; i++ {
+		s.Moving[i] = false
+		s.Velocity[i] = 0
+		s.HeartRate[i] = 90
+	}
+	d, ok := AerobicDecoupling(s, BasisPace)
+	if !ok || math.Abs(d.Pct) > 0.01 || d.MovingSec > 3000 {
+		t.Fatalf("expected no drift over 50 moving minutes, got %+v", d)
+	}
+}
+
+func TestAerobicDecoupling_TooShort(t *testing.T) {
+	if _, ok := AerobicDecoupling(steady(600, 3, 140, 150), BasisPace); ok {
+		t.Fatalf("10-minute workout should be skipped")
+	}
+	if _, ok := AerobicDecoupling(steady(3600, 3, 140, 150), BasisPower); ok {
+		t.Fatalf("power basis without a power stream should be skipped")
+	}
+}
+
+func TestGradeAdjustedSpeed(t *testing.T) {
+	flat := steady(1200, 3, 140, 140)
+	flat.Altitude = make([]float64, len(flat.Time))
+	if v, ok := GradeAdjustedSpeed(flat); !ok || math.Abs(v-3) > 1e-9 {
+		t.Fatalf("flat GAP = %.3f ok=%v, want 3", v, ok)
+	}
+
+	climb := steady(1200, 3, 140, 140)
+	for i := range climb.Time {
+		climb.Altitude = append(climb.Altitude, float64(i)*3*0.05) // 5% grade
+	}
+	v, ok := GradeAdjustedSpeed(climb)
+	if !ok || v < 3.5 || v > 4.0 {
+		t.Fatalf("5%% climb GAP = %.3f, want roughly 3.7 m/s", v)
+	}
+}
+
+func TestNormalizedPower(t *testing.T) {
+	s := steady(600, 8, 140, 140)
+	s.Watts = make([]float64, len(s.Time))
+	for i := range s.Watts {
+		s.Watts[i] = 200
+	}
+	p, ok := NormalizedPower(s)
+	if !ok || math.Abs(p.Normalized-200) > 0.01 || math.Abs(p.VI-1) > 0.001 {
+		t.Fatalf("steady 200W: %+v ok=%v", p, ok)
+	}
+
+	// Alternating 5-minute blocks of 300W and 100W average 200W but NP is higher.
+	for i := range s.Watts {
+		if (i/300)%2 == 0 {
+			s.Watts[i] = 300
+		} else {
+			s.Watts[i] = 100
+		}
+	}
+	p, _ = NormalizedPower(s)
+	if p.Normalized < 230 || p.VI < 1.1 {
+		t.Fatalf("variable effort should raise NP: %+v", p)
+	}
+
+	sum := Summarize(s, "Ride", Thresholds{FTP: 250})
+	if math.Abs(sum.IF-p.Normalized/250) > 1e-9 || sum.TSS <= 0 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+}
+
+func TestTimeInZones(t *testing.T) {
+	// HR climbs 100 -> 180 over 801 samples: 1 bpm every 10 seconds.
+	s := steady(801, 3, 100, 180)
+	z := TimeInZones(s, []float64{120, 140, 160, 170})
+	want := []int{200, 200, 200, 100, 100}
+	for i := range want {
+		if math.Abs(float64(z[i]-want[i])) > 1 {
+			t.Fatalf("zones = %v, want %v", z, want)
+		}
+	}
+
+	s.HeartRate = nil
+	if TimeInZones(s, []float64{120}) != nil {
+		t.Fatal("expected nil without heart rate")
+	}
+}
+
+func TestSummarizeSport(t *testing.T) {
+	// An hour at 8 m/s up a steady 2% grade, with HR but no power.
+	s := steady(3600, 8, 140, 150)
+	for i := range s.Time {
+		s.Altitude = append(s.Altitude, 0.02*8*float64(i))
+	}
+	th := Thresholds{FTP: 250, ThresholdSpeed: 4}
+
+	ride := Summarize(s, "Ride", th)
+	if ride.IF != 0 || ride.TSS != 0 || ride.GAPSpeed != 0 || ride.HasDecoupling {
+		t.Fatalf("ride without power got run metrics: %+v", ride)
+	}
+
+	s.Velocity = slicesOf(len(s.Time), 3)
+	for i := range s.Altitude {
+		s.Altitude[i] = 0.02 * 3 * float64(i)
+	}
+	run := Summarize(s, "TrailRun", th)
+	if run.GAPSpeed <= 3 || run.IF <= 0.75 || run.TSS <= 0 || !run.HasDecoupling || run.Decoupling.Basis != BasisPace {
+		t.Fatalf("uphill run summary: %+v", run)
+	}
+}
+
+func slicesOf(n int, v float64) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
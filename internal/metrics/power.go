@@ -0,0 +1,53 @@
+package metrics
+
+import "math"
+
+// npWindow is the rolling average length, in seconds, for normalized power.
+const npWindow = 30
+
+// Power summarizes a power stream.
+type Power struct {
+	Average    float64
+	Normalized float64
+	VI         float64 // variability index, NP / average
+}
+
+// NormalizedPower computes Coggan's normalized power over moving time. The
+// stream is expanded to one-second samples so irregular recording intervals
+// weigh correctly; coasting zeros count, stopped time does not.
+func NormalizedPower(s Streams) (Power, bool) {
+	if len(s.Watts) != len(s.Time) {
+		return Power{}, false
+	}
+
+	var series []float64
+	for _, m := range s.movingSamples() {
+		for k := 0; k < int(math.Round(m.dt)); k++ {
+			series = append(series, s.Watts[m.idx])
+		}
+	}
+	if len(series) < npWindow {
+		return Power{}, false
+	}
+
+	var total, window, fourth float64
+	for i, w := range series {
+		total += w
+		window += w
+		if i >= npWindow {
+			window -= series[i-npWindow]
+		}
+		if i >= npWindow-1 {
+			fourth += math.Pow(window/npWindow, 4)
+		}
+	}
+	p := Power{
+		Average:    total / float64(len(series)),
+		Normalized: math.Pow(fourth/float64(len(series)-npWindow+1), 0.25),
+	}
+	if p.Average == 0 {
+		return Power{}, false
+	}
+	p.VI = p.Normalized / p.Average
+	return p, true
+}
@@ -0,0 +1,57 @@
+// Package metrics derives per-workout numbers from the second-by-second
+// streams recorded by the athlete's device.
+package metrics
+
+// Streams are the sampled series of one activity. Every non-nil slice has
+// the same length as Time.
+type Streams struct {
+	Time      []float64 // seconds since start
+	HeartRate []float64 // bpm
+	Velocity  []float64 // m/s, smoothed
+	Watts     []float64
+	Altitude  []float64 // metres
+	Cadence   []float64 // rpm; steps per minute per leg for runs
+	Moving    []bool
+}
+
+// maxSampleGap drops samples that follow a recording pause longer than this
+// many seconds, even when the device still flags them as moving.
+const maxSampleGap = 30
+
+// minMovingSpeed is used to detect stops when the moving stream is missing.
+const minMovingSpeed = 0.3
+
+// sample is one moving sample, weighted by the seconds since the previous one.
+type sample struct {
+	dt  float64
+	idx int
+}
+
+// movingSamples returns the samples recorded while moving, in order.
+func (s Streams) movingSamples() []sample {
+	var out []sample
+	for i := 1; i < len(s.Time); i++ {
+		dt := s.Time[i] - s.Time[i-1]
+		if dt <= 0 || dt > maxSampleGap {
+			continue
+		}
+		if len(s.Moving) == len(s.Time) {
+			if !s.Moving[i] {
+				continue
+			}
+		} else if len(s.Velocity) == len(s.Time) && s.Velocity[i] < minMovingSpeed {
+			continue
+		}
+		out = append(out, sample{dt: dt, idx: i})
+	}
+	return out
+}
+
+// MovingTime is the total seconds spent moving.
+func (s Streams) MovingTime() float64 {
+	var total float64
+	for _, m := range s.movingSamples() {
+		total += m.dt
+	}
+	return total
+}
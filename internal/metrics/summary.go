@@ -0,0 +1,59 @@
+package metrics
+
+import "strings"
+
+// Thresholds are the athlete's values intensity is measured against. Zero
+// means unknown.
+type Thresholds struct {
+	FTP            float64   // watts
+	ThresholdSpeed float64   // m/s at threshold pace
+	HRZones        []float64 // bpm at the top of each zone but the last
+}
+
+// Summary is everything derived from one activity's streams.
+type Summary struct {
+	MovingSec     float64
+	Decoupling    Decoupling
+	HasDecoupling bool
+	GAPSpeed      float64 // m/s; 0 without altitude
+	Power         Power
+	HasPower      bool
+	IF            float64 // intensity factor; 0 when thresholds are unknown
+	TSS           float64 // training stress; 0 when IF is unknown
+	ZoneSec       []int   // moving seconds per heart-rate zone; nil without HR
+}
+
+// IsRun reports whether sport is a kind of running, the only sports
+// grade-adjusted pace and threshold pace describe.
+func IsRun(sport string) bool {
+	return strings.HasSuffix(sport, "Run")
+}
+
+// Summarize derives all stream metrics for an activity of the given sport.
+// Intensity uses power against FTP when available, otherwise, for runs
+// only, grade-adjusted speed against threshold pace. Other sports without
+// power get no intensity: a ride's speed against a running threshold means
+// nothing.
+func Summarize(s Streams, sport string, th Thresholds) Summary {
+	run := IsRun(sport)
+	sum := Summary{MovingSec: s.MovingTime()}
+	if basis := BasisFor(s); basis == BasisPower || run {
+		sum.Decoupling, sum.HasDecoupling = AerobicDecoupling(s, basis)
+	}
+	if run {
+		sum.GAPSpeed, _ = GradeAdjustedSpeed(s)
+	}
+	sum.Power, sum.HasPower = NormalizedPower(s)
+
+	switch {
+	case sum.HasPower && th.FTP > 0:
+		sum.IF = sum.Power.Normalized / th.FTP
+	case run && sum.GAPSpeed > 0 && th.ThresholdSpeed > 0:
+		sum.IF = sum.GAPSpeed / th.ThresholdSpeed
+	}
+	if sum.IF > 0 {
+		sum.TSS = sum.MovingSec / 3600 * sum.IF * sum.IF * 100
+	}
+	sum.ZoneSec = TimeInZones(s, th.HRZones)
+	return sum
+}
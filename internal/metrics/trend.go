@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// AerobicSample is one stored decoupling result.
+type AerobicSample struct {
+	Start time.Time
+	Pct   float64
+	EF    float64
+}
+
+// AerobicWeek averages the samples in one Monday-start week.
+type AerobicWeek struct {
+	WeekStart time.Time
+	Count     int
+	AvgPct    float64
+	AvgEF     float64
+}
+
+// WeeklyAerobic buckets samples into consecutive weeks from..to in loc.
+// Weeks without samples are present with Count 0.
+func WeeklyAerobic(samples []AerobicSample, loc *time.Location, from, to time.Time) []AerobicWeek {
+	from, to = training.WeekStart(from, loc), training.WeekStart(to, loc)
+	var weeks []AerobicWeek
+	index := make(map[string]int)
+	for w := from; !w.After(to); w = w.AddDate(0, 0, 7) {
+		index[w.Format(time.DateOnly)] = len(weeks)
+		weeks = append(weeks, AerobicWeek{WeekStart: w})
+	}
+	for _, s := range samples {
+		i, ok := index[training.WeekStart(s.Start, loc).Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		weeks[i].Count++
+		weeks[i].AvgPct += s.Pct
+		weeks[i].AvgEF += s.EF
+	}
+	for i := range weeks {
+		if n := float64(weeks[i].Count); n > 0 {
+			weeks[i].AvgPct /= n
+			weeks[i].AvgEF /= n
+		}
+	}
+	return weeks
+}
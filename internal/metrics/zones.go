@@ -0,0 +1,34 @@
+package metrics
+
+import "math"
+
+// TimeInZones buckets moving heart-rate samples into len(bounds)+1 zones,
+// where bounds are the ascending bpm tops of every zone but the last. It
+// returns nil without bounds or a heart-rate stream.
+func TimeInZones(s Streams, bounds []float64) []int {
+	if len(bounds) == 0 || len(s.HeartRate) != len(s.Time) {
+		return nil
+	}
+	secs := make([]float64, len(bounds)+1)
+	var seen bool
+	for _, m := range s.movingSamples() {
+		hr := s.HeartRate[m.idx]
+		if hr <= 0 {
+			continue
+		}
+		z := 0
+		for z < len(bounds) && hr > bounds[z] {
+			z++
+		}
+		secs[z] += m.dt
+		seen = true
+	}
+	if !seen {
+		return nil
+	}
+	out := make([]int, len(secs))
+	for i, v := range secs {
+		out[i] = int(math.Round(v))
+	}
+	return out
+}
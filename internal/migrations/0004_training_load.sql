@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE athlete
+  ADD COLUMN IF NOT EXISTS max_hr INT,
+  ADD COLUMN IF NOT EXISTS resting_hr INT;
+
+ALTER TABLE workout
+  ADD COLUMN IF NOT EXISTS load FLOAT;                   -- TRIMP, computed at sync
+
+CREATE TABLE IF NOT EXISTS athlete_alert (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  athlete_id   UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  kind         TEXT NOT NULL,                            -- acwr, volume_jump, hard_streak, training_gap
+  day          DATE NOT NULL,                            -- athlete-local day the rule fired on
+  value        FLOAT NOT NULL,
+  threshold    FLOAT NOT NULL,
+  message      TEXT NOT NULL,
+  dismissed_at TIMESTAMPTZ,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (athlete_id, kind, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_athlete_started
+  ON workout (athlete_id, started_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_workout_athlete_started;
+DROP TABLE IF EXISTS athlete_alert;
+ALTER TABLE workout DROP COLUMN IF EXISTS load;
+ALTER TABLE athlete
+  DROP COLUMN IF EXISTS resting_hr,
+  DROP COLUMN IF EXISTS max_hr;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS workout_metrics (
+  workout_id        UUID PRIMARY KEY REFERENCES workout(id) ON DELETE CASCADE,
+  moving_sec        INT NOT NULL,
+  decoupling_basis  TEXT,                                  -- pace or power
+  decoupling_pct    FLOAT,
+  efficiency_factor FLOAT,
+  computed_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS workout_metrics;
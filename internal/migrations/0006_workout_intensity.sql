@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE athlete
+  ADD COLUMN IF NOT EXISTS ftp_watts INT,
+  ADD COLUMN IF NOT EXISTS threshold_pace_sec INT;       -- seconds per km
+
+ALTER TABLE workout_metrics
+  ADD COLUMN IF NOT EXISTS gap_speed FLOAT,              -- m/s, grade adjusted
+  ADD COLUMN IF NOT EXISTS avg_power FLOAT,
+  ADD COLUMN IF NOT EXISTS normalized_power FLOAT,
+  ADD COLUMN IF NOT EXISTS variability_index FLOAT,
+  ADD COLUMN IF NOT EXISTS intensity_factor FLOAT;
+
+-- +goose Down
+ALTER TABLE workout_metrics
+  DROP COLUMN IF EXISTS intensity_factor,
+  DROP COLUMN IF EXISTS variability_index,
+  DROP COLUMN IF EXISTS normalized_power,
+  DROP COLUMN IF EXISTS avg_power,
+  DROP COLUMN IF EXISTS gap_speed;
+
+ALTER TABLE athlete
+  DROP COLUMN IF EXISTS threshold_pace_sec,
+  DROP COLUMN IF EXISTS ftp_watts;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE workout
+  ADD COLUMN IF NOT EXISTS review_status TEXT NOT NULL DEFAULT 'ok',   -- ok, flagged, accepted, rejected
+  ADD COLUMN IF NOT EXISTS review_flags TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE workout
+  DROP COLUMN IF EXISTS review_flags,
+  DROP COLUMN IF EXISTS review_status;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS planned_workout (
+  id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  athlete_id          UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  day                 DATE NOT NULL,                     -- athlete-local day
+  sport               TEXT NOT NULL,
+  title               TEXT NOT NULL,
+  description         TEXT,
+  target_duration_sec INT,
+  target_distance_m   FLOAT,
+  target_load         FLOAT,
+  created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_planned_workout_athlete_day
+  ON planned_workout (athlete_id, day);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_planned_workout_athlete_day;
+DROP TABLE IF EXISTS planned_workout;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE planned_workout
+  ADD COLUMN IF NOT EXISTS target_zone INT,              -- heart-rate zone 1-5
+  ADD COLUMN IF NOT EXISTS workout_id UUID REFERENCES workout(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'planned',  -- planned, completed, partial
+  ADD COLUMN IF NOT EXISTS compliance FLOAT;             -- 0-1 score against targets
+
+ALTER TABLE workout_metrics
+  ADD COLUMN IF NOT EXISTS zone_sec INT[];               -- moving seconds per HR zone
+
+-- +goose Down
+ALTER TABLE workout_metrics DROP COLUMN IF EXISTS zone_sec;
+ALTER TABLE planned_workout
+  DROP COLUMN IF EXISTS compliance,
+  DROP COLUMN IF EXISTS status,
+  DROP COLUMN IF EXISTS workout_id,
+  DROP COLUMN IF EXISTS target_zone;
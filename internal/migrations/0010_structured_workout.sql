@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE planned_workout
+  ADD COLUMN IF NOT EXISTS structure JSONB;              -- structured.Workout
+
+-- +goose Down
+ALTER TABLE planned_workout DROP COLUMN IF EXISTS structure;
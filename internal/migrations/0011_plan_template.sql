@@ -0,0 +1,61 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS plan_template (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id    UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  name        TEXT NOT NULL,
+  description TEXT,
+  weeks       INT NOT NULL CHECK (weeks BETWEEN 1 AND 52),
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_template_coach ON plan_template (coach_id);
+
+CREATE TABLE IF NOT EXISTS plan_template_session (
+  id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  template_id         UUID NOT NULL REFERENCES plan_template(id) ON DELETE CASCADE,
+  week                INT NOT NULL CHECK (week >= 1),  -- 1-based
+  day                 INT NOT NULL CHECK (day BETWEEN 1 AND 7),
+  sport               TEXT NOT NULL,
+  title               TEXT NOT NULL,
+  description         TEXT,
+  target_duration_sec INT,
+  target_distance_m   FLOAT,
+  target_load         FLOAT,
+  target_zone         INT,
+  structure           JSONB,
+  created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_template_session_template
+  ON plan_template_session (template_id, week, day);
+
+-- One row per time a template was put on an athlete's calendar.
+CREATE TABLE IF NOT EXISTS plan_template_application (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  template_id UUID NOT NULL REFERENCES plan_template(id) ON DELETE CASCADE,
+  athlete_id  UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  start_day   DATE NOT NULL,                          -- week 1 day 1
+  scale       FLOAT NOT NULL DEFAULT 1,               -- volume factor
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_template_application_template
+  ON plan_template_application (template_id);
+
+ALTER TABLE planned_workout
+  ADD COLUMN IF NOT EXISTS template_session_id UUID REFERENCES plan_template_session(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS application_id UUID REFERENCES plan_template_application(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_planned_workout_template_session
+  ON planned_workout (template_session_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_planned_workout_template_session;
+ALTER TABLE planned_workout
+  DROP COLUMN IF EXISTS application_id,
+  DROP COLUMN IF EXISTS template_session_id;
+DROP TABLE IF EXISTS plan_template_application;
+DROP TABLE IF EXISTS plan_template_session;
+DROP TABLE IF EXISTS plan_template;
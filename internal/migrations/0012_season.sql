@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS athlete_event (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  athlete_id    UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  name          TEXT NOT NULL,
+  day           DATE NOT NULL,
+  priority      TEXT NOT NULL CHECK (priority IN ('A', 'B', 'C')),
+  sport         TEXT NOT NULL,
+  distance_m    FLOAT,
+  goal_time_sec INT,
+  notes         TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_athlete_event_athlete_day ON athlete_event (athlete_id, day);
+
+CREATE TABLE IF NOT EXISTS training_phase (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  athlete_id    UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  kind          TEXT NOT NULL,                          -- base, build, peak, taper, recovery
+  start_day     DATE NOT NULL,
+  end_day       DATE NOT NULL CHECK (end_day >= start_day),
+  ramp_per_week FLOAT,                                  -- planned CTL change; NULL uses the kind's default
+  notes         TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_phase_athlete_start ON training_phase (athlete_id, start_day);
+
+-- +goose Down
+DROP TABLE IF EXISTS training_phase;
+DROP TABLE IF EXISTS athlete_event;
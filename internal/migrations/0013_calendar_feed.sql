@@ -0,0 +1,19 @@
+-- +goose Up
+-- Secret ICS feed URLs. A row with an athlete is that athlete's feed; one
+-- without is the coach's feed across all their athletes. Regenerating
+-- replaces the token, revoking deletes the row.
+CREATE TABLE IF NOT EXISTS calendar_feed (
+  token      TEXT PRIMARY KEY,
+  coach_id   UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  athlete_id UUID REFERENCES athlete(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_calendar_feed_athlete
+  ON calendar_feed (athlete_id) WHERE athlete_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_calendar_feed_coach
+  ON calendar_feed (coach_id) WHERE athlete_id IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS calendar_feed;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Bulk calendar edits. undo holds the sessions the operation created and
+-- the prior state of those it changed; undone_at is set once reverted.
+CREATE TABLE IF NOT EXISTS plan_operation (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id   UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  kind       TEXT NOT NULL,                          -- shift, copy_week, copy_athletes, scale
+  summary    TEXT NOT NULL,
+  undo       JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  undone_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_operation_coach_created
+  ON plan_operation (coach_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS plan_operation;
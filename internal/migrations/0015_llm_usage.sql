@@ -0,0 +1,18 @@
+-- +goose Up
+-- One row per model call, for per-coach usage and limits.
+CREATE TABLE IF NOT EXISTS llm_usage (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id          UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  feature           TEXT NOT NULL,                     -- what the call was for, e.g. weekly_report
+  model             TEXT NOT NULL,
+  prompt_tokens     INT NOT NULL,
+  completion_tokens INT NOT NULL,
+  estimated         BOOLEAN NOT NULL DEFAULT false,    -- counts approximated, not reported by the server
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_coach_created
+  ON llm_usage (coach_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS llm_usage;
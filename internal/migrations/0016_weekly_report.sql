@@ -0,0 +1,19 @@
+-- +goose Up
+-- The Monday digest for a coach. facts is the structured week the model was
+-- given; the narrative fields are what it wrote back.
+CREATE TABLE IF NOT EXISTS weekly_report (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id   UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  week_start DATE NOT NULL,                            -- Monday of the week reported on
+  facts      JSONB NOT NULL,
+  summary    TEXT NOT NULL,
+  highlights TEXT[] NOT NULL DEFAULT '{}',
+  concerns   TEXT[] NOT NULL DEFAULT '{}',
+  model      TEXT NOT NULL,                            -- empty when written without the model
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  emailed_at TIMESTAMPTZ,
+  UNIQUE (coach_id, week_start)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS weekly_report;
@@ -0,0 +1,52 @@
+-- +goose Up
+-- Coach conversations with the assistant about one athlete. replying_at is
+-- set while a reply streams, so a second tab can't start another.
+CREATE TABLE IF NOT EXISTS chat_conversation (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id    UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  athlete_id  UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  title       TEXT NOT NULL,
+  replying_at TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_conversation_athlete_updated
+  ON chat_conversation (athlete_id, updated_at DESC);
+
+-- A reply's turns are written in one transaction, so they share created_at;
+-- the identity key keeps them in order.
+CREATE TABLE IF NOT EXISTS chat_message (
+  id              BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  conversation_id UUID NOT NULL REFERENCES chat_conversation(id) ON DELETE CASCADE,
+  role            TEXT NOT NULL,                       -- user, assistant, tool
+  content         TEXT NOT NULL DEFAULT '',
+  tool_calls      JSONB,                               -- assistant turns that called tools
+  tool_call_id    TEXT,                                -- tool results: the call answered
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_message_conversation
+  ON chat_message (conversation_id, id);
+
+-- Audit log of every tool the model ran and what it was shown.
+CREATE TABLE IF NOT EXISTS chat_tool_call (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  conversation_id UUID NOT NULL REFERENCES chat_conversation(id) ON DELETE CASCADE,
+  coach_id        UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  athlete_id      UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  tool            TEXT NOT NULL,
+  arguments       TEXT NOT NULL,
+  result          TEXT NOT NULL,
+  error           TEXT,
+  duration_ms     INT NOT NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_tool_call_coach_created
+  ON chat_tool_call (coach_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS chat_tool_call;
+DROP TABLE IF EXISTS chat_message;
+DROP TABLE IF EXISTS chat_conversation;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Laps as recorded by the device, in order. Speeds are m/s.
+CREATE TABLE IF NOT EXISTS workout_lap (
+  workout_id  UUID NOT NULL REFERENCES workout(id) ON DELETE CASCADE,
+  lap_index   INT NOT NULL,                            -- 1-based
+  elapsed_sec INT NOT NULL,
+  moving_sec  INT NOT NULL,
+  distance_m  FLOAT NOT NULL,
+  avg_speed   FLOAT,
+  avg_hr      FLOAT,
+  max_hr      FLOAT,
+  avg_watts   FLOAT,
+  PRIMARY KEY (workout_id, lap_index)
+);
+
+-- The model's note on a workout. Nothing reaches the athlete until the coach
+-- approves it; body starts as the draft and carries the coach's edits.
+CREATE TABLE IF NOT EXISTS workout_commentary (
+  workout_id  UUID PRIMARY KEY REFERENCES workout(id) ON DELETE CASCADE,
+  draft       TEXT NOT NULL,                           -- as the model wrote it
+  body        TEXT NOT NULL,
+  status      TEXT NOT NULL DEFAULT 'draft',           -- draft, approved, discarded
+  facts       JSONB NOT NULL,
+  model       TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_commentary_draft
+  ON workout_commentary (created_at) WHERE status = 'draft';
+
+-- +goose Down
+DROP TABLE IF EXISTS workout_commentary;
+DROP TABLE IF EXISTS workout_lap;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- A plan the language model drafted for one athlete. The sessions are
+-- written into a plan template, which the coach edits like any other; the
+-- draft is published by applying that template from start_day.
+CREATE TABLE IF NOT EXISTS plan_draft (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  coach_id     UUID NOT NULL REFERENCES coach(id) ON DELETE CASCADE,
+  athlete_id   UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  event_id     UUID REFERENCES athlete_event(id) ON DELETE SET NULL,   -- goal race
+  template_id  UUID REFERENCES plan_template(id) ON DELETE SET NULL,    -- set once generated
+  start_day    DATE NOT NULL,                                          -- a Monday
+  weeks        INT NOT NULL CHECK (weeks BETWEEN 1 AND 52),
+  days         INT[] NOT NULL,                                         -- training weekdays, 1 = Monday
+  notes        TEXT,
+  status       TEXT NOT NULL DEFAULT 'generating',                     -- generating, ready, failed, published
+  error        TEXT,
+  warnings     TEXT[] NOT NULL DEFAULT '{}',                           -- sessions dropped as invalid
+  model        TEXT NOT NULL DEFAULT '',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_draft_athlete ON plan_draft (athlete_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_plan_draft_template ON plan_draft (template_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS plan_draft;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- The prompt template, as name@version, each piece of model output was
+-- written from. Rows from before templates were versioned have ''.
+ALTER TABLE weekly_report
+  ADD COLUMN IF NOT EXISTS prompt_version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE workout_commentary
+  ADD COLUMN IF NOT EXISTS prompt_version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE plan_draft
+  ADD COLUMN IF NOT EXISTS prompt_version TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE chat_message
+  ADD COLUMN IF NOT EXISTS model TEXT,                   -- assistant messages only
+  ADD COLUMN IF NOT EXISTS prompt_version TEXT;
+
+-- +goose Down
+ALTER TABLE chat_message
+  DROP COLUMN IF EXISTS prompt_version,
+  DROP COLUMN IF EXISTS model;
+
+ALTER TABLE plan_draft DROP COLUMN IF EXISTS prompt_version;
+ALTER TABLE workout_commentary DROP COLUMN IF EXISTS prompt_version;
+ALTER TABLE weekly_report DROP COLUMN IF EXISTS prompt_version;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- A conversation between coach and athlete about one workout or one
+-- planned session, started by the first comment. Each side has a secret
+-- token: it is the address part of the Reply-To on that side's
+-- notification emails, and the athlete's also opens the thread in the
+-- browser without signing in.
+CREATE TABLE IF NOT EXISTS comment_thread (
+  id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  athlete_id         UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  workout_id         UUID UNIQUE REFERENCES workout(id) ON DELETE CASCADE,
+  planned_workout_id UUID UNIQUE REFERENCES planned_workout(id) ON DELETE CASCADE,
+  coach_token        TEXT NOT NULL UNIQUE,
+  athlete_token      TEXT NOT NULL UNIQUE,
+  created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (num_nonnulls(workout_id, planned_workout_id) = 1)
+);
+
+CREATE TABLE IF NOT EXISTS thread_comment (
+  id         BIGSERIAL PRIMARY KEY,
+  thread_id  UUID NOT NULL REFERENCES comment_thread(id) ON DELETE CASCADE,
+  author     TEXT NOT NULL CHECK (author IN ('coach', 'athlete')),
+  body       TEXT NOT NULL,
+  via        TEXT NOT NULL DEFAULT 'web',                -- web or email
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_comment_thread ON thread_comment (thread_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS thread_comment;
+DROP TABLE IF EXISTS comment_thread;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- How a workout felt, from the athlete. rpe is session RPE on the CR-10
+-- scale; times the workout's minutes it gives session-RPE load.
+CREATE TABLE IF NOT EXISTS workout_feedback (
+  workout_id UUID PRIMARY KEY REFERENCES workout(id) ON DELETE CASCADE,
+  athlete_id UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  rpe        INT NOT NULL CHECK (rpe BETWEEN 1 AND 10),
+  notes      TEXT,
+  pain       TEXT[] NOT NULL DEFAULT '{}',              -- body areas that hurt
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_feedback_athlete ON workout_feedback (athlete_id);
+
+-- One check-in per athlete-local day. Any field may be skipped. Scores are
+-- 1-5: higher is better for sleep_quality and mood, worse for soreness and
+-- stress.
+CREATE TABLE IF NOT EXISTS wellness_checkin (
+  athlete_id    UUID NOT NULL REFERENCES athlete(id) ON DELETE CASCADE,
+  day           DATE NOT NULL,
+  sleep_hours   FLOAT CHECK (sleep_hours BETWEEN 0 AND 24),
+  sleep_quality INT CHECK (sleep_quality BETWEEN 1 AND 5),
+  soreness      INT CHECK (soreness BETWEEN 1 AND 5),
+  stress        INT CHECK (stress BETWEEN 1 AND 5),
+  mood          INT CHECK (mood BETWEEN 1 AND 5),
+  resting_hr    INT,
+  notes         TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (athlete_id, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS wellness_checkin;
+DROP TABLE IF EXISTS workout_feedback;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- Every email the app sends is written here first, in the same transaction
+-- as whatever it announces, and sent by the worker, which retries until
+-- the attempts run out and the email is failed. coach_id and athlete_id say
+-- whose it is, for showing delivery to the coach; both are empty for mail
+-- to people who aren't users yet.
+CREATE TABLE IF NOT EXISTS email_outbox (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind       TEXT NOT NULL,                              -- invite, sign_in, comment, ...
+  coach_id   UUID REFERENCES coach(id) ON DELETE CASCADE,
+  athlete_id UUID REFERENCES athlete(id) ON DELETE CASCADE,
+  to_addr    TEXT NOT NULL,
+  reply_to   TEXT,
+  subject    TEXT NOT NULL,
+  html       TEXT NOT NULL,
+  status     TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+  attempts   INT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox (created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_coach ON email_outbox (coach_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_athlete ON email_outbox (athlete_id, kind, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_outbox;
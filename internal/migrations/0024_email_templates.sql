@@ -0,0 +1,22 @@
+-- +goose Up
+-- Emails are now multipart: the outbox keeps the plain-text alternative
+-- next to the HTML, and the unsubscribe link for mail that offers one.
+ALTER TABLE email_outbox
+  ADD COLUMN IF NOT EXISTS text_body        TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS list_unsubscribe TEXT;
+
+-- Addresses that asked not to get a kind of email (the daily check-in,
+-- so far). Mail people need to use the app, like sign-in links, is never
+-- suppressed.
+CREATE TABLE IF NOT EXISTS email_suppression (
+  address    TEXT NOT NULL,
+  kind       TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (address, kind)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_suppression;
+ALTER TABLE email_outbox
+  DROP COLUMN IF EXISTS list_unsubscribe,
+  DROP COLUMN IF EXISTS text_body;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Where a workout's load came from: the heart-rate estimate set on every
+-- sync, or power or pace TSS from its streams. A re-sync only replaces an
+-- estimate, so a failed streams fetch can't downgrade a stream load.
+ALTER TABLE workout
+  ADD COLUMN IF NOT EXISTS load_source TEXT NOT NULL DEFAULT 'hr' CHECK (load_source IN ('hr', 'stream'));
+
+-- Workouts with a power or pace intensity already carry their stream load.
+UPDATE workout w
+SET load_source = 'stream'
+FROM workout_metrics m
+WHERE m.workout_id = w.id AND m.intensity_factor IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE workout DROP COLUMN IF EXISTS load_source;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Portal sign-in matches addresses without regard to case, so they must be
+-- unique that way too, or two athletes could answer to one address.
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_athlete_email_lower
+  ON athlete (lower(email)) WHERE email IS NOT NULL;
+DROP INDEX IF EXISTS uniq_athlete_email;
+
+-- +goose Down
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_athlete_email
+  ON athlete (email) WHERE email IS NOT NULL;
+DROP INDEX IF EXISTS uniq_athlete_email_lower;
@@ -0,0 +1,149 @@
+// Package outbox is how the app sends email. An email is stored first, in
+// the same transaction as whatever it announces, and the worker sends it
+// from there, retrying with backoff. The stored row records delivery, so a
+// coach can see whether an invite arrived, and nothing is lost when the
+// mail server is down for a request.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+	"github.com/briangreenhill/coachgpt/internal/jobs"
+)
+
+// Kinds of email. Each is also the name of its template in package email.
+const (
+	KindInvite        = "invite"
+	KindSignIn        = "sign_in"
+	KindAthleteSignIn = "athlete_sign_in"
+	KindInterest      = "interest"
+	KindComment       = "comment"
+	KindSession       = "session"
+	KindCheckin       = "checkin"
+	KindAlert         = "alert"
+	KindWeeklyReport  = "weekly_report"
+)
+
+// Delivery statuses. An email is pending until it is sent or its retries
+// run out.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// MaxRetry is how many times a failed send is retried. With asynq's
+// backoff the last try is about a day after the first.
+const MaxRetry = 10
+
+// StaleAfter is how long a pending email can go untouched before the
+// sweep assumes its task was lost and queues it again.
+const StaleAfter = 10 * time.Minute
+
+// Owner is whose email it is: the coach and athlete it concerns, either
+// of which may be unset.
+type Owner struct {
+	CoachID   pgtype.UUID
+	AthleteID pgtype.UUID
+}
+
+// Add stores m to be sent. Call it with the queries of the transaction
+// that makes the email true, then Enqueue it once that commits.
+func Add(ctx context.Context, q *db.Queries, kind string, m email.Message, owner Owner) (db.EmailOutbox, error) {
+	if m.To == "" {
+		return db.EmailOutbox{}, fmt.Errorf("recipient empty")
+	}
+	row, err := q.CreateOutboxEmail(ctx, db.CreateOutboxEmailParams{
+		Kind:            kind,
+		CoachID:         owner.CoachID,
+		AthleteID:       owner.AthleteID,
+		ToAddr:          m.To,
+		ReplyTo:         pgtype.Text{String: m.ReplyTo, Valid: m.ReplyTo != ""},
+		Subject:         m.Subject,
+		Html:            m.HTML,
+		TextBody:        m.Text,
+		ListUnsubscribe: pgtype.Text{String: m.Unsubscribe, Valid: m.Unsubscribe != ""},
+	})
+	if err != nil {
+		return db.EmailOutbox{}, fmt.Errorf("store email: %w", err)
+	}
+	return row, nil
+}
+
+// Enqueue queues the send task for a stored email. Its task ID makes
+// queueing an email twice harmless. An email whose task never makes it
+// stays pending, and the worker's sweep queues it again.
+func Enqueue(ctx context.Context, client *asynq.Client, id uuid.UUID) error {
+	payload, err := json.Marshal(jobs.SendEmailPayload{EmailID: id.String()})
+	if err != nil {
+		return err
+	}
+	_, err = client.EnqueueContext(ctx, asynq.NewTask(jobs.TaskSendEmail, payload),
+		asynq.TaskID("email:"+id.String()),
+		asynq.MaxRetry(MaxRetry),
+		asynq.Timeout(time.Minute),
+	)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		return nil
+	}
+	return err
+}
+
+// Deliver sends a stored email and records how it went. After the last
+// attempt a failure marks the email failed for good. An email that isn't
+// pending is left alone, so a duplicate task doesn't send it twice.
+func Deliver(ctx context.Context, q *db.Queries, sender email.Sender, id uuid.UUID, last bool) error {
+	row, err := q.GetOutboxEmail(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get email: %w", err)
+	}
+	if row.Status != StatusPending {
+		return nil
+	}
+
+	sendErr := sender.SendMessage(Message(row))
+	if sendErr == nil {
+		if err := q.MarkOutboxEmailSent(ctx, id); err != nil {
+			return fmt.Errorf("mark email sent: %w", err)
+		}
+		return nil
+	}
+	status := StatusPending
+	if last {
+		status = StatusFailed
+	}
+	if err := q.MarkOutboxEmailAttempt(ctx, db.MarkOutboxEmailAttemptParams{
+		Status:    status,
+		LastError: pgtype.Text{String: sendErr.Error(), Valid: true},
+		ID:        id,
+	}); err != nil {
+		return fmt.Errorf("record failed send: %w (send: %v)", err, sendErr)
+	}
+	return sendErr
+}
+
+// Message is the email a stored row sends.
+func Message(row db.EmailOutbox) email.Message {
+	return email.Message{
+		To:          row.ToAddr,
+		ReplyTo:     row.ReplyTo.String,
+		Subject:     row.Subject,
+		HTML:        row.Html,
+		Text:        row.TextBody,
+		Unsubscribe: row.ListUnsubscribe.String,
+	}
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/email"
+)
+
+func TestAddNeedsRecipient(t *testing.T) {
+	if _, err := Add(context.Background(), nil, KindInvite, email.Message{Subject: "s", HTML: "h"}, Owner{}); err == nil {
+		t.Fatal("stored an email with no recipient")
+	}
+}
+
+func TestMessage(t *testing.T) {
+	row := db.EmailOutbox{ToAddr: "a@example.com", Subject: "Hi", Html: "<p>x</p>"}
+	if m := Message(row); m != (email.Message{To: "a@example.com", Subject: "Hi", HTML: "<p>x</p>"}) {
+		t.Fatalf("Message = %+v", m)
+	}
+	row.ReplyTo = pgtype.Text{String: "reply+t@example.com", Valid: true}
+	if m := Message(row); m.ReplyTo != "reply+t@example.com" {
+		t.Fatalf("ReplyTo = %q", m.ReplyTo)
+	}
+	row.TextBody = "x"
+	row.ListUnsubscribe = pgtype.Text{String: "https://example.com/unsubscribe", Valid: true}
+	if m := Message(row); m.Text != "x" || m.Unsubscribe != "https://example.com/unsubscribe" {
+		t.Fatalf("Text = %q, Unsubscribe = %q", m.Text, m.Unsubscribe)
+	}
+}
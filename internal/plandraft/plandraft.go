@@ -0,0 +1,304 @@
+// Package plandraft asks the language model for a block of training and
+// checks what comes back. The athlete, goal race, available days and
+// recent load go to the model as a Brief; its sessions are validated
+// against the same rules as a hand-written planned workout, with one round
+// of repair for anything that fails. The sessions are saved as a plan
+// template the coach edits before publishing it to the calendar.
+package plandraft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/prompts"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// Feature is the usage label for plan drafts.
+const Feature = "plan_draft"
+
+// Draft states.
+const (
+	StatusGenerating = "generating"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+	StatusPublished  = "published"
+)
+
+// Limits on a draft and its sessions.
+const (
+	MaxWeeks          = 24
+	MaxSessionsPerDay = 2
+	MaxDurationMin    = 600
+	MaxDistanceKm     = 300
+	MaxTitle          = 100
+	MaxDescription    = 1000
+	MaxNotes          = 2000
+)
+
+// Sports the model may plan, the same Strava sport types offered when
+// planning a session by hand.
+var Sports = []string{"Run", "TrailRun", "Ride", "VirtualRide", "Swim", "WeightTraining", "Workout"}
+
+// Brief is what the model is asked to plan from.
+type Brief struct {
+	Athlete     Athlete `json:"athlete"`
+	Goal        *Race   `json:"goal_race,omitempty"`
+	OtherRaces  []Race  `json:"other_races,omitempty"`
+	Start       string  `json:"start_date"` // a Monday
+	Weeks       int     `json:"weeks"`
+	Days        []int   `json:"available_days"` // 1 = Monday
+	Notes       string  `json:"coach_notes,omitempty"`
+	Phases      []Phase `json:"phases,omitempty"`
+	RecentWeeks []Week  `json:"recent_weeks"` // oldest first
+	CTL         float64 `json:"current_ctl"`
+}
+
+// Athlete is the athlete's profile.
+type Athlete struct {
+	Name          string    `json:"name"`
+	MaxHR         int       `json:"max_hr,omitempty"`
+	RestingHR     int       `json:"resting_hr,omitempty"`
+	HRZones       []float64 `json:"hr_zone_upper_bounds,omitempty"`
+	FTP           int       `json:"ftp_w,omitempty"`
+	ThresholdPace string    `json:"threshold_pace_per_km,omitempty"`
+}
+
+// Race is a race on the calendar. Week and Day place it in the block, and
+// are zero when it falls outside.
+type Race struct {
+	Name       string  `json:"name"`
+	Date       string  `json:"date"`
+	Week       int     `json:"week,omitempty"`
+	Day        int     `json:"day,omitempty"`
+	Priority   string  `json:"priority"`
+	Sport      string  `json:"sport"`
+	DistanceKm float64 `json:"distance_km,omitempty"`
+	GoalTime   string  `json:"goal_time,omitempty"`
+}
+
+// Phase is a training phase the coach has set that overlaps the block.
+type Phase struct {
+	Kind  string  `json:"kind"`
+	Start string  `json:"start"`
+	End   string  `json:"end"`
+	Ramp  float64 `json:"ctl_ramp_per_week"`
+}
+
+// Week is one recent week of training.
+type Week struct {
+	Start      string  `json:"week_of"`
+	Sessions   int     `json:"sessions"`
+	Hours      float64 `json:"hours"`
+	DistanceKm float64 `json:"distance_km"`
+	Load       float64 `json:"load"`
+}
+
+// Plan is the model's answer.
+type Plan struct {
+	Summary  string    `json:"summary"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Session is one planned session, placed by week and day of the block.
+// Structure is in the compact workout syntax.
+type Session struct {
+	Week        int     `json:"week"`
+	Day         int     `json:"day"`
+	Sport       string  `json:"sport"`
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	DurationMin float64 `json:"duration_min,omitempty"`
+	DistanceKm  float64 `json:"distance_km,omitempty"`
+	Zone        int     `json:"zone,omitempty"`
+	Structure   string  `json:"structure,omitempty"`
+}
+
+// Name is the title of the template a draft is saved as.
+func Name(b Brief) string {
+	if b.Goal != nil {
+		return fmt.Sprintf("%s: %s (draft)", b.Athlete.Name, b.Goal.Name)
+	}
+	return fmt.Sprintf("%s: %d weeks from %s (draft)", b.Athlete.Name, b.Weeks, b.Start)
+}
+
+// Check validates one session against the brief, returning the first
+// problem found.
+func Check(b Brief, s Session) error {
+	switch {
+	case s.Week < 1 || s.Week > b.Weeks:
+		return fmt.Errorf("week must be 1-%d", b.Weeks)
+	case s.Day < 1 || s.Day > 7:
+		return errors.New("day must be 1-7")
+	case !slices.Contains(b.Days, s.Day):
+		return fmt.Errorf("day %d is not an available day", s.Day)
+	case !slices.Contains(Sports, s.Sport):
+		return fmt.Errorf("sport %q is not one of %s", s.Sport, strings.Join(Sports, ", "))
+	case strings.TrimSpace(s.Title) == "":
+		return errors.New("title required")
+	case len(s.Title) > MaxTitle:
+		return errors.New("title is too long")
+	case len(s.Description) > MaxDescription:
+		return errors.New("description is too long")
+	case s.DurationMin < 0 || s.DurationMin > MaxDurationMin:
+		return fmt.Errorf("duration_min must be 0-%d", MaxDurationMin)
+	case s.DistanceKm < 0 || s.DistanceKm > MaxDistanceKm:
+		return fmt.Errorf("distance_km must be 0-%d", MaxDistanceKm)
+	case s.Zone < 0 || s.Zone > 5:
+		return errors.New("zone must be 1-5, or 0 for none")
+	case s.DurationMin == 0 && s.DistanceKm == 0 && s.Structure == "":
+		return errors.New("give a duration, a distance or a structure")
+	}
+	if s.Structure != "" {
+		if _, err := structured.Parse(s.Structure); err != nil {
+			return fmt.Errorf("structure: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate splits the plan's sessions into those that pass Check and a
+// description of each that doesn't. Sessions beyond MaxSessionsPerDay on a
+// day are rejected too.
+func Validate(b Brief, p Plan) ([]Session, []string) {
+	var valid []Session
+	var problems []string
+	perDay := map[[2]int]int{}
+	for i, s := range p.Sessions {
+		err := Check(b, s)
+		if err == nil {
+			if perDay[[2]int{s.Week, s.Day}] >= MaxSessionsPerDay {
+				err = fmt.Errorf("more than %d sessions on the day", MaxSessionsPerDay)
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("session %d (week %d day %d, %q): %v", i+1, s.Week, s.Day, s.Title, err))
+			continue
+		}
+		perDay[[2]int{s.Week, s.Day}]++
+		valid = append(valid, s)
+	}
+	if len(p.Sessions) == 0 {
+		problems = append(problems, "the plan has no sessions")
+	}
+	return valid, problems
+}
+
+// Prompt is the template plans are drafted from.
+var Prompt = prompts.Register[Brief](prompts.Def{
+	Name:    Feature,
+	Version: 1,
+	System: `You are an assistant to an endurance coach, drafting a block of training for one athlete. The coach will review and edit it before the athlete sees it.
+You are given the athlete, their goal race, the weeks to plan, the days they can train and their recent training as JSON.
+Plan every week from week 1 to the last. Days are numbered 1 (Monday) to 7 (Sunday) and week 1 starts on start_date; only use available_days, at most two sessions a day.
+Build volume gradually from the recent weeks (roughly 5-10% a week, with an easier week every third or fourth), follow any phases given, and taper into the goal race.
+Reply with JSON only, in exactly this shape:
+{"summary": "<two or three sentences on how the block is built>",
+ "sessions": [{"week": 1, "day": 2, "sport": "Run", "title": "Threshold intervals", "description": "<optional note to the athlete>",
+   "duration_min": 60, "distance_km": 0, "zone": 4, "structure": "wu 15min @z2, 3x(10min @threshold, 2min rest @z1), cd 10min"}]}
+sport is one of: Run, TrailRun, Ride, VirtualRide, Swim, WeightTraining, Workout.
+duration_min or distance_km may be 0 when the other is given; zone is the main heart-rate zone 1-5, or 0 for none.
+structure is optional, for sessions with intervals. Its syntax is one step or block per comma: an optional intent (wu, cd, rest), an amount (15min, 90s, 400m, 2km) and an optional "@target": z2 or z2-3, recovery, easy, tempo, threshold, vo2, a pace like 4:30/km, watts like 250w, or 95-105% of threshold. "Nx" repeats the next step or parenthesised block, as in 5x(400m @3:30/km, 200m rest).`,
+	User:        "{{ json . }}",
+	Temperature: 0.4,
+	MaxTokens:   12000,
+})
+
+// RepairPrompt sends the problems with a plan back to the model, continuing
+// the conversation that produced it.
+var RepairPrompt = prompts.Register[[]string](prompts.Def{
+	Name:    Feature + "_repair",
+	Version: 1,
+	User: `That plan has problems:
+{{ range . }}- {{ . }}
+{{ end }}Reply with the whole corrected plan, as JSON in the same shape.`,
+})
+
+// Parse reads the model's answer, tolerating code fences around the JSON.
+func Parse(content string) (Plan, error) {
+	s := strings.TrimSpace(content)
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+
+	var p Plan
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &p); err != nil {
+		return Plan{}, fmt.Errorf("not a JSON plan: %w", err)
+	}
+	p.Summary = strings.TrimSpace(p.Summary)
+	for i := range p.Sessions {
+		s := &p.Sessions[i]
+		s.Title = strings.TrimSpace(s.Title)
+		s.Description = strings.TrimSpace(s.Description)
+		s.Structure = strings.TrimSpace(s.Structure)
+	}
+	return p, nil
+}
+
+// Result is a generated plan. Warnings describe the sessions that were
+// still invalid after repair and were left out.
+type Result struct {
+	Summary  string
+	Sessions []Session
+	Warnings []string
+	Model    string
+}
+
+// Generate asks the model for a plan. An answer that doesn't parse or has
+// invalid sessions is sent back once with the problems listed; whatever is
+// still invalid after that is dropped with a warning. It fails only when
+// no valid session is left. llm.ErrQuotaExceeded is returned as is.
+func Generate(ctx context.Context, p llm.Provider, b Brief) (Result, error) {
+	req, err := Prompt.Render(b)
+	if err != nil {
+		return Result{}, err
+	}
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return Result{}, err
+	}
+	plan, err := Parse(resp.Message.Content)
+	var problems []string
+	if err != nil {
+		problems = []string{err.Error()}
+	} else if _, problems = Validate(b, plan); len(problems) == 0 {
+		return Result{Summary: plan.Summary, Sessions: plan.Sessions, Model: resp.Model}, nil
+	}
+
+	repair, err := RepairPrompt.Render(problems)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Messages = append(append(req.Messages, resp.Message), repair.Messages...)
+	resp, err = p.Complete(ctx, req)
+	if err != nil {
+		return Result{}, err
+	}
+	plan, err = Parse(resp.Message.Content)
+	if err != nil {
+		return Result{}, fmt.Errorf("repaired plan: %w", err)
+	}
+	valid, problems := Validate(b, plan)
+	if len(valid) == 0 {
+		return Result{}, errors.New("the model's plan has no valid sessions")
+	}
+	return Result{Summary: plan.Summary, Sessions: valid, Warnings: problems, Model: resp.Model}, nil
+}
+
+func clock(secs float64) string {
+	t := int(math.Round(secs))
+	if t >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", t/3600, t/60%60, t%60)
+	}
+	return fmt.Sprintf("%d:%02d", t/60, t%60)
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
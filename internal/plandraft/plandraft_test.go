@@ -0,0 +1,161 @@
+package plandraft
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+var brief = Brief{Start: "2025-03-03", Weeks: 4, Days: []int{2, 4, 6, 7}}
+
+func TestCheck(t *testing.T) {
+	ok := Session{Week: 1, Day: 2, Sport: "Run", Title: "Easy", DurationMin: 45, Zone: 2}
+	if err := Check(brief, ok); err != nil {
+		t.Fatalf("valid session: %v", err)
+	}
+	cases := []struct {
+		name string
+		edit func(*Session)
+		want string
+	}{
+		{"week past the block", func(s *Session) { s.Week = 5 }, "week must be 1-4"},
+		{"unavailable day", func(s *Session) { s.Day = 3 }, "day 3 is not an available day"},
+		{"unknown sport", func(s *Session) { s.Sport = "Rowing" }, `sport "Rowing"`},
+		{"no title", func(s *Session) { s.Title = " " }, "title required"},
+		{"too long", func(s *Session) { s.DurationMin = 700 }, "duration_min"},
+		{"zone", func(s *Session) { s.Zone = 6 }, "zone"},
+		{"no target", func(s *Session) { s.DurationMin = 0 }, "give a duration"},
+		{"bad structure", func(s *Session) { s.Structure = "5x(400m @fast" }, "structure:"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := ok
+			c.edit(&s)
+			if err := Check(brief, s); err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Fatalf("err = %v, want %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	run := Session{Week: 1, Day: 2, Sport: "Run", Title: "Run", DurationMin: 30}
+	valid, problems := Validate(brief, Plan{Sessions: []Session{run, run, run, {Week: 9}}})
+	if len(valid) != 2 || len(problems) != 2 {
+		t.Fatalf("valid %d, problems %q", len(valid), problems)
+	}
+	if !strings.Contains(problems[0], "session 3") || !strings.Contains(problems[0], "more than 2 sessions") {
+		t.Errorf("problem = %q", problems[0])
+	}
+	if _, problems := Validate(brief, Plan{}); len(problems) != 1 {
+		t.Errorf("empty plan problems = %q", problems)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse("```json\n{\"summary\": \" Base. \", \"sessions\": [{\"week\": 1, \"day\": 2, \"sport\": \"Run\", \"title\": \" Easy \"}]}\n```")
+	if err != nil || p.Summary != "Base." || len(p.Sessions) != 1 || p.Sessions[0].Title != "Easy" {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+	if _, err := Parse("Here is your plan!"); err == nil {
+		t.Error("prose parsed as a plan")
+	}
+}
+
+const goodPlan = `{"summary": "Two easy weeks.", "sessions": [
+  {"week": 1, "day": 2, "sport": "Run", "title": "Easy", "duration_min": 40, "zone": 2},
+  {"week": 2, "day": 6, "sport": "Run", "title": "Strides", "structure": "wu 10min, 6x(20s @z5, 1min rest), cd 10min"}]}`
+
+func TestGenerate(t *testing.T) {
+	fake := &llm.Fake{Replies: []llm.Message{{Content: goodPlan}}}
+	r, err := Generate(context.Background(), fake, brief)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Sessions) != 2 || r.Summary != "Two easy weeks." || r.Warnings != nil || r.Model != "fake" {
+		t.Fatalf("got %+v", r)
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Fatalf("%d calls for a valid plan", n)
+	}
+	if !strings.Contains(fake.Calls()[0].Messages[1].Content, `"available_days": [`) {
+		t.Errorf("brief = %s", fake.Calls()[0].Messages[1].Content)
+	}
+}
+
+func TestGenerateRepairs(t *testing.T) {
+	bad := `{"summary": "x", "sessions": [{"week": 1, "day": 1, "sport": "Run", "title": "Monday run", "duration_min": 30}]}`
+	fake := &llm.Fake{Replies: []llm.Message{{Content: bad}, {Content: goodPlan}}}
+	r, err := Generate(context.Background(), fake, brief)
+	if err != nil || len(r.Sessions) != 2 || r.Warnings != nil {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	repair := fake.Calls()[1].Messages
+	if len(repair) != 4 || repair[2].Content != bad {
+		t.Fatalf("repair request = %+v", repair)
+	}
+	if !strings.Contains(repair[3].Content, "day 1 is not an available day") {
+		t.Errorf("repair prompt = %q", repair[3].Content)
+	}
+}
+
+func TestGenerateDropsInvalid(t *testing.T) {
+	mixed := `{"summary": "x", "sessions": [
+  {"week": 1, "day": 2, "sport": "Run", "title": "Easy", "duration_min": 40},
+  {"week": 1, "day": 4, "sport": "Kayak", "title": "Paddle", "duration_min": 40}]}`
+	fake := &llm.Fake{Replies: []llm.Message{{Content: "not json"}, {Content: mixed}}}
+	r, err := Generate(context.Background(), fake, brief)
+	if err != nil || len(r.Sessions) != 1 || len(r.Warnings) != 1 || !strings.Contains(r.Warnings[0], "Kayak") {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+
+	fake = &llm.Fake{Replies: []llm.Message{{Content: "not json"}, {Content: "still not"}}}
+	if _, err := Generate(context.Background(), fake, brief); err == nil {
+		t.Error("unparseable repair accepted")
+	}
+	fake = &llm.Fake{Replies: []llm.Message{{Content: `{"sessions": []}`}, {Content: `{"sessions": []}`}}}
+	if _, err := Generate(context.Background(), fake, brief); err == nil {
+		t.Error("empty plan accepted")
+	}
+}
+
+func TestTemplateSession(t *testing.T) {
+	tid := uuid.New()
+	p, err := Session{Week: 2, Day: 6, Sport: "Run", Title: "Strides", Zone: 2, Structure: "wu 10min, 6x(20s @z5, 1min rest), cd 10min"}.templateSession(tid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TemplateID != tid || p.Week != 2 || p.Day != 6 || p.TargetDurationSec.Int32 != 1680 || p.TargetDistanceM.Valid || len(p.Structure) == 0 {
+		t.Fatalf("got %+v", p)
+	}
+	p, _ = Session{Week: 1, Day: 2, Sport: "Run", Title: "Long", DistanceKm: 18.25}.templateSession(tid)
+	if p.TargetDistanceM.Float64 != 18250 || p.TargetDurationSec.Valid || p.TargetZone.Valid {
+		t.Fatalf("got %+v", p)
+	}
+}
+
+func TestRace(t *testing.T) {
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	e := db.AthleteEvent{
+		Name:        "Spring 10k",
+		Day:         pgtype.Date{Time: time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC), Valid: true},
+		Priority:    "A",
+		Sport:       "Run",
+		DistanceM:   pgtype.Float8{Float64: 10000, Valid: true},
+		GoalTimeSec: pgtype.Int4{Int32: 2700, Valid: true},
+	}
+	r := race(e, start, start.AddDate(0, 0, 28))
+	if r.Week != 4 || r.Day != 7 || r.GoalTime != "45:00" || r.DistanceKm != 10 {
+		t.Fatalf("got %+v", r)
+	}
+	if r := race(e, start, start.AddDate(0, 0, 21)); r.Week != 0 {
+		t.Fatalf("race after the block placed in week %d", r.Week)
+	}
+}
@@ -0,0 +1,222 @@
+package plandraft
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/season"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Recent history handed to the model.
+const (
+	recentWeeks = 8
+	ctlDays     = 90
+)
+
+// Collect gathers the brief for draft d: the athlete's profile, the goal
+// race and any others in the block, the coach's phases, and the last eight
+// full weeks of training before now.
+func Collect(ctx context.Context, q *db.Queries, d db.PlanDraft, athlete db.Athlete, now time.Time) (Brief, error) {
+	start := d.StartDay.Time
+	end := start.AddDate(0, 0, int(d.Weeks)*7)
+	b := Brief{
+		Athlete: Athlete{
+			Name:      athlete.Name,
+			MaxHR:     int(athlete.MaxHr.Int32),
+			RestingHR: int(athlete.RestingHr.Int32),
+			FTP:       int(athlete.FtpWatts.Int32),
+		},
+		Start:       start.Format(time.DateOnly),
+		Weeks:       int(d.Weeks),
+		Notes:       d.Notes.String,
+		RecentWeeks: []Week{},
+	}
+	for _, day := range d.Days {
+		b.Days = append(b.Days, int(day))
+	}
+	if athlete.MaxHr.Valid && athlete.RestingHr.Valid {
+		b.Athlete.HRZones = training.HeartRateFor(int(athlete.MaxHr.Int32), int(athlete.RestingHr.Int32)).ZoneBounds()
+		for i, z := range b.Athlete.HRZones {
+			b.Athlete.HRZones[i] = math.Round(z)
+		}
+	}
+	if athlete.ThresholdPaceSec.Valid && athlete.ThresholdPaceSec.Int32 > 0 {
+		b.Athlete.ThresholdPace = clock(float64(athlete.ThresholdPaceSec.Int32))
+	}
+
+	events, err := q.ListAthleteEvents(ctx, athlete.ID)
+	if err != nil {
+		return b, fmt.Errorf("list events: %w", err)
+	}
+	for _, e := range events {
+		r := race(e, start, end)
+		switch {
+		case d.EventID.Valid && e.ID == d.EventID.Bytes:
+			b.Goal = &r
+		case r.Week > 0:
+			b.OtherRaces = append(b.OtherRaces, r)
+		}
+	}
+
+	phases, err := q.ListTrainingPhases(ctx, athlete.ID)
+	if err != nil {
+		return b, fmt.Errorf("list phases: %w", err)
+	}
+	for _, p := range phases {
+		if p.EndDay.Time.Before(start) || !p.StartDay.Time.Before(end) {
+			continue
+		}
+		ramp := season.Phase{Kind: p.Kind, Ramp: p.RampPerWeek.Float64}.WeeklyRamp()
+		b.Phases = append(b.Phases, Phase{
+			Kind:  p.Kind,
+			Start: p.StartDay.Time.Format(time.DateOnly),
+			End:   p.EndDay.Time.Format(time.DateOnly),
+			Ramp:  ramp,
+		})
+	}
+
+	loc := training.Location(athlete.Tz)
+	thisWeek := training.WeekStart(now, loc)
+	from := thisWeek.AddDate(0, 0, -7*recentWeeks)
+	workouts, err := q.ListWorkoutsBetween(ctx, db.ListWorkoutsBetweenParams{
+		AthleteID: athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: thisWeek, Valid: true},
+	})
+	if err != nil {
+		return b, fmt.Errorf("list workouts: %w", err)
+	}
+	weeks := make([]Week, recentWeeks)
+	for i := range weeks {
+		weeks[i].Start = from.AddDate(0, 0, 7*i).Format(time.DateOnly)
+	}
+	for _, w := range workouts {
+		if w.ReviewStatus != "ok" && w.ReviewStatus != "accepted" {
+			continue
+		}
+		i := int(w.StartedAt.Time.Sub(from).Hours()) / (7 * 24)
+		if i < 0 || i >= recentWeeks {
+			continue
+		}
+		weeks[i].Sessions++
+		weeks[i].Hours += float64(w.DurationSec) / 3600
+		weeks[i].DistanceKm += w.DistanceM.Float64 / 1000
+		weeks[i].Load += w.Load.Float64
+	}
+	for _, w := range weeks {
+		w.Hours, w.DistanceKm, w.Load = round1(w.Hours), round1(w.DistanceKm), math.Round(w.Load)
+		b.RecentWeeks = append(b.RecentWeeks, w)
+	}
+
+	days, err := training.DailyLoads(ctx, q, athlete, now.AddDate(0, 0, -ctlDays), now)
+	if err != nil {
+		return b, err
+	}
+	if ctl := training.CTL(days, 0); len(ctl) > 0 {
+		b.CTL = math.Round(ctl[len(ctl)-1])
+	}
+	return b, nil
+}
+
+// race describes an event, placed in the block from start to end when it
+// falls inside.
+func race(e db.AthleteEvent, start, end time.Time) Race {
+	r := Race{
+		Name:       e.Name,
+		Date:       e.Day.Time.Format(time.DateOnly),
+		Priority:   e.Priority,
+		Sport:      e.Sport,
+		DistanceKm: round1(e.DistanceM.Float64 / 1000),
+	}
+	if e.GoalTimeSec.Valid {
+		r.GoalTime = clock(float64(e.GoalTimeSec.Int32))
+	}
+	if d := e.Day.Time; !d.Before(start) && d.Before(end) {
+		days := int(math.Round(d.Sub(start).Hours() / 24))
+		r.Week, r.Day = days/7+1, days%7+1
+	}
+	return r
+}
+
+// Save writes the generated sessions into a new plan template and marks
+// the draft ready. Run it in a transaction.
+func Save(ctx context.Context, q *db.Queries, d db.PlanDraft, name string, r Result) (uuid.UUID, error) {
+	t, err := q.CreatePlanTemplate(ctx, db.CreatePlanTemplateParams{
+		CoachID:     d.CoachID,
+		Name:        name,
+		Description: pgtype.Text{String: r.Summary, Valid: r.Summary != ""},
+		Weeks:       d.Weeks,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("create template: %w", err)
+	}
+	for _, s := range r.Sessions {
+		params, err := s.templateSession(t.ID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if _, err := q.CreatePlanTemplateSession(ctx, params); err != nil {
+			return uuid.Nil, fmt.Errorf("create template session: %w", err)
+		}
+	}
+	warnings := r.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	if err := q.SetPlanDraftReady(ctx, db.SetPlanDraftReadyParams{
+		ID:            d.ID,
+		TemplateID:    pgtype.UUID{Bytes: t.ID, Valid: true},
+		Warnings:      warnings,
+		Model:         r.Model,
+		PromptVersion: Prompt.ID(),
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("mark draft ready: %w", err)
+	}
+	return t.ID, nil
+}
+
+// templateSession converts a checked session. As with a session planned
+// by hand, totals the structure pins down exactly fill in missing targets.
+func (s Session) templateSession(templateID uuid.UUID) (db.CreatePlanTemplateSessionParams, error) {
+	p := db.CreatePlanTemplateSessionParams{
+		TemplateID:        templateID,
+		Week:              int32(s.Week),
+		Day:               int32(s.Day),
+		Sport:             s.Sport,
+		Title:             s.Title,
+		Description:       pgtype.Text{String: s.Description, Valid: s.Description != ""},
+		TargetDurationSec: pgtype.Int4{Int32: int32(math.Round(s.DurationMin * 60)), Valid: s.DurationMin > 0},
+		TargetDistanceM:   pgtype.Float8{Float64: math.Round(s.DistanceKm * 1000), Valid: s.DistanceKm > 0},
+		TargetZone:        pgtype.Int4{Int32: int32(s.Zone), Valid: s.Zone > 0},
+	}
+	if s.Structure == "" {
+		return p, nil
+	}
+	sw, err := structured.Parse(s.Structure)
+	if err != nil {
+		return p, fmt.Errorf("session %q structure: %w", s.Title, err)
+	}
+	if p.Structure, err = sw.Encode(); err != nil {
+		return p, fmt.Errorf("session %q structure: %w", s.Title, err)
+	}
+	var timed, measured bool
+	for _, st := range sw.Flatten() {
+		timed = timed || st.DurationSec > 0
+		measured = measured || st.DistanceM > 0
+	}
+	if !p.TargetDurationSec.Valid && !measured {
+		p.TargetDurationSec = pgtype.Int4{Int32: int32(sw.Seconds(0)), Valid: true}
+	}
+	if !p.TargetDistanceM.Valid && !timed {
+		p.TargetDistanceM = pgtype.Float8{Float64: sw.Meters(), Valid: true}
+	}
+	return p, nil
+}
@@ -0,0 +1,105 @@
+// Package planops applies bulk edits to athletes' calendars: shifting
+// sessions, copying weeks and scaling volume. Each operation returns an
+// Undo recording what it created and what the sessions it changed looked
+// like before, so the whole edit can be reverted in one step.
+package planops
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/plantemplate"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// Operation kinds.
+const (
+	KindShift        = "shift"
+	KindCopyWeek     = "copy_week"
+	KindCopyAthletes = "copy_athletes"
+	KindScale        = "scale"
+)
+
+// MaxShiftDays bounds a shift either way.
+const MaxShiftDays = 90
+
+// Undo is what reverting an operation needs.
+type Undo struct {
+	Created []db.PlannedWorkout `json:"created,omitempty"`
+	Before  []db.PlannedWorkout `json:"before,omitempty"`
+}
+
+// Encode serialises the undo entry for storage.
+func (u Undo) Encode() ([]byte, error) {
+	return json.Marshal(u)
+}
+
+// DecodeUndo reads a stored undo entry.
+func DecodeUndo(b []byte) (Undo, error) {
+	var u Undo
+	if err := json.Unmarshal(b, &u); err != nil {
+		return Undo{}, fmt.Errorf("decode undo: %w", err)
+	}
+	return u, nil
+}
+
+// Touched collects the days an operation changed per athlete, so matching
+// can be re-run for them.
+type Touched map[uuid.UUID][]pgtype.Date
+
+func (t Touched) add(athleteID uuid.UUID, day pgtype.Date) {
+	if !slices.ContainsFunc(t[athleteID], func(d pgtype.Date) bool { return d.Time.Equal(day.Time) }) {
+		t[athleteID] = append(t[athleteID], day)
+	}
+}
+
+// Sessions counts the sessions an undo entry covers.
+func (u Undo) Sessions() int {
+	return len(u.Created) + len(u.Before)
+}
+
+// ScaleTargets multiplies a session's volume by f, rounding like template
+// scaling does. Repeat counts in the structure scale, interval lengths
+// don't.
+func ScaleTargets(p db.PlannedWorkout, f float64) (db.PlannedWorkout, error) {
+	t := plantemplate.Targets{
+		DurationSec: float64(p.TargetDurationSec.Int32),
+		DistanceM:   p.TargetDistanceM.Float64,
+		Load:        p.TargetLoad.Float64,
+	}
+	if len(p.Structure) > 0 {
+		sw, err := structured.Decode(p.Structure)
+		if err != nil {
+			return p, fmt.Errorf("planned workout %s: %w", p.ID, err)
+		}
+		t.Structure = &sw
+	}
+	t = plantemplate.Scale(t, f)
+
+	p.TargetDurationSec.Int32 = int32(t.DurationSec)
+	p.TargetDistanceM.Float64 = t.DistanceM
+	p.TargetLoad.Float64 = t.Load
+	if t.Structure != nil {
+		b, err := t.Structure.Encode()
+		if err != nil {
+			return p, fmt.Errorf("planned workout %s: %w", p.ID, err)
+		}
+		p.Structure = b
+	}
+	return p, nil
+}
+
+// WeekOffset is the number of days from one week's Monday to another's.
+func WeekOffset(from, to time.Time) int {
+	return int(to.Sub(from).Round(24*time.Hour).Hours() / 24)
+}
+
+func addDays(d pgtype.Date, n int) pgtype.Date {
+	return pgtype.Date{Time: d.Time.AddDate(0, 0, n), Valid: true}
+}
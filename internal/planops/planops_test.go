@@ -0,0 +1,104 @@
+package planops
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+func session(t *testing.T, src string) db.PlannedWorkout {
+	t.Helper()
+	p := db.PlannedWorkout{
+		ID:                uuid.New(),
+		AthleteID:         uuid.New(),
+		Day:               pgtype.Date{Time: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), Valid: true},
+		Sport:             "Run",
+		Title:             "Intervals",
+		TargetDurationSec: pgtype.Int4{Int32: 3600, Valid: true},
+		TargetLoad:        pgtype.Float8{Float64: 80, Valid: true},
+		TemplateSessionID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+	}
+	if src != "" {
+		sw, err := structured.Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Structure, err = sw.Encode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return p
+}
+
+func TestScaleTargets(t *testing.T) {
+	p := session(t, "wu 15min, 5x(1km @4:00/km, 2min rest), cd 10min")
+	got, err := ScaleTargets(p, 0.8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TargetDurationSec.Int32 != 2880 || !got.TargetDurationSec.Valid {
+		t.Fatalf("duration = %+v, want 48min", got.TargetDurationSec)
+	}
+	if got.TargetLoad.Float64 != 64 {
+		t.Fatalf("load = %v, want 64", got.TargetLoad.Float64)
+	}
+	if got.TargetDistanceM.Valid {
+		t.Fatalf("unset distance became set")
+	}
+	sw, err := structured.Decode(got.Structure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw.Steps[0].DurationSec != 720 || sw.Steps[1].Repeat != 4 {
+		t.Fatalf("structure = %s", sw.DSL())
+	}
+}
+
+func TestUndoRoundTrip(t *testing.T) {
+	u := Undo{
+		Created: []db.PlannedWorkout{session(t, "")},
+		Before:  []db.PlannedWorkout{session(t, "3x(1km @Z4, 90s rest)")},
+	}
+	b, err := u.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeUndo(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sessions() != 2 {
+		t.Fatalf("sessions = %d", got.Sessions())
+	}
+	want, have := u.Before[0], got.Before[0]
+	if have.ID != want.ID || !have.Day.Time.Equal(want.Day.Time) || have.TemplateSessionID != want.TemplateSessionID ||
+		have.TargetDurationSec != want.TargetDurationSec || string(have.Structure) != string(want.Structure) {
+		t.Fatalf("round trip lost data:\n got %+v\nwant %+v", have, want)
+	}
+}
+
+func TestWeekOffsetAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	from := time.Date(2025, 3, 24, 0, 0, 0, 0, berlin)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, berlin) // clocks change in between
+	if got := WeekOffset(from, to); got != 7 {
+		t.Fatalf("offset = %d, want 7", got)
+	}
+
+	touched := Touched{}
+	id := uuid.New()
+	d := pgtype.Date{Time: from, Valid: true}
+	touched.add(id, d)
+	touched.add(id, d)
+	if len(touched[id]) != 1 {
+		t.Fatalf("duplicate day recorded")
+	}
+}
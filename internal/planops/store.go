@@ -0,0 +1,219 @@
+package planops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+// horizon is how far ahead "from this date forward" reaches.
+const horizon = 5 * 365
+
+// The operations below run in the caller's transaction and leave matching
+// to the caller, which re-runs it for the Touched days. Sessions already
+// matched to a workout record what happened and are never moved or
+// rescaled. Changed sessions are detached from their template session,
+// like any hand edit.
+
+// Shift moves the athlete's sessions on or after from by days.
+func Shift(ctx context.Context, q *db.Queries, athleteID uuid.UUID, from time.Time, days int) (Undo, Touched, error) {
+	start := date(from)
+	planned, err := q.ListPlannedWorkoutsBetween(ctx, db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: athleteID,
+		FromDay:   start,
+		ToDay:     addDays(start, horizon),
+	})
+	if err != nil {
+		return Undo{}, nil, fmt.Errorf("list planned workouts: %w", err)
+	}
+
+	var u Undo
+	touched := Touched{}
+	for _, p := range planned {
+		if p.WorkoutID.Valid {
+			continue
+		}
+		moved := p
+		moved.Day = addDays(p.Day, days)
+		if err := update(ctx, q, moved); err != nil {
+			return Undo{}, nil, err
+		}
+		u.Before = append(u.Before, p)
+		touched.add(athleteID, p.Day)
+		touched.add(athleteID, moved.Day)
+	}
+	return u, touched, nil
+}
+
+// CopyWeek copies every session in the week starting srcWeek on the source
+// athlete's calendar into the week starting dstWeek for each target, on
+// the same weekdays. Copies are plain sessions with no template link and
+// are added alongside whatever the target week already holds.
+func CopyWeek(ctx context.Context, q *db.Queries, sourceID uuid.UUID, srcWeek time.Time, targetIDs []uuid.UUID, dstWeek time.Time) (Undo, Touched, error) {
+	start := date(srcWeek)
+	planned, err := q.ListPlannedWorkoutsBetween(ctx, db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: sourceID,
+		FromDay:   start,
+		ToDay:     addDays(start, 6),
+	})
+	if err != nil {
+		return Undo{}, nil, fmt.Errorf("list planned workouts: %w", err)
+	}
+
+	offset := WeekOffset(srcWeek, dstWeek)
+	var u Undo
+	touched := Touched{}
+	for _, target := range targetIDs {
+		for _, p := range planned {
+			c, err := q.CreatePlannedWorkout(ctx, db.CreatePlannedWorkoutParams{
+				AthleteID:         target,
+				Day:               addDays(p.Day, offset),
+				Sport:             p.Sport,
+				Title:             p.Title,
+				Description:       p.Description,
+				TargetDurationSec: p.TargetDurationSec,
+				TargetDistanceM:   p.TargetDistanceM,
+				TargetLoad:        p.TargetLoad,
+				TargetZone:        p.TargetZone,
+				Structure:         p.Structure,
+			})
+			if err != nil {
+				return Undo{}, nil, fmt.Errorf("create planned workout: %w", err)
+			}
+			u.Created = append(u.Created, c)
+			touched.add(target, c.Day)
+		}
+	}
+	return u, touched, nil
+}
+
+// Scale multiplies the volume of the athlete's sessions from..to inclusive
+// by f.
+func Scale(ctx context.Context, q *db.Queries, athleteID uuid.UUID, from, to time.Time, f float64) (Undo, Touched, error) {
+	planned, err := q.ListPlannedWorkoutsBetween(ctx, db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: athleteID,
+		FromDay:   date(from),
+		ToDay:     date(to),
+	})
+	if err != nil {
+		return Undo{}, nil, fmt.Errorf("list planned workouts: %w", err)
+	}
+
+	var u Undo
+	touched := Touched{}
+	for _, p := range planned {
+		if p.WorkoutID.Valid {
+			continue
+		}
+		scaled, err := ScaleTargets(p, f)
+		if err != nil {
+			return Undo{}, nil, err
+		}
+		if err := update(ctx, q, scaled); err != nil {
+			return Undo{}, nil, err
+		}
+		u.Before = append(u.Before, p)
+		touched.add(athleteID, p.Day)
+	}
+	return u, touched, nil
+}
+
+// ErrEditedSince means a session the operation touched has been changed
+// since, and undoing it would throw that change away.
+var ErrEditedSince = errors.New("sessions have been edited since; undo them by hand")
+
+// Revert deletes the sessions the operation created and puts the ones it
+// changed back as they were. Sessions deleted since are skipped. applied is
+// when the operation ran; a session updated after it fails the revert with
+// ErrEditedSince rather than losing the edit.
+func Revert(ctx context.Context, q *db.Queries, u Undo, applied time.Time) (Touched, error) {
+	touched := Touched{}
+	for _, p := range u.Created {
+		_, ok, err := current(ctx, q, p, applied)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := q.DeletePlannedWorkout(ctx, db.DeletePlannedWorkoutParams{ID: p.ID, AthleteID: p.AthleteID}); err != nil {
+			return nil, fmt.Errorf("delete planned workout: %w", err)
+		}
+		touched.add(p.AthleteID, p.Day)
+	}
+	for _, p := range u.Before {
+		cur, ok, err := current(ctx, q, p, applied)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, err := q.RestorePlannedWorkout(ctx, db.RestorePlannedWorkoutParams{
+			ID:                p.ID,
+			AthleteID:         p.AthleteID,
+			Day:               p.Day,
+			Sport:             p.Sport,
+			Title:             p.Title,
+			Description:       p.Description,
+			TargetDurationSec: p.TargetDurationSec,
+			TargetDistanceM:   p.TargetDistanceM,
+			TargetLoad:        p.TargetLoad,
+			TargetZone:        p.TargetZone,
+			Structure:         p.Structure,
+			TemplateSessionID: p.TemplateSessionID,
+		}); err != nil {
+			return nil, fmt.Errorf("restore planned workout: %w", err)
+		}
+		touched.add(p.AthleteID, cur.Day)
+		touched.add(p.AthleteID, p.Day)
+	}
+	return touched, nil
+}
+
+// current reads p as it is now, or false if it has been deleted. The
+// operation wrote every session it touched in its own transaction, so their
+// updated_at is applied unless something has edited them since.
+func current(ctx context.Context, q *db.Queries, p db.PlannedWorkout, applied time.Time) (db.PlannedWorkout, bool, error) {
+	cur, err := q.GetPlannedWorkout(ctx, db.GetPlannedWorkoutParams{ID: p.ID, AthleteID: p.AthleteID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.PlannedWorkout{}, false, nil
+	}
+	if err != nil {
+		return db.PlannedWorkout{}, false, fmt.Errorf("get planned workout: %w", err)
+	}
+	if cur.UpdatedAt.Time.After(applied) {
+		return db.PlannedWorkout{}, false, fmt.Errorf("planned workout %s: %w", p.ID, ErrEditedSince)
+	}
+	return cur, true, nil
+}
+
+func update(ctx context.Context, q *db.Queries, p db.PlannedWorkout) error {
+	if _, err := q.UpdatePlannedWorkout(ctx, db.UpdatePlannedWorkoutParams{
+		ID:                p.ID,
+		AthleteID:         p.AthleteID,
+		Day:               p.Day,
+		Sport:             p.Sport,
+		Title:             p.Title,
+		Description:       p.Description,
+		TargetDurationSec: p.TargetDurationSec,
+		TargetDistanceM:   p.TargetDistanceM,
+		TargetLoad:        p.TargetLoad,
+		TargetZone:        p.TargetZone,
+		Structure:         p.Structure,
+	}); err != nil {
+		return fmt.Errorf("update planned workout: %w", err)
+	}
+	return nil
+}
+
+func date(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
@@ -0,0 +1,99 @@
+// Package plantemplate lays reusable training blocks out on an athlete's
+// calendar. A template is a number of weeks of sessions placed by week and
+// day within the week, both counted from 1; applying it anchors day one to
+// a date and scales the volume.
+package plantemplate
+
+import (
+	"math"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+// Anchors say which end of the block the chosen date pins down.
+const (
+	AnchorStart = "start" // the date is day 1 of week 1
+	AnchorRace  = "race"  // the date is the last day of the final week
+)
+
+// Limits on templates and scaling.
+const (
+	DaysPerWeek = 7
+	MaxWeeks    = 52
+	MinScale    = 0.25
+	MaxScale    = 3.0
+)
+
+// Start returns the first day of the block for the anchor date.
+func Start(date time.Time, anchor string, weeks int) time.Time {
+	if anchor == AnchorRace {
+		return date.AddDate(0, 0, 1-weeks*DaysPerWeek)
+	}
+	return date
+}
+
+// Day places a session from its week and day relative to the block start.
+func Day(start time.Time, week, day int) time.Time {
+	return start.AddDate(0, 0, (week-1)*DaysPerWeek+day-1)
+}
+
+// Targets are the volume targets of one session; zero means unset.
+type Targets struct {
+	DurationSec float64
+	DistanceM   float64
+	Load        float64
+	Structure   *structured.Workout
+}
+
+// Scale multiplies the session volume by f, rounding to values a coach
+// would write by hand.
+func Scale(t Targets, f float64) Targets {
+	if f == 1 {
+		return t
+	}
+	out := Targets{
+		DurationSec: roundSeconds(t.DurationSec * f),
+		DistanceM:   roundMeters(t.DistanceM * f),
+		Load:        math.Round(t.Load * f),
+	}
+	if t.Structure != nil {
+		sw := ScaleWorkout(*t.Structure, f)
+		out.Structure = &sw
+	}
+	return out
+}
+
+// ScaleWorkout scales steady steps by time or distance. Repeat blocks keep
+// their interval lengths and scale the number of repeats instead, so 0.8 of
+// 5x1km is 4x1km rather than 5x800m.
+func ScaleWorkout(w structured.Workout, f float64) structured.Workout {
+	steps := make([]structured.Step, len(w.Steps))
+	for i, s := range w.Steps {
+		if s.IsRepeat() {
+			s.Repeat = min(structured.MaxRepeat, max(1, int(math.Round(float64(s.Repeat)*f))))
+		} else {
+			s.DurationSec = roundSeconds(s.DurationSec * f)
+			s.DistanceM = roundMeters(s.DistanceM * f)
+		}
+		steps[i] = s
+	}
+	return structured.Workout{Steps: steps}
+}
+
+// roundSeconds rounds to whole minutes from ten minutes up and to five
+// seconds below that.
+func roundSeconds(v float64) float64 {
+	if v >= 600 {
+		return math.Round(v/60) * 60
+	}
+	return math.Round(v/5) * 5
+}
+
+// roundMeters rounds to 100 m from a kilometre up and to 10 m below that.
+func roundMeters(v float64) float64 {
+	if v >= 1000 {
+		return math.Round(v/100) * 100
+	}
+	return math.Round(v/10) * 10
+}
@@ -0,0 +1,57 @@
+package plantemplate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/structured"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func TestStart(t *testing.T) {
+	if got := Start(day("2025-03-03"), AnchorStart, 12); !got.Equal(day("2025-03-03")) {
+		t.Fatalf("start anchor = %s", got.Format(time.DateOnly))
+	}
+
+	// A 12-week block for a Sunday race ends on race day.
+	race := day("2025-05-25")
+	start := Start(race, AnchorRace, 12)
+	if !start.Equal(day("2025-03-03")) {
+		t.Fatalf("race anchor = %s", start.Format(time.DateOnly))
+	}
+	if last := Day(start, 12, 7); !last.Equal(race) {
+		t.Fatalf("week 12 day 7 = %s, want race day", last.Format(time.DateOnly))
+	}
+	if got := Day(start, 2, 3); !got.Equal(day("2025-03-12")) {
+		t.Fatalf("week 2 day 3 = %s", got.Format(time.DateOnly))
+	}
+}
+
+func TestScale(t *testing.T) {
+	sw, err := structured.Parse("wu 15min, 5x(1km @4:00/km, 2min rest), cd 10min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Scale(Targets{DurationSec: 3600, DistanceM: 12000, Load: 80, Structure: &sw}, 0.8)
+	if got.DurationSec != 2880 || got.DistanceM != 9600 || got.Load != 64 {
+		t.Fatalf("scaled targets = %+v", got)
+	}
+	if dsl := got.Structure.DSL(); dsl != "wu 12min\n4x(1km @4:00/km, rest 2min)\ncd 8min" {
+		t.Fatalf("scaled structure:\n%s", dsl)
+	}
+	if sw.Steps[1].Repeat != 5 {
+		t.Fatal("scaling changed the original workout")
+	}
+
+	small := Scale(Targets{DurationSec: 390}, 1.1)
+	if small.DurationSec != 430 {
+		t.Fatalf("short durations round to 5 s, got %v", small.DurationSec)
+	}
+	if one := ScaleWorkout(structured.Workout{Steps: []structured.Step{{Repeat: 2, Steps: sw.Steps[1].Steps}}}, 0.25); one.Steps[0].Repeat != 1 {
+		t.Fatalf("repeats never drop below one, got %d", one.Steps[0].Repeat)
+	}
+}
@@ -0,0 +1,186 @@
+package plantemplate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/structured"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Apply clones the template's sessions onto the athlete's calendar from
+// start, scaled by scale, and records the application they link back to.
+// It returns the days that received sessions so the caller can rematch
+// any that are already past. Run it in a transaction.
+func Apply(ctx context.Context, q *db.Queries, templateID, athleteID uuid.UUID, start time.Time, scale float64) ([]pgtype.Date, error) {
+	app, err := q.CreatePlanTemplateApplication(ctx, db.CreatePlanTemplateApplicationParams{
+		TemplateID: templateID,
+		AthleteID:  athleteID,
+		StartDay:   date(start),
+		Scale:      scale,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create application: %w", err)
+	}
+	sessions, err := q.ListPlanTemplateSessions(ctx, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("list template sessions: %w", err)
+	}
+
+	days := make([]pgtype.Date, 0, len(sessions))
+	for _, s := range sessions {
+		v, err := valuesFor(s, app.StartDay.Time, app.Scale)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := q.CreatePlannedWorkout(ctx, db.CreatePlannedWorkoutParams{
+			AthleteID:         athleteID,
+			Day:               v.Day,
+			Sport:             s.Sport,
+			Title:             s.Title,
+			Description:       s.Description,
+			TargetDurationSec: v.DurationSec,
+			TargetDistanceM:   v.DistanceM,
+			TargetLoad:        v.Load,
+			TargetZone:        s.TargetZone,
+			Structure:         v.Structure,
+			TemplateSessionID: pgtype.UUID{Bytes: s.ID, Valid: true},
+			ApplicationID:     pgtype.UUID{Bytes: app.ID, Valid: true},
+		}); err != nil {
+			return nil, fmt.Errorf("create planned workout: %w", err)
+		}
+		days = append(days, v.Day)
+	}
+	return days, nil
+}
+
+// Push brings the calendars the template was applied to up to date with
+// it. Upcoming sessions still linked to a template session are rewritten,
+// and sessions added to the template after an application are created.
+// Past and completed sessions, and ones the coach has edited by hand
+// (which detaches them), are left alone. Run it in a transaction.
+func Push(ctx context.Context, q *db.Queries, templateID uuid.UUID, now time.Time) (updated, created int, err error) {
+	apps, err := q.ListPlanTemplateApplications(ctx, templateID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list applications: %w", err)
+	}
+	sessions, err := q.ListPlanTemplateSessions(ctx, templateID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list template sessions: %w", err)
+	}
+	linked, err := q.ListTemplatePlannedWorkouts(ctx, templateID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list linked sessions: %w", err)
+	}
+
+	type key struct{ app, session uuid.UUID }
+	existing := make(map[key]db.ListTemplatePlannedWorkoutsRow, len(linked))
+	for _, p := range linked {
+		existing[key{p.ApplicationID.Bytes, p.TemplateSessionID.Bytes}] = p
+	}
+
+	for _, app := range apps {
+		today := date(training.Day(now, training.Location(app.Tz))).Time
+		for _, s := range sessions {
+			v, err := valuesFor(s, app.StartDay.Time, app.Scale)
+			if err != nil {
+				return updated, created, err
+			}
+			if v.Day.Time.Before(today) {
+				continue
+			}
+
+			p, ok := existing[key{app.ID, s.ID}]
+			switch {
+			case ok && (p.WorkoutID.Valid || p.Day.Time.Before(today)):
+				continue
+			case ok:
+				if err := q.UpdatePlannedWorkoutFromTemplate(ctx, db.UpdatePlannedWorkoutFromTemplateParams{
+					ID:                p.ID,
+					Day:               v.Day,
+					Sport:             s.Sport,
+					Title:             s.Title,
+					Description:       s.Description,
+					TargetDurationSec: v.DurationSec,
+					TargetDistanceM:   v.DistanceM,
+					TargetLoad:        v.Load,
+					TargetZone:        s.TargetZone,
+					Structure:         v.Structure,
+				}); err != nil {
+					return updated, created, fmt.Errorf("update planned workout: %w", err)
+				}
+				updated++
+			case s.CreatedAt.Time.After(app.CreatedAt.Time):
+				// New to the template. Older sessions with no link were
+				// deleted or edited on the calendar and stay that way.
+				if _, err := q.CreatePlannedWorkout(ctx, db.CreatePlannedWorkoutParams{
+					AthleteID:         app.AthleteID,
+					Day:               v.Day,
+					Sport:             s.Sport,
+					Title:             s.Title,
+					Description:       s.Description,
+					TargetDurationSec: v.DurationSec,
+					TargetDistanceM:   v.DistanceM,
+					TargetLoad:        v.Load,
+					TargetZone:        s.TargetZone,
+					Structure:         v.Structure,
+					TemplateSessionID: pgtype.UUID{Bytes: s.ID, Valid: true},
+					ApplicationID:     pgtype.UUID{Bytes: app.ID, Valid: true},
+				}); err != nil {
+					return updated, created, fmt.Errorf("create planned workout: %w", err)
+				}
+				created++
+			}
+		}
+	}
+	return updated, created, nil
+}
+
+// values are the scaled, placed targets of one template session.
+type values struct {
+	Day         pgtype.Date
+	DurationSec pgtype.Int4
+	DistanceM   pgtype.Float8
+	Load        pgtype.Float8
+	Structure   []byte
+}
+
+func valuesFor(s db.PlanTemplateSession, start time.Time, scale float64) (values, error) {
+	t := Targets{
+		DurationSec: float64(s.TargetDurationSec.Int32),
+		DistanceM:   s.TargetDistanceM.Float64,
+		Load:        s.TargetLoad.Float64,
+	}
+	if len(s.Structure) > 0 {
+		sw, err := structured.Decode(s.Structure)
+		if err != nil {
+			return values{}, fmt.Errorf("template session %s: %w", s.ID, err)
+		}
+		t.Structure = &sw
+	}
+	t = Scale(t, scale)
+
+	v := values{
+		Day:         date(Day(start, int(s.Week), int(s.Day))),
+		DurationSec: pgtype.Int4{Int32: int32(t.DurationSec), Valid: s.TargetDurationSec.Valid},
+		DistanceM:   pgtype.Float8{Float64: t.DistanceM, Valid: s.TargetDistanceM.Valid},
+		Load:        pgtype.Float8{Float64: t.Load, Valid: s.TargetLoad.Valid},
+	}
+	if t.Structure != nil {
+		b, err := t.Structure.Encode()
+		if err != nil {
+			return values{}, fmt.Errorf("template session %s: %w", s.ID, err)
+		}
+		v.Structure = b
+	}
+	return v, nil
+}
+
+func date(t time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
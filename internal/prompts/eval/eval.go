@@ -0,0 +1,87 @@
+// Package eval runs the prompt templates offline. Each suite feeds
+// recorded inputs to the code that builds a prompt, answers with recorded
+// model replies from the fake provider, and snapshots what was sent and
+// what came out. A change to a template or to the facts it is built from
+// then shows up as a diff in the snapshots, reviewed like any other code.
+//
+// Fixtures live in testdata/<suite>/<case>.json and snapshots next to them
+// as <case>.golden. Run `make eval` to check them and `make eval-update`
+// to rewrite the snapshots after an intended change.
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+// Fixture is one recorded case: the input, and the model's replies in the
+// order they are asked for. Once the replies run out the fake provider
+// echoes the prompt back.
+type Fixture[In any] struct {
+	Input   In       `json:"input"`
+	Replies []string `json:"replies"`
+}
+
+// Suite evaluates one feature's prompts.
+type Suite struct {
+	Name    string   // testdata directory
+	Prompts []string // template IDs the suite exercises
+	run     func(ctx context.Context, fixture []byte) (string, error)
+}
+
+// NewSuite builds a suite from run, which does what the feature does with
+// the provider it is given and returns the result to snapshot.
+func NewSuite[In any](name string, prompts []string, run func(context.Context, llm.Provider, In) (any, error)) Suite {
+	return Suite{Name: name, Prompts: prompts, run: func(ctx context.Context, fixture []byte) (string, error) {
+		var f Fixture[In]
+		dec := json.NewDecoder(bytes.NewReader(fixture))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return "", fmt.Errorf("read fixture: %w", err)
+		}
+		fake := &llm.Fake{}
+		for _, r := range f.Replies {
+			fake.Replies = append(fake.Replies, llm.Message{Role: llm.RoleAssistant, Content: r})
+		}
+		out, err := run(ctx, fake, f.Input)
+		return snapshot(prompts, fake.Calls(), out, err)
+	}}
+}
+
+// Run evaluates a fixture and returns its snapshot.
+func (s Suite) Run(ctx context.Context, fixture []byte) (string, error) {
+	return s.run(ctx, fixture)
+}
+
+// snapshot writes the requests and the result as plain text, so a diff
+// reads like the prompt itself.
+func snapshot(prompts []string, calls []llm.Request, out any, err error) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "prompts: %s\n", strings.Join(prompts, ", "))
+	for i, req := range calls {
+		fmt.Fprintf(&b, "\n=== request %d (temperature %g, max tokens %d, %d tools)\n", i+1, req.Temperature, req.MaxTokens, len(req.Tools))
+		for _, m := range req.Messages {
+			fmt.Fprintf(&b, "--- %s\n%s\n", m.Role, m.Content)
+			for _, c := range m.ToolCalls {
+				fmt.Fprintf(&b, "[call %s %s]\n", c.Name, c.Arguments)
+			}
+		}
+	}
+	b.WriteString("\n=== result\n")
+	if err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+		return b.String(), nil
+	}
+	data, merr := json.MarshalIndent(out, "", "  ")
+	if merr != nil {
+		return "", merr
+	}
+	b.Write(data)
+	b.WriteString("\n")
+	return b.String(), nil
+}
@@ -0,0 +1,69 @@
+package eval
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/briangreenhill/coachgpt/internal/prompts"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden snapshots")
+
+func TestSuites(t *testing.T) {
+	for _, s := range Suites {
+		t.Run(s.Name, func(t *testing.T) {
+			fixtures, err := filepath.Glob(filepath.Join("testdata", s.Name, "*.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fixtures) == 0 {
+				t.Fatalf("no fixtures in testdata/%s", s.Name)
+			}
+			for _, path := range fixtures {
+				name := strings.TrimSuffix(filepath.Base(path), ".json")
+				t.Run(name, func(t *testing.T) {
+					fixture, err := os.ReadFile(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got, err := s.Run(context.Background(), fixture)
+					if err != nil {
+						t.Fatal(err)
+					}
+					golden := strings.TrimSuffix(path, ".json") + ".golden"
+					if *update {
+						if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+							t.Fatal(err)
+						}
+						return
+					}
+					want, err := os.ReadFile(golden)
+					if err != nil {
+						t.Fatalf("%v (run make eval-update to create it)", err)
+					}
+					if got != string(want) {
+						t.Errorf("snapshot changed; run make eval-update if that's intended\n--- want\n%s\n--- got\n%s", want, got)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestEveryPromptHasASuite(t *testing.T) {
+	covered := map[string]bool{}
+	for _, s := range Suites {
+		for _, id := range s.Prompts {
+			covered[id] = true
+		}
+	}
+	for _, d := range prompts.All() {
+		if !covered[d.ID()] {
+			t.Errorf("prompt %s has no eval suite", d.ID())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/assistant"
+	"github.com/briangreenhill/coachgpt/internal/commentary"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/plandraft"
+	"github.com/briangreenhill/coachgpt/internal/report"
+)
+
+// Suites covers every registered prompt.
+var Suites = []Suite{
+	NewSuite(report.Prompt.Name(), []string{report.Prompt.ID()},
+		func(ctx context.Context, p llm.Provider, f report.Facts) (any, error) {
+			n, _, err := report.Write(ctx, p, f)
+			return n, err
+		}),
+	NewSuite(commentary.Prompt.Name(), []string{commentary.Prompt.ID()},
+		func(ctx context.Context, p llm.Provider, f commentary.Facts) (any, error) {
+			note, _, err := commentary.Write(ctx, p, f)
+			return note, err
+		}),
+	NewSuite(plandraft.Prompt.Name(), []string{plandraft.Prompt.ID(), plandraft.RepairPrompt.ID()},
+		func(ctx context.Context, p llm.Provider, b plandraft.Brief) (any, error) {
+			r, err := plandraft.Generate(ctx, p, b)
+			return r, err
+		}),
+	NewSuite(assistant.Prompt.Name(), []string{assistant.Prompt.ID()},
+		func(ctx context.Context, p llm.Provider, c Chat) (any, error) {
+			athlete := c.athlete()
+			system, err := assistant.SystemPrompt(athlete, c.Now)
+			if err != nil {
+				return nil, err
+			}
+			history := []llm.Message{
+				{Role: llm.RoleSystem, Content: system},
+				{Role: llm.RoleUser, Content: c.Question},
+			}
+			turn, err := assistant.Reply(ctx, p, assistant.Tools{Athlete: athlete}, history, assistant.Events{})
+			if err != nil {
+				return nil, err
+			}
+			return turn.Messages[len(turn.Messages)-1].Content, nil
+		}),
+}
+
+// Chat is a question put to the assistant about an athlete. Recorded
+// replies are text only, so the tools, which need the database, never run.
+type Chat struct {
+	Athlete          string    `json:"athlete"`
+	TZ               string    `json:"tz"`
+	MaxHR            int32     `json:"max_hr,omitempty"`
+	RestingHR        int32     `json:"resting_hr,omitempty"`
+	FTP              int32     `json:"ftp_w,omitempty"`
+	ThresholdPaceSec int32     `json:"threshold_pace_sec,omitempty"`
+	Now              time.Time `json:"now"`
+	Question         string    `json:"question"`
+}
+
+func (c Chat) athlete() db.Athlete {
+	return db.Athlete{
+		Name:             c.Athlete,
+		Tz:               c.TZ,
+		MaxHr:            pgtype.Int4{Int32: c.MaxHR, Valid: c.MaxHR > 0},
+		RestingHr:        pgtype.Int4{Int32: c.RestingHR, Valid: c.RestingHR > 0},
+		FtpWatts:         pgtype.Int4{Int32: c.FTP, Valid: c.FTP > 0},
+		ThresholdPaceSec: pgtype.Int4{Int32: c.ThresholdPaceSec, Valid: c.ThresholdPaceSec > 0},
+	}
+}
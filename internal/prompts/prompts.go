@@ -0,0 +1,144 @@
+// Package prompts keeps what is sent to the language model as named,
+// versioned templates. Each is rendered with text/template from a typed
+// input, and its ID is stored with whatever the model's answer becomes, so
+// a report or note can be traced to the exact wording that produced it.
+// Changing the wording of a prompt means giving it a new version.
+package prompts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+// Def defines a template. System and User are text/template sources
+// rendered with the input as dot. Either may be empty: a prompt that only
+// sets up a conversation has no user message, and one that continues a
+// conversation has no system message.
+type Def struct {
+	Name        string
+	Version     int
+	System      string
+	User        string
+	Temperature float64
+	MaxTokens   int
+}
+
+// ID names a version of a template, as in "weekly_report@2".
+func (d Def) ID() string {
+	return fmt.Sprintf("%s@%d", d.Name, d.Version)
+}
+
+// Template is a registered Def rendered from input In.
+type Template[In any] struct {
+	def          Def
+	system, user *template.Template
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Def{}
+)
+
+// funcs are available to every template.
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.MarshalIndent(v, "", "  ")
+		return string(b), err
+	},
+	"join": strings.Join,
+}
+
+// Register parses d and adds it to the registry. Like template.Must it is
+// meant for package-level variables, and panics on a bad template or a
+// name and version that is already taken.
+func Register[In any](d Def) *Template[In] {
+	if d.Name == "" || d.Version < 1 {
+		panic("prompts: a template needs a name and a version from 1")
+	}
+	if d.System == "" && d.User == "" {
+		panic("prompts: " + d.ID() + " has no messages")
+	}
+	t := &Template[In]{def: d}
+	if d.System != "" {
+		t.system = parse(d.ID()+"/system", d.System)
+	}
+	if d.User != "" {
+		t.user = parse(d.ID()+"/user", d.User)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := registry[d.ID()]; dup {
+		panic("prompts: " + d.ID() + " registered twice")
+	}
+	registry[d.ID()] = d
+	return t
+}
+
+// ID is the template's name and version, for recording on artifacts.
+func (t *Template[In]) ID() string { return t.def.ID() }
+
+// Def is the template's definition.
+func (t *Template[In]) Def() Def { return t.def }
+
+// Name is the template's name.
+func (t *Template[In]) Name() string { return t.def.Name }
+
+// Version is the template's version.
+func (t *Template[In]) Version() int { return t.def.Version }
+
+// Render builds the model request for in: the system message then the user
+// message, for those the template has.
+func (t *Template[In]) Render(in In) (llm.Request, error) {
+	req := llm.Request{Temperature: t.def.Temperature, MaxTokens: t.def.MaxTokens}
+	for _, m := range []struct {
+		role string
+		tmpl *template.Template
+	}{{llm.RoleSystem, t.system}, {llm.RoleUser, t.user}} {
+		if m.tmpl == nil {
+			continue
+		}
+		var b bytes.Buffer
+		if err := m.tmpl.Execute(&b, in); err != nil {
+			return llm.Request{}, fmt.Errorf("render %s: %w", m.tmpl.Name(), err)
+		}
+		req.Messages = append(req.Messages, llm.Message{Role: m.role, Content: strings.TrimSpace(b.String())})
+	}
+	return req, nil
+}
+
+func parse(name, src string) *template.Template {
+	return template.Must(template.New(name).Funcs(funcs).Option("missingkey=error").Parse(src))
+}
+
+// Lookup finds a registered template by ID.
+func Lookup(id string) (Def, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	d, ok := registry[id]
+	return d, ok
+}
+
+// All lists the registered templates by name, then version.
+func All() []Def {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Def, 0, len(registry))
+	for _, d := range registry {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
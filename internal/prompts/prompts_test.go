@@ -0,0 +1,94 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+)
+
+type greeting struct {
+	Name   string
+	Sports []string
+}
+
+func TestRender(t *testing.T) {
+	tmpl := Register[greeting](Def{
+		Name:        "test_render",
+		Version:     2,
+		System:      "Coach {{ .Name }}.\n",
+		User:        `{{ join .Sports ", " }} {{ json .Sports }}`,
+		Temperature: 0.5,
+		MaxTokens:   10,
+	})
+	if tmpl.ID() != "test_render@2" {
+		t.Fatalf("id = %q", tmpl.ID())
+	}
+	req, err := tmpl.Render(greeting{Name: "Anna", Sports: []string{"Run", "Ride"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Messages) != 2 || req.Temperature != 0.5 || req.MaxTokens != 10 {
+		t.Fatalf("got %+v", req)
+	}
+	if m := req.Messages[0]; m.Role != llm.RoleSystem || m.Content != "Coach Anna." {
+		t.Errorf("system = %+v", m)
+	}
+	if m := req.Messages[1]; m.Role != llm.RoleUser || m.Content != "Run, Ride [\n  \"Run\",\n  \"Ride\"\n]" {
+		t.Errorf("user = %+v", m)
+	}
+	if d, ok := Lookup("test_render@2"); !ok || d.MaxTokens != 10 {
+		t.Errorf("lookup = %+v, %v", d, ok)
+	}
+}
+
+func TestRenderUserOnly(t *testing.T) {
+	tmpl := Register[[]string](Def{Name: "test_user_only", Version: 1, User: "{{ range . }}- {{ . }}\n{{ end }}"})
+	req, err := tmpl.Render([]string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Role != llm.RoleUser || req.Messages[0].Content != "- a\n- b" {
+		t.Fatalf("got %+v", req.Messages)
+	}
+}
+
+func TestRenderMissingKey(t *testing.T) {
+	tmpl := Register[map[string]string](Def{Name: "test_missing", Version: 1, System: "Hi {{ .name }}"})
+	if _, err := tmpl.Render(map[string]string{}); err == nil || !strings.Contains(err.Error(), "test_missing@1/system") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	Register[int](Def{Name: "test_dup", Version: 1, System: "x"})
+	for name, d := range map[string]Def{
+		"duplicate":   {Name: "test_dup", Version: 1, System: "y"},
+		"no version":  {Name: "test_nover", System: "x"},
+		"no messages": {Name: "test_empty", Version: 1},
+		"bad syntax":  {Name: "test_syntax", Version: 1, System: "{{ .Name"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			Register[int](d)
+		})
+	}
+}
+
+func TestAll(t *testing.T) {
+	Register[int](Def{Name: "test_all", Version: 2, System: "x"})
+	Register[int](Def{Name: "test_all", Version: 1, System: "x"})
+	var ids []string
+	for _, d := range All() {
+		if d.Name == "test_all" {
+			ids = append(ids, d.ID())
+		}
+	}
+	if strings.Join(ids, ",") != "test_all@1,test_all@2" {
+		t.Fatalf("got %v", ids)
+	}
+}
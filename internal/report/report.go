@@ -0,0 +1,243 @@
+// Package report writes the weekly digest a coach gets on Monday morning.
+// The week is gathered into Facts, handed to the language model as JSON
+// with instructions to answer in a fixed shape, and the answer is parsed
+// into a Narrative. Facts are kept next to the narrative so the app can
+// show the numbers the model was looking at.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/prompts"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Feature is the usage label for report generation.
+const Feature = "weekly_report"
+
+// Facts is one coach's week across their athletes.
+type Facts struct {
+	Coach     string    `json:"coach"`
+	WeekStart string    `json:"week_start"` // Monday, YYYY-MM-DD
+	WeekEnd   string    `json:"week_end"`   // Sunday
+	Athletes  []Athlete `json:"athletes"`
+}
+
+// Athlete is one athlete's week. Load is the sum of daily training load;
+// LoadChangePct compares it with the week before and is nil when there is
+// nothing to compare to.
+type Athlete struct {
+	Name          string      `json:"name"`
+	Workouts      []Workout   `json:"workouts"`
+	Load          float64     `json:"load"`
+	PrevLoad      float64     `json:"previous_week_load"`
+	LoadChangePct *float64    `json:"load_change_pct,omitempty"`
+	ACWR          *float64    `json:"acwr,omitempty"`
+	Compliance    *Compliance `json:"compliance,omitempty"`
+	PRs           []PR        `json:"new_prs,omitempty"`
+	Alerts        []string    `json:"alerts,omitempty"`
+}
+
+// Workout is a completed session, rounded for the prompt.
+type Workout struct {
+	Day         string  `json:"day"`
+	Sport       string  `json:"sport"`
+	Name        string  `json:"name"`
+	DurationMin int     `json:"duration_min"`
+	DistanceKm  float64 `json:"distance_km,omitempty"`
+	Load        float64 `json:"load,omitempty"`
+}
+
+// Compliance is how the week's planned sessions went.
+type Compliance struct {
+	Due       int     `json:"due"`
+	Completed int     `json:"completed"`
+	Partial   int     `json:"partial"`
+	Missed    int     `json:"missed"`
+	Percent   float64 `json:"percent"`
+}
+
+// PR is a new best set during the week. Times are h:mm:ss; Longest run
+// records are in km instead.
+type PR struct {
+	Distance string `json:"distance"`
+	Value    string `json:"value"`
+	Previous string `json:"previous"`
+}
+
+// Narrative is what the model wrote about the week.
+type Narrative struct {
+	Summary    string   `json:"summary"`
+	Highlights []string `json:"highlights"`
+	Concerns   []string `json:"concerns"`
+}
+
+// LoadChange sets LoadChangePct from Load and PrevLoad.
+func (a *Athlete) LoadChange() {
+	a.LoadChangePct = nil
+	if a.PrevLoad > 0 {
+		pct := round1((a.Load - a.PrevLoad) / a.PrevLoad * 100)
+		a.LoadChangePct = &pct
+	}
+}
+
+// Prompt is the template the week is written from.
+var Prompt = prompts.Register[Facts](prompts.Def{
+	Name:    Feature,
+	Version: 1,
+	System: `You are an assistant to an endurance coach, writing their Monday-morning digest of last week.
+You are given the week as JSON, one entry per athlete. Use only those facts; don't invent workouts, numbers or causes.
+Write for the coach, not the athletes. Be brief and concrete, and name athletes when you mention them.
+
+Answer with a single JSON object and nothing else:
+{"summary": "<one short paragraph on the group's week>",
+ "highlights": ["<one line per thing that went well, e.g. new PRs, strong compliance>"],
+ "concerns": ["<one line per thing the coach should look at, e.g. alerts, big load jumps, missed sessions, no training>"]}
+Use an empty list when there is nothing to say.`,
+	User:        "{{ json . }}",
+	Temperature: 0.3,
+	MaxTokens:   1200,
+})
+
+// Write asks the model for the week's narrative. A coach over their AI
+// allowance still gets the week, written by Fallback with no model name.
+func Write(ctx context.Context, p llm.Provider, f Facts) (Narrative, string, error) {
+	req, err := Prompt.Render(f)
+	if err != nil {
+		return Narrative{}, "", err
+	}
+	resp, err := p.Complete(ctx, req)
+	if errors.Is(err, llm.ErrQuotaExceeded) {
+		return Fallback(f), "", nil
+	}
+	if err != nil {
+		return Narrative{}, "", err
+	}
+	return Parse(resp.Message.Content), resp.Model, nil
+}
+
+// Parse reads the model's answer. Code fences around the JSON are
+// tolerated; anything that still doesn't parse is kept whole as the
+// summary rather than lost.
+func Parse(content string) Narrative {
+	s := strings.TrimSpace(content)
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	s = strings.TrimSpace(s)
+
+	var n Narrative
+	if err := json.Unmarshal([]byte(s), &n); err != nil || n.Summary == "" {
+		return Narrative{Summary: strings.TrimSpace(content), Highlights: []string{}, Concerns: []string{}}
+	}
+	n.Highlights = nonEmpty(n.Highlights)
+	n.Concerns = nonEmpty(n.Concerns)
+	return n
+}
+
+// Fallback writes a plain narrative straight from the facts, for when the
+// model can't be used.
+func Fallback(f Facts) Narrative {
+	n := Narrative{Highlights: []string{}, Concerns: []string{}}
+	var sessions, active int
+	for _, a := range f.Athletes {
+		sessions += len(a.Workouts)
+		if len(a.Workouts) > 0 {
+			active++
+		} else {
+			n.Concerns = append(n.Concerns, a.Name+": no workouts recorded")
+		}
+		for _, pr := range a.PRs {
+			n.Highlights = append(n.Highlights, fmt.Sprintf("%s: new %s best, %s (was %s)", a.Name, pr.Distance, pr.Value, pr.Previous))
+		}
+		if c := a.Compliance; c != nil && c.Missed > 0 {
+			n.Concerns = append(n.Concerns, fmt.Sprintf("%s: missed %d of %d planned sessions", a.Name, c.Missed, c.Due))
+		}
+		if a.LoadChangePct != nil && *a.LoadChangePct >= 30 {
+			n.Concerns = append(n.Concerns, fmt.Sprintf("%s: load up %.0f%% on the week before", a.Name, *a.LoadChangePct))
+		}
+		for _, msg := range a.Alerts {
+			n.Concerns = append(n.Concerns, a.Name+": "+msg)
+		}
+	}
+	n.Summary = fmt.Sprintf("%d of %d athletes trained in the week of %s, logging %d sessions between them.",
+		active, len(f.Athletes), f.WeekStart, sessions)
+	return n
+}
+
+// longestRun is the pseudo-distance for distance records.
+const longestRun = "Longest run"
+
+// prTolerance is how far past a race distance a run may go and still count
+// as an effort at it; its time is scaled back to the distance.
+const prTolerance = 1.05
+
+// NewPRs finds race-distance bests and the longest run set on or after
+// from, compared with the efforts before it. A distance with no earlier
+// effort has no record to beat and is skipped.
+func NewPRs(efforts []training.Effort, from time.Time) []PR {
+	var out []PR
+	for _, d := range training.RaceDistances {
+		before, during := math.Inf(1), math.Inf(1)
+		for _, e := range efforts {
+			if e.DistanceM < d.Meters || e.DistanceM > d.Meters*prTolerance || e.Seconds <= 0 {
+				continue
+			}
+			t := e.Seconds * d.Meters / e.DistanceM
+			if e.Start.Before(from) {
+				before = min(before, t)
+			} else {
+				during = min(during, t)
+			}
+		}
+		if !math.IsInf(before, 1) && during < before {
+			out = append(out, PR{Distance: d.Name, Value: clock(during), Previous: clock(before)})
+		}
+	}
+
+	var before, during float64
+	for _, e := range efforts {
+		if e.Start.Before(from) {
+			before = max(before, e.DistanceM)
+		} else {
+			during = max(during, e.DistanceM)
+		}
+	}
+	if before > 0 && during > before {
+		out = append(out, PR{Distance: longestRun, Value: km(during), Previous: km(before)})
+	}
+	return out
+}
+
+func clock(secs float64) string {
+	s := int(math.Round(secs))
+	if s < 3600 {
+		return fmt.Sprintf("%d:%02d", s/60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60)
+}
+
+func km(m float64) string {
+	return fmt.Sprintf("%.1f km", m/1000)
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+func nonEmpty(ss []string) []string {
+	out := []string{}
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/llm"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+var monday = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+
+func run(daysFromMonday int, meters, secs float64) training.Effort {
+	return training.Effort{Start: monday.AddDate(0, 0, daysFromMonday), DistanceM: meters, Seconds: secs}
+}
+
+func TestNewPRs(t *testing.T) {
+	efforts := []training.Effort{
+		run(-30, 5000, 1500),  // 25:00
+		run(-20, 10200, 3060), // 10K in 30:00 once scaled
+		run(-10, 16000, 5400),
+		run(2, 5100, 1479), // 24:10 for 5K
+		run(4, 10000, 3100),
+		run(6, 18000, 6300),
+		run(5, 21100, 7200), // first half marathon: nothing to beat
+	}
+	got := NewPRs(efforts, monday)
+	want := []PR{
+		{Distance: "5K", Value: "24:10", Previous: "25:00"},
+		{Distance: longestRun, Value: "21.1 km", Previous: "16.0 km"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PR %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if prs := NewPRs(efforts[:3], monday); len(prs) != 0 {
+		t.Errorf("no efforts this week should give no PRs, got %+v", prs)
+	}
+}
+
+func TestLoadChange(t *testing.T) {
+	a := Athlete{Load: 390, PrevLoad: 300}
+	a.LoadChange()
+	if a.LoadChangePct == nil || *a.LoadChangePct != 30 {
+		t.Fatalf("change = %v", a.LoadChangePct)
+	}
+	a.PrevLoad = 0
+	a.LoadChange()
+	if a.LoadChangePct != nil {
+		t.Fatalf("no previous load should leave the change unset, got %v", *a.LoadChangePct)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for name, in := range map[string]string{
+		"plain":  `{"summary":"Solid week.","highlights":["Ann ran a 5K PR"," "],"concerns":[]}`,
+		"fenced": "```json\n{\"summary\":\"Solid week.\",\"highlights\":[\"Ann ran a 5K PR\"]}\n```",
+	} {
+		n := Parse(in)
+		if n.Summary != "Solid week." || len(n.Highlights) != 1 || n.Highlights[0] != "Ann ran a 5K PR" || n.Concerns == nil || len(n.Concerns) != 0 {
+			t.Errorf("%s: got %+v", name, n)
+		}
+	}
+
+	n := Parse("  The week went fine.\n")
+	if n.Summary != "The week went fine." || len(n.Highlights) != 0 {
+		t.Errorf("prose answer should become the summary, got %+v", n)
+	}
+}
+
+func facts() Facts {
+	up := 45.0
+	return Facts{
+		WeekStart: "2024-03-11",
+		Athletes: []Athlete{
+			{
+				Name:          "Ann",
+				Workouts:      []Workout{{Day: "Tue", Sport: "Run", DurationMin: 40}},
+				LoadChangePct: &up,
+				Compliance:    &Compliance{Due: 4, Completed: 3, Missed: 1},
+				PRs:           []PR{{Distance: "5K", Value: "24:10", Previous: "25:00"}},
+				Alerts:        []string{"Acute:chronic load ratio is 1.6"},
+			},
+			{Name: "Ben", Workouts: []Workout{}},
+		},
+	}
+}
+
+func TestFallback(t *testing.T) {
+	n := Fallback(facts())
+	if !strings.HasPrefix(n.Summary, "1 of 2 athletes trained") {
+		t.Errorf("summary = %q", n.Summary)
+	}
+	if len(n.Highlights) != 1 || !strings.Contains(n.Highlights[0], "5K") {
+		t.Errorf("highlights = %q", n.Highlights)
+	}
+	want := []string{
+		"Ann: missed 1 of 4 planned sessions",
+		"Ann: load up 45% on the week before",
+		"Ann: Acute:chronic load ratio is 1.6",
+		"Ben: no workouts recorded",
+	}
+	if strings.Join(n.Concerns, "|") != strings.Join(want, "|") {
+		t.Errorf("concerns = %q", n.Concerns)
+	}
+}
+
+type overQuota struct{ *llm.Fake }
+
+func (overQuota) Complete(context.Context, llm.Request) (llm.Response, error) {
+	return llm.Response{}, llm.ErrQuotaExceeded
+}
+
+func TestWrite(t *testing.T) {
+	fake := &llm.Fake{Model: "fake-1", Replies: []llm.Message{{Content: `{"summary":"Good week.","highlights":[],"concerns":["Ben didn't train"]}`}}}
+	n, model, err := Write(context.Background(), fake, facts())
+	if err != nil || model != "fake-1" || n.Summary != "Good week." || len(n.Concerns) != 1 {
+		t.Fatalf("got %+v %q %v", n, model, err)
+	}
+	prompt := fake.Calls()[0].Messages[1].Content
+	if !strings.Contains(prompt, `"name": "Ann"`) || !strings.Contains(prompt, `"load_change_pct": 45`) {
+		t.Errorf("facts missing from prompt:\n%s", prompt)
+	}
+
+	n, model, err = Write(context.Background(), overQuota{}, facts())
+	if err != nil || model != "" || n.Summary != Fallback(facts()).Summary {
+		t.Fatalf("over quota: got %+v %q %v", n, model, err)
+	}
+}
@@ -0,0 +1,156 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/compliance"
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// prHistory is how far back new bests are compared.
+const prHistory = 365 * 24 * time.Hour
+
+// Collect gathers the coach's week starting weekStart, a Monday given as a
+// calendar date. Each athlete's week runs over their own local days.
+func Collect(ctx context.Context, q *db.Queries, coach db.Coach, weekStart time.Time) (Facts, error) {
+	f := Facts{
+		Coach:     coach.Name.String,
+		WeekStart: weekStart.Format(time.DateOnly),
+		WeekEnd:   weekStart.AddDate(0, 0, 6).Format(time.DateOnly),
+	}
+	if f.Coach == "" {
+		f.Coach = coach.Email
+	}
+	athletes, err := q.ListAthletesByCoach(ctx, coach.ID)
+	if err != nil {
+		return f, fmt.Errorf("list athletes: %w", err)
+	}
+	for _, a := range athletes {
+		week, err := collectAthlete(ctx, q, a, weekStart)
+		if err != nil {
+			return f, fmt.Errorf("athlete %s: %w", a.ID, err)
+		}
+		f.Athletes = append(f.Athletes, week)
+	}
+	return f, nil
+}
+
+func collectAthlete(ctx context.Context, q *db.Queries, athlete db.Athlete, weekStart time.Time) (Athlete, error) {
+	loc := training.Location(athlete.Tz)
+	from := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 7)
+	out := Athlete{Name: athlete.Name, Workouts: []Workout{}}
+
+	workouts, err := q.ListWorkoutsBetween(ctx, db.ListWorkoutsBetweenParams{
+		AthleteID: athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return out, fmt.Errorf("list workouts: %w", err)
+	}
+	for _, w := range workouts {
+		if w.ReviewStatus != "ok" && w.ReviewStatus != "accepted" {
+			continue
+		}
+		out.Workouts = append(out.Workouts, Workout{
+			Day:         w.StartedAt.Time.In(loc).Format("Mon"),
+			Sport:       w.Sport,
+			Name:        w.Name.String,
+			DurationMin: int(math.Round(float64(w.DurationSec) / 60)),
+			DistanceKm:  round1(w.DistanceM.Float64 / 1000),
+			Load:        math.Round(w.Load.Float64),
+		})
+	}
+
+	// Four weeks ending with this one: enough for the week-on-week change
+	// and the acute:chronic ratio at the end of the week.
+	days, err := training.DailyLoads(ctx, q, athlete, from.AddDate(0, 0, -21), to.AddDate(0, 0, -1))
+	if err != nil {
+		return out, err
+	}
+	for i, d := range days {
+		switch {
+		case i >= len(days)-7:
+			out.Load += d.Load
+		case i >= len(days)-14:
+			out.PrevLoad += d.Load
+		}
+	}
+	out.Load, out.PrevLoad = math.Round(out.Load), math.Round(out.PrevLoad)
+	out.LoadChange()
+	if acwr, ok := training.ACWR(days); ok {
+		acwr = math.Round(acwr*100) / 100
+		out.ACWR = &acwr
+	}
+
+	fromDay := pgtype.Date{Time: weekStart, Valid: true}
+	toDay := pgtype.Date{Time: weekStart.AddDate(0, 0, 6), Valid: true}
+	planned, err := q.ListPlannedWorkoutsBetween(ctx, db.ListPlannedWorkoutsBetweenParams{
+		AthleteID: athlete.ID,
+		FromDay:   fromDay,
+		ToDay:     toDay,
+	})
+	if err != nil {
+		return out, fmt.Errorf("list planned workouts: %w", err)
+	}
+	if len(planned) > 0 {
+		sessions := make([]compliance.Session, len(planned))
+		for i, p := range planned {
+			d := p.Day.Time
+			sessions[i] = compliance.Session{
+				Day:    time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc),
+				Status: p.Status,
+				Score:  p.Compliance.Float64,
+			}
+		}
+		// Judged from the Monday after, so every session is due.
+		w := compliance.Summarize(sessions, to)
+		out.Compliance = &Compliance{
+			Due:       w.Due,
+			Completed: w.Completed,
+			Partial:   w.Partial,
+			Missed:    w.Missed,
+			Percent:   math.Round(w.Percent),
+		}
+	}
+
+	runs, err := q.ListRunEffortsSince(ctx, db.ListRunEffortsSinceParams{
+		AthleteID: athlete.ID,
+		StartedAt: pgtype.Timestamptz{Time: from.Add(-prHistory), Valid: true},
+	})
+	if err != nil {
+		return out, fmt.Errorf("list run efforts: %w", err)
+	}
+	efforts := make([]training.Effort, 0, len(runs))
+	for _, r := range runs {
+		if !r.StartedAt.Time.Before(to) {
+			continue
+		}
+		efforts = append(efforts, training.Effort{
+			Start:     r.StartedAt.Time,
+			DistanceM: r.DistanceM.Float64,
+			Seconds:   float64(r.DurationSec),
+		})
+	}
+	out.PRs = NewPRs(efforts, from)
+
+	alerts, err := q.ListAthleteAlertsBetween(ctx, db.ListAthleteAlertsBetweenParams{
+		AthleteID: athlete.ID,
+		FromDay:   fromDay,
+		ToDay:     toDay,
+	})
+	if err != nil {
+		return out, fmt.Errorf("list alerts: %w", err)
+	}
+	for _, a := range alerts {
+		out.Alerts = append(out.Alerts, a.Message)
+	}
+	return out, nil
+}
@@ -0,0 +1,140 @@
+// Package season models an athlete's season: goal races, the training
+// phases leading up to them and the fitness (CTL) ramp those phases imply.
+// Days are calendar dates at midnight UTC, as stored.
+package season
+
+import (
+	"math"
+	"time"
+)
+
+// Race priorities. A races are what the season is built around; C races
+// are trained through.
+const (
+	PriorityA = "A"
+	PriorityB = "B"
+	PriorityC = "C"
+)
+
+// Priorities lists race priorities, most important first.
+var Priorities = []string{PriorityA, PriorityB, PriorityC}
+
+// Phase kinds.
+const (
+	PhaseBase     = "base"
+	PhaseBuild    = "build"
+	PhasePeak     = "peak"
+	PhaseTaper    = "taper"
+	PhaseRecovery = "recovery"
+)
+
+// PhaseKinds lists the phase kinds in their usual order through a season.
+var PhaseKinds = []string{PhaseBase, PhaseBuild, PhasePeak, PhaseTaper, PhaseRecovery}
+
+// DefaultRamp is the planned weekly CTL change for each phase kind when the
+// coach doesn't set one.
+var DefaultRamp = map[string]float64{
+	PhaseBase:     3,
+	PhaseBuild:    5,
+	PhasePeak:     2,
+	PhaseTaper:    -6,
+	PhaseRecovery: -4,
+}
+
+// Phase is a block of training from Start to End inclusive.
+type Phase struct {
+	Kind  string
+	Start time.Time
+	End   time.Time
+	Ramp  float64 // CTL per week; 0 uses DefaultRamp
+}
+
+// WeeklyRamp is the phase's planned CTL change per week.
+func (p Phase) WeeklyRamp() float64 {
+	if p.Ramp != 0 {
+		return p.Ramp
+	}
+	return DefaultRamp[p.Kind]
+}
+
+// Contains reports whether day falls within the phase.
+func (p Phase) Contains(day time.Time) bool {
+	return !day.Before(p.Start) && !day.After(p.End)
+}
+
+// Overlaps reports whether p shares a day with any of phases.
+func Overlaps(p Phase, phases []Phase) bool {
+	for _, o := range phases {
+		if !p.End.Before(o.Start) && !o.End.Before(p.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// At returns the phase covering day.
+func At(phases []Phase, day time.Time) (Phase, bool) {
+	for _, p := range phases {
+		if p.Contains(day) {
+			return p, true
+		}
+	}
+	return Phase{}, false
+}
+
+// Project returns the planned CTL for each day from..to inclusive. It starts
+// at startCTL on from, then each day inside a phase adds a seventh of the
+// phase's weekly ramp; days between phases hold steady.
+func Project(phases []Phase, from, to time.Time, startCTL float64) []float64 {
+	var out []float64
+	ctl := startCTL
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if d.After(from) {
+			if p, ok := At(phases, d); ok {
+				ctl = math.Max(0, ctl+p.WeeklyRamp()/7)
+			}
+		}
+		out = append(out, ctl)
+	}
+	return out
+}
+
+// Track statuses comparing actual CTL to the plan.
+const (
+	OnTrack = "on track"
+	Behind  = "behind"
+	Ahead   = "ahead"
+)
+
+// Compare says how actual CTL sits against planned: within 5% of the plan,
+// and never less than 3 points either side, counts as on track.
+func Compare(planned, actual float64) string {
+	tolerance := math.Max(3, planned*0.05)
+	switch {
+	case actual < planned-tolerance:
+		return Behind
+	case actual > planned+tolerance:
+		return Ahead
+	}
+	return OnTrack
+}
+
+// Event is a race on the calendar.
+type Event struct {
+	Name     string
+	Day      time.Time
+	Priority string
+}
+
+// NextGoal returns the first race on or after today, preferring A races,
+// then B, then C. Events must be in date order.
+func NextGoal(events []Event, today time.Time) (Event, bool) {
+	for _, prio := range Priorities {
+		for _, e := range events {
+			if e.Priority == prio && !e.Day.Before(today) {
+				return e, true
+			}
+		}
+	}
+	return Event{}, false
+}
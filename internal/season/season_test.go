@@ -0,0 +1,75 @@
+package season
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func TestProject(t *testing.T) {
+	phases := []Phase{
+		{Kind: PhaseBase, Start: day("2025-01-06"), End: day("2025-01-19")},           // +3/week
+		{Kind: PhaseBuild, Start: day("2025-01-27"), End: day("2025-02-02"), Ramp: 7}, // after a gap week
+	}
+	got := Project(phases, day("2025-01-05"), day("2025-02-02"), 40)
+	if len(got) != 29 {
+		t.Fatalf("len = %d, want 29", len(got))
+	}
+	if got[0] != 40 {
+		t.Fatalf("projection should start at the seed, got %.2f", got[0])
+	}
+	// Two base weeks add 6, the gap week holds, the build week adds 7.
+	if v := got[14]; math.Abs(v-46) > 1e-9 {
+		t.Fatalf("after base = %.2f, want 46", v)
+	}
+	if got[21] != got[14] {
+		t.Fatalf("days between phases should hold steady")
+	}
+	if v := got[len(got)-1]; math.Abs(v-53) > 1e-9 {
+		t.Fatalf("after build = %.2f, want 53", v)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, c := range []struct {
+		planned, actual float64
+		want            string
+	}{
+		{50, 49, OnTrack},
+		{50, 46, Behind},
+		{100, 96, OnTrack},
+		{100, 106, Ahead},
+		{20, 22.5, OnTrack},
+	} {
+		if got := Compare(c.planned, c.actual); got != c.want {
+			t.Fatalf("Compare(%v, %v) = %s, want %s", c.planned, c.actual, got, c.want)
+		}
+	}
+}
+
+func TestOverlapsAndNextGoal(t *testing.T) {
+	base := Phase{Kind: PhaseBase, Start: day("2025-01-06"), End: day("2025-02-02")}
+	if !Overlaps(Phase{Start: day("2025-02-02"), End: day("2025-02-10")}, []Phase{base}) {
+		t.Fatal("phases sharing an end day overlap")
+	}
+	if Overlaps(Phase{Start: day("2025-02-03"), End: day("2025-02-10")}, []Phase{base}) {
+		t.Fatal("adjacent phases don't overlap")
+	}
+
+	events := []Event{
+		{Name: "Old A", Day: day("2024-10-01"), Priority: PriorityA},
+		{Name: "Tune-up", Day: day("2025-03-01"), Priority: PriorityC},
+		{Name: "Marathon", Day: day("2025-04-27"), Priority: PriorityA},
+	}
+	if e, ok := NextGoal(events, day("2025-01-10")); !ok || e.Name != "Marathon" {
+		t.Fatalf("next goal = %+v", e)
+	}
+	if e, ok := NextGoal(events[:2], day("2025-01-10")); !ok || e.Name != "Tune-up" {
+		t.Fatalf("falls back to lower priorities, got %+v", e)
+	}
+}
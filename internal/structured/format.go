@@ -0,0 +1,230 @@
+package structured
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DSL renders the workout back into the compact syntax; Parse(w.DSL())
+// yields the same workout.
+func (w Workout) DSL() string {
+	parts := make([]string, len(w.Steps))
+	for i, s := range w.Steps {
+		parts[i] = s.dsl()
+	}
+	return strings.Join(parts, "\n")
+}
+
+var intentWords = map[string]string{
+	IntentWarmup:   "wu",
+	IntentCooldown: "cd",
+	IntentRecovery: "rest",
+}
+
+func (s Step) dsl() string {
+	if s.IsRepeat() {
+		inner := make([]string, len(s.Steps))
+		for i, c := range s.Steps {
+			inner[i] = c.dsl()
+		}
+		return fmt.Sprintf("%dx(%s)", s.Repeat, strings.Join(inner, ", "))
+	}
+
+	var b strings.Builder
+	if word := intentWords[s.Intent]; word != "" {
+		b.WriteString(word + " ")
+	}
+	if s.DurationSec > 0 {
+		if math.Mod(s.DurationSec, 60) == 0 {
+			b.WriteString(num(s.DurationSec/60) + "min")
+		} else {
+			b.WriteString(num(s.DurationSec) + "s")
+		}
+	} else if math.Mod(s.DistanceM, 1000) == 0 {
+		b.WriteString(num(s.DistanceM/1000) + "km")
+	} else {
+		b.WriteString(num(s.DistanceM) + "m")
+	}
+	if t := s.Target; t != nil {
+		b.WriteString(" @")
+		b.WriteString(t.dsl())
+	}
+	return b.String()
+}
+
+func (t Target) dsl() string {
+	switch t.Kind {
+	case TargetHRZone:
+		return "z" + span(t.Low, t.High, num)
+	case TargetPace:
+		return t.pace()
+	case TargetPower:
+		return span(t.Low, t.High, num) + "w"
+	case TargetThresholdPct:
+		return span(t.Low, t.High, num) + "%"
+	case TargetRPE:
+		return "rpe " + span(t.Low, t.High, num)
+	}
+	return ""
+}
+
+// pace renders a pace range in the unit it was written in. Whole seconds
+// per mile survive the trip through seconds per km, so Parse reads back
+// the same target.
+func (t Target) pace() string {
+	if t.PerMile {
+		return span(t.Low*mile/1000, t.High*mile/1000, clock) + "/mi"
+	}
+	return span(t.Low, t.High, clock) + "/km"
+}
+
+func span(lo, hi float64, f func(float64) string) string {
+	if lo == hi {
+		return f(lo)
+	}
+	return f(lo) + "-" + f(hi)
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// clock formats seconds as m:ss, rounding to the second.
+func clock(secs float64) string {
+	t := int(math.Round(secs))
+	if t >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", t/3600, t%3600/60, t%60)
+	}
+	return fmt.Sprintf("%d:%02d", t/60, t%60)
+}
+
+// Lines renders the workout for people, one line per top-level step, with
+// repeat blocks summarised inline.
+func (w Workout) Lines() []string {
+	out := make([]string, len(w.Steps))
+	for i, s := range w.Steps {
+		out[i] = s.text()
+	}
+	return out
+}
+
+// Text is Lines joined with newlines.
+func (w Workout) Text() string {
+	return strings.Join(w.Lines(), "\n")
+}
+
+var intentNames = map[string]string{
+	IntentWarmup:   "Warm-up",
+	IntentCooldown: "Cool-down",
+	IntentRecovery: "Recovery",
+}
+
+func (s Step) text() string {
+	if s.IsRepeat() {
+		inner := make([]string, len(s.Steps))
+		for i, c := range s.Steps {
+			inner[i] = c.text()
+		}
+		if len(inner) == 1 {
+			return fmt.Sprintf("%d × %s", s.Repeat, inner[0])
+		}
+		return fmt.Sprintf("%d × (%s)", s.Repeat, strings.Join(inner, ", "))
+	}
+
+	var parts []string
+	if name := intentNames[s.Intent]; name != "" {
+		parts = append(parts, name)
+	}
+	parts = append(parts, s.amountText())
+	if t := s.Target; t != nil {
+		parts = append(parts, "@ "+t.Text())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s Step) amountText() string {
+	if s.DurationSec > 0 {
+		switch {
+		case s.DurationSec < 60:
+			return num(s.DurationSec) + " s"
+		case math.Mod(s.DurationSec, 60) == 0:
+			return num(s.DurationSec/60) + " min"
+		}
+		return clock(s.DurationSec)
+	}
+	if s.DistanceM >= 1000 {
+		return num(math.Round(s.DistanceM/10)/100) + " km"
+	}
+	return num(math.Round(s.DistanceM)) + " m"
+}
+
+// Text renders the target range for people, e.g. "Z2-3" or "4:20-4:30/km".
+func (t Target) Text() string {
+	switch t.Kind {
+	case TargetHRZone:
+		return "Z" + span(t.Low, t.High, num)
+	case TargetPace:
+		return t.pace()
+	case TargetPower:
+		return span(t.Low, t.High, num) + " W"
+	case TargetThresholdPct:
+		return span(t.Low, t.High, num) + "% of threshold"
+	case TargetRPE:
+		return "RPE " + span(t.Low, t.High, num)
+	}
+	return ""
+}
+
+// Level places the target on a rough 0-1 intensity scale for charts.
+// Absolute pace and power can't be placed without athlete thresholds and
+// sit mid-scale.
+func (t *Target) Level() float64 {
+	if t == nil {
+		return 0.3
+	}
+	mid := (t.Low + t.High) / 2
+	switch t.Kind {
+	case TargetHRZone:
+		return mid / 5
+	case TargetThresholdPct:
+		return math.Min(1, mid/120)
+	case TargetRPE:
+		return mid / 10
+	}
+	return 0.6
+}
+
+// Bar is one rectangle of a step chart, in chart units.
+type Bar struct {
+	X, Y, W, H float64
+	Intent     string
+	Label      string
+}
+
+// Chart lays the flattened steps out as bars across width x height, each as
+// wide as its estimated duration and as tall as its intensity.
+func (w Workout) Chart(width, height, speed float64) []Bar {
+	steps := w.Flatten()
+	total := w.Seconds(speed)
+	if total <= 0 {
+		return nil
+	}
+	bars := make([]Bar, 0, len(steps))
+	x := 0.0
+	for _, s := range steps {
+		bw := s.StepSeconds(speed) / total * width
+		h := math.Max(0.08, s.Target.Level()) * height
+		bars = append(bars, Bar{
+			X:      x,
+			Y:      height - h,
+			W:      bw,
+			H:      h,
+			Intent: s.Intent,
+			Label:  s.text(),
+		})
+		x += bw
+	}
+	return bars
+}
@@ -0,0 +1,388 @@
+package structured
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The compact syntax, one step or block per comma, semicolon or line:
+//
+//	wu 15min @z2, 3x(10min @threshold, 2min rest @z1), cd 10min
+//	5x(400m @3:30-3:40/km, 200m rest), 2km @95-100%
+//
+// A step is an optional intent (wu, cd, rest, ...), an amount (15min, 90s,
+// 1:30, 400m, 2km) and optional "@target": z2 or z2-3, a named zone
+// (recovery, easy, tempo, threshold, vo2), 4:30/km, 250w, 95-105% of
+// threshold, or rpe 7. "Nx" repeats the next step or parenthesised block.
+
+var intents = map[string]string{
+	"wu": IntentWarmup, "warmup": IntentWarmup, "warm": IntentWarmup,
+	"cd": IntentCooldown, "cooldown": IntentCooldown, "cool": IntentCooldown,
+	"rest": IntentRecovery, "rec": IntentRecovery, "recovery": IntentRecovery, "jog": IntentRecovery, "float": IntentRecovery,
+	"active": IntentActive, "on": IntentActive,
+}
+
+// namedZones map intensity words onto heart-rate zones.
+var namedZones = map[string]float64{
+	"recovery": 1, "easy": 2, "endurance": 2, "aerobic": 2,
+	"tempo": 3, "threshold": 4, "lt": 4, "vo2": 5, "vo2max": 5,
+}
+
+var timeUnits = map[string]float64{
+	"s": 1, "sec": 1, "secs": 1, "second": 1, "seconds": 1,
+	"min": 60, "mins": 60, "minute": 60, "minutes": 60,
+	"h": 3600, "hr": 3600, "hrs": 3600, "hour": 3600, "hours": 3600,
+}
+
+// mile is a mile in metres.
+const mile = 1609.344
+
+var distanceUnits = map[string]float64{
+	"m": 1, "meter": 1, "meters": 1, "metre": 1, "metres": 1,
+	"km": 1000, "mi": mile, "mile": mile, "miles": mile,
+	"yd": 0.9144, "yds": 0.9144,
+}
+
+type tokenKind int
+
+const (
+	tokNum tokenKind = iota
+	tokClock
+	tokWord
+	tokSym
+	tokSep
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// SyntaxError points at the offending part of the input.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("at %d: %s", e.Pos+1, e.Msg)
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == '\n' || r == ',' || r == ';' || r == '+':
+			toks = append(toks, token{kind: tokSep, text: string(r), pos: i})
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || (rs[j] == ':' && j+1 < len(rs) && unicode.IsDigit(rs[j+1]))) {
+				j++
+			}
+			text := string(rs[i:j])
+			if strings.Contains(text, ":") {
+				secs, ok := clockSeconds(text)
+				if !ok {
+					return nil, &SyntaxError{i, fmt.Sprintf("bad time %q", text)}
+				}
+				toks = append(toks, token{kind: tokClock, text: text, num: secs, pos: i})
+			} else {
+				n, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, &SyntaxError{i, fmt.Sprintf("bad number %q", text)}
+				}
+				toks = append(toks, token{kind: tokNum, text: text, num: n, pos: i})
+			}
+			i = j
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(rs) && unicode.IsLetter(rs[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tokWord, text: strings.ToLower(string(rs[i:j])), pos: i})
+			i = j
+		case strings.ContainsRune("()@-%/:×", r):
+			text := string(r)
+			if r == '×' {
+				text = "x"
+			}
+			toks = append(toks, token{kind: tokSym, text: text, pos: i})
+			i++
+		default:
+			return nil, &SyntaxError{i, fmt.Sprintf("unexpected %q", r)}
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+// clockSeconds parses m:ss or h:mm:ss.
+func clockSeconds(v string) (float64, bool) {
+	parts := strings.Split(v, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+	total := 0
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (i > 0 && (len(p) != 2 || n >= 60)) {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return float64(total), true
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isSym(s string) bool {
+	t := p.peek()
+	return (t.kind == tokSym || t.kind == tokWord) && t.text == s
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{p.peek().pos, fmt.Sprintf(format, args...)}
+}
+
+// Parse reads the compact syntax and validates the result.
+func Parse(src string) (Workout, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return Workout{}, err
+	}
+	p := &parser{toks: toks}
+	steps, err := p.seq()
+	if err != nil {
+		return Workout{}, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return Workout{}, p.errorf("unexpected %q", t.text)
+	}
+	w := Workout{Steps: steps}
+	return w, w.Validate()
+}
+
+func (p *parser) seq() ([]Step, error) {
+	var steps []Step
+	for {
+		for p.peek().kind == tokSep {
+			p.next()
+		}
+		if t := p.peek(); t.kind == tokEOF || p.isSym(")") {
+			return steps, nil
+		}
+		s, err := p.item()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+		if t := p.peek(); t.kind != tokSep && t.kind != tokEOF && !p.isSym(")") {
+			return nil, p.errorf("expected a comma or new line before %q", t.text)
+		}
+	}
+}
+
+func (p *parser) item() (Step, error) {
+	// "3x" or "3 x" starts a repeat
+	if t := p.peek(); t.kind == tokNum && p.toks[p.i+1].text == "x" {
+		n := t.num
+		if n != float64(int(n)) || n < 1 {
+			return Step{}, p.errorf("repeat count must be a whole number")
+		}
+		p.next()
+		p.next()
+		block := Step{Repeat: int(n)}
+		if p.isSym("(") {
+			p.next()
+			inner, err := p.seq()
+			if err != nil {
+				return Step{}, err
+			}
+			if !p.isSym(")") {
+				return Step{}, p.errorf("missing )")
+			}
+			p.next()
+			block.Steps = inner
+			return block, nil
+		}
+		s, err := p.step()
+		if err != nil {
+			return Step{}, err
+		}
+		block.Steps = []Step{s}
+		return block, nil
+	}
+	return p.step()
+}
+
+func (p *parser) step() (Step, error) {
+	s := Step{Intent: IntentActive}
+	if t := p.peek(); t.kind == tokWord {
+		intent, ok := intents[t.text]
+		if !ok {
+			return Step{}, p.errorf("unknown step %q", t.text)
+		}
+		s.Intent = intent
+		p.next()
+		if p.isSym(":") {
+			p.next()
+		}
+	}
+
+	if err := p.amount(&s); err != nil {
+		return Step{}, err
+	}
+
+	for {
+		t := p.peek()
+		switch {
+		case p.isSym("@"):
+			p.next()
+			tgt, err := p.target()
+			if err != nil {
+				return Step{}, err
+			}
+			s.Target = &tgt
+		case t.kind == tokWord && intents[t.text] != "":
+			s.Intent = intents[t.text]
+			p.next()
+		case t.kind == tokWord && namedZones[t.text] > 0:
+			z := namedZones[t.text]
+			s.Target = &Target{Kind: TargetHRZone, Low: z, High: z}
+			p.next()
+		default:
+			return s, nil
+		}
+	}
+}
+
+func (p *parser) amount(s *Step) error {
+	t := p.peek()
+	switch t.kind {
+	case tokClock:
+		p.next()
+		s.DurationSec = t.num
+		return nil
+	case tokNum:
+		p.next()
+		u := p.peek()
+		if u.kind != tokWord {
+			return p.errorf("%s needs a unit such as min or km", t.text)
+		}
+		if f, ok := timeUnits[u.text]; ok {
+			s.DurationSec = t.num * f
+		} else if f, ok := distanceUnits[u.text]; ok {
+			s.DistanceM = t.num * f
+		} else {
+			return p.errorf("unknown unit %q", u.text)
+		}
+		p.next()
+		return nil
+	}
+	return p.errorf("expected a duration or distance")
+}
+
+// rangeOf reads "a" or "a-b" of the given token kind.
+func (p *parser) rangeOf(kind tokenKind) (float64, float64, error) {
+	t := p.peek()
+	if t.kind != kind {
+		return 0, 0, p.errorf("expected a number")
+	}
+	p.next()
+	lo, hi := t.num, t.num
+	if p.isSym("-") {
+		p.next()
+		if p.peek().kind == tokWord && p.peek().text == "z" {
+			p.next()
+		}
+		u := p.peek()
+		if u.kind != kind {
+			return 0, 0, p.errorf("expected the end of the range")
+		}
+		p.next()
+		hi = u.num
+	}
+	return lo, hi, nil
+}
+
+func (p *parser) target() (Target, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokWord && t.text == "z":
+		p.next()
+		lo, hi, err := p.rangeOf(tokNum)
+		return Target{Kind: TargetHRZone, Low: lo, High: hi}, err
+	case t.kind == tokWord && t.text == "zone":
+		p.next()
+		lo, hi, err := p.rangeOf(tokNum)
+		return Target{Kind: TargetHRZone, Low: lo, High: hi}, err
+	case t.kind == tokWord && t.text == "rpe":
+		p.next()
+		lo, hi, err := p.rangeOf(tokNum)
+		return Target{Kind: TargetRPE, Low: lo, High: hi}, err
+	case t.kind == tokWord && namedZones[t.text] > 0:
+		p.next()
+		z := namedZones[t.text]
+		return Target{Kind: TargetHRZone, Low: z, High: z}, nil
+	case t.kind == tokClock:
+		lo, hi, err := p.rangeOf(tokClock)
+		if err != nil {
+			return Target{}, err
+		}
+		perMile := false
+		if p.isSym("/") {
+			p.next()
+			switch p.peek().text {
+			case "km":
+			case "mi", "mile":
+				perMile = true
+			default:
+				return Target{}, p.errorf("pace must be per km or mi")
+			}
+			p.next()
+		}
+		if perMile {
+			lo, hi = lo*1000/mile, hi*1000/mile
+		}
+		return Target{Kind: TargetPace, Low: lo, High: hi, PerMile: perMile}, nil
+	case t.kind == tokNum:
+		lo, hi, err := p.rangeOf(tokNum)
+		if err != nil {
+			return Target{}, err
+		}
+		switch {
+		case p.isSym("w"):
+			p.next()
+			return Target{Kind: TargetPower, Low: lo, High: hi}, nil
+		case p.isSym("%"):
+			p.next()
+			if u := p.peek(); u.kind == tokWord && (u.text == "ftp" || u.text == "threshold") {
+				p.next()
+			}
+			return Target{Kind: TargetThresholdPct, Low: lo, High: hi}, nil
+		}
+		return Target{}, p.errorf("target needs a unit: w or %%")
+	}
+	return Target{}, p.errorf("unknown target %q", t.text)
+}
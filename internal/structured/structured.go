@@ -0,0 +1,232 @@
+// Package structured describes a workout as nested steps and repeat blocks
+// with measurable targets, so planned sessions can be validated, rendered
+// and exported to devices instead of living in free text.
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Step intents, matching the phases devices know about.
+const (
+	IntentWarmup   = "warmup"
+	IntentActive   = "active"
+	IntentRecovery = "recovery"
+	IntentCooldown = "cooldown"
+)
+
+// Target kinds. Ranges are inclusive; Low == High is a single value.
+const (
+	TargetHRZone       = "hr_zone"       // zones 1-5
+	TargetPace         = "pace"          // seconds per km; Low is the faster end
+	TargetPower        = "power"         // watts
+	TargetThresholdPct = "threshold_pct" // % of FTP, or of threshold pace for sports without power
+	TargetRPE          = "rpe"           // 1-10
+)
+
+// Limits enforced by Validate.
+const (
+	MaxRepeat = 50
+	MaxDepth  = 3
+	MaxSteps  = 200 // after expanding repeats
+)
+
+// Workout is the structure stored as JSON on a planned workout.
+type Workout struct {
+	Steps []Step `json:"steps"`
+}
+
+// Step is either a single interval (Duration or Distance set) or, when
+// Repeat is set, a block of Steps done Repeat times.
+type Step struct {
+	Intent      string  `json:"intent,omitempty"`
+	DurationSec float64 `json:"duration_sec,omitempty"`
+	DistanceM   float64 `json:"distance_m,omitempty"`
+	Target      *Target `json:"target,omitempty"`
+	Repeat      int     `json:"repeat,omitempty"`
+	Steps       []Step  `json:"steps,omitempty"`
+}
+
+// Target is the intensity range for a step. Paces are seconds per km;
+// PerMile records that one was written per mile, so it is shown that way.
+type Target struct {
+	Kind    string  `json:"kind"`
+	Low     float64 `json:"low"`
+	High    float64 `json:"high"`
+	PerMile bool    `json:"per_mile,omitempty"`
+}
+
+// IsRepeat reports whether the step is a repeat block.
+func (s Step) IsRepeat() bool { return s.Repeat > 0 }
+
+// Decode reads the stored JSON form and validates it.
+func Decode(b []byte) (Workout, error) {
+	var w Workout
+	if err := json.Unmarshal(b, &w); err != nil {
+		return Workout{}, fmt.Errorf("decode workout: %w", err)
+	}
+	return w, w.Validate()
+}
+
+// Encode returns the JSON stored on the planned workout.
+func (w Workout) Encode() ([]byte, error) {
+	return json.Marshal(w)
+}
+
+// Validate checks the structure is something a device could execute.
+func (w Workout) Validate() error {
+	if len(w.Steps) == 0 {
+		return errors.New("workout has no steps")
+	}
+	n, err := validateSteps(w.Steps, 1)
+	if err != nil {
+		return err
+	}
+	if n > MaxSteps {
+		return fmt.Errorf("workout expands to %d steps, more than %d", n, MaxSteps)
+	}
+	return nil
+}
+
+// validateSteps returns the number of steps after expanding repeats.
+func validateSteps(steps []Step, depth int) (int, error) {
+	total := 0
+	for i, s := range steps {
+		if s.IsRepeat() {
+			if depth >= MaxDepth {
+				return 0, fmt.Errorf("repeats nested more than %d deep", MaxDepth-1)
+			}
+			if s.Repeat > MaxRepeat {
+				return 0, fmt.Errorf("step %d: repeat %d is more than %d", i+1, s.Repeat, MaxRepeat)
+			}
+			if len(s.Steps) == 0 {
+				return 0, fmt.Errorf("step %d: empty repeat block", i+1)
+			}
+			if s.DurationSec != 0 || s.DistanceM != 0 || s.Target != nil {
+				return 0, fmt.Errorf("step %d: a repeat block can't have its own duration or target", i+1)
+			}
+			n, err := validateSteps(s.Steps, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			total += n * s.Repeat
+			continue
+		}
+
+		if len(s.Steps) > 0 {
+			return 0, fmt.Errorf("step %d: nested steps need a repeat count", i+1)
+		}
+		switch s.Intent {
+		case "", IntentWarmup, IntentActive, IntentRecovery, IntentCooldown:
+		default:
+			return 0, fmt.Errorf("step %d: unknown intent %q", i+1, s.Intent)
+		}
+		if (s.DurationSec > 0) == (s.DistanceM > 0) {
+			return 0, fmt.Errorf("step %d: needs either a duration or a distance", i+1)
+		}
+		if s.DurationSec < 0 || s.DistanceM < 0 || s.DurationSec > 24*3600 || s.DistanceM > 500_000 {
+			return 0, fmt.Errorf("step %d: duration or distance out of range", i+1)
+		}
+		if s.Target != nil {
+			if err := s.Target.validate(); err != nil {
+				return 0, fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+		total++
+	}
+	return total, nil
+}
+
+func (t Target) validate() error {
+	var lo, hi float64
+	switch t.Kind {
+	case TargetHRZone:
+		lo, hi = 1, 5
+	case TargetPace:
+		lo, hi = 120, 1200
+	case TargetPower:
+		lo, hi = 1, 2000
+	case TargetThresholdPct:
+		lo, hi = 30, 200
+	case TargetRPE:
+		lo, hi = 1, 10
+	default:
+		return fmt.Errorf("unknown target %q", t.Kind)
+	}
+	if t.Low < lo || t.High > hi {
+		return fmt.Errorf("%s target must be within %g-%g", t.Kind, lo, hi)
+	}
+	if t.Low > t.High {
+		return fmt.Errorf("%s target range is reversed", t.Kind)
+	}
+	return nil
+}
+
+// Flatten expands repeats into the sequence of steps actually performed.
+func (w Workout) Flatten() []Step {
+	return flatten(w.Steps)
+}
+
+func flatten(steps []Step) []Step {
+	var out []Step
+	for _, s := range steps {
+		if !s.IsRepeat() {
+			out = append(out, s)
+			continue
+		}
+		inner := flatten(s.Steps)
+		for i := 0; i < s.Repeat; i++ {
+			out = append(out, inner...)
+		}
+	}
+	return out
+}
+
+// TypicalSpeed is a rough m/s used to estimate how long distance-based
+// steps take when the step has no pace target.
+func TypicalSpeed(sport string) float64 {
+	s := strings.ToLower(sport)
+	switch {
+	case strings.HasSuffix(s, "ride"):
+		return 8
+	case strings.Contains(s, "swim"):
+		return 0.8
+	}
+	return 3 // about 5:30 per km
+}
+
+// StepSeconds estimates how long a single step takes.
+func (s Step) StepSeconds(speed float64) float64 {
+	if s.DurationSec > 0 {
+		return s.DurationSec
+	}
+	if t := s.Target; t != nil && t.Kind == TargetPace {
+		return s.DistanceM / 1000 * (t.Low + t.High) / 2
+	}
+	if speed <= 0 {
+		return 0
+	}
+	return s.DistanceM / speed
+}
+
+// Seconds estimates the total duration, with distance steps timed at their
+// pace target or at speed m/s.
+func (w Workout) Seconds(speed float64) float64 {
+	var total float64
+	for _, s := range w.Flatten() {
+		total += s.StepSeconds(speed)
+	}
+	return total
+}
+
+// Meters is the total distance of distance-based steps.
+func (w Workout) Meters() float64 {
+	var total float64
+	for _, s := range w.Flatten() {
+		total += s.DistanceM
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package structured
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	w, err := Parse("wu 15min @z2, 3x(10min @threshold, 2min rest @z1), cd 10min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Workout{Steps: []Step{
+		{Intent: IntentWarmup, DurationSec: 900, Target: &Target{Kind: TargetHRZone, Low: 2, High: 2}},
+		{Repeat: 3, Steps: []Step{
+			{Intent: IntentActive, DurationSec: 600, Target: &Target{Kind: TargetHRZone, Low: 4, High: 4}},
+			{Intent: IntentRecovery, DurationSec: 120, Target: &Target{Kind: TargetHRZone, Low: 1, High: 1}},
+		}},
+		{Intent: IntentCooldown, DurationSec: 600},
+	}}
+	if !reflect.DeepEqual(w, want) {
+		t.Fatalf("got %+v", w)
+	}
+	if got := w.Seconds(3); got != 900+3*720+600 {
+		t.Fatalf("seconds = %v", got)
+	}
+}
+
+func TestParse_Targets(t *testing.T) {
+	cases := map[string]Target{
+		"1km @4:20-4:30/km": {Kind: TargetPace, Low: 260, High: 270},
+		"20min @240-260w":   {Kind: TargetPower, Low: 240, High: 260},
+		"8min @95-105% ftp": {Kind: TargetThresholdPct, Low: 95, High: 105},
+		"30min @rpe 6":      {Kind: TargetRPE, Low: 6, High: 6},
+		"1h @z2-z3":         {Kind: TargetHRZone, Low: 2, High: 3},
+	}
+	for src, want := range cases {
+		w, err := Parse(src)
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if got := *w.Steps[0].Target; got != want {
+			t.Fatalf("%q: target %+v, want %+v", src, got, want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"10 @z2",
+		"10min @z7",
+		"3x(10min",
+		"60x1min",
+		"2x(2x(2x(1min)))",
+		"10min @4:30-4:20/km",
+		"fast 10min",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("%q: expected an error", src)
+		}
+	}
+}
+
+func TestDSL_RoundTrip(t *testing.T) {
+	src := "wu 2km @easy\n5x(400m @3:30-3:40/km, 90s rest)\n3x(1mi @6:50-7:05/mi)\n20min @88-94%\ncd 1:30"
+	w, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := Parse(w.DSL())
+	if err != nil {
+		t.Fatalf("re-parse %q: %v", w.DSL(), err)
+	}
+	if !reflect.DeepEqual(w, back) {
+		t.Fatalf("round trip changed the workout:\n%s", w.DSL())
+	}
+
+	b, err := w.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := Decode(b)
+	if err != nil || !reflect.DeepEqual(w, dec) {
+		t.Fatalf("json round trip: %v", err)
+	}
+
+	text := w.Text()
+	if !strings.Contains(text, "5 × (400 m @ 3:30-3:40/km, Recovery 1:30)") {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+}
+
+func TestChart(t *testing.T) {
+	w, _ := Parse("10min @z1, 3x(5min @z5, 5min @z1)")
+	bars := w.Chart(400, 100, 3)
+	if len(bars) != 7 {
+		t.Fatalf("bars = %d, want 7", len(bars))
+	}
+	last := bars[len(bars)-1]
+	if math.Abs(last.X+last.W-400) > 1e-9 {
+		t.Fatalf("bars should fill the width, end at %v", last.X+last.W)
+	}
+	if bars[1].H <= bars[0].H {
+		t.Fatalf("z5 bar should be taller than z1")
+	}
+}
@@ -0,0 +1,124 @@
+package training
+
+import "fmt"
+
+// Alert kinds, stored in athlete_alert.kind.
+const (
+	AlertACWR        = "acwr"
+	AlertVolumeJump  = "volume_jump"
+	AlertHardStreak  = "hard_streak"
+	AlertTrainingGap = "training_gap"
+)
+
+// Alert is a rule that fired on the last day of a load series.
+type Alert struct {
+	Kind      string
+	Value     float64
+	Threshold float64
+	Message   string
+}
+
+// Rule inspects a daily load series ending today.
+type Rule interface {
+	Check(days []DailyLoad) (Alert, bool)
+}
+
+// Evaluate runs every rule against the series and returns those that fired.
+func Evaluate(days []DailyLoad, rules []Rule) []Alert {
+	var alerts []Alert
+	for _, r := range rules {
+		if a, ok := r.Check(days); ok {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
+
+// ACWRRule fires when the acute:chronic workload ratio exceeds Max.
+type ACWRRule struct {
+	Max float64
+}
+
+func (r ACWRRule) Check(days []DailyLoad) (Alert, bool) {
+	ratio, ok := ACWR(days)
+	if !ok || ratio <= r.Max {
+		return Alert{}, false
+	}
+	return Alert{
+		Kind:      AlertACWR,
+		Value:     ratio,
+		Threshold: r.Max,
+		Message:   fmt.Sprintf("Acute:chronic workload ratio is %.2f (limit %.2f).", ratio, r.Max),
+	}, true
+}
+
+// VolumeJumpRule fires when the last 7 days carry more than MaxPct percent
+// more load than the 7 days before them.
+type VolumeJumpRule struct {
+	MaxPct float64
+}
+
+func (r VolumeJumpRule) Check(days []DailyLoad) (Alert, bool) {
+	if len(days) < 14 {
+		return Alert{}, false
+	}
+	this := sumLoad(days, 7)
+	prev := sumLoad(days[:len(days)-7], 7)
+	if prev == 0 {
+		return Alert{}, false
+	}
+	pct := (this - prev) / prev * 100
+	if pct <= r.MaxPct {
+		return Alert{}, false
+	}
+	return Alert{
+		Kind:      AlertVolumeJump,
+		Value:     pct,
+		Threshold: r.MaxPct,
+		Message:   fmt.Sprintf("Weekly load is up %.0f%% on the previous week (limit %.0f%%).", pct, r.MaxPct),
+	}, true
+}
+
+// HardStreakRule fires when more than MaxDays consecutive days, ending
+// today, included a high-intensity session.
+type HardStreakRule struct {
+	MaxDays int
+}
+
+func (r HardStreakRule) Check(days []DailyLoad) (Alert, bool) {
+	streak := 0
+	for i := len(days) - 1; i >= 0 && days[i].Hard; i-- {
+		streak++
+	}
+	if streak <= r.MaxDays {
+		return Alert{}, false
+	}
+	return Alert{
+		Kind:      AlertHardStreak,
+		Value:     float64(streak),
+		Threshold: float64(r.MaxDays),
+		Message:   fmt.Sprintf("%d high-intensity days in a row (limit %d).", streak, r.MaxDays),
+	}, true
+}
+
+// GapRule fires when the athlete has not trained for more than MaxDays days.
+// Series with no training at all never fire, so new athletes stay quiet.
+type GapRule struct {
+	MaxDays int
+}
+
+func (r GapRule) Check(days []DailyLoad) (Alert, bool) {
+	gap := 0
+	for i := len(days) - 1; i >= 0 && days[i].Load == 0; i-- {
+		gap++
+	}
+	if gap == len(days) || gap <= r.MaxDays {
+		return Alert{}, false
+	}
+	return Alert{
+		Kind:      AlertTrainingGap,
+		Value:     float64(gap),
+		Threshold: float64(r.MaxDays),
+		Message:   fmt.Sprintf("No training recorded for %d days (limit %d).", gap, r.MaxDays),
+	}, true
+}
@@ -0,0 +1,197 @@
+// Package training turns synced workouts into daily load series and the
+// derived numbers coaches plan around.
+package training
+
+import (
+	"math"
+	"time"
+)
+
+// HeartRate holds the athlete values TRIMP is scaled against.
+type HeartRate struct {
+	Max  int
+	Rest int
+}
+
+// DefaultHeartRate is used until the coach records the athlete's own values.
+var DefaultHeartRate = HeartRate{Max: 190, Rest: 60}
+
+// HeartRateFor fills unset (zero) values from DefaultHeartRate.
+func HeartRateFor(maxHR, restHR int) HeartRate {
+	hr := DefaultHeartRate
+	if maxHR > 0 {
+		hr.Max = maxHR
+	}
+	if restHR > 0 {
+		hr.Rest = restHR
+	}
+	return hr
+}
+
+// Location resolves an athlete's tz, falling back to UTC when it is invalid.
+func Location(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// zoneReserves are the heart-rate reserve fractions separating the five
+// training zones (Karvonen).
+var zoneReserves = []float64{0.6, 0.7, 0.8, 0.9}
+
+// ZoneBounds returns the bpm at the top of zones 1 to 4; anything above the
+// last bound is zone 5.
+func (hr HeartRate) ZoneBounds() []float64 {
+	if hr.Max <= hr.Rest {
+		hr = DefaultHeartRate
+	}
+	bounds := make([]float64, len(zoneReserves))
+	for i, r := range zoneReserves {
+		bounds[i] = float64(hr.Rest) + r*float64(hr.Max-hr.Rest)
+	}
+	return bounds
+}
+
+// hardReserve is the fraction of heart-rate reserve above which a session
+// counts as high intensity.
+const hardReserve = 0.8
+
+// assumedReserve stands in for sessions recorded without heart rate, roughly
+// an easy aerobic effort.
+const assumedReserve = 0.6
+
+// reserve returns the fraction of heart-rate reserve used at avgHR, clamped
+// to [0, 1].
+func (hr HeartRate) reserve(avgHR int) float64 {
+	if hr.Max <= hr.Rest {
+		hr = DefaultHeartRate
+	}
+	r := float64(avgHR-hr.Rest) / float64(hr.Max-hr.Rest)
+	return math.Max(0, math.Min(1, r))
+}
+
+// TRIMP is Banister's training impulse for a session. When avgHR is zero the
+// session is scored as an easy aerobic effort of the same duration.
+func TRIMP(durationSec, avgHR int, hr HeartRate) float64 {
+	r := assumedReserve
+	if avgHR > 0 {
+		r = hr.reserve(avgHR)
+	}
+	minutes := float64(durationSec) / 60
+	return minutes * r * 0.64 * math.Exp(1.92*r)
+}
+
+// thresholdReserve approximates lactate threshold as a share of HR reserve.
+const thresholdReserve = 0.85
+
+// HardIF is the intensity factor above which a session counts as high
+// intensity.
+const HardIF = 0.85
+
+// HRTSS scales TRIMP so an hour at threshold heart rate scores 100, putting
+// heart-rate-only sessions on the same scale as power and pace TSS.
+func HRTSS(durationSec, avgHR int, hr HeartRate) float64 {
+	hour := 60 * thresholdReserve * 0.64 * math.Exp(1.92*thresholdReserve)
+	return TRIMP(durationSec, avgHR, hr) / hour * 100
+}
+
+// IsHard reports whether a session at avgHR counts as high intensity.
+func IsHard(avgHR int, hr HeartRate) bool {
+	return avgHR > 0 && hr.reserve(avgHR) >= hardReserve
+}
+
+// Session is the slice of a workout the load series needs.
+type Session struct {
+	Start time.Time
+	Load  float64
+	Hard  bool
+}
+
+// DailyLoad is one calendar day in the athlete's time zone.
+type DailyLoad struct {
+	Day  time.Time // local midnight
+	Load float64
+	Hard bool
+}
+
+// Day truncates t to local midnight in loc.
+func Day(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// WeekStart returns local midnight of the Monday starting t's week.
+func WeekStart(t time.Time, loc *time.Location) time.Time {
+	d := Day(t, loc)
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// DailySeries buckets sessions into consecutive local days from..to
+// inclusive. Days without training are present with zero load.
+func DailySeries(sessions []Session, loc *time.Location, from, to time.Time) []DailyLoad {
+	from, to = Day(from, loc), Day(to, loc)
+	if to.Before(from) {
+		return nil
+	}
+	var days []DailyLoad
+	index := make(map[string]int)
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		index[d.Format(time.DateOnly)] = len(days)
+		days = append(days, DailyLoad{Day: d})
+	}
+	for _, s := range sessions {
+		i, ok := index[Day(s.Start, loc).Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		days[i].Load += s.Load
+		days[i].Hard = days[i].Hard || s.Hard
+	}
+	return days
+}
+
+// sumLoad adds the load of the last n days of the series.
+func sumLoad(days []DailyLoad, n int) float64 {
+	if n > len(days) {
+		n = len(days)
+	}
+	var sum float64
+	for _, d := range days[len(days)-n:] {
+		sum += d.Load
+	}
+	return sum
+}
+
+// ACWR is the acute:chronic workload ratio at the end of the series, using
+// rolling 7-day and 28-day average daily load. It returns false when the
+// series is shorter than 28 days or there is no chronic load to compare to.
+func ACWR(days []DailyLoad) (float64, bool) {
+	if len(days) < 28 {
+		return 0, false
+	}
+	chronic := sumLoad(days, 28) / 28
+	if chronic == 0 {
+		return 0, false
+	}
+	acute := sumLoad(days, 7) / 7
+	return acute / chronic, true
+}
+
+// ctlDays is the time constant of chronic training load.
+const ctlDays = 42
+
+// CTL returns chronic training load ("fitness") at the end of each day of
+// the series: an exponentially weighted average of daily load with a 42-day
+// time constant, seeded with start.
+func CTL(days []DailyLoad, start float64) []float64 {
+	out := make([]float64, len(days))
+	ctl := start
+	for i, d := range days {
+		ctl += (d.Load - ctl) / ctlDays
+		out[i] = ctl
+	}
+	return out
+}
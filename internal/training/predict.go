@@ -0,0 +1,237 @@
+package training
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RaceDistance is a named target distance in metres.
+type RaceDistance struct {
+	Name   string
+	Meters float64
+}
+
+// RaceDistances are the standard targets shown on the predictions page.
+var RaceDistances = []RaceDistance{
+	{Name: "5K", Meters: 5000},
+	{Name: "10K", Meters: 10000},
+	{Name: "Half marathon", Meters: 21097.5},
+	{Name: "Marathon", Meters: 42195},
+}
+
+// minEffortMeters keeps strides and warm-up jogs out of the predictions.
+const minEffortMeters = 1500
+
+// riegelExponent is the fatigue factor from Riegel's 1977 paper.
+const riegelExponent = 1.06
+
+// Effort is a completed run used as evidence of current fitness.
+type Effort struct {
+	Start     time.Time
+	DistanceM float64
+	Seconds   float64
+	AvgHR     int
+}
+
+// Riegel predicts the time for d2 metres from a performance of t1 seconds
+// over d1 metres.
+func Riegel(d1, t1, d2 float64) float64 {
+	return t1 * math.Pow(d2/d1, riegelExponent)
+}
+
+// vo2Cost is Daniels' oxygen cost of running at v metres per minute.
+func vo2Cost(v float64) float64 {
+	return -4.60 + 0.182258*v + 0.000104*v*v
+}
+
+// fractionSustained is Daniels' fraction of VO2max sustainable for a race of
+// the given length in minutes.
+func fractionSustained(minutes float64) float64 {
+	return 0.8 + 0.1894393*math.Exp(-0.012778*minutes) + 0.2989558*math.Exp(-0.1932605*minutes)
+}
+
+// VDOT is Daniels' and Gilbert's performance index for a race effort; it
+// reproduces the published VDOT tables.
+func VDOT(distanceM, seconds float64) float64 {
+	if distanceM <= 0 || seconds <= 0 {
+		return 0
+	}
+	minutes := seconds / 60
+	return vo2Cost(distanceM/minutes) / fractionSustained(minutes)
+}
+
+// VDOTTime is the equivalent race time in seconds for distanceM at the given
+// VDOT, i.e. a row lookup in the VDOT tables.
+func VDOTTime(vdot, distanceM float64) float64 {
+	if vdot <= 0 || distanceM <= 0 {
+		return 0
+	}
+	// VDOT falls as time grows, so bisect between 10 m/s and 0.5 m/s.
+	lo, hi := distanceM/10, distanceM/0.5
+	for i := 0; i < 60; i++ {
+		mid := (lo + hi) / 2
+		if VDOT(distanceM, mid) > vdot {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// EstimateVO2max estimates VO2max (ml/kg/min) from a steady run, assuming
+// the share of heart-rate reserve used matches the share of VO2 reserve
+// (Swain). It returns false when the effort is too easy to extrapolate from.
+func EstimateVO2max(distanceM, seconds float64, avgHR int, hr HeartRate) (float64, bool) {
+	if distanceM < minEffortMeters || seconds <= 0 || avgHR <= 0 {
+		return 0, false
+	}
+	frac := hr.reserve(avgHR)
+	if frac < 0.5 {
+		return 0, false
+	}
+	vo2 := vo2Cost(distanceM / (seconds / 60))
+	return 3.5 + (vo2-3.5)/frac, true
+}
+
+// Confidence levels for a Prediction.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Prediction is the expected race time for one distance.
+type Prediction struct {
+	Distance      RaceDistance
+	Seconds       float64 // blend of the two models below
+	RiegelSeconds float64
+	VDOTSeconds   float64
+	VDOT          float64
+	Source        Effort
+	Confidence    float64 // 0..1
+	Level         string
+}
+
+// Predict uses the highest-VDOT effort as the athlete's current fitness and
+// scores confidence by how close that effort is in distance and time to the
+// target, and by how many other efforts back it up.
+func Predict(efforts []Effort, target RaceDistance, now time.Time) (Prediction, bool) {
+	best, ok := bestEffort(efforts)
+	if !ok {
+		return Prediction{}, false
+	}
+	vdot := VDOT(best.DistanceM, best.Seconds)
+
+	p := Prediction{
+		Distance:      target,
+		RiegelSeconds: Riegel(best.DistanceM, best.Seconds, target.Meters),
+		VDOTSeconds:   VDOTTime(vdot, target.Meters),
+		VDOT:          vdot,
+		Source:        best,
+	}
+	p.Seconds = (p.RiegelSeconds + p.VDOTSeconds) / 2
+
+	ratio := math.Min(best.DistanceM, target.Meters) / math.Max(best.DistanceM, target.Meters)
+	ageDays := now.Sub(best.Start).Hours() / 24
+	support := 0
+	for _, e := range efforts {
+		if e.DistanceM >= minEffortMeters && VDOT(e.DistanceM, e.Seconds) >= vdot*0.97 {
+			support++
+		}
+	}
+	p.Confidence = math.Sqrt(ratio) * math.Exp(-math.Max(0, ageDays)/60) * math.Min(1, 0.5+0.25*float64(support-1))
+	switch {
+	case p.Confidence >= 0.6:
+		p.Level = ConfidenceHigh
+	case p.Confidence >= 0.3:
+		p.Level = ConfidenceMedium
+	default:
+		p.Level = ConfidenceLow
+	}
+	return p, true
+}
+
+func bestEffort(efforts []Effort) (Effort, bool) {
+	var best Effort
+	var bestVDOT float64
+	for _, e := range efforts {
+		if e.DistanceM < minEffortMeters {
+			continue
+		}
+		if v := VDOT(e.DistanceM, e.Seconds); v > bestVDOT {
+			best, bestVDOT = e, v
+		}
+	}
+	return best, bestVDOT > 0
+}
+
+// TrendPoint is one week of a fitness trend.
+type TrendPoint struct {
+	Day    time.Time
+	VDOT   float64 // best VDOT in the trailing window; 0 if none
+	VO2max float64 // median HR-based estimate in the trailing window; 0 if none
+}
+
+// Trend samples fitness weekly from..to, looking back window at each point.
+func Trend(efforts []Effort, hr HeartRate, from, to time.Time, window time.Duration) []TrendPoint {
+	var points []TrendPoint
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 7) {
+		var inWindow []Effort
+		for _, e := range efforts {
+			if e.Start.After(day.Add(-window)) && !e.Start.After(day) {
+				inWindow = append(inWindow, e)
+			}
+		}
+		pt := TrendPoint{Day: day}
+		if best, ok := bestEffort(inWindow); ok {
+			pt.VDOT = VDOT(best.DistanceM, best.Seconds)
+		}
+		pt.VO2max = MedianVO2max(inWindow, hr)
+		points = append(points, pt)
+	}
+	return points
+}
+
+// MedianVO2max is the median of the per-run estimates, or 0 when no run
+// qualifies.
+func MedianVO2max(efforts []Effort, hr HeartRate) float64 {
+	var estimates []float64
+	for _, e := range efforts {
+		if v, ok := EstimateVO2max(e.DistanceM, e.Seconds, e.AvgHR, hr); ok {
+			estimates = append(estimates, v)
+		}
+	}
+	if len(estimates) == 0 {
+		return 0
+	}
+	sort.Float64s(estimates)
+	mid := len(estimates) / 2
+	if len(estimates)%2 == 0 {
+		return (estimates[mid-1] + estimates[mid]) / 2
+	}
+	return estimates[mid]
+}
+
+// VDOTSlope is the least-squares VDOT change per week across the points
+// that have a value.
+func VDOTSlope(points []TrendPoint) float64 {
+	var n, sx, sy, sxx, sxy float64
+	for i, p := range points {
+		if p.VDOT == 0 {
+			continue
+		}
+		x := float64(i)
+		n++
+		sx += x
+		sy += p.VDOT
+		sxx += x * x
+		sxy += x * p.VDOT
+	}
+	den := n*sxx - sx*sx
+	if n < 2 || den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
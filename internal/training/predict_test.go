@@ -0,0 +1,56 @@
+package training
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVDOT_MatchesTables(t *testing.T) {
+	// Daniels' tables: a 20:00 5K is VDOT ~49.8, a 3:10:49 marathon is ~49.8 too.
+	if v := VDOT(5000, 20*60); math.Abs(v-49.8) > 0.2 {
+		t.Fatalf("VDOT(5K, 20:00) = %.2f, want ~49.8", v)
+	}
+	marathon := VDOTTime(VDOT(5000, 20*60), 42195)
+	if math.Abs(marathon-(3*3600+10*60+49)) > 90 {
+		t.Fatalf("VDOT marathon equivalent = %.0fs, want ~3:10:49", marathon)
+	}
+}
+
+func TestRiegel(t *testing.T) {
+	got := Riegel(10000, 40*60, 21097.5)
+	if math.Abs(got-5295) > 5 {
+		t.Fatalf("Riegel 10K 40:00 -> half = %.0fs, want ~5295", got)
+	}
+}
+
+func TestEstimateVO2max(t *testing.T) {
+	hr := HeartRate{Max: 190, Rest: 50}
+	if _, ok := EstimateVO2max(10000, 3600, 110, hr); ok {
+		t.Fatalf("easy effort should not produce an estimate")
+	}
+	v, ok := EstimateVO2max(10000, 45*60, 169, hr)
+	if !ok || v < 45 || v > 60 {
+		t.Fatalf("EstimateVO2max = %.1f ok=%v, want a plausible value", v, ok)
+	}
+}
+
+func TestPredict_Confidence(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	efforts := []Effort{
+		{Start: now.AddDate(0, 0, -3), DistanceM: 10000, Seconds: 42 * 60},
+		{Start: now.AddDate(0, 0, -10), DistanceM: 10000, Seconds: 42*60 + 20},
+		{Start: now.AddDate(0, 0, -5), DistanceM: 800, Seconds: 120}, // too short
+	}
+	near, ok := Predict(efforts, RaceDistance{Name: "10K", Meters: 10000}, now)
+	if !ok || near.Level != ConfidenceHigh {
+		t.Fatalf("expected high confidence for 10K, got %+v", near)
+	}
+	far, _ := Predict(efforts, RaceDistance{Name: "Marathon", Meters: 42195}, now)
+	if far.Confidence >= near.Confidence {
+		t.Fatalf("marathon confidence %.2f should be below 10K %.2f", far.Confidence, near.Confidence)
+	}
+	if _, ok := Predict(nil, RaceDistances[0], now); ok {
+		t.Fatalf("expected no prediction without efforts")
+	}
+}
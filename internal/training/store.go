@@ -0,0 +1,42 @@
+package training
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+)
+
+// DailyLoads loads the athlete's reviewed workouts and buckets them into
+// local days from..to inclusive. Workouts without a stored load are scored
+// with HRTSS.
+func DailyLoads(ctx context.Context, q *db.Queries, athlete db.Athlete, from, to time.Time) ([]DailyLoad, error) {
+	loc := Location(athlete.Tz)
+	hr := HeartRateFor(int(athlete.MaxHr.Int32), int(athlete.RestingHr.Int32))
+	from = Day(from, loc)
+
+	rows, err := q.ListWorkoutLoadsSince(ctx, db.ListWorkoutLoadsSinceParams{
+		AthleteID: athlete.ID,
+		StartedAt: pgtype.Timestamptz{Time: from, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list workout loads: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, r := range rows {
+		load := r.Load.Float64
+		if !r.Load.Valid {
+			load = HRTSS(int(r.DurationSec), int(r.AvgHr.Int32), hr)
+		}
+		sessions = append(sessions, Session{
+			Start: r.StartedAt.Time,
+			Load:  load,
+			Hard:  IsHard(int(r.AvgHr.Int32), hr) || r.IntensityFactor.Float64 >= HardIF,
+		})
+	}
+	return DailySeries(sessions, loc, from, to), nil
+}
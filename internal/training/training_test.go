@@ -0,0 +1,110 @@
+package training
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func series(loads ...float64) []DailyLoad {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	days := make([]DailyLoad, len(loads))
+	for i, l := range loads {
+		days[i] = DailyLoad{Day: start.AddDate(0, 0, i), Load: l}
+	}
+	return days
+}
+
+func repeat(v float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+func TestTRIMP(t *testing.T) {
+	hr := HeartRate{Max: 200, Rest: 50}
+	// 60 min at 50% reserve: 60 * 0.5 * 0.64 * e^0.96
+	got := TRIMP(3600, 125, hr)
+	if got < 50.1 || got > 50.2 {
+		t.Fatalf("TRIMP = %.3f, want ~50.14", got)
+	}
+	if TRIMP(3600, 0, hr) <= 0 {
+		t.Fatalf("expected positive load for session without HR")
+	}
+	// An hour at threshold HR (85% of reserve) is 100 on the TSS scale.
+	if got := HRTSS(3600, 169, HeartRate{Max: 190, Rest: 50}); got < 99.9 || got > 100.1 {
+		t.Fatalf("HRTSS at threshold = %.1f, want ~100", got)
+	}
+	if !IsHard(175, hr) || IsHard(150, hr) || IsHard(0, hr) {
+		t.Fatalf("IsHard threshold wrong")
+	}
+}
+
+func TestDailySeries_LocalDays(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	from := time.Date(2025, 3, 1, 12, 0, 0, 0, berlin)
+	to := time.Date(2025, 3, 3, 12, 0, 0, 0, berlin)
+	sessions := []Session{
+		// 23:30 UTC on Mar 1 is already Mar 2 in Berlin.
+		{Start: time.Date(2025, 3, 1, 23, 30, 0, 0, time.UTC), Load: 10, Hard: true},
+		{Start: time.Date(2025, 3, 2, 9, 0, 0, 0, berlin), Load: 5},
+		{Start: time.Date(2025, 3, 9, 9, 0, 0, 0, berlin), Load: 99},
+	}
+	days := DailySeries(sessions, berlin, from, to)
+	if len(days) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(days))
+	}
+	if days[0].Load != 0 || days[1].Load != 15 || !days[1].Hard || days[2].Load != 0 {
+		t.Fatalf("unexpected series: %+v", days)
+	}
+}
+
+func TestRules(t *testing.T) {
+	steady := series(repeat(50, 28)...)
+	if alerts := Evaluate(steady, []Rule{ACWRRule{Max: 1.5}, VolumeJumpRule{MaxPct: 30}, GapRule{MaxDays: 5}}); len(alerts) != 0 {
+		t.Fatalf("steady training should not alert, got %+v", alerts)
+	}
+
+	ramp := series(append(repeat(30, 21), repeat(100, 7)...)...)
+	a, ok := ACWRRule{Max: 1.5}.Check(ramp)
+	if !ok || a.Kind != AlertACWR {
+		t.Fatalf("expected ACWR alert for ramp")
+	}
+	if _, ok := (VolumeJumpRule{MaxPct: 30}).Check(ramp); !ok {
+		t.Fatalf("expected volume jump alert for ramp")
+	}
+
+	gap := series(append(repeat(40, 20), repeat(0, 8)...)...)
+	if a, ok := (GapRule{MaxDays: 5}).Check(gap); !ok || a.Value != 8 {
+		t.Fatalf("expected 8-day gap alert, got %+v ok=%v", a, ok)
+	}
+	if _, ok := (GapRule{MaxDays: 5}).Check(series(repeat(0, 28)...)); ok {
+		t.Fatalf("athlete with no history should not get a gap alert")
+	}
+
+	hard := series(repeat(60, 5)...)
+	for i := 1; i < len(hard); i++ {
+		hard[i].Hard = true
+	}
+	if a, ok := (HardStreakRule{MaxDays: 3}).Check(hard); !ok || a.Value != 4 {
+		t.Fatalf("expected 4-day hard streak alert, got %+v ok=%v", a, ok)
+	}
+}
+
+func TestCTL(t *testing.T) {
+	ctl := CTL(series(repeat(60, 200)...), 0)
+	if last := ctl[len(ctl)-1]; math.Abs(last-60) > 0.5 {
+		t.Fatalf("CTL should settle at the daily load, got %.2f", last)
+	}
+	if ctl[41] < 37 || ctl[41] > 39 {
+		t.Fatalf("after one time constant CTL should be ~63%% of load, got %.2f", ctl[41])
+	}
+	if got := CTL(series(0), 50); got[0] >= 50 {
+		t.Fatalf("a rest day should lower CTL, got %.2f", got[0])
+	}
+}
@@ -0,0 +1,45 @@
+package wellness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Load builds the athlete's Series for local days from..to inclusive.
+func Load(ctx context.Context, q *db.Queries, athlete db.Athlete, from, to time.Time) ([]Day, error) {
+	loc := training.Location(athlete.Tz)
+	from, to = training.Day(from, loc), training.Day(to, loc)
+
+	days, err := training.DailyLoads(ctx, q, athlete, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rated, err := q.ListSessionRPE(ctx, db.ListSessionRPEParams{
+		AthleteID: athlete.ID,
+		FromTime:  pgtype.Timestamptz{Time: from, Valid: true},
+		ToTime:    pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list session RPE: %w", err)
+	}
+	checkins, err := q.ListWellnessCheckins(ctx, db.ListWellnessCheckinsParams{
+		AthleteID: athlete.ID,
+		FromDay:   Date(from),
+		ToDay:     Date(to),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list check-ins: %w", err)
+	}
+	return Series(days, rated, checkins, loc), nil
+}
+
+// Date is the calendar date of a local day, as check-ins store it.
+func Date(day time.Time) pgtype.Date {
+	return pgtype.Date{Time: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+}
@@ -0,0 +1,155 @@
+// Package wellness is how training felt, in the athlete's words: a session
+// RPE, notes and pain flags on each workout, and a daily check-in on
+// sleep, soreness, stress, mood and resting heart rate. Session RPE times
+// duration is a load that needs no heart rate or power data, and is shown
+// next to the load computed from the workout.
+package wellness
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+// Scales.
+const (
+	MinRPE   = 1 // CR-10: 1 is very easy, 10 maximal
+	MaxRPE   = 10
+	MinScore = 1 // check-in scores, see CheckIn
+	MaxScore = 5
+	MaxNotes = 1000
+)
+
+// PainAreas are the body areas an athlete can flag on a workout.
+var PainAreas = []string{"foot", "ankle", "achilles", "calf", "shin", "knee", "hamstring", "quad", "hip", "back", "shoulder", "other"}
+
+// CheckPain reports the first area that isn't one of PainAreas.
+func CheckPain(areas []string) error {
+	for _, a := range areas {
+		if !slices.Contains(PainAreas, a) {
+			return fmt.Errorf("unknown pain area %q", a)
+		}
+	}
+	return nil
+}
+
+// SessionLoad is Foster's session-RPE load: RPE times minutes.
+func SessionLoad(rpe, durationSec int32) float64 {
+	return float64(rpe) * float64(durationSec) / 60
+}
+
+// ErrEmptyCheckIn is returned for a check-in with nothing filled in.
+var ErrEmptyCheckIn = errors.New("fill in at least one answer")
+
+// CheckEmpty rejects a check-in that records nothing.
+func CheckEmpty(c db.WellnessCheckin) error {
+	if c.SleepHours.Valid || c.SleepQuality.Valid || c.Soreness.Valid || c.Stress.Valid ||
+		c.Mood.Valid || c.RestingHr.Valid || (c.Notes.Valid && c.Notes.String != "") {
+		return nil
+	}
+	return ErrEmptyCheckIn
+}
+
+// Day is one athlete-local day of subjective and objective load.
+type Day struct {
+	Day     time.Time // local midnight
+	Load    float64   // from heart rate or power
+	RPELoad float64   // session RPE × minutes
+	Rated   int       // workouts with an RPE
+	CheckIn *db.WellnessCheckin
+}
+
+// Series lines up the objective daily loads with session-RPE load and the
+// check-ins on the same local days. days decides the range.
+func Series(days []training.DailyLoad, rated []db.ListSessionRPERow, checkins []db.WellnessCheckin, loc *time.Location) []Day {
+	out := make([]Day, len(days))
+	index := make(map[string]int, len(days))
+	for i, d := range days {
+		out[i] = Day{Day: d.Day, Load: d.Load}
+		index[d.Day.Format(time.DateOnly)] = i
+	}
+	for _, r := range rated {
+		i, ok := index[training.Day(r.StartedAt.Time, loc).Format(time.DateOnly)]
+		if !ok {
+			continue
+		}
+		out[i].RPELoad += SessionLoad(r.Rpe, r.DurationSec)
+		out[i].Rated++
+	}
+	for j := range checkins {
+		// Check-in days are calendar dates, stored at UTC midnight.
+		if i, ok := index[checkins[j].Day.Time.Format(time.DateOnly)]; ok {
+			out[i].CheckIn = &checkins[j]
+		}
+	}
+	return out
+}
+
+// Summary totals the loads and averages each check-in answer over the days
+// it was given.
+type Summary struct {
+	Days         int
+	Load         float64
+	RPELoad      float64
+	CheckIns     int
+	SleepHours   float64
+	SleepQuality float64
+	Soreness     float64
+	Stress       float64
+	Mood         float64
+	RestingHR    float64
+}
+
+// Summarize rolls up days. Averages with no answers are zero.
+func Summarize(days []Day) Summary {
+	s := Summary{Days: len(days)}
+	var sleep, quality, soreness, stress, mood, rhr mean
+	for _, d := range days {
+		s.Load += d.Load
+		s.RPELoad += d.RPELoad
+		c := d.CheckIn
+		if c == nil {
+			continue
+		}
+		s.CheckIns++
+		sleep.addFloat(c.SleepHours.Float64, c.SleepHours.Valid)
+		quality.add(c.SleepQuality.Int32, c.SleepQuality.Valid)
+		soreness.add(c.Soreness.Int32, c.Soreness.Valid)
+		stress.add(c.Stress.Int32, c.Stress.Valid)
+		mood.add(c.Mood.Int32, c.Mood.Valid)
+		rhr.add(c.RestingHr.Int32, c.RestingHr.Valid)
+	}
+	s.SleepHours = sleep.value()
+	s.SleepQuality = quality.value()
+	s.Soreness = soreness.value()
+	s.Stress = stress.value()
+	s.Mood = mood.value()
+	s.RestingHR = rhr.value()
+	return s
+}
+
+type mean struct {
+	sum float64
+	n   int
+}
+
+func (m *mean) addFloat(v float64, ok bool) {
+	if ok {
+		m.sum += v
+		m.n++
+	}
+}
+
+func (m *mean) add(v int32, ok bool) { m.addFloat(float64(v), ok) }
+
+func (m mean) value() float64 {
+	if m.n == 0 {
+		return 0
+	}
+	return math.Round(m.sum/float64(m.n)*10) / 10
+}
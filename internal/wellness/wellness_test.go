@@ -0,0 +1,90 @@
+package wellness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/briangreenhill/coachgpt/internal/db"
+	"github.com/briangreenhill/coachgpt/internal/training"
+)
+
+func TestSessionLoad(t *testing.T) {
+	if got := SessionLoad(6, 45*60); got != 270 {
+		t.Fatalf("SessionLoad(6, 45min) = %.1f, want 270", got)
+	}
+}
+
+func TestCheckPain(t *testing.T) {
+	if err := CheckPain([]string{"knee", "calf"}); err != nil {
+		t.Fatalf("known areas: %v", err)
+	}
+	if err := CheckPain([]string{"knee", "elbow"}); err == nil {
+		t.Fatal("accepted an unknown area")
+	}
+}
+
+func TestCheckEmpty(t *testing.T) {
+	if err := CheckEmpty(db.WellnessCheckin{Notes: pgtype.Text{String: "", Valid: true}}); err != ErrEmptyCheckIn {
+		t.Fatalf("blank check-in: err = %v", err)
+	}
+	if err := CheckEmpty(db.WellnessCheckin{Mood: pgtype.Int4{Int32: 4, Valid: true}}); err != nil {
+		t.Fatalf("mood only: %v", err)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	mon := time.Date(2025, 6, 2, 0, 0, 0, 0, loc)
+	tue := mon.AddDate(0, 0, 1)
+	days := []training.DailyLoad{{Day: mon, Load: 50}, {Day: tue, Load: 0}}
+	rated := []db.ListSessionRPERow{
+		// 23:30 Monday in New York is already Tuesday in UTC.
+		{StartedAt: pgtype.Timestamptz{Time: mon.Add(23*time.Hour + 30*time.Minute).UTC(), Valid: true}, DurationSec: 3600, Rpe: 5},
+		{StartedAt: pgtype.Timestamptz{Time: mon.Add(7 * time.Hour).UTC(), Valid: true}, DurationSec: 1800, Rpe: 4},
+		{StartedAt: pgtype.Timestamptz{Time: mon.AddDate(0, 0, -1).UTC(), Valid: true}, DurationSec: 1800, Rpe: 4},
+	}
+	checkins := []db.WellnessCheckin{
+		{Day: pgtype.Date{Time: time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC), Valid: true}, Mood: pgtype.Int4{Int32: 4, Valid: true}},
+	}
+
+	got := Series(days, rated, checkins, loc)
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2", len(got))
+	}
+	if got[0].RPELoad != 420 || got[0].Rated != 2 || got[0].Load != 50 {
+		t.Fatalf("Monday = %+v, want 420 sRPE from 2 workouts", got[0])
+	}
+	if got[0].CheckIn != nil {
+		t.Fatal("Monday picked up Tuesday's check-in")
+	}
+	if got[1].RPELoad != 0 || got[1].CheckIn == nil || got[1].CheckIn.Mood.Int32 != 4 {
+		t.Fatalf("Tuesday = %+v, want the check-in and no sRPE", got[1])
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	days := []Day{
+		{Load: 40, RPELoad: 300, CheckIn: &db.WellnessCheckin{
+			SleepHours: pgtype.Float8{Float64: 7.5, Valid: true},
+			Soreness:   pgtype.Int4{Int32: 2, Valid: true},
+		}},
+		{Load: 10, RPELoad: 100, CheckIn: &db.WellnessCheckin{
+			SleepHours: pgtype.Float8{Float64: 6, Valid: true},
+			Soreness:   pgtype.Int4{Int32: 3, Valid: true},
+			Mood:       pgtype.Int4{Int32: 4, Valid: true},
+		}},
+		{},
+	}
+	s := Summarize(days)
+	if s.Days != 3 || s.CheckIns != 2 || s.Load != 50 || s.RPELoad != 400 {
+		t.Fatalf("totals = %+v", s)
+	}
+	if s.SleepHours != 6.8 || s.Soreness != 2.5 || s.Mood != 4 || s.Stress != 0 {
+		t.Fatalf("averages = %+v", s)
+	}
+}
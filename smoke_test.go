@@ -18,7 +18,6 @@ import (
 	"github.com/briangreenhill/coachgpt/internal/auth"
 	"github.com/briangreenhill/coachgpt/internal/config"
 	"github.com/briangreenhill/coachgpt/internal/db"
-	"github.com/briangreenhill/coachgpt/internal/email"
 	"github.com/briangreenhill/coachgpt/internal/http/routes"
 	"github.com/briangreenhill/coachgpt/internal/jobs"
 	"github.com/google/uuid"
@@ -126,10 +125,10 @@ func TestSmokeTest(t *testing.T) {
 		Sess:   sess,
 		Tmpl:   tmpl,
 		Q:      queries,
+		DB:     pool,
 		Magic:  ml,
 		Invite: inv,
 		Cfg:    cfg,
-		Email:  email.StdoutSender{},
 	})
 
 	// Override Strava endpoints to use mock server
@@ -194,6 +193,12 @@ func TestSmokeTest(t *testing.T) {
 		require.Equal(t, "Test Athlete", athlete.Name)
 		require.Equal(t, athleteEmail, athlete.Email.String)
 
+		// The invite is stored with the athlete, for the worker to send
+		invites, err := queries.ListLatestInvitesByCoach(ctx, pgtype.UUID{Bytes: coach.ID, Valid: true})
+		require.NoError(t, err)
+		require.Len(t, invites, 1)
+		require.Equal(t, athlete.ID, uuid.UUID(invites[0].AthleteID.Bytes))
+
 		// 4. Test Strava OAuth start (verify redirect works)
 		req = httptest.NewRequest("GET", "/oauth/strava/start?aid="+athlete.ID.String(), nil)
 		w = httptest.NewRecorder()